@@ -2,22 +2,30 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // digest timezones must resolve even without system zoneinfo
 
 	"github.com/labstack/echo/v4"
 
+	"github.com/ronak4195/personal-assistant/internal/bot"
 	"github.com/ronak4195/personal-assistant/internal/config"
 	"github.com/ronak4195/personal-assistant/internal/db"
+	"github.com/ronak4195/personal-assistant/internal/extract"
 	"github.com/ronak4195/personal-assistant/internal/handlers"
+	"github.com/ronak4195/personal-assistant/internal/mailer"
 	appmw "github.com/ronak4195/personal-assistant/internal/middleware"
 	"github.com/ronak4195/personal-assistant/internal/repositories"
 	"github.com/ronak4195/personal-assistant/internal/routes"
+	"github.com/ronak4195/personal-assistant/internal/scheduler"
 	"github.com/ronak4195/personal-assistant/internal/services"
+	"github.com/ronak4195/personal-assistant/internal/smtpd"
+	"github.com/ronak4195/personal-assistant/internal/storage"
 
 	echomw "github.com/labstack/echo/v4/middleware"
 )
@@ -47,6 +55,18 @@ func main() {
 	e.HideBanner = true
 	e.HidePort = true
 
+	// Client addresses feed login throttling and the audit log, so forwarded
+	// headers are only believed from configured proxies.
+	if len(cfg.TrustedProxies) > 0 {
+		trust := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+		for _, n := range cfg.TrustedProxies {
+			trust = append(trust, echo.TrustIPRange(n))
+		}
+		e.IPExtractor = echo.ExtractIPFromXFFHeader(trust...)
+	} else {
+		e.IPExtractor = echo.ExtractIPDirect()
+	}
+
 	// Global middleware
 	e.Use(echomw.Recover())
 	e.Use(echomw.Logger())
@@ -69,6 +89,7 @@ func main() {
 			echo.HeaderContentType,
 			echo.HeaderAccept,
 			echo.HeaderAuthorization,
+			appmw.HeaderHouseholdID,
 		},
 	}))
 
@@ -77,32 +98,239 @@ func main() {
 	categoryRepo := repositories.NewCategoryRepository(database)
 	transactionRepo := repositories.NewTransactionRepository(database)
 	reminderRepo := repositories.NewReminderRepository(database)
+	subscriptionDecisionRepo := repositories.NewSubscriptionDecisionRepository(database)
+	insightRepo := repositories.NewInsightRepository(database)
+	digestRepo := repositories.NewDigestRepository(database)
+	sessionRepo := repositories.NewSessionRepository(database)
+	userTokenRepo := repositories.NewUserTokenRepository(database)
+	loginThrottleRepo := repositories.NewLoginThrottleRepository(database)
+	apiTokenRepo := repositories.NewAPITokenRepository(database)
+	exportRepo := repositories.NewExportRepository(database)
+	auditRepo := repositories.NewAuditRepository(database)
+	householdRepo := repositories.NewHouseholdRepository(database)
+	contactRepo := repositories.NewContactRepository(database)
+	splitRepo := repositories.NewSplitRepository(database)
+	tagRepo := repositories.NewTagRepository(database)
+	searchRepo := repositories.NewSearchRepository(database)
+	attachmentRepo := repositories.NewAttachmentRepository(database)
+	botRepo := repositories.NewBotRepository(database)
+	alertTemplateRepo := repositories.NewAlertTemplateRepository(database)
+	draftRepo := repositories.NewDraftRepository(database)
+	inboundEmailRepo := repositories.NewInboundEmailRepository(database)
+
+	for _, r := range []interface{ EnsureIndexes(context.Context) error }{
+		digestRepo, sessionRepo, userTokenRepo, loginThrottleRepo, apiTokenRepo, exportRepo, auditRepo, householdRepo,
+		contactRepo, splitRepo, tagRepo, searchRepo, attachmentRepo, botRepo,
+		alertTemplateRepo, draftRepo, inboundEmailRepo,
+	} {
+		if err := r.EnsureIndexes(ctx); err != nil {
+			log.Fatalf("failed to create indexes: %v", err)
+		}
+	}
+
+	// Mail
+	var mail mailer.Mailer = mailer.NewLogMailer(cfg.MailLogDir, cfg.MailFrom)
+	if cfg.SMTPHost != "" {
+		mail = mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
+
+	// Attachment storage
+	var store storage.Store
+	if cfg.StorageBackend == "s3" {
+		store, err = storage.NewS3Store(storage.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			PathStyle:       cfg.S3PathStyle,
+		})
+	} else {
+		store, err = storage.NewLocalStore(cfg.StorageDir)
+	}
+	if err != nil {
+		log.Fatalf("failed to set up attachment storage: %v", err)
+	}
+
+	// Receipt OCR
+	var ocr extract.OCR
+	if cfg.TesseractPath != "off" {
+		if ocr, err = extract.NewTesseract(cfg.TesseractPath, cfg.OCRLanguages); err != nil {
+			log.Printf("receipt OCR disabled, only PDFs with text can be read: %v", err)
+		}
+	}
 
 	// Services
-	authService := services.NewAuthService(userRepo, cfg.JWTSecret)
-	categoryService := services.NewCategoryService(categoryRepo)
-	transactionService := services.NewTransactionService(transactionRepo, categoryRepo)
-	reportService := services.NewReportService(transactionRepo, categoryRepo)
-	reminderService := services.NewReminderService(reminderRepo)
+	auditService := services.NewAuditService(auditRepo)
+	authService := services.NewAuthService(userRepo, sessionRepo, apiTokenRepo, userTokenRepo, loginThrottleRepo, auditService, mail, services.AuthConfig{
+		JWTSecret:       cfg.JWTSecret,
+		AccessTokenTTL:  cfg.AccessTokenTTL,
+		RefreshTokenTTL: cfg.RefreshTokenTTL,
+		AppURL:          cfg.FrontEndURL,
+		DeletionGrace:   cfg.AccountDeletionGrace,
+		LoginThrottle: services.LoginThrottleConfig{
+			MaxFailures: cfg.LoginMaxFailures,
+			Lockout:     cfg.LoginLockout,
+			MaxLockout:  cfg.LoginMaxLockout,
+		},
+	})
+	categoryService := services.NewCategoryService(categoryRepo, auditService)
+	insightService := services.NewInsightService(insightRepo, transactionRepo, categoryRepo)
+	attachmentService := services.NewAttachmentService(attachmentRepo, transactionRepo, store, int64(cfg.AttachmentMaxBytes), auditService)
+	transactionService := services.NewTransactionService(transactionRepo, categoryRepo, tagRepo, splitRepo, attachmentService, insightService, auditService)
+	reportService := services.NewReportService(transactionRepo, categoryRepo, reminderRepo, tagRepo)
+	statementService := services.NewStatementService(reportService, transactionRepo, categoryRepo)
+	reminderService := services.NewReminderService(reminderRepo, tagRepo, auditService)
+	subscriptionService := services.NewSubscriptionService(transactionRepo, subscriptionDecisionRepo, reminderService)
+	digestService := services.NewDigestService(digestRepo, userRepo, reportService, reminderRepo, mail)
+	householdService := services.NewHouseholdService(householdRepo, userRepo, mail, cfg.FrontEndURL,
+		categoryRepo, transactionRepo, reminderRepo, tagRepo, attachmentService, draftRepo,
+	)
+	tagService := services.NewTagService(tagRepo, transactionRepo, reminderRepo, auditService)
+	searchService := services.NewSearchService(searchRepo, categoryRepo, tagRepo)
+	extractor := extract.New(ocr)
+	receiptService := services.NewReceiptService(attachmentService, extractor)
+	assistantService := services.NewAssistantService(categoryRepo, transactionService, reminderService)
+	alertService := services.NewAlertService(alertTemplateRepo, draftRepo, transactionService)
+	inboundEmailService := services.NewInboundEmailService(inboundEmailRepo, draftRepo, transactionService, attachmentService,
+		store, extractor, cfg.InboundEmailDomain, int64(cfg.InboundEmailMaxBytes))
+	draftService := services.NewDraftService(draftRepo, transactionService, inboundEmailService)
+	splitService := services.NewSplitService(splitRepo, contactRepo, userRepo, householdRepo, transactionService)
+	apiTokenService := services.NewAPITokenService(apiTokenRepo, userRepo, auditService)
+	accountPurgeService := services.NewAccountPurgeService(userRepo,
+		categoryRepo, transactionRepo, reminderRepo, subscriptionDecisionRepo, insightRepo,
+		digestRepo, sessionRepo, userTokenRepo, apiTokenRepo, exportRepo, auditRepo, householdService,
+		contactRepo, splitRepo, tagRepo, attachmentService, botRepo, alertTemplateRepo, draftRepo,
+		inboundEmailService,
+	)
+	accountRepos := services.AccountRepositories{
+		Users:                 userRepo,
+		Categories:            categoryRepo,
+		Tags:                  tagRepo,
+		Transactions:          transactionRepo,
+		Reminders:             reminderRepo,
+		SubscriptionDecisions: subscriptionDecisionRepo,
+		Insights:              insightRepo,
+		Digests:               digestRepo,
+		APITokens:             apiTokenRepo,
+		Contacts:              contactRepo,
+		Splits:                splitRepo,
+		Households:            householdRepo,
+		Attachments:           attachmentRepo,
+		AlertTemplates:        alertTemplateRepo,
+		Drafts:                draftRepo,
+		InboundEmails:         inboundEmailRepo,
+	}
+	exportService := services.NewExportService(exportRepo, accountRepos, attachmentService, inboundEmailService, cfg.JWTSecret)
+	importService := services.NewImportService(exportService, accountRepos, attachmentService, repositories.NewTransactor(dbClient))
 
 	// Handlers
 	authHandler := handlers.NewAuthHandler(authService)
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
-	transactionHandler := handlers.NewTransactionHandler(transactionService)
-	reportHandler := handlers.NewReportHandler(reportService)
-	reminderHandler := handlers.NewReminderHandler(reminderService)
+	transactionHandler := handlers.NewTransactionHandler(transactionService, tagService)
+	reportHandler := handlers.NewReportHandler(reportService, statementService)
+	reminderHandler := handlers.NewReminderHandler(reminderService, tagService)
+	insightHandler := handlers.NewInsightHandler(insightService, subscriptionService)
+	digestHandler := handlers.NewDigestHandler(digestService)
+	apiTokenHandler := handlers.NewAPITokenHandler(apiTokenService)
+	exportHandler := handlers.NewExportHandler(exportService)
+	importHandler := handlers.NewImportHandler(importService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	householdHandler := handlers.NewHouseholdHandler(householdService)
+	splitHandler := handlers.NewSplitHandler(splitService)
+	tagHandler := handlers.NewTagHandler(tagService)
+	searchHandler := handlers.NewSearchHandler(searchService)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, int64(cfg.AttachmentMaxBytes))
+	receiptHandler := handlers.NewReceiptHandler(receiptService)
+	assistantHandler := handlers.NewAssistantHandler(assistantService)
+	alertHandler := handlers.NewAlertHandler(alertService)
+	draftHandler := handlers.NewDraftHandler(draftService)
 
-	// JWT middleware
-	jwtMiddleware := appmw.JWTAuth(cfg.JWTSecret)
+	// Chat bot
+	var botService services.BotService
+	var botHandler *handlers.BotHandler
+	if cfg.BotToken != "" {
+		botService = services.NewBotService(botRepo, bot.NewClient(cfg.BotAPIURL, cfg.BotToken),
+			assistantService, reportService, transactionService, reminderService)
+		botHandler = handlers.NewBotHandler(botService, cfg.BotWebhookSecret)
+	}
+
+	// Inbound email
+	var inboundEmailHandler *handlers.InboundEmailHandler
+	if cfg.InboundEmailDomain != "" {
+		inboundEmailHandler = handlers.NewInboundEmailHandler(inboundEmailService, cfg.InboundWebhookSecret, int64(cfg.InboundEmailMaxBytes))
+	}
+
+	// Auth middleware
+	jwtMiddleware := appmw.JWTAuth(cfg.JWTSecret, authService, apiTokenService)
+	verifiedMiddleware := appmw.RequireVerifiedEmail(cfg.RequireEmailVerification)
 
 	// Routes
 	routes.RegisterV1Routes(e, routes.Handlers{
-		AuthHandler:        authHandler,
-		CategoryHandler:    categoryHandler,
-		TransactionHandler: transactionHandler,
-		ReportHandler:      reportHandler,
-		ReminderHandler:    reminderHandler,
-	}, jwtMiddleware)
+		AuthHandler:         authHandler,
+		CategoryHandler:     categoryHandler,
+		TransactionHandler:  transactionHandler,
+		ReportHandler:       reportHandler,
+		ReminderHandler:     reminderHandler,
+		InsightHandler:      insightHandler,
+		DigestHandler:       digestHandler,
+		APITokenHandler:     apiTokenHandler,
+		ExportHandler:       exportHandler,
+		ImportHandler:       importHandler,
+		AuditHandler:        auditHandler,
+		HouseholdHandler:    householdHandler,
+		SplitHandler:        splitHandler,
+		TagHandler:          tagHandler,
+		SearchHandler:       searchHandler,
+		AttachmentHandler:   attachmentHandler,
+		ReceiptHandler:      receiptHandler,
+		AssistantHandler:    assistantHandler,
+		AlertHandler:        alertHandler,
+		DraftHandler:        draftHandler,
+		BotHandler:          botHandler,
+		InboundEmailHandler: inboundEmailHandler,
+	}, routes.Middleware{
+		JWT:           jwtMiddleware,
+		VerifiedEmail: verifiedMiddleware,
+		Workspace:     appmw.Workspace(householdService),
+	})
+
+	// Background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	jobs := scheduler.New()
+	jobs.Every("digests", time.Minute, digestService.SendDue)
+	jobs.Every("account-purge", time.Hour, accountPurgeService.PurgeDue)
+	jobs.Every("exports", 15*time.Second, exportService.ProcessPending)
+	if botService != nil {
+		jobs.Every("bot-reminders", time.Minute, botService.SendDueReminders)
+	}
+	jobs.Start(jobsCtx)
+
+	// Inbound SMTP listener. Messages it cannot use are refused for good so
+	// the sending server gives up; other failures make it retry later.
+	var smtpServer *smtpd.Server
+	if cfg.InboundSMTPAddr != "" {
+		smtpServer = &smtpd.Server{
+			Addr:      cfg.InboundSMTPAddr,
+			Hostname:  cfg.InboundEmailDomain,
+			MaxSize:   int64(cfg.InboundEmailMaxBytes),
+			Recipient: inboundEmailService.Accepts,
+			Deliver: func(ctx context.Context, _ string, to []string, data []byte) error {
+				_, err := inboundEmailService.Receive(ctx, to, data, services.InboundViaSMTP)
+				if errors.Is(err, services.ErrInvalidEmail) || errors.Is(err, services.ErrInboundMailboxNotFound) ||
+					errors.Is(err, services.ErrInboundEmailTooLarge) {
+					return smtpd.Rejection(err.Error())
+				}
+				return err
+			},
+		}
+		go func() {
+			if err := smtpServer.ListenAndServe(); err != nil && err != smtpd.ErrServerClosed {
+				log.Fatalf("inbound SMTP listener: %v", err)
+			}
+		}()
+		log.Printf("receiving email for %s on %s", cfg.InboundEmailDomain, cfg.InboundSMTPAddr)
+	}
 
 	// Start server with graceful shutdown
 	go func() {
@@ -117,8 +345,16 @@ func main() {
 
 	log.Println("shutting down server...")
 
+	stopJobs()
+	jobs.Wait()
+
 	ctxShutdown, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelShutdown()
+	if smtpServer != nil {
+		if err := smtpServer.Shutdown(ctxShutdown); err != nil {
+			log.Printf("inbound SMTP listener: %v", err)
+		}
+	}
 	if err := e.Shutdown(ctxShutdown); err != nil {
 		log.Fatalf("server forced to shutdown: %v", err)
 	}
@@ -0,0 +1,341 @@
+// Package archive reads and writes account export archives: ZIP files with
+// one JSON document per collection, CSV copies of the tabular data for
+// people, and a manifest carrying the format version. The layout is
+// documented in docs/export-format.md; bump FormatVersion for any change
+// that an older reader could misinterpret.
+package archive
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+)
+
+const (
+	FormatName    = "personal-assistant-export"
+	FormatVersion = 2
+
+	ManifestFile              = "manifest.json"
+	ProfileFile               = "profile.json"
+	CategoriesFile            = "categories.json"
+	TagsFile                  = "tags.json"
+	TransactionsFile          = "transactions.json"
+	RemindersFile             = "reminders.json"
+	SubscriptionDecisionsFile = "subscription_decisions.json"
+	InsightsFile              = "insights.json"
+	DigestsFile               = "digests.json"
+	APITokensFile             = "api_tokens.json"
+	ContactsFile              = "contacts.json"
+	ContactLinksFile          = "contact_links.json"
+	SplitsFile                = "splits.json"
+	SettlementsFile           = "settlements.json"
+	LedgerEntriesFile         = "ledger_entries.json"
+	HouseholdsFile            = "households.json"
+	AttachmentsFile           = "attachments.json"
+	AlertTemplatesFile        = "alert_templates.json"
+	DraftsFile                = "drafts.json"
+	InboundEmailsFile         = "inbound_emails.json"
+	CategoriesCSV             = "csv/categories.csv"
+	TransactionsCSV           = "csv/transactions.csv"
+	RemindersCSV              = "csv/reminders.csv"
+)
+
+var (
+	ErrNotAnArchive       = errors.New("not an export archive")
+	ErrUnsupportedVersion = errors.New("unsupported export format version")
+)
+
+type Manifest struct {
+	Format    string         `json:"format"`
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UserID    string         `json:"userId"`
+	Counts    map[string]int `json:"counts"`
+	Files     []string       `json:"files"`
+}
+
+// Profile is the exported part of the user document. Credentials are never
+// exported.
+type Profile struct {
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"emailVerified"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// Membership is one household the user belongs to.
+type Membership struct {
+	HouseholdID string               `json:"householdId"`
+	Name        string               `json:"name"`
+	Role        models.HouseholdRole `json:"role"`
+	JoinedAt    time.Time            `json:"joinedAt"`
+}
+
+// Blob is a stored file carried in the archive under Path, such as an
+// attachment's content. Write calls Open to copy it in; Read sets Open to
+// read it back out of the archive.
+type Blob struct {
+	Path string
+	Open func() (io.ReadCloser, error)
+}
+
+// Paths of the stored files in an archive.
+func AttachmentPath(id string) string {
+	return "files/attachments/" + id
+}
+
+func InboundEmailPath(id string) string {
+	return "files/inbound_emails/" + id + "/message.eml"
+}
+
+func InboundEmailFilePath(id string, index int) string {
+	return "files/inbound_emails/" + id + "/" + strconv.Itoa(index)
+}
+
+// Contents is everything an archive holds for one user.
+type Contents struct {
+	UserID                string
+	Profile               Profile
+	Categories            []models.Category
+	Tags                  []models.Tag
+	Transactions          []models.Transaction
+	Reminders             []models.Reminder
+	SubscriptionDecisions []models.SubscriptionDecision
+	Insights              []models.Insight
+	Digests               []models.DigestSubscription
+	APITokens             []models.APIToken
+	Contacts              []models.Contact
+	ContactLinks          []models.ContactLink
+	Splits                []models.ExpenseSplit
+	Settlements           []models.Settlement
+	LedgerEntries         []models.LedgerEntry
+	Households            []Membership
+	Attachments           []models.Attachment
+	AlertTemplates        []models.AlertTemplate
+	Drafts                []models.TransactionDraft
+	InboundEmails         []models.InboundEmail
+	Blobs                 []Blob
+}
+
+// Blob returns the stored file at path, if the archive has it.
+func (c *Contents) Blob(path string) (Blob, bool) {
+	for _, b := range c.Blobs {
+		if b.Path == path {
+			return b, true
+		}
+	}
+	return Blob{}, false
+}
+
+func (c *Contents) counts() map[string]int {
+	return map[string]int{
+		"categories":            len(c.Categories),
+		"tags":                  len(c.Tags),
+		"transactions":          len(c.Transactions),
+		"reminders":             len(c.Reminders),
+		"subscriptionDecisions": len(c.SubscriptionDecisions),
+		"insights":              len(c.Insights),
+		"digests":               len(c.Digests),
+		"apiTokens":             len(c.APITokens),
+		"contacts":              len(c.Contacts),
+		"contactLinks":          len(c.ContactLinks),
+		"splits":                len(c.Splits),
+		"settlements":           len(c.Settlements),
+		"ledgerEntries":         len(c.LedgerEntries),
+		"households":            len(c.Households),
+		"attachments":           len(c.Attachments),
+		"alertTemplates":        len(c.AlertTemplates),
+		"drafts":                len(c.Drafts),
+		"inboundEmails":         len(c.InboundEmails),
+		"files":                 len(c.Blobs),
+	}
+}
+
+// Write encodes c as an archive into w and returns its manifest.
+func Write(w io.Writer, c *Contents, createdAt time.Time) (*Manifest, error) {
+	zw := zip.NewWriter(w)
+
+	docs := []struct {
+		name string
+		v    any
+	}{
+		{ProfileFile, c.Profile},
+		{CategoriesFile, nonNil(c.Categories)},
+		{TagsFile, nonNil(c.Tags)},
+		{TransactionsFile, nonNil(c.Transactions)},
+		{RemindersFile, nonNil(c.Reminders)},
+		{SubscriptionDecisionsFile, nonNil(c.SubscriptionDecisions)},
+		{InsightsFile, nonNil(c.Insights)},
+		{DigestsFile, nonNil(c.Digests)},
+		{APITokensFile, nonNil(c.APITokens)},
+		{ContactsFile, nonNil(c.Contacts)},
+		{ContactLinksFile, nonNil(c.ContactLinks)},
+		{SplitsFile, nonNil(c.Splits)},
+		{SettlementsFile, nonNil(c.Settlements)},
+		{LedgerEntriesFile, nonNil(c.LedgerEntries)},
+		{HouseholdsFile, nonNil(c.Households)},
+		{AttachmentsFile, nonNil(c.Attachments)},
+		{AlertTemplatesFile, nonNil(c.AlertTemplates)},
+		{DraftsFile, nonNil(c.Drafts)},
+		{InboundEmailsFile, nonNil(c.InboundEmails)},
+	}
+
+	m := &Manifest{
+		Format:    FormatName,
+		Version:   FormatVersion,
+		CreatedAt: createdAt.UTC(),
+		UserID:    c.UserID,
+		Counts:    c.counts(),
+	}
+	for _, d := range docs {
+		m.Files = append(m.Files, d.name)
+	}
+	m.Files = append(m.Files, CategoriesCSV, TransactionsCSV, RemindersCSV)
+	for _, b := range c.Blobs {
+		m.Files = append(m.Files, b.Path)
+	}
+
+	// The manifest goes first so readers can reject an archive early.
+	if err := writeJSON(zw, ManifestFile, m, createdAt); err != nil {
+		return nil, err
+	}
+	for _, d := range docs {
+		if err := writeJSON(zw, d.name, d.v, createdAt); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeCSVs(zw, c, createdAt); err != nil {
+		return nil, err
+	}
+	for _, b := range c.Blobs {
+		if err := writeBlob(zw, b, createdAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Path, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Read decodes an archive. CSV files are ignored; the JSON documents are
+// authoritative.
+func Read(r io.ReaderAt, size int64) (*Manifest, *Contents, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, ErrNotAnArchive
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var m Manifest
+	if err := readJSON(files, ManifestFile, &m); err != nil {
+		return nil, nil, err
+	}
+	if m.Format != FormatName {
+		return nil, nil, ErrNotAnArchive
+	}
+	if m.Version < 1 || m.Version > FormatVersion {
+		return nil, nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, m.Version)
+	}
+
+	c := &Contents{UserID: m.UserID}
+	targets := []struct {
+		name string
+		v    any
+	}{
+		{ProfileFile, &c.Profile},
+		{CategoriesFile, &c.Categories},
+		{TagsFile, &c.Tags},
+		{TransactionsFile, &c.Transactions},
+		{RemindersFile, &c.Reminders},
+		{SubscriptionDecisionsFile, &c.SubscriptionDecisions},
+		{InsightsFile, &c.Insights},
+		{DigestsFile, &c.Digests},
+		{APITokensFile, &c.APITokens},
+		{ContactsFile, &c.Contacts},
+		{ContactLinksFile, &c.ContactLinks},
+		{SplitsFile, &c.Splits},
+		{SettlementsFile, &c.Settlements},
+		{LedgerEntriesFile, &c.LedgerEntries},
+		{HouseholdsFile, &c.Households},
+		{AttachmentsFile, &c.Attachments},
+		{AlertTemplatesFile, &c.AlertTemplates},
+		{DraftsFile, &c.Drafts},
+		{InboundEmailsFile, &c.InboundEmails},
+	}
+	for _, t := range targets {
+		if err := readJSON(files, t.name, t.v); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, f := range zr.File {
+		if strings.HasPrefix(f.Name, "files/") {
+			c.Blobs = append(c.Blobs, Blob{Path: f.Name, Open: f.Open})
+		}
+	}
+	return &m, c, nil
+}
+
+func writeJSON(zw *zip.Writer, name string, v any, modified time.Time) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeBlob(zw *zip.Writer, b Blob, modified time.Time) error {
+	rc, err := b.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	// Stored files are mostly images and PDFs, which do not compress.
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: b.Path, Method: zip.Store, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, rc)
+	return err
+}
+
+func readJSON(files map[string]*zip.File, name string, v any) error {
+	f, ok := files[name]
+	if !ok {
+		if name == ManifestFile {
+			return ErrNotAnArchive
+		}
+		// Absent collections are empty; a reader must not require every file.
+		return nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// nonNil makes empty collections encode as [] rather than null.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,60 @@
+package archive
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+)
+
+func TestWriteReadBlobs(t *testing.T) {
+	content := []byte("%PDF-1.4 receipt")
+	in := &Contents{
+		UserID:      "user-1",
+		Attachments: []models.Attachment{{ID: "a1", TransactionID: "t1", FileName: "receipt.pdf"}},
+		Blobs: []Blob{{
+			Path: AttachmentPath("a1"),
+			Open: func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(content)), nil },
+		}},
+	}
+	var buf bytes.Buffer
+	m, err := Write(&buf, in, time.Date(2026, 10, 19, 7, 30, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != FormatVersion || m.Counts["attachments"] != 1 || m.Counts["files"] != 1 {
+		t.Errorf("manifest = %+v", m)
+	}
+
+	_, out, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Attachments) != 1 || out.Attachments[0].FileName != "receipt.pdf" {
+		t.Errorf("attachments = %+v", out.Attachments)
+	}
+	b, ok := out.Blob(AttachmentPath("a1"))
+	if !ok {
+		t.Fatalf("blob missing; have %+v", out.Blobs)
+	}
+	rc, err := b.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	got, _ := io.ReadAll(rc)
+	if !bytes.Equal(got, content) {
+		t.Errorf("blob = %q, want %q", got, content)
+	}
+	if _, ok := out.Blob(AttachmentPath("a2")); ok {
+		t.Error("found a blob that was never written")
+	}
+	for _, b := range out.Blobs {
+		if !strings.HasPrefix(b.Path, "files/") {
+			t.Errorf("unexpected blob %s", b.Path)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+)
+
+func writeCSVs(zw *zip.Writer, c *Contents, modified time.Time) error {
+	paths := CategoryPaths(c.Categories)
+	tagNames := make(map[string]string, len(c.Tags))
+	for _, t := range c.Tags {
+		tagNames[t.ID] = t.Name
+	}
+
+	categories := [][]string{{"id", "name", "parent_id", "path"}}
+	for _, cat := range c.Categories {
+		categories = append(categories, []string{cat.ID, cat.Name, deref(cat.ParentID), paths[cat.ID]})
+	}
+
+	transactions := [][]string{{"id", "date", "type", "amount", "currency", "category", "subcategory", "description", "tags"}}
+	for _, t := range c.Transactions {
+		transactions = append(transactions, []string{
+			t.ID,
+			t.Date.UTC().Format(time.RFC3339),
+			string(t.Type),
+			formatAmount(t.Amount),
+			t.Currency,
+			pathOf(paths, t.CategoryID),
+			pathOf(paths, t.SubcategoryID),
+			deref(t.Description),
+			joinTags(tagNames, t.TagIDs),
+		})
+	}
+
+	reminders := [][]string{{"id", "title", "description", "due_at", "repeat", "active", "amount", "currency", "type", "category", "tags"}}
+	for _, r := range c.Reminders {
+		amount := ""
+		if r.Amount != nil {
+			amount = formatAmount(*r.Amount)
+		}
+		typ := ""
+		if r.Type != nil {
+			typ = string(*r.Type)
+		}
+		reminders = append(reminders, []string{
+			r.ID,
+			r.Title,
+			deref(r.Description),
+			r.DueAt.UTC().Format(time.RFC3339),
+			string(r.RepeatInterval),
+			strconv.FormatBool(r.IsActive),
+			amount,
+			deref(r.Currency),
+			typ,
+			pathOf(paths, r.CategoryID),
+			joinTags(tagNames, r.TagIDs),
+		})
+	}
+
+	for _, f := range []struct {
+		name string
+		rows [][]string
+	}{
+		{CategoriesCSV, categories},
+		{TransactionsCSV, transactions},
+		{RemindersCSV, reminders},
+	} {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return err
+		}
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(f.rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CategoryPaths maps category IDs to "Parent / Child" paths.
+func CategoryPaths(categories []models.Category) map[string]string {
+	byID := make(map[string]models.Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+	paths := make(map[string]string, len(categories))
+	for _, c := range categories {
+		var parts []string
+		seen := map[string]bool{}
+		for cur, ok := c, true; ok && !seen[cur.ID]; {
+			seen[cur.ID] = true
+			parts = append([]string{cur.Name}, parts...)
+			if cur.ParentID == nil {
+				break
+			}
+			cur, ok = byID[*cur.ParentID]
+		}
+		paths[c.ID] = strings.Join(parts, " / ")
+	}
+	return paths
+}
+
+func pathOf(paths map[string]string, id *string) string {
+	if id == nil {
+		return ""
+	}
+	return paths[*id]
+}
+
+// joinTags lists the names of the given tags, separated by semicolons.
+func joinTags(names map[string]string, ids []string) string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if n, ok := names[id]; ok {
+			out = append(out, n)
+		}
+	}
+	return strings.Join(out, ";")
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
@@ -0,0 +1,92 @@
+package assistant
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var amountRe = regexp.MustCompile(`(?i)(₹|\$|€|£|¥|\brs\.?|\b(?:inr|usd|eur|gbp|jpy|aud|cad|sgd)\b)?\s*` +
+	`(\d{1,3}(?:,\d{2,3})+(?:\.\d+)?|\d+(?:\.\d+)?)(k\b)?` +
+	`(?:\s*(rs\b\.?|rupees?\b|(?:inr|usd|eur|gbp|jpy|aud|cad|sgd)\b|dollars?\b|bucks\b|euros?\b|pounds?\b|quid\b|yen\b))?`)
+
+// currencyWords maps symbols and words to ISO codes. "$" is taken to be US
+// dollars.
+var currencyWords = map[string]string{
+	"₹": "INR", "rs": "INR", "rs.": "INR", "rupee": "INR", "rupees": "INR",
+	"$": "USD", "dollar": "USD", "dollars": "USD", "bucks": "USD",
+	"€": "EUR", "euro": "EUR", "euros": "EUR",
+	"£": "GBP", "pound": "GBP", "pounds": "GBP", "quid": "GBP",
+	"¥": "JPY", "yen": "JPY",
+}
+
+func currencyCode(s string) string {
+	s = strings.ToLower(s)
+	if code, ok := currencyWords[s]; ok {
+		return code
+	}
+	return strings.ToUpper(s)
+}
+
+// amount takes the first amount with a currency, or else the first number.
+// Any others are offered as alternatives, since "spent 450 on 2 pizzas"
+// has two numbers.
+func (p *parser) amount() {
+	var found [][]int
+	for _, loc := range amountRe.FindAllSubmatchIndex(p.s, -1) {
+		// A number glued to a word, like "3rd" or "a4", is not an amount.
+		if loc[2] < 0 {
+			if r, _ := utf8.DecodeLastRune(p.s[:loc[4]]); loc[4] > 0 && isWordRune(r) {
+				continue
+			}
+		}
+		if loc[1] < len(p.s) && loc[8] < 0 {
+			if r, _ := utf8.DecodeRune(p.s[loc[1]:]); isWordRune(r) {
+				continue
+			}
+		}
+		found = append(found, loc)
+	}
+	if len(found) == 0 {
+		return
+	}
+	pick := 0
+	for i, loc := range found {
+		if loc[2] >= 0 || loc[8] >= 0 {
+			pick = i
+			break
+		}
+	}
+	var others []string
+	for i, loc := range found {
+		if i != pick {
+			others = append(others, strings.TrimSpace(string(p.s[loc[0]:loc[1]])))
+		}
+	}
+
+	m := p.consume(found[pick])
+	v, err := strconv.ParseFloat(strings.ReplaceAll(m[2], ",", ""), 64)
+	if err != nil || v <= 0 {
+		return
+	}
+	if m[3] != "" {
+		v *= 1000
+	}
+	p.in.Amount = &v
+	switch {
+	case m[1] != "":
+		p.in.Currency = currencyCode(strings.TrimSpace(m[1]))
+	case m[4] != "":
+		p.in.Currency = currencyCode(m[4])
+	}
+	if len(others) > 0 {
+		p.in.ambiguous("amount", fmt.Sprintf("several numbers found; took %s as the amount", strconv.FormatFloat(v, 'f', -1, 64)), false, others...)
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,290 @@
+// Package assistant turns short English sentences such as "spent 450 on
+// groceries yesterday with card" or "remind me to pay rent every month on
+// the 1st at 9am" into a transaction or reminder.
+//
+// The grammar is a fixed set of patterns, so the same sentence always gives
+// the same result. Whatever the parser had to guess is reported as an
+// Ambiguity for the user to check before anything is saved.
+package assistant
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+)
+
+type Kind string
+
+const (
+	KindTransaction Kind = "transaction"
+	KindReminder    Kind = "reminder"
+)
+
+// Ambiguity is something the parser could not settle. Blocking ones must be
+// resolved before the intent can be executed.
+type Ambiguity struct {
+	Field    string   `json:"field"`
+	Message  string   `json:"message"`
+	Options  []string `json:"options,omitempty"`
+	Blocking bool     `json:"blocking"`
+}
+
+// Intent is the structured reading of a sentence.
+type Intent struct {
+	// Kind is empty when the sentence was not understood.
+	Kind     Kind                   `json:"kind,omitempty"`
+	Type     models.TransactionType `json:"type,omitempty"`
+	Amount   *float64               `json:"amount,omitempty"`
+	Currency string                 `json:"currency,omitempty"`
+	// Date is the transaction date or the reminder's first due time.
+	Date   time.Time             `json:"date"`
+	Repeat models.RepeatInterval `json:"repeat,omitempty"`
+	// Title is the reminder's text, or what a transaction was for.
+	Title string `json:"title,omitempty"`
+	// Category is the phrase to look up among the user's categories.
+	Category    string      `json:"category,omitempty"`
+	Merchant    string      `json:"merchant,omitempty"`
+	Payment     string      `json:"payment,omitempty"`
+	Ambiguities []Ambiguity `json:"ambiguities"`
+}
+
+// Blocked reports whether a blocking ambiguity is left.
+func (in *Intent) Blocked() bool {
+	for _, a := range in.Ambiguities {
+		if a.Blocking {
+			return true
+		}
+	}
+	return false
+}
+
+func (in *Intent) ambiguous(field, msg string, blocking bool, options ...string) {
+	in.Ambiguities = append(in.Ambiguities, Ambiguity{Field: field, Message: msg, Options: options, Blocking: blocking})
+}
+
+var (
+	reminderRe = regexp.MustCompile(`(?i)^\s*(?:please\s+)?(?:remind(?:\s+me)?|reminder:?|don'?t\s+(?:let\s+me\s+)?forget)(?:\s+(?:to|about|that|of))?\b`)
+	expenseRe  = regexp.MustCompile(`(?i)\b(?:spent|spend|paid|pay|bought|buy|purchased|expense)\b`)
+	incomeRe   = regexp.MustCompile(`(?i)\b(?:received|receive|got|earned|earn|income|credited|refunded)\b`)
+	// incomeHintRe marks income without being part of the grammar, so the
+	// word stays in the text for the category.
+	incomeHintRe = regexp.MustCompile(`(?i)\b(?:salary|refund|paycheck|bonus|dividend|interest)\b`)
+	paymentRe    = regexp.MustCompile(`(?i)\b(?:with|by|using|via|through)\s+(?:my\s+|a\s+|the\s+)?(credit\s+card|debit\s+card|card|cash|upi|paypal|bank\s+transfer|cheque|check|apple\s+pay|google\s+pay|gpay|phonepe|paytm|net\s*banking)\b`)
+	merchantRe   = regexp.MustCompile(`(?i)\b(?:at|from)\s+(.+?)(?:\s+(?:on|for)\b|$)`)
+	categoryRe   = regexp.MustCompile(`(?i)\b(?:on|for)\s+(.+?)(?:\s+(?:at|from)\b|$)`)
+	fillerRe     = regexp.MustCompile(`(?i)^(?:i\s+|i've\s+|we\s+|just\s+|have\s+|has\s+|a\s+|an\s+|the\s+|some\s+|my\s+|on\s+|for\s+|of\s+|to\s+|and\s+)+|(?:\s+(?:on|for|of|at|to|and|from|with|by|every|the|a|an))+$`)
+)
+
+// Parse reads text. now is the current time in the user's time zone; dates
+// in the result are in the same zone.
+func Parse(text string, now time.Time) *Intent {
+	p := &parser{s: []byte(strings.Join(strings.Fields(text), " ")), now: now}
+	in := &Intent{Ambiguities: []Ambiguity{}}
+	p.in = in
+
+	switch {
+	case p.take(reminderRe) != nil:
+		in.Kind = KindReminder
+	case p.take(incomeRe) != nil:
+		in.Kind, in.Type = KindTransaction, models.TransactionTypeIncome
+	case p.take(expenseRe) != nil:
+		in.Kind, in.Type = KindTransaction, models.TransactionTypeExpense
+	}
+
+	p.recurrence()
+	p.clock()
+	p.date()
+	p.amount()
+
+	if m := p.take(paymentRe); m != nil {
+		in.Payment = strings.ToLower(strings.Join(strings.Fields(m[1]), " "))
+	}
+
+	switch in.Kind {
+	case KindReminder:
+		p.finishReminder()
+	case KindTransaction:
+		p.finishTransaction()
+	default:
+		// A bare amount, as in "450 groceries", is taken as spending.
+		if in.Amount == nil {
+			in.ambiguous("kind", "could not tell whether this is a transaction or a reminder", true)
+			return in
+		}
+		in.Kind = KindTransaction
+		in.Type = models.TransactionTypeExpense
+		if incomeHintRe.Match(p.s) {
+			in.Type = models.TransactionTypeIncome
+		}
+		in.ambiguous("type", "no verb such as spent or received; assumed "+string(in.Type), false,
+			string(models.TransactionTypeExpense), string(models.TransactionTypeIncome))
+		p.finishTransaction()
+	}
+	return in
+}
+
+type parser struct {
+	// s is the sentence with every part already understood blanked out.
+	s   []byte
+	now time.Time
+	in  *Intent
+
+	hasDate bool
+	// roll moves a reminder date that has already passed to the next
+	// occurrence, e.g. a week on for "monday".
+	roll     func(time.Time) time.Time
+	hasClock bool
+	hour     int
+	minute   int
+}
+
+// take finds the first match of re, blanks it and returns its submatches.
+func (p *parser) take(re *regexp.Regexp) []string {
+	loc := re.FindSubmatchIndex(p.s)
+	if loc == nil {
+		return nil
+	}
+	return p.consume(loc)
+}
+
+func (p *parser) consume(loc []int) []string {
+	m := make([]string, len(loc)/2)
+	for i := range m {
+		if loc[2*i] >= 0 {
+			m[i] = string(p.s[loc[2*i]:loc[2*i+1]])
+		}
+	}
+	for i := loc[0]; i < loc[1]; i++ {
+		p.s[i] = ' '
+	}
+	return m
+}
+
+// takePhrase is take for a pattern whose first group is a phrase ended by
+// a word that may start the next phrase; that word is left in place.
+func (p *parser) takePhrase(re *regexp.Regexp) string {
+	loc := re.FindSubmatchIndex(p.s)
+	if loc == nil {
+		return ""
+	}
+	return p.consume([]int{loc[0], loc[3], loc[2], loc[3]})[1]
+}
+
+// rest is what is left of the sentence.
+func (p *parser) rest() string {
+	return strings.Join(strings.Fields(string(p.s)), " ")
+}
+
+func (p *parser) finishReminder() {
+	in := p.in
+	if !p.hasDate {
+		in.Date = p.now
+	}
+	if !p.hasClock {
+		p.hour, p.minute = 9, 0
+		in.ambiguous("time", "no time given; assumed 09:00", false)
+	}
+	in.Date = atClock(in.Date, p.hour, p.minute)
+	if !in.Date.After(p.now) {
+		switch {
+		case !p.hasDate:
+			in.Date = in.Date.AddDate(0, 0, 1)
+		case p.roll != nil:
+			in.Date = atClock(p.roll(in.Date), p.hour, p.minute)
+		default:
+			in.ambiguous("date", "the reminder would be due in the past", true)
+		}
+	}
+	if in.Repeat == "" {
+		in.Repeat = models.RepeatNone
+	}
+	if in.Amount != nil {
+		in.Type = models.TransactionTypeExpense
+		if incomeHintRe.Match(p.s) {
+			in.Type = models.TransactionTypeIncome
+		}
+	}
+
+	in.Title = clean(p.rest())
+	in.Category = in.Title
+	if in.Title == "" {
+		in.ambiguous("title", "what should the reminder say?", true)
+		return
+	}
+	in.Title = capitalize(in.Title)
+}
+
+func (p *parser) finishTransaction() {
+	in := p.in
+	if in.Amount == nil {
+		in.ambiguous("amount", "no amount found", true)
+	}
+	if in.Repeat != "" && in.Repeat != models.RepeatNone {
+		in.ambiguous("repeat", "transactions do not repeat; set up a reminder for recurring payments", false)
+		in.Repeat = ""
+	}
+	if !p.hasDate {
+		in.Date = p.now
+	}
+	if p.hasClock {
+		in.Date = atClock(in.Date, p.hour, p.minute)
+	}
+	if in.Date.After(p.now) {
+		in.ambiguous("date", "the date is in the future", false)
+	}
+	if in.Type == models.TransactionTypeExpense && incomeHintRe.Match(p.s) {
+		in.ambiguous("type", "recorded as an expense but mentions income", false,
+			string(models.TransactionTypeExpense), string(models.TransactionTypeIncome))
+	}
+
+	if m := p.takePhrase(merchantRe); m != "" {
+		in.Merchant = clean(m)
+	}
+	if m := p.takePhrase(categoryRe); m != "" {
+		in.Category = clean(m)
+	}
+	if left := clean(p.rest()); left != "" {
+		if in.Category == "" {
+			in.Category = left
+		} else {
+			in.Category = left + " " + in.Category
+		}
+	}
+	switch {
+	case in.Merchant != "" && in.Category != "":
+		if in.Type == models.TransactionTypeIncome {
+			in.Title = in.Category + " from " + in.Merchant
+		} else {
+			in.Title = in.Category + " at " + in.Merchant
+		}
+	case in.Merchant != "":
+		in.Title = in.Merchant
+	default:
+		in.Title = in.Category
+	}
+	in.Title = capitalize(in.Title)
+}
+
+// clean trims connecting words left at either end of a phrase.
+func clean(s string) string {
+	for {
+		t := strings.TrimSpace(fillerRe.ReplaceAllString(strings.TrimSpace(s), ""))
+		t = strings.Trim(t, " ,.;:-")
+		if t == s {
+			return t
+		}
+		s = t
+	}
+}
+
+func capitalize(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	if n == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[n:]
+}
@@ -0,0 +1,116 @@
+package assistant
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+)
+
+// minCategoryScore is the lowest score MatchCategories reports.
+const minCategoryScore = 0.75
+
+type CategoryMatch struct {
+	Category models.Category `json:"category"`
+	Score    float64         `json:"score"`
+}
+
+// MatchCategories scores categories against a phrase such as "groceries"
+// or "electricity bill", best first. Words are compared loosely so plurals
+// and small typos still match.
+func MatchCategories(phrase string, categories []models.Category) []CategoryMatch {
+	words := nameWords(phrase)
+	if len(words) == 0 {
+		return nil
+	}
+	var res []CategoryMatch
+	for _, c := range categories {
+		names := nameWords(c.Name)
+		if len(names) == 0 {
+			continue
+		}
+		best, matched := 0.0, 0
+		for _, n := range names {
+			s := 0.0
+			for _, w := range words {
+				s = max(s, wordSimilarity(w, n))
+			}
+			if s >= minCategoryScore {
+				matched++
+			}
+			best = max(best, s)
+		}
+		// Every word of the name matching beats one word of it matching.
+		score := best * (0.9 + 0.1*float64(matched)/float64(len(names)))
+		if score >= minCategoryScore {
+			res = append(res, CategoryMatch{Category: c, Score: score})
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Score > res[j].Score })
+	return res
+}
+
+// stopWords are skipped when comparing names.
+var stopWords = map[string]bool{"and": true, "the": true, "of": true, "for": true, "on": true, "my": true}
+
+func nameWords(s string) []string {
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !stopWords[w] {
+			words = append(words, stem(w))
+		}
+	}
+	return words
+}
+
+// stem drops plural endings.
+func stem(w string) string {
+	switch {
+	case len(w) > 4 && strings.HasSuffix(w, "ies"):
+		return w[:len(w)-3] + "y"
+	case len(w) > 4 && (strings.HasSuffix(w, "ses") || strings.HasSuffix(w, "xes") || strings.HasSuffix(w, "ches") || strings.HasSuffix(w, "shes")):
+		return w[:len(w)-2]
+	case len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func wordSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	short, long := min(len(ra), len(rb)), max(len(ra), len(rb))
+	// "groc" for "grocery", "elec" for "electricity".
+	if short >= 4 && (strings.HasPrefix(a, b) || strings.HasPrefix(b, a)) {
+		return 0.85
+	}
+	if short < 4 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(long)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,416 @@
+package assistant
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+)
+
+const monthPattern = `(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\.?`
+
+const weekdayPattern = `(sunday|monday|tuesday|wednesday|thursday|friday|saturday)s?`
+
+const countPattern = `(\d+|a|an|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve)`
+
+var (
+	repeatRe        = regexp.MustCompile(`(?i)\b(?:every\s*day|everyday|daily|every\s+week|weekly|every\s+month|monthly|every\s+year|yearly|annually)\b`)
+	repeatWeekdayRe = regexp.MustCompile(`(?i)\b(?:on\s+)?every\s+` + weekdayPattern + `\b`)
+	repeatEveryNRe  = regexp.MustCompile(`(?i)\bevery\s+(?:other|` + countPattern + `)\s+(day|week|month|year)s?\b`)
+
+	clock12Re   = regexp.MustCompile(`(?i)\b(?:at\s+|by\s+)?(\d{1,2})(?:[:.](\d{2}))?\s*(?:(am|pm)\b|(a|p)\.m\.?)`)
+	clock24Re   = regexp.MustCompile(`(?i)\b(?:at|by)\s+(\d{1,2}):(\d{2})\b`)
+	clockWordRe = regexp.MustCompile(`(?i)\b(?:at\s+|in\s+the\s+|this\s+)?(noon|midday|midnight|morning|afternoon|evening|tonight)\b`)
+
+	isoDateRe      = regexp.MustCompile(`\b(?:on\s+)?(\d{4})-(\d{1,2})-(\d{1,2})\b`)
+	numericDateRe  = regexp.MustCompile(`\b(?:on\s+)?(\d{1,2})/(\d{1,2})(?:/(\d{2}|\d{4}))?\b`)
+	dayMonthRe     = regexp.MustCompile(`(?i)\b(?:on\s+)?(?:the\s+)?(\d{1,2})(?:st|nd|rd|th)?\s+(?:of\s+)?` + monthPattern + `(?:,?\s+(\d{4}))?\b`)
+	monthDayRe     = regexp.MustCompile(`(?i)\b(?:on\s+)?` + monthPattern + `\s+(\d{1,2})(?:st|nd|rd|th)?(?:,?\s+(\d{4}))?\b`)
+	twoDaysRe      = regexp.MustCompile(`(?i)\b(?:the\s+)?day\s+(before\s+yesterday|after\s+tomorrow)\b`)
+	dayWordRe      = regexp.MustCompile(`(?i)\b(today|yesterday|tomorrow|tmrw|tmr)\b`)
+	inCountRe      = regexp.MustCompile(`(?i)\bin\s+` + countPattern + `\s+(day|week|month|year)s?\b`)
+	agoRe          = regexp.MustCompile(`(?i)\b` + countPattern + `\s+(day|week|month|year)s?\s+ago\b`)
+	relativeUnitRe = regexp.MustCompile(`(?i)\b(next|last|this)\s+(week|month|year)\b`)
+	weekdayRe      = regexp.MustCompile(`(?i)\b(?:on\s+)?(?:(next|last|this|coming|past)\s+)?` + weekdayPattern + `\b`)
+	dayOfMonthRe   = regexp.MustCompile(`(?i)\b(?:on\s+)?(?:the\s+)?(\d{1,2})(st|nd|rd|th)(?:\s+of\s+(?:the\s+|each\s+|every\s+)?month)?\b`)
+)
+
+var countWords = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+}
+
+func count(s string) int {
+	if n, ok := countWords[strings.ToLower(s)]; ok {
+		return n
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func (p *parser) recurrence() {
+	in := p.in
+	if m := p.take(repeatEveryNRe); m != nil {
+		in.ambiguous("repeat", fmt.Sprintf("%q is not supported; reminders repeat daily, weekly, monthly or yearly", strings.TrimSpace(m[0])), true,
+			string(models.RepeatDaily), string(models.RepeatWeekly), string(models.RepeatMonthly), string(models.RepeatYearly))
+		return
+	}
+	if m := p.take(repeatWeekdayRe); m != nil {
+		in.Repeat = models.RepeatWeekly
+		p.setWeekday("", m[1])
+		return
+	}
+	if m := p.take(repeatRe); m != nil {
+		w := strings.ToLower(strings.Join(strings.Fields(m[0]), ""))
+		switch {
+		case strings.Contains(w, "day") || w == "daily":
+			in.Repeat = models.RepeatDaily
+		case strings.Contains(w, "week"):
+			in.Repeat = models.RepeatWeekly
+		case strings.Contains(w, "month"):
+			in.Repeat = models.RepeatMonthly
+		default:
+			in.Repeat = models.RepeatYearly
+		}
+	}
+}
+
+func (p *parser) clock() {
+	if m := p.take(clock12Re); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		mi, _ := strconv.Atoi(m[2])
+		pm := strings.EqualFold(m[3], "pm") || strings.EqualFold(m[4], "p")
+		if h < 1 || h > 12 || mi > 59 {
+			p.in.ambiguous("time", fmt.Sprintf("%q is not a valid time", strings.TrimSpace(m[0])), true)
+			return
+		}
+		h %= 12
+		if pm {
+			h += 12
+		}
+		p.setClock(h, mi)
+		return
+	}
+	if m := p.take(clock24Re); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		mi, _ := strconv.Atoi(m[2])
+		if h > 23 || mi > 59 {
+			p.in.ambiguous("time", fmt.Sprintf("%q is not a valid time", strings.TrimSpace(m[0])), true)
+			return
+		}
+		p.setClock(h, mi)
+		return
+	}
+	if m := p.take(clockWordRe); m != nil {
+		switch strings.ToLower(m[1]) {
+		case "noon", "midday":
+			p.setClock(12, 0)
+		case "midnight":
+			p.setClock(0, 0)
+		case "morning":
+			p.setClock(9, 0)
+		case "afternoon":
+			p.setClock(15, 0)
+		case "evening":
+			p.setClock(18, 0)
+		case "tonight":
+			p.setClock(20, 0)
+		}
+	}
+}
+
+func (p *parser) setClock(h, m int) {
+	p.hasClock, p.hour, p.minute = true, h, m
+}
+
+// future is whether dates without a direction point ahead: reminders are
+// for later, transactions have already happened.
+func (p *parser) future() bool {
+	return p.in.Kind == KindReminder
+}
+
+func (p *parser) date() {
+	// The recurrence may already have set it, as in "every monday".
+	if p.hasDate {
+		return
+	}
+	for _, f := range []func() bool{p.isoDate, p.numericDate, p.namedDate, p.relativeDate, p.weekday, p.dayOfMonth} {
+		if f() {
+			return
+		}
+	}
+}
+
+func (p *parser) setDate(t time.Time, roll func(time.Time) time.Time) {
+	p.hasDate = true
+	p.in.Date = t
+	p.roll = roll
+}
+
+// day is the given date at the current time of day.
+func (p *parser) day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, p.now.Hour(), p.now.Minute(), 0, 0, p.now.Location())
+}
+
+// validDay builds a date, reporting false for dates like 31 April.
+func (p *parser) validDay(y int, m time.Month, d int) (time.Time, bool) {
+	t := p.day(y, m, d)
+	return t, t.Month() == m && t.Day() == d
+}
+
+func (p *parser) isoDate() bool {
+	m := p.take(isoDateRe)
+	if m == nil {
+		return false
+	}
+	y, _ := strconv.Atoi(m[1])
+	mo, _ := strconv.Atoi(m[2])
+	d, _ := strconv.Atoi(m[3])
+	p.explicitDate(m[0], y, time.Month(mo), d)
+	return true
+}
+
+func (p *parser) numericDate() bool {
+	m := p.take(numericDateRe)
+	if m == nil {
+		return false
+	}
+	a, _ := strconv.Atoi(m[1])
+	b, _ := strconv.Atoi(m[2])
+	y := p.now.Year()
+	if m[3] != "" {
+		y, _ = strconv.Atoi(m[3])
+		if y < 100 {
+			y += 2000
+		}
+	}
+	// Day first, as written outside the US. When both readings are
+	// possible the other one is offered.
+	d, mo := a, b
+	if mo > 12 && d <= 12 {
+		d, mo = mo, d
+	}
+	if m[3] == "" {
+		p.yearlessDate(m[0], time.Month(mo), d)
+	} else {
+		p.explicitDate(m[0], y, time.Month(mo), d)
+	}
+	if a != b && a <= 12 && b <= 12 && p.hasDate {
+		alt := time.Date(p.in.Date.Year(), time.Month(d), mo, 0, 0, 0, 0, time.UTC)
+		p.in.ambiguous("date", fmt.Sprintf("%q could be day/month or month/day; read as day/month", strings.TrimSpace(m[0])), false,
+			p.in.Date.Format("2006-01-02"), alt.Format("2006-01-02"))
+	}
+	return true
+}
+
+func (p *parser) namedDate() bool {
+	var d int
+	var mo time.Month
+	var year string
+	if m := p.take(dayMonthRe); m != nil {
+		d, _ = strconv.Atoi(m[1])
+		mo, year = monthNumber(m[2]), m[3]
+	} else if m := p.take(monthDayRe); m != nil {
+		d, _ = strconv.Atoi(m[2])
+		mo, year = monthNumber(m[1]), m[3]
+	} else {
+		return false
+	}
+	phrase := fmt.Sprintf("%d %s", d, mo)
+	if year == "" {
+		p.yearlessDate(phrase, mo, d)
+	} else {
+		y, _ := strconv.Atoi(year)
+		p.explicitDate(phrase, y, mo, d)
+	}
+	return true
+}
+
+func monthNumber(s string) time.Month {
+	s = strings.ToLower(s)
+	for m := time.January; m <= time.December; m++ {
+		if strings.HasPrefix(strings.ToLower(m.String()), s[:3]) {
+			return m
+		}
+	}
+	return 0
+}
+
+func (p *parser) explicitDate(phrase string, y int, mo time.Month, d int) {
+	t, ok := p.validDay(y, mo, d)
+	if !ok {
+		p.in.ambiguous("date", fmt.Sprintf("%q is not a valid date", strings.TrimSpace(phrase)), true)
+		return
+	}
+	p.setDate(t, nil)
+}
+
+// yearlessDate picks the nearest year in the intent's direction.
+func (p *parser) yearlessDate(phrase string, mo time.Month, d int) {
+	y := p.now.Year()
+	if _, ok := p.validDay(y, mo, d); !ok && !(mo == time.February && d == 29) {
+		p.in.ambiguous("date", fmt.Sprintf("%q is not a valid date", strings.TrimSpace(phrase)), true)
+		return
+	}
+	// 29 February only exists in leap years; step until one is found.
+	next := func(dir int) time.Time {
+		for {
+			if t, ok := p.validDay(y, mo, d); ok {
+				return t
+			}
+			y += dir
+		}
+	}
+	today := p.day(p.now.Year(), p.now.Month(), p.now.Day())
+	t := next(1)
+	if p.future() {
+		if t.Before(today) {
+			y++
+			t = next(1)
+		}
+		p.setDate(t, func(t time.Time) time.Time { y++; return next(1) })
+		return
+	}
+	if t.After(today) {
+		y--
+		t = next(-1)
+	}
+	p.setDate(t, nil)
+}
+
+func (p *parser) relativeDate() bool {
+	if m := p.take(twoDaysRe); m != nil {
+		if strings.HasPrefix(strings.ToLower(m[1]), "before") {
+			p.setDate(p.now.AddDate(0, 0, -2), nil)
+		} else {
+			p.setDate(p.now.AddDate(0, 0, 2), nil)
+		}
+		return true
+	}
+	if m := p.take(dayWordRe); m != nil {
+		switch strings.ToLower(m[1]) {
+		case "today":
+			p.setDate(p.now, nil)
+		case "yesterday":
+			p.setDate(p.now.AddDate(0, 0, -1), nil)
+		default:
+			p.setDate(p.now.AddDate(0, 0, 1), nil)
+		}
+		return true
+	}
+	if m := p.take(inCountRe); m != nil {
+		p.setDate(addUnits(p.now, m[2], count(m[1])), nil)
+		return true
+	}
+	if m := p.take(agoRe); m != nil {
+		p.setDate(addUnits(p.now, m[2], -count(m[1])), nil)
+		return true
+	}
+	if m := p.take(relativeUnitRe); m != nil {
+		n := 0
+		switch strings.ToLower(m[1]) {
+		case "next":
+			n = 1
+		case "last":
+			n = -1
+		}
+		p.setDate(addUnits(p.now, m[2], n), nil)
+		if n != 0 {
+			p.in.ambiguous("date", fmt.Sprintf("%q read as %s", strings.TrimSpace(m[0]), p.in.Date.Format("Mon 2 Jan 2006")), false)
+		}
+		return true
+	}
+	return false
+}
+
+func addUnits(t time.Time, unit string, n int) time.Time {
+	switch strings.ToLower(unit) {
+	case "day":
+		return t.AddDate(0, 0, n)
+	case "week":
+		return t.AddDate(0, 0, 7*n)
+	case "month":
+		return t.AddDate(0, n, 0)
+	}
+	return t.AddDate(n, 0, 0)
+}
+
+func (p *parser) weekday() bool {
+	m := p.take(weekdayRe)
+	if m == nil {
+		return false
+	}
+	p.setWeekday(strings.ToLower(m[1]), m[2])
+	return true
+}
+
+func (p *parser) setWeekday(qualifier, name string) {
+	var wd time.Weekday
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.HasPrefix(strings.ToLower(name), strings.ToLower(d.String())) {
+			wd = d
+		}
+	}
+	ahead := (int(wd) - int(p.now.Weekday()) + 7) % 7
+	back := (int(p.now.Weekday()) - int(wd) + 7) % 7
+	switch {
+	case qualifier == "next" || qualifier == "coming":
+		if ahead == 0 {
+			ahead = 7
+		}
+		p.setDate(p.now.AddDate(0, 0, ahead), nil)
+	case qualifier == "last" || qualifier == "past":
+		if back == 0 {
+			back = 7
+		}
+		p.setDate(p.now.AddDate(0, 0, -back), nil)
+	case qualifier == "this" || p.future():
+		p.setDate(p.now.AddDate(0, 0, ahead), func(t time.Time) time.Time { return t.AddDate(0, 0, 7) })
+	default:
+		p.setDate(p.now.AddDate(0, 0, -back), nil)
+	}
+}
+
+func (p *parser) dayOfMonth() bool {
+	m := p.take(dayOfMonthRe)
+	if m == nil {
+		return false
+	}
+	d, _ := strconv.Atoi(m[1])
+	if d < 1 || d > 31 {
+		p.in.ambiguous("date", fmt.Sprintf("%q is not a day of the month", strings.TrimSpace(m[0])), true)
+		return true
+	}
+	if d > 28 {
+		p.in.ambiguous("date", fmt.Sprintf("not every month has a %d%s; shorter months use their last day", d, m[2]), false)
+	}
+
+	// in returns day d of the month offset months from now, or the last
+	// day of that month when it is shorter.
+	in := func(offset int) time.Time {
+		first := p.day(p.now.Year(), p.now.Month()+time.Month(offset), 1)
+		last := first.AddDate(0, 1, -1).Day()
+		return first.AddDate(0, 0, min(d, last)-1)
+	}
+	offset := 0
+	if p.future() {
+		if d < p.now.Day() {
+			offset = 1
+		}
+		p.setDate(in(offset), func(time.Time) time.Time { offset++; return in(offset) })
+		return true
+	}
+	if d > p.now.Day() {
+		offset = -1
+	}
+	p.setDate(in(offset), nil)
+	return true
+}
+
+// atClock is t's date at h:m.
+func atClock(t time.Time, h, m int) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), h, m, 0, 0, t.Location())
+}
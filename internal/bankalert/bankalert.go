@@ -0,0 +1,226 @@
+// Package bankalert reads the SMS and push notifications banks send for
+// each transaction, e.g. "INR 1,250.00 debited from A/c XX1234 at AMAZON
+// on 12-10-26".
+//
+// Each bank words its alerts differently, so messages are matched against
+// templates: regular expressions with named groups for the fields. Only the
+// amount group is required; fields a template does not capture are looked
+// for with generic patterns.
+package bankalert
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+)
+
+// Group names a template pattern may use.
+const (
+	GroupAmount   = "amount"
+	GroupCurrency = "currency"
+	GroupType     = "type"
+	GroupAccount  = "account"
+	GroupMerchant = "merchant"
+	GroupDate     = "date"
+	GroupRef      = "ref"
+	GroupBalance  = "balance"
+)
+
+var ErrNoAmountGroup = errors.New("pattern must have an (?P<amount>...) group")
+
+// Template describes one bank's alert format.
+type Template struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Bank    string `json:"bank,omitempty"`
+	Pattern string `json:"pattern"`
+	// DateLayout is a Go time layout for the date group, tried before the
+	// common ones.
+	DateLayout string `json:"dateLayout,omitempty"`
+	// Type is used when the text does not say debit or credit.
+	Type models.TransactionType `json:"type,omitempty"`
+	// Currency is used when the text has none.
+	Currency string `json:"currency,omitempty"`
+	BuiltIn  bool   `json:"builtIn"`
+}
+
+// Matcher is a compiled Template.
+type Matcher struct {
+	Template
+	re *regexp.Regexp
+}
+
+// Compile checks and compiles a template. Patterns are case-insensitive.
+func Compile(t Template) (*Matcher, error) {
+	re, err := regexp.Compile("(?i)" + t.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	if re.SubexpIndex(GroupAmount) < 0 {
+		return nil, ErrNoAmountGroup
+	}
+	if t.Type != "" && t.Type != models.TransactionTypeExpense && t.Type != models.TransactionTypeIncome {
+		return nil, errors.New("type must be expense or income")
+	}
+	return &Matcher{Template: t, re: re}, nil
+}
+
+// Field sources reported in Alert.Sources.
+const (
+	SourceTemplate = "template"
+	SourceGeneric  = "generic"
+	SourceDefault  = "default"
+)
+
+// Alert is what was read from a message.
+type Alert struct {
+	Template string                 `json:"template"`
+	Type     models.TransactionType `json:"type,omitempty"`
+	Amount   float64                `json:"amount"`
+	Currency string                 `json:"currency,omitempty"`
+	Account  string                 `json:"account,omitempty"`
+	Merchant string                 `json:"merchant,omitempty"`
+	// Date is nil when the message has no readable date.
+	Date      *time.Time `json:"date,omitempty"`
+	Reference string     `json:"reference,omitempty"`
+	Balance   *float64   `json:"balance,omitempty"`
+	// Sources says where each field came from: the template, a generic
+	// pattern or the template's default.
+	Sources map[string]string `json:"sources"`
+	// Warnings lists fields that were found but could not be used.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Parse tries the matchers in order and reads the message with the first
+// that matches. loc is the time zone of dates in the message. ok is false
+// when no matcher matched.
+func Parse(text string, matchers []*Matcher, loc *time.Location) (*Alert, bool) {
+	text = strings.Join(strings.Fields(text), " ")
+	for _, m := range matchers {
+		if a, ok := m.Parse(text, loc); ok {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// Parse reads text with this template alone.
+func (m *Matcher) Parse(text string, loc *time.Location) (*Alert, bool) {
+	text = strings.Join(strings.Fields(text), " ")
+	sub := m.re.FindStringSubmatch(text)
+	if sub == nil {
+		return nil, false
+	}
+	group := func(name string) string {
+		if i := m.re.SubexpIndex(name); i >= 0 {
+			return strings.TrimSpace(sub[i])
+		}
+		return ""
+	}
+	amount, ok := parseAmount(group(GroupAmount))
+	if !ok {
+		return nil, false
+	}
+	a := &Alert{Template: m.Name, Amount: amount, Sources: map[string]string{"amount": SourceTemplate}}
+
+	// field fills one field from the template's group, or else from the
+	// generic pattern, recording which it was.
+	field := func(name, key string, generic func(string) string) string {
+		if v := group(name); v != "" {
+			a.Sources[key] = SourceTemplate
+			return v
+		}
+		if generic == nil {
+			return ""
+		}
+		if v := generic(text); v != "" {
+			a.Sources[key] = SourceGeneric
+			return v
+		}
+		return ""
+	}
+
+	if t := field(GroupType, "type", genericType); t != "" {
+		a.Type = transactionType(t)
+	}
+	if a.Type == "" && m.Type != "" {
+		a.Type = m.Type
+		a.Sources["type"] = SourceDefault
+	}
+
+	a.Currency = currencyCode(field(GroupCurrency, "currency", genericCurrency))
+	if a.Currency == "" && m.Currency != "" {
+		a.Currency = strings.ToUpper(m.Currency)
+		a.Sources["currency"] = SourceDefault
+	}
+
+	a.Account = field(GroupAccount, "account", genericAccount)
+	a.Merchant = cleanMerchant(field(GroupMerchant, "merchant", genericMerchant))
+	if a.Merchant == "" {
+		delete(a.Sources, "merchant")
+	}
+	a.Reference = field(GroupRef, "reference", genericRef)
+
+	if d := field(GroupDate, "date", genericDate); d != "" {
+		if t, ok := parseDate(d, m.DateLayout, loc); ok {
+			a.Date = &t
+		} else {
+			delete(a.Sources, "date")
+			a.Warnings = append(a.Warnings, fmt.Sprintf("could not read the date %q", d))
+		}
+	}
+	if b := field(GroupBalance, "balance", genericBalance); b != "" {
+		if v, ok := parseAmount(b); ok {
+			a.Balance = &v
+		}
+	}
+	return a, true
+}
+
+func parseAmount(s string) (float64, bool) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	s = strings.TrimSuffix(s, ".")
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 {
+		return 0, false
+	}
+	return v, true
+}
+
+var (
+	debitWords  = regexp.MustCompile(`(?i)^(?:debit(?:ed)?|dr\.?|spent|withdrawn|paid|sent|purchase[ds]?|deducted|transferred)$`)
+	creditWords = regexp.MustCompile(`(?i)^(?:credit(?:ed)?|cr\.?|received|deposited|refund(?:ed)?|added)$`)
+)
+
+func transactionType(s string) models.TransactionType {
+	switch {
+	case debitWords.MatchString(s):
+		return models.TransactionTypeExpense
+	case creditWords.MatchString(s):
+		return models.TransactionTypeIncome
+	}
+	return ""
+}
+
+var currencyCodes = map[string]string{"RS": "INR", "RS.": "INR", "₹": "INR", "$": "USD", "€": "EUR", "£": "GBP"}
+
+func currencyCode(s string) string {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if c, ok := currencyCodes[s]; ok {
+		return c
+	}
+	return s
+}
+
+// cleanMerchant tidies a merchant name such as "AMAZON PAY INDIA." or
+// "VPA amazon@apl".
+func cleanMerchant(s string) string {
+	s = strings.Trim(strings.TrimSpace(s), ".,;:-/ ")
+	s = strings.TrimPrefix(s, "VPA ")
+	return strings.TrimSpace(s)
+}
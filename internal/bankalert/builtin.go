@@ -0,0 +1,75 @@
+package bankalert
+
+import "github.com/ronak4195/personal-assistant/internal/models"
+
+const amountPattern = `(?P<amount>\d[\d,]*(?:\.\d{1,2})?)`
+
+// BuiltIn are the templates every user has. Bank-specific ones come first;
+// the generic ones at the end catch most other wordings.
+var BuiltIn = []Template{
+	{
+		ID:       "builtin-hdfc-card",
+		Name:     "HDFC Bank card spend",
+		Bank:     "HDFC Bank",
+		Pattern:  `(?P<currency>Rs\.?|INR)\s?` + amountPattern + `\s+(?P<type>spent)\s+on\s+.*?card\s+(?P<account>x?\d{4})\s+at\s+(?P<merchant>.+?)\s+on\s+(?P<date>\d{4}-\d{2}-\d{2})`,
+		Currency: "INR",
+	},
+	{
+		ID:       "builtin-hdfc-upi",
+		Name:     "HDFC Bank UPI transfer",
+		Bank:     "HDFC Bank",
+		Pattern:  `(?P<type>sent|received)\s+(?P<currency>Rs\.?|INR)\s?` + amountPattern + `\s+(?:from|to)\s+.*?a/c\s+(?P<account>[x*]*\d{3,6})\s+(?:to|from)\s+(?P<merchant>.+?)\s+on\s+(?P<date>\d{1,2}/\d{1,2}/\d{2,4})(?:\s+ref\s+(?P<ref>\d+))?`,
+		Currency: "INR",
+	},
+	{
+		ID:       "builtin-icici",
+		Name:     "ICICI Bank account",
+		Bank:     "ICICI Bank",
+		Pattern:  `acct\s+(?P<account>[x*]*\d{3,6})\s+(?P<type>debited|credited)\s+(?:for|with)\s+(?P<currency>Rs\.?|INR)\s?` + amountPattern + `\s+on\s+(?P<date>\d{1,2}-[a-z]{3}-\d{2,4})(?:;\s*(?P<merchant>[^.;]+?)\s+(?:credited|debited))?(?:.*?UPI:\s*(?P<ref>\d+))?`,
+		Currency: "INR",
+	},
+	{
+		ID:       "builtin-sbi-upi",
+		Name:     "SBI UPI",
+		Bank:     "State Bank of India",
+		Pattern:  `a/c\s+(?P<account>[x*]*\d{3,6})\s+(?P<type>debited|credited)\s+by\s+` + amountPattern + `\s+on\s+date\s+(?P<date>\d{1,2}[a-z]{3}\d{2,4})\s+trf\s+(?:to|from)\s+(?P<merchant>.+?)\s+ref\s*no\s+(?P<ref>\d+)`,
+		Currency: "INR",
+	},
+	{
+		ID:       "builtin-axis-upi",
+		Name:     "Axis Bank UPI",
+		Bank:     "Axis Bank",
+		Pattern:  `(?P<currency>INR)\s?` + amountPattern + `\s+(?P<type>debited|credited)\s+a/c\s+no\.\s+(?P<account>[x*]*\d{3,6})\s+(?P<date>\d{2}-\d{2}-\d{2,4})(?:\s+[\d:]+)?\s+UPI/[A-Z0-9]+/(?P<ref>\d+)/(?P<merchant>[^/.]+?)(?:\s+(?:not\s+you|if\s+not|call|sms)\b|[.?]|$)`,
+		Currency: "INR",
+	},
+	{
+		ID:      "builtin-generic-amount-first",
+		Name:    "Generic: amount then debited/credited",
+		Pattern: `(?P<currency>INR|Rs\.?|₹|USD|\$|EUR|€|GBP|£|AED|SGD)\s?` + amountPattern + `\s+(?:has\s+been\s+|is\s+|was\s+)?(?P<type>debited|credited|spent|paid|withdrawn|received|deposited|refunded)`,
+	},
+	{
+		ID:      "builtin-generic-verb-first",
+		Name:    "Generic: debited/credited then amount",
+		Pattern: `(?P<type>debited|credited|spent|paid|withdrawn|received|deposited|refunded)\s+(?:with|by|for|of)?\s*(?P<currency>INR|Rs\.?|₹|USD|\$|EUR|€|GBP|£|AED|SGD)\s?` + amountPattern,
+	},
+	{
+		ID:      "builtin-generic-card",
+		Name:    "Generic: card transaction",
+		Pattern: `(?:transaction|purchase|txn)\s+of\s+(?P<currency>INR|Rs\.?|₹|USD|\$|EUR|€|GBP|£|AED|SGD)\s?` + amountPattern,
+		Type:    models.TransactionTypeExpense,
+	},
+}
+
+// BuiltInMatchers are the compiled BuiltIn templates.
+var BuiltInMatchers = func() []*Matcher {
+	res := make([]*Matcher, len(BuiltIn))
+	for i, t := range BuiltIn {
+		t.BuiltIn = true
+		m, err := Compile(t)
+		if err != nil {
+			panic("bankalert: " + t.ID + ": " + err.Error())
+		}
+		res[i] = m
+	}
+	return res
+}()
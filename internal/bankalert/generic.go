@@ -0,0 +1,75 @@
+package bankalert
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	genericTypeRe     = regexp.MustCompile(`(?i)\b(debited|credited|debit|credit|spent|withdrawn|paid|sent|received|deposited|refunded|deducted|transferred)\b`)
+	genericCurrencyRe = regexp.MustCompile(`(?i)(₹|\b(?:INR|USD|EUR|GBP|AED|SGD|Rs\.?)\s?)\d`)
+	genericAccountRe  = regexp.MustCompile(`(?i)\b(?:a/c|acct|account|card)\s*(?:no\.?\s*|ending\s*(?:with\s*)?|x{0,2})?([x*]*\d{3,6})\b`)
+	genericMerchantRe = regexp.MustCompile(`(?i)\b(?:at|to|towards|info:?|merchant:?)\s+((?:VPA\s+)?[A-Za-z0-9][A-Za-z0-9&@._'\- ]{1,40}?)(?:\s+on\b|\s+via\b|\s+ref\b|\s+for\b|\s+avl\b|\s+avbl\b|\s+using\b|[.,;(]|$)`)
+	genericRefRe      = regexp.MustCompile(`(?i)\b(?:ref(?:erence)?|txn|transaction|utr|rrn|upi)\s*(?:no\.?|num(?:ber)?|id|#)?\s*[:.\-]?\s*(\d[A-Za-z0-9]{5,}|[A-Za-z]{2,4}\d{6,})\b`)
+	genericBalanceRe  = regexp.MustCompile(`(?i)\b(?:avl|avbl|available|total)\.?\s*(?:bal(?:ance)?|limit)\.?\s*(?:is\s*)?[:\-]?\s*(?:INR|Rs\.?|₹)?\s*([\d,]+(?:\.\d+)?)`)
+	genericDateRe     = regexp.MustCompile(`(?i)\b(\d{4}-\d{2}-\d{2}|\d{1,2}[-/.]\d{1,2}[-/.]\d{2,4}|\d{1,2}[- ]?(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*[- ,]?\d{2,4}|(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.? \d{1,2},? \d{4})\b`)
+)
+
+func firstGroup(re *regexp.Regexp, text string) string {
+	if m := re.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func genericType(text string) string { return firstGroup(genericTypeRe, text) }
+
+func genericCurrency(text string) string {
+	return strings.TrimSpace(firstGroup(genericCurrencyRe, text))
+}
+
+func genericAccount(text string) string  { return firstGroup(genericAccountRe, text) }
+func genericMerchant(text string) string { return firstGroup(genericMerchantRe, text) }
+func genericRef(text string) string      { return firstGroup(genericRefRe, text) }
+func genericBalance(text string) string  { return firstGroup(genericBalanceRe, text) }
+func genericDate(text string) string     { return firstGroup(genericDateRe, text) }
+
+// dateLayouts are the formats banks use, day first.
+var dateLayouts = []string{
+	"2006-01-02",
+	"02-01-06", "02-01-2006", "2-1-06", "2-1-2006",
+	"02/01/06", "02/01/2006", "2/1/06", "2/1/2006",
+	"02.01.06", "02.01.2006",
+	"02-Jan-06", "02-Jan-2006", "2-Jan-06", "2-Jan-2006",
+	"02Jan06", "02Jan2006", "2Jan06", "2Jan2006",
+	"02 Jan 06", "02 Jan 2006", "2 Jan 2006",
+	"02 January 2006", "2 January 2006",
+	"Jan 2, 2006", "Jan 2 2006", "January 2, 2006", "January 2 2006",
+}
+
+// parseDate reads a date, trying layout first. The result is midday in loc,
+// which keeps it on the same day in nearby time zones.
+func parseDate(s, layout string, loc *time.Location) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	// Month names are matched case-sensitively by time.Parse.
+	s = titleMonth(s)
+	layouts := dateLayouts
+	if layout != "" {
+		layouts = append([]string{layout}, dateLayouts...)
+	}
+	for _, l := range layouts {
+		if t, err := time.ParseInLocation(l, s, loc); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 12, 0, 0, 0, loc), true
+		}
+	}
+	return time.Time{}, false
+}
+
+var monthWordRe = regexp.MustCompile(`(?i)[a-z]{3,}`)
+
+func titleMonth(s string) string {
+	return monthWordRe.ReplaceAllStringFunc(s, func(w string) string {
+		return strings.ToUpper(w[:1]) + strings.ToLower(w[1:])
+	})
+}
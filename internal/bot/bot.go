@@ -0,0 +1,122 @@
+// Package bot talks to a Telegram-style chat bot API: it decodes the
+// updates the API posts to our webhook and sends messages back.
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SecretHeader carries the secret given to the API when the webhook was
+// registered, so updates can be told apart from forged requests.
+const SecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// MaxMessageLength is the longest text the API accepts in one message.
+const MaxMessageLength = 4096
+
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message,omitempty"`
+}
+
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	From      *User  `json:"from,omitempty"`
+	Chat      Chat   `json:"chat"`
+	Date      int64  `json:"date"`
+	Text      string `json:"text,omitempty"`
+}
+
+type User struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username,omitempty"`
+	FirstName string `json:"first_name,omitempty"`
+}
+
+type Chat struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type"`
+	Username string `json:"username,omitempty"`
+}
+
+// Command splits "/add 450 lunch" into "add" and "450 lunch". The
+// "@botname" suffix used in group chats is dropped. ok is false when text
+// is not a command.
+func Command(text string) (name, args string, ok bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", "", false
+	}
+	name, args, _ = strings.Cut(text[1:], " ")
+	name, _, _ = strings.Cut(name, "@")
+	return strings.ToLower(name), strings.TrimSpace(args), name != ""
+}
+
+type Client interface {
+	SendMessage(ctx context.Context, chatID int64, text string) error
+}
+
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewClient returns a client for the bot with the given token. baseURL is
+// the API root, e.g. https://api.telegram.org.
+func NewClient(baseURL, token string) Client {
+	return &client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (c *client) SendMessage(ctx context.Context, chatID int64, text string) error {
+	if r := []rune(text); len(r) > MaxMessageLength {
+		text = string(r[:MaxMessageLength-1]) + "…"
+	}
+	return c.call(ctx, "sendMessage", map[string]any{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	})
+}
+
+func (c *client) call(ctx context.Context, method string, params any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/bot"+c.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// The URL holds the token; keep it out of logs.
+		return fmt.Errorf("bot: %s: request failed", method)
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(data, &res); err != nil {
+		return fmt.Errorf("bot: %s: %s", method, resp.Status)
+	}
+	if !res.OK {
+		return fmt.Errorf("bot: %s: %s", method, res.Description)
+	}
+	return nil
+}
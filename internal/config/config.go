@@ -2,8 +2,13 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"log"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +19,63 @@ type Config struct {
 	JWTSecret   string
 	HTTPPort    string
 	FrontEndURL string
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For header
+	// is believed. With none, the client address is the connection's peer.
+	TrustedProxies []*net.IPNet
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// When set, accounts must confirm their email before using the API.
+	RequireEmailVerification bool
+	// How long a deleted account can still be restored before it is purged.
+	AccountDeletionGrace time.Duration
+	// Failed logins allowed per email address before it is locked out (0
+	// disables lockout). The first lockout lasts LoginLockout and doubles
+	// with each further failure up to LoginMaxLockout.
+	LoginMaxFailures int
+	LoginLockout     time.Duration
+	LoginMaxLockout  time.Duration
+
+	// Outgoing mail. When SMTPHost is empty, mail is written to MailLogDir
+	// (or the log if that is empty too) instead of being sent.
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+	MailLogDir   string
+
+	// Attachment files. StorageBackend is "local", keeping them below
+	// StorageDir, or "s3" for an S3-compatible bucket such as MinIO.
+	StorageBackend     string
+	StorageDir         string
+	S3Endpoint         string
+	S3Region           string
+	S3Bucket           string
+	S3AccessKeyID      string
+	S3SecretAccessKey  string
+	S3PathStyle        bool
+	AttachmentMaxBytes int
+
+	// Receipt OCR. TesseractPath defaults to tesseract on PATH; "off"
+	// disables OCR so only PDFs with a text layer are read.
+	TesseractPath string
+	OCRLanguages  string
+
+	// Chat bot. It is off unless BotToken is set; BotAPIURL can point at a
+	// local fake of the Telegram bot API.
+	BotToken         string
+	BotAPIURL        string
+	BotWebhookSecret string
+
+	// Inbound email. Each user gets an address at InboundEmailDomain for
+	// forwarding e-receipts. Mail arrives through the SMTP listener on
+	// InboundSMTPAddr, through the webhook when InboundWebhookSecret is
+	// set, or both; either may be left off.
+	InboundEmailDomain   string
+	InboundSMTPAddr      string
+	InboundWebhookSecret string
+	InboundEmailMaxBytes int
 }
 
 func Load() (*Config, error) {
@@ -25,6 +87,68 @@ func Load() (*Config, error) {
 		JWTSecret:   os.Getenv("JWT_SECRET"),
 		HTTPPort:    os.Getenv("HTTP_PORT"),
 		FrontEndURL: os.Getenv("FRONTEND_URL"),
+
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     os.Getenv("SMTP_PORT"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		MailFrom:     os.Getenv("MAIL_FROM"),
+		MailLogDir:   os.Getenv("MAIL_LOG_DIR"),
+
+		StorageBackend:    os.Getenv("STORAGE_BACKEND"),
+		StorageDir:        os.Getenv("STORAGE_DIR"),
+		S3Endpoint:        os.Getenv("S3_ENDPOINT"),
+		S3Region:          os.Getenv("S3_REGION"),
+		S3Bucket:          os.Getenv("S3_BUCKET"),
+		S3AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+		S3SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+
+		TesseractPath: os.Getenv("TESSERACT_PATH"),
+		OCRLanguages:  os.Getenv("OCR_LANGUAGES"),
+
+		BotToken:         os.Getenv("BOT_TOKEN"),
+		BotAPIURL:        os.Getenv("BOT_API_URL"),
+		BotWebhookSecret: os.Getenv("BOT_WEBHOOK_SECRET"),
+
+		InboundEmailDomain:   os.Getenv("INBOUND_EMAIL_DOMAIN"),
+		InboundSMTPAddr:      os.Getenv("INBOUND_SMTP_ADDR"),
+		InboundWebhookSecret: os.Getenv("INBOUND_EMAIL_WEBHOOK_SECRET"),
+	}
+
+	var err error
+	if cfg.AccessTokenTTL, err = getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.RefreshTokenTTL, err = getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.AccountDeletionGrace, err = getEnvDuration("ACCOUNT_DELETION_GRACE", 7*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.LoginMaxFailures, err = getEnvInt("LOGIN_MAX_FAILURES", 5); err != nil {
+		return nil, err
+	}
+	if cfg.LoginLockout, err = getEnvDuration("LOGIN_LOCKOUT", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.LoginMaxLockout, err = getEnvDuration("LOGIN_MAX_LOCKOUT", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.RequireEmailVerification, err = getEnvBool("REQUIRE_EMAIL_VERIFICATION", false); err != nil {
+		return nil, err
+	}
+	if cfg.S3PathStyle, err = getEnvBool("S3_PATH_STYLE", false); err != nil {
+		return nil, err
+	}
+	if cfg.AttachmentMaxBytes, err = getEnvInt("ATTACHMENT_MAX_BYTES", 10<<20); err != nil {
+		return nil, err
+	}
+
+	if cfg.TrustedProxies, err = getEnvNets("TRUSTED_PROXIES"); err != nil {
+		return nil, err
+	}
+	if cfg.InboundEmailMaxBytes, err = getEnvInt("INBOUND_EMAIL_MAX_BYTES", 25<<20); err != nil {
+		return nil, err
 	}
 
 	log.Default().Println("Configuration Loaded:" + cfg.MongoDBName)
@@ -44,10 +168,103 @@ func Load() (*Config, error) {
 	if cfg.FrontEndURL == "" {
 		cfg.FrontEndURL = "http://localhost:5173"
 	}
+	if cfg.SMTPPort == "" {
+		cfg.SMTPPort = "25"
+	}
+	if cfg.MailFrom == "" {
+		cfg.MailFrom = "Personal Assistant <no-reply@localhost>"
+	}
+	switch cfg.StorageBackend {
+	case "":
+		cfg.StorageBackend = "local"
+	case "local", "s3":
+	default:
+		return nil, errors.New("STORAGE_BACKEND must be local or s3")
+	}
+	if cfg.StorageDir == "" {
+		cfg.StorageDir = "data/attachments"
+	}
+	if cfg.OCRLanguages == "" {
+		cfg.OCRLanguages = "eng"
+	}
+	if cfg.BotAPIURL == "" {
+		cfg.BotAPIURL = "https://api.telegram.org"
+	}
+	if cfg.BotToken != "" && cfg.BotWebhookSecret == "" {
+		return nil, errors.New("BOT_WEBHOOK_SECRET is required when BOT_TOKEN is set")
+	}
+	if cfg.InboundEmailDomain == "" && (cfg.InboundSMTPAddr != "" || cfg.InboundWebhookSecret != "") {
+		return nil, errors.New("INBOUND_EMAIL_DOMAIN is required to receive email")
+	}
 
 	return cfg, nil
 }
 
+// getEnvNets reads a comma-separated list of IP addresses and CIDR ranges.
+func getEnvNets(key string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, s := range strings.Split(os.Getenv(key), ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("%s: invalid address %q", key, s)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func getEnvBool(key string, def bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false", key)
+	}
+	return b, nil
+}
+
+func getEnvInt(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+	return n, nil
+}
+
+func getEnvDuration(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration (e.g. 15m)", key)
+	}
+	return d, nil
+}
+
 // package config
 
 // import (
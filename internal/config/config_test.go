@@ -0,0 +1,32 @@
+package config
+
+import "testing"
+
+func TestGetEnvNets(t *testing.T) {
+	t.Setenv("TEST_NETS", " 10.0.0.0/8, 203.0.113.7 ,2001:db8::1,")
+	nets, err := getEnvNets("TEST_NETS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.0/8", "203.0.113.7/32", "2001:db8::1/128"}
+	if len(nets) != len(want) {
+		t.Fatalf("got %v, want %v", nets, want)
+	}
+	for i, n := range nets {
+		if n.String() != want[i] {
+			t.Errorf("nets[%d] = %s, want %s", i, n, want[i])
+		}
+	}
+
+	t.Setenv("TEST_NETS", "")
+	if nets, err := getEnvNets("TEST_NETS"); err != nil || len(nets) != 0 {
+		t.Errorf("empty: got %v, %v", nets, err)
+	}
+
+	for _, bad := range []string{"proxy.local", "10.0.0.0/33"} {
+		t.Setenv("TEST_NETS", bad)
+		if _, err := getEnvNets("TEST_NETS"); err == nil {
+			t.Errorf("%q was accepted", bad)
+		}
+	}
+}
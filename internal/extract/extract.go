@@ -0,0 +1,117 @@
+// Package extract reads receipts: it gets the text out of a PDF or image
+// and finds the merchant, date, total and currency in it.
+//
+// PDFs with a text layer are read in pure Go. Images, and PDFs that are
+// only scans, need an OCR engine; Tesseract is used when it is installed.
+package extract
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	SourcePDFText = "pdf-text"
+	SourceOCR     = "ocr"
+)
+
+var (
+	// ErrNoText means a PDF has no text layer and no OCR engine could read
+	// it instead.
+	ErrNoText = errors.New("extract: document has no readable text")
+	// ErrOCRUnavailable means an image was given but no OCR engine is
+	// configured.
+	ErrOCRUnavailable = errors.New("extract: no OCR engine is available for images")
+	ErrUnsupported    = errors.New("extract: unsupported content type")
+	// ErrTooLarge means a document decompresses to more than the extractor
+	// is willing to hold in memory.
+	ErrTooLarge = errors.New("extract: document expands beyond the size limit")
+)
+
+// OCR turns an image into text.
+type OCR interface {
+	Recognize(ctx context.Context, contentType string, data []byte) (string, error)
+}
+
+// Candidate is one possible value for a field, with a confidence between 0
+// and 1 and the line of text it was found on.
+type Candidate[T any] struct {
+	Value      T       `json:"value"`
+	Confidence float64 `json:"confidence"`
+	Line       string  `json:"line,omitempty"`
+}
+
+// Fields holds the candidates for each field, most likely first.
+type Fields struct {
+	Merchant []Candidate[string]    `json:"merchant"`
+	Date     []Candidate[time.Time] `json:"date"`
+	Total    []Candidate[float64]   `json:"total"`
+	Currency []Candidate[string]    `json:"currency"`
+}
+
+type Result struct {
+	// Source is SourcePDFText or SourceOCR.
+	Source string `json:"source"`
+	Text   string `json:"text"`
+	Fields Fields `json:"fields"`
+}
+
+type Extractor interface {
+	// Extract reads a receipt. now bounds the dates considered plausible.
+	Extract(ctx context.Context, contentType string, data []byte, now time.Time) (*Result, error)
+}
+
+type extractor struct {
+	ocr OCR
+}
+
+// New returns an Extractor. ocr may be nil, in which case only PDFs with a
+// text layer can be read.
+func New(ocr OCR) Extractor {
+	return &extractor{ocr: ocr}
+}
+
+func (e *extractor) Extract(ctx context.Context, contentType string, data []byte, now time.Time) (*Result, error) {
+	var (
+		text   string
+		source string
+		err    error
+	)
+	switch {
+	case contentType == "application/pdf":
+		text, err = PDFText(data)
+		if err != nil {
+			return nil, err
+		}
+		source = SourcePDFText
+		if strings.TrimSpace(text) == "" {
+			// Scanned PDFs have no text layer. Tesseract cannot read PDFs,
+			// but some engines can.
+			if e.ocr == nil {
+				return nil, ErrNoText
+			}
+			if text, err = e.ocr.Recognize(ctx, contentType, data); err != nil {
+				return nil, ErrNoText
+			}
+			source = SourceOCR
+		}
+	case strings.HasPrefix(contentType, "image/"):
+		if e.ocr == nil {
+			return nil, ErrOCRUnavailable
+		}
+		if text, err = e.ocr.Recognize(ctx, contentType, data); err != nil {
+			return nil, err
+		}
+		source = SourceOCR
+	default:
+		return nil, ErrUnsupported
+	}
+
+	return &Result{
+		Source: source,
+		Text:   text,
+		Fields: ParseReceipt(text, now),
+	}, nil
+}
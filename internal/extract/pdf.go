@@ -0,0 +1,408 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// The PDF reader below only does what getting text out of receipts needs:
+// it finds objects by scanning rather than through the xref table, inflates
+// Flate streams, resolves fonts' ToUnicode maps and interprets the text
+// operators of page content. Encrypted PDFs are not supported.
+
+// Flate streams are inflated with limits so that a small file cannot
+// decompress into gigabytes: no single stream may exceed maxStreamBytes and
+// all of them together may not exceed maxDocumentBytes.
+const (
+	maxStreamBytes   = 16 << 20
+	maxDocumentBytes = 64 << 20
+)
+
+var (
+	errNotPDF    = errors.New("extract: not a PDF document")
+	errEncrypted = errors.New("extract: encrypted PDFs are not supported")
+
+	objRe        = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	refRe        = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+	filterRe     = regexp.MustCompile(`/Filter\s*\[?\s*/(\w+)`)
+	namedRefRe   = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+(\d+)\s+\d+\s+R`)
+	fontResRe    = regexp.MustCompile(`/Font\s*(?:<<((?:[^<>]|<[^<])*?)>>|(\d+)\s+\d+\s+R)`)
+	toUnicodeRe  = regexp.MustCompile(`/ToUnicode\s+(\d+)\s+\d+\s+R`)
+	contentsRe   = regexp.MustCompile(`/Contents\s*(\[[^\]]*\]|\d+\s+\d+\s+R)`)
+	pageTypeRe   = regexp.MustCompile(`/Type\s*/Page\b`)
+	intEntryRe   = regexp.MustCompile(`/(Length|N|First)\s+(\d+)\b(\s+\d+\s+R)?`)
+	hexRe        = regexp.MustCompile(`<([0-9A-Fa-f\s]*)>`)
+	cmapRangeRe  = regexp.MustCompile(`<([0-9A-Fa-f]+)>\s*<([0-9A-Fa-f]+)>\s*(<[0-9A-Fa-f]+>|\[[^\]]*\])`)
+	cmapCharRe   = regexp.MustCompile(`<([0-9A-Fa-f]+)>\s*<([0-9A-Fa-f]+)>`)
+	codespaceRe  = regexp.MustCompile(`begincodespacerange\s*<([0-9A-Fa-f]+)>`)
+	skipStreamRe = regexp.MustCompile(`/Subtype\s*/Image|/Length1|/Length2|/Subtype\s*/(Type1C|CIDFontType0C|OpenType)|/Type\s*/(ObjStm|XRef|Metadata|EmbeddedFile)`)
+)
+
+type pdfObject struct {
+	dict    []byte
+	stream  []byte // raw, nil when the object has none
+	decoded []byte
+	done    bool
+}
+
+type pdfDoc struct {
+	objects map[int]*pdfObject
+	// inflated counts the bytes decoded so far; err is set once a limit is
+	// hit, after which nothing more is decoded.
+	inflated int
+	err      error
+}
+
+// PDFText returns the text layer of a PDF, a line per line of text. It is
+// empty for scanned documents.
+func PDFText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return "", errNotPDF
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", errEncrypted
+	}
+	doc := &pdfDoc{objects: map[int]*pdfObject{}}
+	doc.scan(data)
+	doc.expandObjectStreams()
+
+	fonts := doc.fonts()
+	contents := doc.contentStreams()
+	if doc.err != nil {
+		return "", doc.err
+	}
+	var out strings.Builder
+	for _, content := range contents {
+		interpretText(content, fonts, &out)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// scan finds every "N G obj ... endobj" in the file. Later definitions win,
+// as with incremental updates.
+func (d *pdfDoc) scan(data []byte) {
+	for _, m := range objRe.FindAllSubmatchIndex(data, -1) {
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		rest := data[m[1]:]
+		end := bytes.Index(rest, []byte("endobj"))
+		streamAt := bytes.Index(rest, []byte("stream"))
+		obj := &pdfObject{}
+		if streamAt >= 0 && (end < 0 || streamAt < end) {
+			obj.dict = rest[:streamAt]
+			body := rest[streamAt+len("stream"):]
+			if bytes.HasPrefix(body, []byte("\r\n")) {
+				body = body[2:]
+			} else if len(body) > 0 && (body[0] == '\n' || body[0] == '\r') {
+				body = body[1:]
+			}
+			if n, ok := directInt(obj.dict, "Length"); ok && n <= len(body) &&
+				bytes.HasPrefix(bytes.TrimLeft(body[n:], " \r\n"), []byte("endstream")) {
+				obj.stream = body[:n]
+			} else if e := bytes.Index(body, []byte("endstream")); e >= 0 {
+				obj.stream = bytes.TrimRight(body[:e], "\r\n")
+			} else {
+				obj.stream = body
+			}
+		} else if end >= 0 {
+			obj.dict = rest[:end]
+		} else {
+			obj.dict = rest
+		}
+		d.objects[num] = obj
+	}
+}
+
+// expandObjectStreams adds the objects packed into /Type /ObjStm streams,
+// where PDF 1.5 and later usually keep font dictionaries.
+func (d *pdfDoc) expandObjectStreams() {
+	nums := d.numbers()
+	for _, num := range nums {
+		obj := d.objects[num]
+		if !bytes.Contains(obj.dict, []byte("/ObjStm")) {
+			continue
+		}
+		data := d.decode(obj)
+		n, ok1 := directInt(obj.dict, "N")
+		first, ok2 := directInt(obj.dict, "First")
+		if !ok1 || !ok2 || first > len(data) {
+			continue
+		}
+		header := strings.Fields(string(data[:first]))
+		type entry struct{ num, off int }
+		var entries []entry
+		for i := 0; i+1 < len(header) && len(entries) < n; i += 2 {
+			on, err1 := strconv.Atoi(header[i])
+			off, err2 := strconv.Atoi(header[i+1])
+			if err1 != nil || err2 != nil {
+				break
+			}
+			entries = append(entries, entry{on, first + off})
+		}
+		for i, e := range entries {
+			end := len(data)
+			if i+1 < len(entries) {
+				end = entries[i+1].off
+			}
+			if e.off > end || end > len(data) {
+				continue
+			}
+			if _, exists := d.objects[e.num]; !exists {
+				d.objects[e.num] = &pdfObject{dict: data[e.off:end]}
+			}
+		}
+	}
+}
+
+// decode returns an object's stream with its filters undone, or nil for
+// filters other than Flate and once a size limit has been hit.
+func (d *pdfDoc) decode(obj *pdfObject) []byte {
+	if obj.done || obj.stream == nil || d.err != nil {
+		return obj.decoded
+	}
+	obj.done = true
+	m := filterRe.FindSubmatch(obj.dict)
+	switch {
+	case m == nil:
+		obj.decoded = obj.stream
+	case string(m[1]) == "FlateDecode":
+		r, err := zlib.NewReader(bytes.NewReader(obj.stream))
+		if err != nil {
+			return nil
+		}
+		limit := min(maxStreamBytes, maxDocumentBytes-d.inflated)
+		// Truncated streams are common; keep whatever inflated.
+		decoded, _ := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+		if len(decoded) > limit {
+			d.err = ErrTooLarge
+			return nil
+		}
+		d.inflated += len(decoded)
+		obj.decoded = decoded
+	}
+	return obj.decoded
+}
+
+func (d *pdfDoc) ref(num int) *pdfObject {
+	return d.objects[num]
+}
+
+// numbers returns the object numbers in order, so the output does not
+// depend on map iteration.
+func (d *pdfDoc) numbers() []int {
+	nums := make([]int, 0, len(d.objects))
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// fonts maps font resource names, such as F1, to their ToUnicode maps.
+// Names are gathered from every page and the first definition wins, so two
+// pages using the same name for different fonts can mix up; receipts rarely
+// do.
+func (d *pdfDoc) fonts() map[string]*cmap {
+	fonts := map[string]*cmap{}
+	for _, num := range d.numbers() {
+		obj := d.objects[num]
+		for _, m := range fontResRe.FindAllSubmatch(obj.dict, -1) {
+			res := m[1]
+			if m[2] != nil {
+				num, _ := strconv.Atoi(string(m[2]))
+				target := d.ref(num)
+				if target == nil {
+					continue
+				}
+				res = target.dict
+			}
+			for _, pair := range namedRefRe.FindAllSubmatch(res, -1) {
+				name := string(pair[1])
+				if _, seen := fonts[name]; seen {
+					continue
+				}
+				num, _ := strconv.Atoi(string(pair[2]))
+				font := d.ref(num)
+				if font == nil {
+					continue
+				}
+				var cm *cmap
+				if tu := toUnicodeRe.FindSubmatch(font.dict); tu != nil {
+					n, _ := strconv.Atoi(string(tu[1]))
+					if o := d.ref(n); o != nil {
+						cm = parseCMap(d.decode(o))
+					}
+				}
+				fonts[name] = cm
+			}
+		}
+	}
+	return fonts
+}
+
+// contentStreams returns page contents in page object order, then any
+// form XObjects, which can also hold text.
+func (d *pdfDoc) contentStreams() [][]byte {
+	nums := d.numbers()
+
+	used := map[int]bool{}
+	var out [][]byte
+	for _, num := range nums {
+		obj := d.objects[num]
+		if !pageTypeRe.Match(obj.dict) {
+			continue
+		}
+		m := contentsRe.FindSubmatch(obj.dict)
+		if m == nil {
+			continue
+		}
+		for _, r := range refRe.FindAllSubmatch(m[1], -1) {
+			cn, _ := strconv.Atoi(string(r[1]))
+			if c := d.ref(cn); c != nil && !used[cn] {
+				used[cn] = true
+				out = append(out, d.decode(c))
+			}
+		}
+	}
+	for _, num := range nums {
+		obj := d.objects[num]
+		if used[num] || obj.stream == nil || skipStreamRe.Match(obj.dict) {
+			continue
+		}
+		if len(out) > 0 && !bytes.Contains(obj.dict, []byte("/Form")) {
+			continue
+		}
+		if data := d.decode(obj); bytes.Contains(data, []byte("BT")) && !bytes.Contains(data, []byte("begincmap")) {
+			out = append(out, data)
+		}
+	}
+	return out
+}
+
+func directInt(dict []byte, key string) (int, bool) {
+	for _, m := range intEntryRe.FindAllSubmatch(dict, -1) {
+		if string(m[1]) == key && m[3] == nil {
+			n, err := strconv.Atoi(string(m[2]))
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// cmap is a font's ToUnicode map from character codes to text.
+type cmap struct {
+	codeLen int
+	codes   map[uint32]string
+}
+
+func parseCMap(data []byte) *cmap {
+	if data == nil {
+		return nil
+	}
+	cm := &cmap{codeLen: 1, codes: map[uint32]string{}}
+	if m := codespaceRe.FindSubmatch(data); m != nil {
+		cm.codeLen = max(1, len(m[1])/2)
+	}
+	for _, section := range sections(data, "beginbfrange", "endbfrange") {
+		for _, m := range cmapRangeRe.FindAllSubmatch(section, -1) {
+			lo, hi := hexUint(m[1]), hexUint(m[2])
+			if hi < lo || hi-lo > 0xffff {
+				continue
+			}
+			if m[3][0] == '[' {
+				for i, h := range hexRe.FindAllSubmatch(m[3], -1) {
+					cm.codes[lo+uint32(i)] = utf16Hex(h[1])
+				}
+				continue
+			}
+			dst := []rune(utf16Hex(bytes.Trim(m[3], "<>")))
+			if len(dst) == 0 {
+				continue
+			}
+			for c := lo; c <= hi; c++ {
+				r := append([]rune(nil), dst...)
+				r[len(r)-1] += rune(c - lo)
+				cm.codes[c] = string(r)
+			}
+		}
+	}
+	for _, section := range sections(data, "beginbfchar", "endbfchar") {
+		for _, m := range cmapCharRe.FindAllSubmatch(section, -1) {
+			cm.codes[hexUint(m[1])] = utf16Hex(m[2])
+		}
+	}
+	return cm
+}
+
+func (cm *cmap) decode(s []byte) string {
+	var b strings.Builder
+	for i := 0; i+cm.codeLen <= len(s); i += cm.codeLen {
+		var code uint32
+		for _, c := range s[i : i+cm.codeLen] {
+			code = code<<8 | uint32(c)
+		}
+		if t, ok := cm.codes[code]; ok {
+			b.WriteString(t)
+		} else if cm.codeLen == 1 {
+			b.WriteRune(winAnsi(s[i]))
+		}
+	}
+	return b.String()
+}
+
+func sections(data []byte, begin, end string) [][]byte {
+	var out [][]byte
+	for {
+		i := bytes.Index(data, []byte(begin))
+		if i < 0 {
+			return out
+		}
+		data = data[i+len(begin):]
+		j := bytes.Index(data, []byte(end))
+		if j < 0 {
+			return append(out, data)
+		}
+		out = append(out, data[:j])
+		data = data[j+len(end):]
+	}
+}
+
+func hexUint(h []byte) uint32 {
+	n, _ := strconv.ParseUint(string(h), 16, 32)
+	return uint32(n)
+}
+
+func utf16Hex(h []byte) string {
+	h = bytes.Join(bytes.Fields(h), nil)
+	units := make([]uint16, 0, len(h)/4)
+	for i := 0; i+4 <= len(h); i += 4 {
+		n, err := strconv.ParseUint(string(h[i:i+4]), 16, 16)
+		if err != nil {
+			return ""
+		}
+		units = append(units, uint16(n))
+	}
+	return string(utf16.Decode(units))
+}
+
+// winAnsi maps a byte of a simple font without a ToUnicode map. Latin-1
+// covers most of it; the differences that show up on receipts are listed.
+func winAnsi(c byte) rune {
+	switch c {
+	case 0x80:
+		return '€'
+	case 0x91, 0x92:
+		return '\''
+	case 0x93, 0x94:
+		return '"'
+	case 0x96, 0x97:
+		return '-'
+	}
+	return rune(c)
+}
@@ -0,0 +1,50 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// flatePDF builds a one-page PDF whose content stream is the Flate
+// compressed content.
+func flatePDF(t *testing.T, content []byte) []byte {
+	t.Helper()
+	var z bytes.Buffer
+	w := zlib.NewWriter(&z)
+	if _, err := w.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	b.WriteString("1 0 obj << /Type /Page /Contents 2 0 R >> endobj\n")
+	fmt.Fprintf(&b, "2 0 obj << /Length %d /Filter /FlateDecode >>\nstream\n", z.Len())
+	b.Write(z.Bytes())
+	b.WriteString("\nendstream\nendobj\n%%EOF\n")
+	return b.Bytes()
+}
+
+func TestPDFTextFlate(t *testing.T) {
+	text, err := PDFText(flatePDF(t, []byte("BT /F1 12 Tf (Total 12.50) Tj ET")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Total 12.50" {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestPDFTextStreamTooLarge(t *testing.T) {
+	bomb := make([]byte, maxStreamBytes+1)
+	data := flatePDF(t, bomb)
+	if len(data) > 1<<20 {
+		t.Fatalf("test document is %d bytes; it should compress well", len(data))
+	}
+	if _, err := PDFText(data); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("err = %v, want ErrTooLarge", err)
+	}
+}
@@ -0,0 +1,343 @@
+package extract
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const maxCandidates = 5
+
+var (
+	currencyToken = `USD|EUR|GBP|INR|JPY|CAD|AUD|NZD|CHF|SGD|HKD|AED|SEK|NOK|DKK|CNY|ZAR|MXN|BRL|Rs\.?|[$€£₹¥]`
+	amountRe      = regexp.MustCompile(`(?:(` + currencyToken + `)\s?)?(-?\d{1,3}(?:[,.\x{00A0} ']\d{3})+(?:[.,]\d{1,2})?|-?\d+(?:[.,]\d{1,2})?)\b(?:\s?(` + currencyToken + `))?`)
+	currencyRe    = regexp.MustCompile(`(?:^|[^A-Za-z])(` + currencyToken + `)(?:$|[^A-Za-z])`)
+
+	strongTotalRe = regexp.MustCompile(`(?i)grand\s*total|total\s*(due|payable|to\s*pay|paid|amount|charged)|amount\s*(due|paid|payable|charged)|balance\s*due|net\s*(payable|amount)|order\s*total|invoice\s*total`)
+	totalRe       = regexp.MustCompile(`(?i)\btotal\b|\bpaid\b|\bcharged\b|\bpayment\b`)
+	notTotalRe    = regexp.MustCompile(`(?i)sub\s*-?\s*total|discount|saving|saved|\bchange\b|\bqty\b|quantity|\bitems?\b|points|cash\s*back|\btip\b`)
+	taxRe         = regexp.MustCompile(`(?i)\b(tax|vat|gst|cgst|sgst|igst)\b`)
+	inclusiveRe   = regexp.MustCompile(`(?i)\b(incl|inc\.|including|with)\b`)
+
+	isoDateRe   = regexp.MustCompile(`\b(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})\b`)
+	numDateRe   = regexp.MustCompile(`\b(\d{1,2})([-/.])(\d{1,2})[-/.](\d{2,4})\b`)
+	dayMonthRe  = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)?[\s-]+(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?[\s,-]+(\d{2,4})\b`)
+	monthDayRe  = regexp.MustCompile(`(?i)\b(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+(\d{1,2})(?:st|nd|rd|th)?,?\s+(\d{4})\b`)
+	dateLabelRe = regexp.MustCompile(`(?i)\b(date|dated|issued|purchased?|transaction|order(ed)?)\b`)
+
+	merchantLabelRe = regexp.MustCompile(`(?i)^\s*(merchant|store|shop|seller|sold\s+by|vendor|retailer|payee)\s*[:\-]\s*(.+)$`)
+	notMerchantRe   = regexp.MustCompile(`(?i)receipt|invoice|\bbill\b|welcome|thank|order\s*(no|#|number)|\btel\b|phone|\bfax\b|gstin|\bvat\s*(no|reg)|www\.|https?://|@|\bpage\b|\bcopy\b`)
+	domainRe        = regexp.MustCompile(`(?i)(?:www\.|https?://(?:www\.)?)([a-z0-9-]+)\.[a-z.]{2,}`)
+)
+
+var months = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+var currencySymbols = map[string]struct {
+	code       string
+	confidence float64
+}{
+	"$":   {"USD", 0.5}, // also CAD, AUD, SGD...
+	"€":   {"EUR", 0.85},
+	"£":   {"GBP", 0.85},
+	"₹":   {"INR", 0.85},
+	"Rs":  {"INR", 0.75},
+	"Rs.": {"INR", 0.75},
+	"¥":   {"JPY", 0.5}, // also CNY
+}
+
+// ParseReceipt finds candidates for a receipt's fields in its text. Dates
+// more than a day after now or more than five years before it are ignored.
+func ParseReceipt(text string, now time.Time) Fields {
+	var lines []string
+	for _, l := range strings.Split(text, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return Fields{
+		Merchant: top(merchantCandidates(lines)),
+		Date:     top(dateCandidates(lines, now)),
+		Total:    top(totalCandidates(lines)),
+		Currency: top(currencyCandidates(lines)),
+	}
+}
+
+// ParseAmount reads a number written with either "." or "," as the
+// decimal separator and any of ",", ".", " " or "'" between thousands.
+func ParseAmount(s string) (float64, bool) {
+	s = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\u00a0' || r == '\'' {
+			return -1
+		}
+		return r
+	}, s)
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 {
+		if frac := len(s) - i - 1; frac == 1 || frac == 2 {
+			s = strings.NewReplacer(".", "", ",", "").Replace(s[:i]) + "." + s[i+1:]
+		} else {
+			s = strings.NewReplacer(".", "", ",", "").Replace(s)
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+type amountMatch struct {
+	value    float64
+	currency string
+	decimals bool
+}
+
+func amountsIn(line string) []amountMatch {
+	var out []amountMatch
+	for _, m := range amountRe.FindAllStringSubmatch(line, -1) {
+		v, ok := ParseAmount(m[2])
+		if !ok {
+			continue
+		}
+		cur := m[1]
+		if cur == "" {
+			cur = m[3]
+		}
+		out = append(out, amountMatch{
+			value:    v,
+			currency: cur,
+			decimals: strings.ContainsAny(m[2][max(0, len(m[2])-3):], ".,"),
+		})
+	}
+	return out
+}
+
+func totalCandidates(lines []string) []Candidate[float64] {
+	var out []Candidate[float64]
+	for i, line := range lines {
+		if notTotalRe.MatchString(line) || (taxRe.MatchString(line) && !inclusiveRe.MatchString(line)) {
+			continue
+		}
+		var conf float64
+		switch {
+		case strongTotalRe.MatchString(line):
+			conf = 0.9
+		case totalRe.MatchString(line):
+			conf = 0.75
+		default:
+			continue
+		}
+		// Later totals on a receipt are usually the final ones.
+		conf += 0.02 * float64(i) / float64(len(lines))
+
+		amounts := amountsIn(line)
+		src := line
+		if len(amounts) == 0 && i+1 < len(lines) {
+			// Label and amount in separate columns often come out on
+			// consecutive lines.
+			amounts = amountsIn(lines[i+1])
+			src = line + " " + lines[i+1]
+			conf -= 0.1
+		}
+		if len(amounts) == 0 {
+			continue
+		}
+		a := amounts[len(amounts)-1]
+		if !a.decimals {
+			conf -= 0.15
+		}
+		out = append(out, Candidate[float64]{Value: a.value, Confidence: conf, Line: src})
+	}
+
+	// With no labelled total, the largest amount is a guess.
+	if len(out) == 0 {
+		var best *Candidate[float64]
+		for _, line := range lines {
+			for _, a := range amountsIn(line) {
+				if a.decimals && a.value > 0 && (best == nil || a.value > best.Value) {
+					best = &Candidate[float64]{Value: a.value, Confidence: 0.3, Line: line}
+				}
+			}
+		}
+		if best != nil {
+			out = append(out, *best)
+		}
+	}
+	return merge(out, func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) })
+}
+
+func currencyCandidates(lines []string) []Candidate[string] {
+	var out []Candidate[string]
+	for _, line := range lines {
+		onTotal := strongTotalRe.MatchString(line) || totalRe.MatchString(line)
+		for _, m := range currencyRe.FindAllStringSubmatch(line, -1) {
+			code, conf := m[1], 0.9
+			if sym, ok := currencySymbols[code]; ok {
+				code, conf = sym.code, sym.confidence
+			}
+			if onTotal {
+				conf += 0.05
+			}
+			out = append(out, Candidate[string]{Value: code, Confidence: conf, Line: line})
+		}
+	}
+	return merge(out, func(v string) string { return v })
+}
+
+func dateCandidates(lines []string, now time.Time) []Candidate[time.Time] {
+	earliest := now.AddDate(-5, 0, 0)
+	latest := now.Add(24 * time.Hour)
+	var out []Candidate[time.Time]
+	add := func(line string, y, m, d int, conf float64) {
+		if y < 100 {
+			y += 2000
+		}
+		if m < 1 || m > 12 || d < 1 || d > 31 {
+			return
+		}
+		t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+		if t.Day() != d || t.Before(earliest) || t.After(latest) {
+			return
+		}
+		if dateLabelRe.MatchString(line) {
+			conf += 0.1
+		}
+		out = append(out, Candidate[time.Time]{Value: t, Confidence: conf, Line: line})
+	}
+
+	for _, line := range lines {
+		for _, m := range isoDateRe.FindAllStringSubmatch(line, -1) {
+			add(line, atoi(m[1]), atoi(m[2]), atoi(m[3]), 0.85)
+		}
+		for _, m := range numDateRe.FindAllStringSubmatch(line, -1) {
+			a, sep, b, y := atoi(m[1]), m[2], atoi(m[3]), atoi(m[4])
+			switch {
+			case a > 12:
+				add(line, y, b, a, 0.75)
+			case b > 12:
+				add(line, y, a, b, 0.75)
+			case sep == ".":
+				// Dotted dates are day first wherever they are used.
+				add(line, y, b, a, 0.7)
+			default:
+				// 03/04 could be either; day first is more common worldwide.
+				add(line, y, b, a, 0.5)
+				add(line, y, a, b, 0.45)
+			}
+		}
+		for _, m := range dayMonthRe.FindAllStringSubmatch(line, -1) {
+			add(line, atoi(m[3]), int(months[strings.ToLower(m[2])]), atoi(m[1]), 0.8)
+		}
+		for _, m := range monthDayRe.FindAllStringSubmatch(line, -1) {
+			add(line, atoi(m[3]), int(months[strings.ToLower(m[1])]), atoi(m[2]), 0.8)
+		}
+	}
+	return merge(out, func(t time.Time) string { return t.Format(time.DateOnly) })
+}
+
+// merchantCandidates prefers a labelled merchant, then the first lines of
+// the receipt, where the store name is printed, then a web address.
+func merchantCandidates(lines []string) []Candidate[string] {
+	var out []Candidate[string]
+	for _, line := range lines {
+		if m := merchantLabelRe.FindStringSubmatch(line); m != nil {
+			out = append(out, Candidate[string]{Value: cleanMerchant(m[2]), Confidence: 0.85, Line: line})
+		}
+	}
+
+	conf := 0.6
+	for i, line := range lines {
+		if i >= 6 || conf < 0.3 {
+			break
+		}
+		if !looksLikeName(line) {
+			continue
+		}
+		out = append(out, Candidate[string]{Value: cleanMerchant(line), Confidence: conf, Line: line})
+		conf -= 0.15
+	}
+
+	for _, line := range lines {
+		if m := domainRe.FindStringSubmatch(line); m != nil {
+			name := strings.ToUpper(m[1][:1]) + m[1][1:]
+			out = append(out, Candidate[string]{Value: name, Confidence: 0.4, Line: line})
+			break
+		}
+	}
+	return merge(out, strings.ToLower)
+}
+
+// looksLikeName reports whether a line is mostly letters and is not an
+// address, date, amount or boilerplate.
+func looksLikeName(line string) bool {
+	if notMerchantRe.MatchString(line) || merchantLabelRe.MatchString(line) {
+		return false
+	}
+	if isoDateRe.MatchString(line) || numDateRe.MatchString(line) {
+		return false
+	}
+	var letters, digits int
+	for _, r := range line {
+		switch {
+		case unicode.IsLetter(r):
+			letters++
+		case unicode.IsDigit(r):
+			digits++
+		}
+	}
+	return letters >= 2 && digits*3 <= letters && len([]rune(line)) <= 60
+}
+
+func cleanMerchant(s string) string {
+	return strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r) && r != '&' && r != ')'
+	})
+}
+
+// merge keeps the best candidate per value, raising its confidence a
+// little when the value was seen more than once.
+func merge[T any](cands []Candidate[T], key func(T) string) []Candidate[T] {
+	best := map[string]int{}
+	var out []Candidate[T]
+	for _, c := range cands {
+		k := key(c.Value)
+		if k == "" {
+			continue
+		}
+		i, seen := best[k]
+		if !seen {
+			best[k] = len(out)
+			out = append(out, c)
+			continue
+		}
+		if c.Confidence > out[i].Confidence {
+			out[i] = c
+		}
+		out[i].Confidence += 0.05
+	}
+	for i := range out {
+		out[i].Confidence = min(0.99, max(0.01, float64(int(out[i].Confidence*100+0.5))/100))
+	}
+	return out
+}
+
+// top sorts candidates by confidence, earlier ones first on ties, and
+// keeps the best few.
+func top[T any](cands []Candidate[T]) []Candidate[T] {
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].Confidence > cands[j].Confidence })
+	if len(cands) > maxCandidates {
+		cands = cands[:maxCandidates]
+	}
+	if cands == nil {
+		cands = []Candidate[T]{}
+	}
+	return cands
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,50 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+type tesseract struct {
+	path      string
+	languages string
+}
+
+// NewTesseract runs the tesseract command at path, or the one on PATH when
+// path is empty. It returns an error when the command cannot be found, so
+// callers can run without OCR. languages is passed to -l, e.g. "eng+deu".
+func NewTesseract(path, languages string) (OCR, error) {
+	if path == "" {
+		path = "tesseract"
+	}
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("extract: tesseract not found: %w", err)
+	}
+	if languages == "" {
+		languages = "eng"
+	}
+	return &tesseract{path: resolved, languages: languages}, nil
+}
+
+// Recognize feeds the image on stdin and reads the text from stdout.
+// Tesseract reads JPEG, PNG, GIF and WebP, but not PDF.
+func (t *tesseract) Recognize(ctx context.Context, contentType string, data []byte) (string, error) {
+	if !strings.HasPrefix(contentType, "image/") {
+		return "", ErrUnsupported
+	}
+	// Page segmentation mode 4 reads a single column of text of variable
+	// sizes, which is how receipts are laid out.
+	cmd := exec.CommandContext(ctx, t.path, "stdin", "stdout", "-l", t.languages, "--psm", "4")
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("extract: tesseract: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
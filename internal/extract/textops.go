@@ -0,0 +1,353 @@
+package extract
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+type operandKind int
+
+const (
+	opNumber operandKind = iota
+	opString
+	opName
+	opArray
+)
+
+type operand struct {
+	kind  operandKind
+	num   float64
+	str   []byte
+	items []operand
+}
+
+// textState follows the text line matrix far enough to tell where lines
+// break.
+type textState struct {
+	out      *strings.Builder
+	fonts    map[string]*cmap
+	font     *cmap
+	y        float64
+	leading  float64
+	lastY    float64
+	started  bool // something was written
+	lineOpen bool // the current line has text
+	moved    bool // positioned since the last text on this line
+}
+
+// interpretText appends the text drawn by a content stream to out.
+func interpretText(content []byte, fonts map[string]*cmap, out *strings.Builder) {
+	st := &textState{out: out, fonts: fonts}
+	lx := &lexer{b: content}
+	var stack []operand
+	for {
+		tok, ok := lx.next()
+		if !ok {
+			break
+		}
+		if tok.op == "" {
+			stack = append(stack, tok.operand)
+			continue
+		}
+		st.apply(tok.op, stack)
+		stack = stack[:0]
+	}
+	if st.lineOpen {
+		out.WriteByte('\n')
+	}
+}
+
+func (st *textState) apply(op string, args []operand) {
+	num := func(i int) float64 {
+		if i < len(args) && args[i].kind == opNumber {
+			return args[i].num
+		}
+		return 0
+	}
+	switch op {
+	case "BT":
+		st.y = 0
+		st.moved = true
+	case "Tf":
+		if len(args) > 0 && args[0].kind == opName {
+			st.font = st.fonts[string(args[0].str)]
+		}
+	case "TL":
+		st.leading = num(0)
+	case "Td":
+		st.y += num(1)
+		st.moved = true
+	case "TD":
+		st.leading = -num(1)
+		st.y += num(1)
+		st.moved = true
+	case "Tm":
+		st.y = num(5)
+		st.moved = true
+	case "T*":
+		st.y -= st.leading
+		st.moved = true
+	case "Tj":
+		if len(args) > 0 {
+			st.show(args[len(args)-1].str)
+		}
+	case "'", "\"":
+		st.y -= st.leading
+		st.moved = true
+		st.newLine()
+		if len(args) > 0 {
+			st.show(args[len(args)-1].str)
+		}
+	case "TJ":
+		if len(args) == 0 || args[0].kind != opArray {
+			return
+		}
+		for _, item := range args[0].items {
+			switch item.kind {
+			case opString:
+				st.show(item.str)
+			case opNumber:
+				// Large negative adjustments move right by about a space.
+				if item.num < -180 {
+					st.moved = true
+				}
+			}
+		}
+	}
+}
+
+func (st *textState) newLine() {
+	if st.lineOpen {
+		st.out.WriteByte('\n')
+		st.lineOpen = false
+	}
+}
+
+func (st *textState) show(s []byte) {
+	var text string
+	if st.font != nil {
+		text = st.font.decode(s)
+	} else {
+		r := make([]rune, len(s))
+		for i, c := range s {
+			r[i] = winAnsi(c)
+		}
+		text = string(r)
+	}
+	if text == "" {
+		return
+	}
+	if st.started && math.Abs(st.y-st.lastY) > 0.5 {
+		st.newLine()
+	} else if st.lineOpen && st.moved && !strings.HasPrefix(text, " ") {
+		st.out.WriteByte(' ')
+	}
+	st.out.WriteString(text)
+	st.started, st.lineOpen, st.moved = true, true, false
+	st.lastY = st.y
+}
+
+type lexToken struct {
+	op      string
+	operand operand
+}
+
+type lexer struct {
+	b []byte
+	i int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelim(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *lexer) next() (lexToken, bool) {
+	for l.i < len(l.b) {
+		c := l.b[l.i]
+		switch {
+		case isPDFSpace(c):
+			l.i++
+		case c == '%':
+			for l.i < len(l.b) && l.b[l.i] != '\n' && l.b[l.i] != '\r' {
+				l.i++
+			}
+		case c == '(':
+			return lexToken{operand: operand{kind: opString, str: l.literal()}}, true
+		case c == '<' && l.i+1 < len(l.b) && l.b[l.i+1] == '<':
+			// Dictionaries only appear as marked-content properties.
+			l.skipDict()
+		case c == '<':
+			return lexToken{operand: operand{kind: opString, str: l.hex()}}, true
+		case c == '[':
+			l.i++
+			return lexToken{operand: operand{kind: opArray, items: l.array()}}, true
+		case c == ']' || c == '>' || c == '{' || c == '}' || c == ')':
+			l.i++
+		case c == '/':
+			l.i++
+			return lexToken{operand: operand{kind: opName, str: l.word()}}, true
+		default:
+			w := l.word()
+			if len(w) == 0 {
+				l.i++
+				continue
+			}
+			if n, err := strconv.ParseFloat(string(w), 64); err == nil {
+				return lexToken{operand: operand{kind: opNumber, num: n}}, true
+			}
+			if string(w) == "ID" {
+				l.skipInlineImage()
+				continue
+			}
+			return lexToken{op: string(w)}, true
+		}
+	}
+	return lexToken{}, false
+}
+
+func (l *lexer) word() []byte {
+	start := l.i
+	for l.i < len(l.b) && !isPDFSpace(l.b[l.i]) && !isPDFDelim(l.b[l.i]) {
+		l.i++
+	}
+	return l.b[start:l.i]
+}
+
+func (l *lexer) array() []operand {
+	var items []operand
+	for l.i < len(l.b) {
+		for l.i < len(l.b) && isPDFSpace(l.b[l.i]) {
+			l.i++
+		}
+		if l.i < len(l.b) && l.b[l.i] == ']' {
+			l.i++
+			break
+		}
+		tok, ok := l.next()
+		if !ok || tok.op != "" {
+			break
+		}
+		items = append(items, tok.operand)
+	}
+	return items
+}
+
+// literal reads a (string) with nested parentheses and escapes.
+func (l *lexer) literal() []byte {
+	l.i++
+	var out []byte
+	depth := 1
+	for l.i < len(l.b) {
+		c := l.b[l.i]
+		l.i++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.i >= len(l.b) {
+				return out
+			}
+			e := l.b[l.i]
+			l.i++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				if e == '\r' && l.i < len(l.b) && l.b[l.i] == '\n' {
+					l.i++
+				}
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for k := 0; k < 2 && l.i < len(l.b) && l.b[l.i] >= '0' && l.b[l.i] <= '7'; k++ {
+						v = v*8 + int(l.b[l.i]-'0')
+						l.i++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (l *lexer) hex() []byte {
+	l.i++
+	var digits []byte
+	for l.i < len(l.b) && l.b[l.i] != '>' {
+		if c := l.b[l.i]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.i++
+	}
+	l.i++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, 0, len(digits)/2)
+	for i := 0; i+1 < len(digits); i += 2 {
+		v, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			return out
+		}
+		out = append(out, byte(v))
+	}
+	return out
+}
+
+func (l *lexer) skipDict() {
+	depth := 0
+	for l.i+1 < len(l.b) {
+		switch {
+		case l.b[l.i] == '<' && l.b[l.i+1] == '<':
+			depth++
+			l.i += 2
+		case l.b[l.i] == '>' && l.b[l.i+1] == '>':
+			depth--
+			l.i += 2
+			if depth == 0 {
+				return
+			}
+		case l.b[l.i] == '(':
+			l.literal()
+		default:
+			l.i++
+		}
+	}
+	l.i = len(l.b)
+}
+
+// skipInlineImage skips the binary data between ID and EI.
+func (l *lexer) skipInlineImage() {
+	for l.i+2 < len(l.b) {
+		if isPDFSpace(l.b[l.i]) && l.b[l.i+1] == 'E' && l.b[l.i+2] == 'I' &&
+			(l.i+3 == len(l.b) || isPDFSpace(l.b[l.i+3])) {
+			l.i += 3
+			return
+		}
+		l.i++
+	}
+	l.i = len(l.b)
+}
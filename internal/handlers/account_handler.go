@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+type updateProfileRequest struct {
+	Name            *string `json:"name"`
+	Email           *string `json:"email"`
+	CurrentPassword string  `json:"currentPassword"` // required to change email
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+type deleteAccountRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"` // required when two-factor auth is on
+}
+
+func (h *AuthHandler) UpdateMe(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req updateProfileRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 15*time.Second)
+	defer cancel()
+
+	user, err := h.svc.UpdateProfile(ctx, userID, req.Name, req.Email, req.CurrentPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCredentials):
+			return respondError(c, http.StatusUnauthorized, "current password is incorrect")
+		case errors.Is(err, services.ErrEmailTaken):
+			return respondError(c, http.StatusConflict, err.Error())
+		}
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[map[string]any]{Data: profileResponse(user)})
+}
+
+func (h *AuthHandler) ChangePassword(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	sessionID := middleware.GetSessionID(c)
+	var req changePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	if req.CurrentPassword == "" || len(req.NewPassword) < 6 {
+		return respondError(c, http.StatusBadRequest, "currentPassword and a newPassword of at least 6 characters are required")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 15*time.Second)
+	defer cancel()
+
+	if err := h.svc.ChangePassword(ctx, userID, sessionID, req.CurrentPassword, req.NewPassword); err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			return respondError(c, http.StatusUnauthorized, "current password is incorrect")
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *AuthHandler) DeleteMe(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	sessionID := middleware.GetSessionID(c)
+	var req deleteAccountRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	if req.Password == "" {
+		return respondError(c, http.StatusBadRequest, "password is required")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 15*time.Second)
+	defer cancel()
+
+	at, err := h.svc.ScheduleDeletion(ctx, userID, sessionID, req.Password, req.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) || errors.Is(err, services.ErrInvalidMFACode) {
+			return respondError(c, http.StatusUnauthorized, err.Error())
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusAccepted, models.SingleResponse[map[string]any]{Data: map[string]any{
+		"deletionScheduledFor": at,
+	}})
+}
+
+func (h *AuthHandler) CancelDeletion(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.CancelDeletion(ctx, userID); err != nil {
+		if errors.Is(err, services.ErrNoDeletionScheduled) {
+			return respondError(c, http.StatusConflict, err.Error())
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/bankalert"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+const maxAlertText = 2000
+
+type AlertHandler struct {
+	svc services.AlertService
+}
+
+func NewAlertHandler(svc services.AlertService) *AlertHandler {
+	return &AlertHandler{svc: svc}
+}
+
+func alertError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrAlertTemplateNotFound):
+		return respondError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrAlertTemplateIsBuiltIn):
+		return respondError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrTooManyAlertTemplates):
+		return respondError(c, http.StatusConflict, err.Error())
+	}
+	return respondError(c, http.StatusBadRequest, err.Error())
+}
+
+// alertText checks the message text of a request. It returns "" after
+// writing the error response.
+func alertText(c echo.Context, text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", respondError(c, http.StatusBadRequest, "text is required")
+	}
+	if utf8.RuneCountInString(text) > maxAlertText {
+		return "", respondError(c, http.StatusBadRequest, "text is too long")
+	}
+	return text, nil
+}
+
+func alertLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.New("invalid timezone")
+	}
+	return loc, nil
+}
+
+type alertIngestRequest struct {
+	Text       string `json:"text"`
+	AutoCreate bool   `json:"autoCreate"`
+	// Currency is used when the message names none.
+	Currency string `json:"currency"`
+	// Timezone is an IANA name such as "Asia/Kolkata"; UTC by default.
+	Timezone string `json:"timezone"`
+}
+
+// Ingest handles POST /alerts/ingest, which phone automation apps call with
+// each bank message they forward.
+func (h *AlertHandler) Ingest(c echo.Context) error {
+	var req alertIngestRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	text, err := alertText(c, req.Text)
+	if text == "" {
+		return err
+	}
+	loc, err := alertLocation(req.Timezone)
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	scope, _ := middleware.GetWorkspace(c)
+	res, err := h.svc.Ingest(ctx, scope, text, services.AlertIngestOptions{
+		AutoCreate: req.AutoCreate,
+		Currency:   strings.ToUpper(strings.TrimSpace(req.Currency)),
+		Location:   loc,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrAlertUnmatched) {
+			return respondError(c, http.StatusUnprocessableEntity, err.Error(), "unmatched")
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	status := http.StatusCreated
+	if res.Status == services.AlertDuplicate {
+		status = http.StatusOK
+	}
+	return c.JSON(status, models.SingleResponse[*services.AlertIngestResult]{Data: res})
+}
+
+func (h *AlertHandler) ListTemplates(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	templates, err := h.svc.ListTemplates(ctx, userID)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"data": templates})
+}
+
+func (h *AlertHandler) CreateTemplate(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req services.AlertTemplateInput
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	t, err := h.svc.CreateTemplate(ctx, userID, req)
+	if err != nil {
+		return alertError(c, err)
+	}
+	return c.JSON(http.StatusCreated, models.SingleResponse[*bankalert.Template]{Data: t})
+}
+
+func (h *AlertHandler) UpdateTemplate(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req services.AlertTemplateInput
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	t, err := h.svc.UpdateTemplate(ctx, userID, c.Param("id"), req)
+	if err != nil {
+		return alertError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*bankalert.Template]{Data: t})
+}
+
+func (h *AlertHandler) DeleteTemplate(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.DeleteTemplate(ctx, userID, c.Param("id")); err != nil {
+		return alertError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+type alertTestRequest struct {
+	Text     string `json:"text"`
+	Timezone string `json:"timezone"`
+	// Template is tried alone when given, so a new pattern can be checked
+	// before it is saved.
+	Template *services.AlertTemplateInput `json:"template"`
+}
+
+// TestTemplates handles POST /alerts/templates/test. Nothing is saved.
+func (h *AlertHandler) TestTemplates(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req alertTestRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	text, err := alertText(c, req.Text)
+	if text == "" {
+		return err
+	}
+	loc, err := alertLocation(req.Timezone)
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	res, err := h.svc.TestTemplate(ctx, userID, text, req.Template, loc)
+	if err != nil {
+		return alertError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*services.AlertTestResult]{Data: res})
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+type APITokenHandler struct {
+	svc services.APITokenService
+}
+
+func NewAPITokenHandler(svc services.APITokenService) *APITokenHandler {
+	return &APITokenHandler{svc: svc}
+}
+
+type apiTokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+func (h *APITokenHandler) Create(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req apiTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	t, token, err := h.svc.Create(ctx, userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+	// The plaintext token is only ever returned here.
+	return c.JSON(http.StatusCreated, models.SingleResponse[map[string]any]{Data: map[string]any{
+		"token":    token,
+		"apiToken": t,
+	}})
+}
+
+func (h *APITokenHandler) List(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	tokens, err := h.svc.List(ctx, userID)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"data": tokens})
+}
+
+func (h *APITokenHandler) Revoke(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	id := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.Revoke(ctx, userID, id); err != nil {
+		if errors.Is(err, services.ErrAPITokenNotFound) {
+			return respondError(c, http.StatusNotFound, err.Error())
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
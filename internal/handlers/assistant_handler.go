@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/assistant"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+const maxAssistantText = 500
+
+type AssistantHandler struct {
+	svc services.AssistantService
+}
+
+func NewAssistantHandler(svc services.AssistantService) *AssistantHandler {
+	return &AssistantHandler{svc: svc}
+}
+
+type assistantRequest struct {
+	Text string `json:"text"`
+	// Currency is used when the text names none.
+	Currency string `json:"currency"`
+	// Timezone is an IANA name such as "Asia/Kolkata"; UTC by default.
+	Timezone   string `json:"timezone"`
+	CategoryID string `json:"categoryId"`
+}
+
+// assistantScopes is the scope an API token needs for each kind, to read
+// and to create.
+var assistantScopes = map[assistant.Kind][2]string{
+	assistant.KindTransaction: {models.ScopeTransactionsRead, models.ScopeTransactionsWrite},
+	assistant.KindReminder:    {models.ScopeRemindersRead, models.ScopeRemindersWrite},
+}
+
+// parse reads the request body and parses its text. When it returns no
+// proposal it has already written the error response.
+func (h *AssistantHandler) parse(c echo.Context, ctx context.Context) (*services.AssistantProposal, error) {
+	var req assistantRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" {
+		return nil, respondError(c, http.StatusBadRequest, "text is required")
+	}
+	if utf8.RuneCountInString(req.Text) > maxAssistantText {
+		return nil, respondError(c, http.StatusBadRequest, "text is too long")
+	}
+	opts := services.AssistantOptions{
+		Currency:   strings.ToUpper(strings.TrimSpace(req.Currency)),
+		CategoryID: req.CategoryID,
+	}
+	if req.Timezone != "" {
+		loc, err := time.LoadLocation(req.Timezone)
+		if err != nil {
+			return nil, respondError(c, http.StatusBadRequest, "invalid timezone")
+		}
+		opts.Location = loc
+	}
+
+	scope, _ := middleware.GetWorkspace(c)
+	p, err := h.svc.Parse(ctx, scope, req.Text, opts)
+	if err != nil {
+		if errors.Is(err, services.ErrCategoryNotFound) {
+			return nil, respondError(c, http.StatusBadRequest, err.Error())
+		}
+		return nil, respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return p, nil
+}
+
+// Parse handles POST /assistant/parse. Nothing is saved; the response shows
+// how the text was read and what it would create.
+func (h *AssistantHandler) Parse(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	p, err := h.parse(c, ctx)
+	if p == nil {
+		return err
+	}
+	if s, ok := assistantScopes[p.Interpretation.Kind]; ok && !middleware.HasScope(c, s[0]) {
+		return respondError(c, http.StatusForbidden, "token is missing scope "+s[0], "insufficient_scope")
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*services.AssistantProposal]{Data: p})
+}
+
+// Execute handles POST /assistant/execute. It takes the same body as Parse
+// and saves the result, unless the text needs clarifying first.
+func (h *AssistantHandler) Execute(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	p, err := h.parse(c, ctx)
+	if p == nil {
+		return err
+	}
+	s, ok := assistantScopes[p.Interpretation.Kind]
+	if ok && !middleware.HasScope(c, s[1]) {
+		return respondError(c, http.StatusForbidden, "token is missing scope "+s[1], "insufficient_scope")
+	}
+	// The interpretation goes back with the error so the client can show
+	// what needs clarifying.
+	if p.Interpretation.Blocked() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{
+			"error": map[string]any{
+				"message": services.ErrAssistantAmbiguous.Error(),
+				"code":    "ambiguous",
+			},
+			"data": p,
+		})
+	}
+
+	scope, _ := middleware.GetWorkspace(c)
+	created, err := h.svc.Execute(ctx, scope, p)
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusCreated, models.SingleResponse[*services.AssistantProposal]{Data: created})
+}
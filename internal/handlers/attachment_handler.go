@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+type AttachmentHandler struct {
+	svc     services.AttachmentService
+	maxSize int64
+}
+
+func NewAttachmentHandler(svc services.AttachmentService, maxSize int64) *AttachmentHandler {
+	return &AttachmentHandler{svc: svc, maxSize: maxSize}
+}
+
+func attachmentError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrAttachmentNotFound), errors.Is(err, services.ErrTransactionNotFound),
+		errors.Is(err, services.ErrNoThumbnail):
+		return respondError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		return respondError(c, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, services.ErrUnsupportedFileType):
+		return respondError(c, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, services.ErrTooManyAttachments):
+		return respondError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrEmptyFile):
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+	return respondError(c, http.StatusInternalServerError, err.Error())
+}
+
+// Upload accepts the file as the "file" field of a multipart form.
+func (h *AttachmentHandler) Upload(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, h.maxSize+1<<20)
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return attachmentError(c, services.ErrAttachmentTooLarge)
+		}
+		return respondError(c, http.StatusBadRequest, "file is required")
+	}
+	if fh.Size > h.maxSize {
+		return attachmentError(c, services.ErrAttachmentTooLarge)
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, "could not read file")
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, h.maxSize+1))
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, "could not read file")
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), time.Minute)
+	defer cancel()
+
+	a, err := h.svc.Upload(ctx, scope, c.Param("id"), fh.Filename, data)
+	if err != nil {
+		return attachmentError(c, err)
+	}
+	return c.JSON(http.StatusCreated, models.SingleResponse[*models.Attachment]{Data: a})
+}
+
+func (h *AttachmentHandler) List(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	attachments, err := h.svc.List(ctx, scope, c.Param("id"))
+	if err != nil {
+		return attachmentError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"data": attachments})
+}
+
+// Download streams the file as sent. Add ?inline=true to display it in
+// the browser instead of saving it.
+func (h *AttachmentHandler) Download(c echo.Context) error {
+	disposition := "attachment"
+	if inline, _ := strconv.ParseBool(c.QueryParam("inline")); inline {
+		disposition = "inline"
+	}
+	return h.serve(c, false, disposition)
+}
+
+func (h *AttachmentHandler) Thumbnail(c echo.Context) error {
+	return h.serve(c, true, "inline")
+}
+
+func (h *AttachmentHandler) serve(c echo.Context, thumbnail bool, disposition string) error {
+	scope, _ := middleware.GetWorkspace(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Minute)
+	defer cancel()
+
+	a, rc, err := h.svc.Open(ctx, scope, c.Param("id"), thumbnail)
+	if err != nil {
+		return attachmentError(c, err)
+	}
+	defer rc.Close()
+
+	header := c.Response().Header()
+	contentType := a.ContentType
+	if thumbnail {
+		contentType = "image/jpeg"
+	} else {
+		header.Set(echo.HeaderContentLength, strconv.FormatInt(a.Size, 10))
+	}
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": a.FileName}))
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	header.Set("Cache-Control", "private, max-age=3600")
+	return c.Stream(http.StatusOK, contentType, rc)
+}
+
+func (h *AttachmentHandler) Delete(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+	defer cancel()
+
+	if err := h.svc.Delete(ctx, scope, c.Param("id")); err != nil {
+		return attachmentError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+const maxAuditPageSize = 200
+
+type AuditHandler struct {
+	svc services.AuditService
+}
+
+func NewAuditHandler(svc services.AuditService) *AuditHandler {
+	return &AuditHandler{svc: svc}
+}
+
+// List returns the caller's audit events, newest first. action accepts a
+// comma-separated list.
+func (h *AuditHandler) List(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	limit, offset := parsePagination(c, 50)
+	limit = min(limit, maxAuditPageSize)
+
+	from, err := parseTimeParam(c, "from")
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid from param")
+	}
+	to, err := parseTimeParam(c, "to")
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid to param")
+	}
+
+	var actions []models.AuditAction
+	for _, a := range strings.Split(c.QueryParam("action"), ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			actions = append(actions, models.AuditAction(a))
+		}
+	}
+
+	filter := repositories.AuditFilter{
+		UserID:     userID,
+		Actions:    actions,
+		EntityType: c.QueryParam("entityType"),
+		EntityID:   c.QueryParam("entityId"),
+		From:       from,
+		To:         to,
+		Limit:      limit,
+		Offset:     offset,
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	events, total, err := h.svc.List(ctx, filter)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, models.ListResponse[models.AuditEvent]{
+		Data: events,
+		Pagination: models.Pagination{
+			Limit:  limit,
+			Offset: offset,
+			Total:  total,
+		},
+	})
+}
@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,16 +24,58 @@ func NewAuthHandler(svc services.AuthService) *AuthHandler {
 }
 
 type signupRequest struct {
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"deviceName"`
 }
 
 type loginRequest struct {
-	Email    string `json:"email"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"deviceName"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+	DeviceName   string `json:"deviceName"`
+}
+
+func sessionMeta(c echo.Context, deviceName string) services.SessionMeta {
+	return services.SessionMeta{
+		DeviceName: strings.TrimSpace(deviceName),
+		IP:         c.RealIP(),
+		UserAgent:  c.Request().UserAgent(),
+	}
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
 	Password string `json:"password"`
 }
 
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+func authResponse(user *models.User, tokens *services.TokenPair) map[string]any {
+	return map[string]any{
+		"user": map[string]any{
+			"id":            user.ID,
+			"name":          user.Name,
+			"email":         user.Email,
+			"emailVerified": user.EmailVerified,
+		},
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresIn":    tokens.ExpiresIn,
+	}
+}
+
 func (h *AuthHandler) Signup(c echo.Context) error {
 	var req signupRequest
 	if err := c.Bind(&req); err != nil {
@@ -47,23 +92,15 @@ func (h *AuthHandler) Signup(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
-	user, token, err := h.svc.Signup(ctx, req.Name, req.Email, req.Password)
+	user, tokens, err := h.svc.Signup(ctx, req.Name, req.Email, req.Password, sessionMeta(c, req.DeviceName))
 	if err != nil {
-		if strings.Contains(err.Error(), "exists") {
+		if errors.Is(err, services.ErrEmailTaken) {
 			return respondError(c, http.StatusConflict, "email already exists")
 		}
 		return respondError(c, http.StatusInternalServerError, err.Error())
 	}
 
-	resp := map[string]any{
-		"user": map[string]any{
-			"id":    user.ID,
-			"name":  user.Name,
-			"email": user.Email,
-		},
-		"token": token,
-	}
-	return c.JSON(http.StatusCreated, models.SingleResponse[map[string]any]{Data: resp})
+	return c.JSON(http.StatusCreated, models.SingleResponse[map[string]any]{Data: authResponse(user, tokens)})
 }
 
 func (h *AuthHandler) Login(c echo.Context) error {
@@ -79,20 +116,108 @@ func (h *AuthHandler) Login(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
-	user, token, err := h.svc.Login(ctx, req.Email, req.Password)
+	res, err := h.svc.Login(ctx, req.Email, req.Password, sessionMeta(c, req.DeviceName))
 	if err != nil {
+		var locked *services.LoginLockedError
+		if errors.As(err, &locked) {
+			secs := int64(math.Ceil(locked.RetryAfter.Seconds()))
+			c.Response().Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+			return respondError(c, http.StatusTooManyRequests, "too many failed login attempts; try again later")
+		}
 		return respondError(c, http.StatusUnauthorized, "invalid credentials")
 	}
 
-	resp := map[string]any{
-		"user": map[string]any{
-			"id":    user.ID,
-			"name":  user.Name,
-			"email": user.Email,
-		},
-		"token": token,
+	if res.Challenge != nil {
+		// Second step: POST /auth/mfa/verify with the challenge token.
+		return c.JSON(http.StatusOK, models.SingleResponse[map[string]any]{Data: map[string]any{
+			"mfaRequired":    true,
+			"challengeToken": res.Challenge.Token,
+			"expiresIn":      res.Challenge.ExpiresIn,
+		}})
+	}
+
+	return c.JSON(http.StatusOK, models.SingleResponse[map[string]any]{Data: authResponse(res.User, res.Tokens)})
+}
+
+func (h *AuthHandler) Refresh(c echo.Context) error {
+	var req refreshRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	if req.RefreshToken == "" {
+		return respondError(c, http.StatusBadRequest, "refreshToken is required")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	tokens, err := h.svc.Refresh(ctx, req.RefreshToken, sessionMeta(c, req.DeviceName))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			return respondError(c, http.StatusUnauthorized, err.Error())
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, models.SingleResponse[*services.TokenPair]{Data: tokens})
+}
+
+func (h *AuthHandler) Logout(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	sessionID := middleware.GetSessionID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.Logout(ctx, userID, sessionID); err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *AuthHandler) ListSessions(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	current := middleware.GetSessionID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	sessions, err := h.svc.ListSessions(ctx, userID)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	resp := make([]map[string]any, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, map[string]any{
+			"id":         s.ID,
+			"deviceName": s.DeviceName,
+			"ip":         s.IP,
+			"userAgent":  s.UserAgent,
+			"createdAt":  s.CreatedAt,
+			"lastUsedAt": s.LastUsedAt,
+			"expiresAt":  s.ExpiresAt,
+			"current":    s.ID == current,
+		})
 	}
-	return c.JSON(http.StatusOK, models.SingleResponse[map[string]any]{Data: resp})
+	return c.JSON(http.StatusOK, map[string]any{"data": resp})
+}
+
+func (h *AuthHandler) RevokeSession(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	id := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	found, err := h.svc.RevokeSession(ctx, userID, id)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	if !found {
+		return respondError(c, http.StatusNotFound, "session not found")
+	}
+	return c.NoContent(http.StatusNoContent)
 }
 
 func (h *AuthHandler) Me(c echo.Context) error {
@@ -112,11 +237,94 @@ func (h *AuthHandler) Me(c echo.Context) error {
 		return respondError(c, http.StatusNotFound, "user not found")
 	}
 
-	resp := map[string]any{
-		"id":        user.ID,
-		"name":      user.Name,
-		"email":     user.Email,
-		"createdAt": user.CreatedAt,
+	return c.JSON(http.StatusOK, models.SingleResponse[map[string]any]{Data: profileResponse(user)})
+}
+
+func profileResponse(user *models.User) map[string]any {
+	return map[string]any{
+		"id":                   user.ID,
+		"name":                 user.Name,
+		"email":                user.Email,
+		"emailVerified":        user.EmailVerified,
+		"mfaEnabled":           user.MFA.Enabled,
+		"deletionScheduledFor": user.DeletionScheduledFor,
+		"createdAt":            user.CreatedAt,
+	}
+}
+
+func (h *AuthHandler) ForgotPassword(c echo.Context) error {
+	var req forgotPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" {
+		return respondError(c, http.StatusBadRequest, "email is required")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 15*time.Second)
+	defer cancel()
+
+	if err := h.svc.ForgotPassword(ctx, req.Email); err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	// Same answer whether or not the account exists.
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (h *AuthHandler) ResetPassword(c echo.Context) error {
+	var req resetPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	if req.Token == "" || len(req.Password) < 6 {
+		return respondError(c, http.StatusBadRequest, "token and a password of at least 6 characters are required")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.ResetPassword(ctx, req.Token, req.Password); err != nil {
+		if errors.Is(err, services.ErrInvalidToken) {
+			return respondError(c, http.StatusBadRequest, err.Error())
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *AuthHandler) VerifyEmail(c echo.Context) error {
+	var req verifyEmailRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	if req.Token == "" {
+		return respondError(c, http.StatusBadRequest, "token is required")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.VerifyEmail(ctx, req.Token); err != nil {
+		if errors.Is(err, services.ErrInvalidToken) {
+			return respondError(c, http.StatusBadRequest, err.Error())
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *AuthHandler) ResendVerification(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 15*time.Second)
+	defer cancel()
+
+	if err := h.svc.SendVerification(ctx, userID); err != nil {
+		if errors.Is(err, services.ErrAlreadyVerified) {
+			return respondError(c, http.StatusConflict, err.Error())
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
 	}
-	return c.JSON(http.StatusOK, models.SingleResponse[map[string]any]{Data: resp})
+	return c.NoContent(http.StatusAccepted)
 }
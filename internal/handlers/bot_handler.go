@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/bot"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+type BotHandler struct {
+	svc    services.BotService
+	secret string
+}
+
+func NewBotHandler(svc services.BotService, secret string) *BotHandler {
+	return &BotHandler{svc: svc, secret: secret}
+}
+
+// Webhook receives updates from the bot API. Failures are logged rather
+// than returned: the API retries non-2xx responses, and a retry would only
+// repeat the same reply.
+func (h *BotHandler) Webhook(c echo.Context) error {
+	got := c.Request().Header.Get(bot.SecretHeader)
+	if subtle.ConstantTimeCompare([]byte(got), []byte(h.secret)) != 1 {
+		return respondError(c, http.StatusUnauthorized, "invalid webhook secret")
+	}
+	var u bot.Update
+	if err := c.Bind(&u); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+	defer cancel()
+
+	if err := h.svc.HandleUpdate(ctx, u); err != nil {
+		log.Printf("bot: update %d: %v", u.UpdateID, err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+type botPairingRequest struct {
+	// Timezone is an IANA name such as "Asia/Kolkata"; UTC by default.
+	Timezone string `json:"timezone"`
+	// Currency is assumed for amounts typed without one.
+	Currency string `json:"currency"`
+}
+
+func (h *BotHandler) CreatePairing(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req botPairingRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	code, err := h.svc.CreatePairingCode(ctx, userID, req.Timezone, req.Currency)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTimezone) {
+			return respondError(c, http.StatusBadRequest, err.Error())
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusCreated, models.SingleResponse[*services.BotPairingCode]{Data: code})
+}
+
+func (h *BotHandler) ListLinks(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	links, err := h.svc.ListLinks(ctx, userID)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"data": links})
+}
+
+func (h *BotHandler) Unlink(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.Unlink(ctx, userID, c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrBotLinkNotFound) {
+			return respondError(c, http.StatusNotFound, err.Error())
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -25,7 +25,7 @@ type categoryRequest struct {
 }
 
 func (h *CategoryHandler) Create(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 	var req categoryRequest
 	if err := c.Bind(&req); err != nil {
 		return respondError(c, http.StatusBadRequest, "invalid payload")
@@ -37,7 +37,7 @@ func (h *CategoryHandler) Create(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
-	cat, err := h.svc.Create(ctx, userID, req.Name, req.ParentID)
+	cat, err := h.svc.Create(ctx, scope, req.Name, req.ParentID)
 	if err != nil {
 		return respondError(c, http.StatusBadRequest, err.Error())
 	}
@@ -52,7 +52,7 @@ func (h *CategoryHandler) Create(c echo.Context) error {
 }
 
 func (h *CategoryHandler) List(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 	parentID := c.QueryParam("parentId")
 	var pid *string
 	if parentID != "" {
@@ -62,7 +62,7 @@ func (h *CategoryHandler) List(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
-	cats, err := h.svc.List(ctx, userID, pid)
+	cats, err := h.svc.List(ctx, scope, pid)
 	if err != nil {
 		return respondError(c, http.StatusInternalServerError, err.Error())
 	}
@@ -80,13 +80,13 @@ func (h *CategoryHandler) List(c echo.Context) error {
 }
 
 func (h *CategoryHandler) Get(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 	id := c.Param("id")
 
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
-	cat, err := h.svc.Get(ctx, userID, id)
+	cat, err := h.svc.Get(ctx, scope, id)
 	if err != nil {
 		return respondError(c, http.StatusInternalServerError, err.Error())
 	}
@@ -103,7 +103,7 @@ func (h *CategoryHandler) Get(c echo.Context) error {
 }
 
 func (h *CategoryHandler) Update(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 	id := c.Param("id")
 	var req categoryRequest
 	if err := c.Bind(&req); err != nil {
@@ -116,7 +116,7 @@ func (h *CategoryHandler) Update(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
-	cat, err := h.svc.Update(ctx, userID, id, req.Name, req.ParentID)
+	cat, err := h.svc.Update(ctx, scope, id, req.Name, req.ParentID)
 	if err != nil {
 		return respondError(c, http.StatusBadRequest, err.Error())
 	}
@@ -130,13 +130,13 @@ func (h *CategoryHandler) Update(c echo.Context) error {
 }
 
 func (h *CategoryHandler) Delete(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 	id := c.Param("id")
 
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
-	if err := h.svc.Delete(ctx, userID, id); err != nil {
+	if err := h.svc.Delete(ctx, scope, id); err != nil {
 		return respondError(c, http.StatusInternalServerError, err.Error())
 	}
 	return c.NoContent(http.StatusNoContent)
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+type DigestHandler struct {
+	svc services.DigestService
+}
+
+func NewDigestHandler(svc services.DigestService) *DigestHandler {
+	return &DigestHandler{svc: svc}
+}
+
+type digestRequest struct {
+	Frequency *string `json:"frequency"`
+	SendTime  *string `json:"sendTime"`
+	Timezone  *string `json:"timezone"`
+	IsActive  *bool   `json:"isActive"`
+}
+
+func (h *DigestHandler) Create(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req digestRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	if req.Frequency == nil {
+		return respondError(c, http.StatusBadRequest, "frequency is required")
+	}
+
+	d := &models.DigestSubscription{
+		UserID:    userID,
+		Frequency: models.DigestFrequency(*req.Frequency),
+	}
+	if req.SendTime != nil {
+		d.SendTime = *req.SendTime
+	}
+	if req.Timezone != nil {
+		d.Timezone = *req.Timezone
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	created, err := h.svc.Create(ctx, d)
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusCreated, models.SingleResponse[*models.DigestSubscription]{Data: created})
+}
+
+func (h *DigestHandler) List(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	subs, err := h.svc.List(ctx, userID)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"data": subs})
+}
+
+func (h *DigestHandler) Update(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	id := c.Param("id")
+
+	var req digestRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	patch := services.DigestPatch{
+		SendTime: req.SendTime,
+		Timezone: req.Timezone,
+		IsActive: req.IsActive,
+	}
+	if req.Frequency != nil {
+		f := models.DigestFrequency(*req.Frequency)
+		patch.Frequency = &f
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	updated, err := h.svc.Update(ctx, userID, id, patch)
+	if err != nil {
+		if errors.Is(err, services.ErrDigestNotFound) {
+			return respondError(c, http.StatusNotFound, err.Error())
+		}
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*models.DigestSubscription]{Data: updated})
+}
+
+func (h *DigestHandler) Delete(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	id := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.Delete(ctx, userID, id); err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
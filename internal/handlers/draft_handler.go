@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+type DraftHandler struct {
+	svc services.DraftService
+}
+
+func NewDraftHandler(svc services.DraftService) *DraftHandler {
+	return &DraftHandler{svc: svc}
+}
+
+func draftError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrDraftNotFound):
+		return respondError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrDraftResolved):
+		return respondError(c, http.StatusConflict, err.Error())
+	}
+	return respondError(c, http.StatusBadRequest, err.Error())
+}
+
+// List handles GET /drafts. Pending drafts are listed unless ?status= asks
+// for approved, rejected or all.
+func (h *DraftHandler) List(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+	limit, offset := parsePagination(c, 20)
+
+	status := models.DraftStatus(c.QueryParam("status"))
+	switch status {
+	case "":
+		status = models.DraftPending
+	case "all":
+		status = ""
+	case models.DraftPending, models.DraftApproved, models.DraftRejected:
+	default:
+		return respondError(c, http.StatusBadRequest, "status must be pending, approved, rejected or all")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	drafts, total, err := h.svc.List(ctx, scope, status, limit, offset)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, models.ListResponse[models.TransactionDraft]{
+		Data:       drafts,
+		Pagination: models.Pagination{Limit: limit, Offset: offset, Total: total},
+	})
+}
+
+func (h *DraftHandler) Get(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	d, err := h.svc.Get(ctx, scope, c.Param("id"))
+	if err != nil {
+		return draftError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*models.TransactionDraft]{Data: d})
+}
+
+// Approve handles POST /drafts/:id/approve. The body may correct the draft
+// and add a category and tags; an empty body approves it as it is.
+func (h *DraftHandler) Approve(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+	var req services.DraftApproval
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	d, err := h.svc.Approve(ctx, scope, c.Param("id"), req)
+	if err != nil {
+		return draftError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*models.TransactionDraft]{Data: d})
+}
+
+func (h *DraftHandler) Reject(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.Reject(ctx, scope, c.Param("id")); err != nil {
+		return draftError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+type ExportHandler struct {
+	svc services.ExportService
+}
+
+func NewExportHandler(svc services.ExportService) *ExportHandler {
+	return &ExportHandler{svc: svc}
+}
+
+func (h *ExportHandler) exportResponse(e *models.Export) map[string]any {
+	resp := map[string]any{
+		"id":        e.ID,
+		"status":    e.Status,
+		"createdAt": e.CreatedAt,
+		"statusUrl": "/api/v1/export/" + e.ID,
+	}
+	if e.Error != "" {
+		resp["error"] = e.Error
+	}
+	if e.CompletedAt != nil {
+		resp["completedAt"] = e.CompletedAt
+	}
+	if e.Status == models.ExportReady {
+		link, expires := h.svc.DownloadURL(e)
+		resp["fileName"] = e.FileName
+		resp["size"] = e.Size
+		resp["counts"] = e.Counts
+		resp["expiresAt"] = e.ExpiresAt
+		resp["downloadUrl"] = link
+		resp["downloadUrlExpiresAt"] = expires
+	}
+	return resp
+}
+
+func (h *ExportHandler) Create(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	e, err := h.svc.Request(ctx, userID)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusAccepted, models.SingleResponse[map[string]any]{Data: h.exportResponse(e)})
+}
+
+func (h *ExportHandler) List(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	exports, err := h.svc.List(ctx, userID)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	resp := make([]map[string]any, 0, len(exports))
+	for i := range exports {
+		resp = append(resp, h.exportResponse(&exports[i]))
+	}
+	return c.JSON(http.StatusOK, map[string]any{"data": resp})
+}
+
+func (h *ExportHandler) Get(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	id := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	e, err := h.svc.Get(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, services.ErrExportNotFound) {
+			return respondError(c, http.StatusNotFound, err.Error())
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[map[string]any]{Data: h.exportResponse(e)})
+}
+
+// Download serves the archive to anyone holding a valid signed link, so it
+// can be opened directly in a browser without an Authorization header.
+func (h *ExportHandler) Download(c echo.Context) error {
+	id := c.Param("id")
+	expires, err := strconv.ParseInt(c.QueryParam("expires"), 10, 64)
+	if err != nil {
+		return respondError(c, http.StatusForbidden, services.ErrInvalidDownloadLink.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Minute)
+	defer cancel()
+
+	e, rc, err := h.svc.OpenDownload(ctx, id, expires, c.QueryParam("sig"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidDownloadLink):
+			return respondError(c, http.StatusForbidden, err.Error())
+		case errors.Is(err, services.ErrExportNotFound):
+			return respondError(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrExportNotReady):
+			return respondError(c, http.StatusConflict, err.Error())
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	defer rc.Close()
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, e.FileName))
+	c.Response().Header().Set(echo.HeaderContentLength, strconv.FormatInt(e.Size, 10))
+	return c.Stream(http.StatusOK, "application/zip", rc)
+}
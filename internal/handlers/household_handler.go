@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+type HouseholdHandler struct {
+	svc services.HouseholdService
+}
+
+func NewHouseholdHandler(svc services.HouseholdService) *HouseholdHandler {
+	return &HouseholdHandler{svc: svc}
+}
+
+type householdRequest struct {
+	Name string `json:"name"`
+}
+
+type invitationRequest struct {
+	Email string               `json:"email"`
+	Role  models.HouseholdRole `json:"role"`
+}
+
+type memberRoleRequest struct {
+	Role models.HouseholdRole `json:"role"`
+}
+
+func householdError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrHouseholdNotFound), errors.Is(err, services.ErrInvitationNotFound):
+		return respondError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrNotHouseholdOwner), errors.Is(err, services.ErrEmailNotVerified):
+		return respondError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrAlreadyMember), errors.Is(err, services.ErrLastOwner):
+		return respondError(c, http.StatusConflict, err.Error())
+	}
+	return respondError(c, http.StatusBadRequest, err.Error())
+}
+
+func (h *HouseholdHandler) Create(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req householdRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	hh, err := h.svc.Create(ctx, userID, req.Name)
+	if err != nil {
+		return householdError(c, err)
+	}
+	return c.JSON(http.StatusCreated, models.SingleResponse[*models.Household]{Data: hh})
+}
+
+func (h *HouseholdHandler) List(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	households, err := h.svc.List(ctx, userID)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"data": households})
+}
+
+func (h *HouseholdHandler) Get(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	d, err := h.svc.Get(ctx, userID, c.Param("id"))
+	if err != nil {
+		return householdError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*services.HouseholdDetails]{Data: d})
+}
+
+func (h *HouseholdHandler) Update(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req householdRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	hh, err := h.svc.Rename(ctx, userID, c.Param("id"), req.Name)
+	if err != nil {
+		return householdError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*models.Household]{Data: hh})
+}
+
+func (h *HouseholdHandler) Delete(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+	defer cancel()
+
+	if err := h.svc.Delete(ctx, userID, c.Param("id")); err != nil {
+		return householdError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HouseholdHandler) Invite(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req invitationRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	if req.Role == "" {
+		req.Role = models.RoleEditor
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	inv, err := h.svc.Invite(ctx, userID, c.Param("id"), req.Email, req.Role)
+	if err != nil {
+		return householdError(c, err)
+	}
+	return c.JSON(http.StatusCreated, models.SingleResponse[*models.HouseholdInvitation]{Data: inv})
+}
+
+func (h *HouseholdHandler) RevokeInvitation(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.RevokeInvitation(ctx, userID, c.Param("id"), c.Param("invitationId")); err != nil {
+		return householdError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HouseholdHandler) UpdateMember(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req memberRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.UpdateMemberRole(ctx, userID, c.Param("id"), c.Param("userId"), req.Role); err != nil {
+		return householdError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RemoveMember also lets members leave: DELETE .../members/<own user ID>.
+func (h *HouseholdHandler) RemoveMember(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.RemoveMember(ctx, userID, c.Param("id"), c.Param("userId")); err != nil {
+		return householdError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *HouseholdHandler) MyInvitations(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	invitations, err := h.svc.PendingInvitations(ctx, userID)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"data": invitations})
+}
+
+func (h *HouseholdHandler) AcceptInvitation(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	hh, err := h.svc.AcceptInvitation(ctx, userID, c.Param("id"))
+	if err != nil {
+		return householdError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*models.Household]{Data: hh})
+}
+
+func (h *HouseholdHandler) DeclineInvitation(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.DeclineInvitation(ctx, userID, c.Param("id")); err != nil {
+		return householdError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+const maxImportSize = 100 << 20
+
+type ImportHandler struct {
+	svc services.ImportService
+}
+
+func NewImportHandler(svc services.ImportService) *ImportHandler {
+	return &ImportHandler{svc: svc}
+}
+
+// Import accepts an export archive either as the "archive" field of a
+// multipart form or as a raw application/zip body.
+func (h *ImportHandler) Import(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	opts := services.ImportOptions{Mode: services.ImportMode(c.QueryParam("mode"))}
+	if opts.Mode == "" {
+		opts.Mode = services.ImportMerge
+	}
+	if v := c.QueryParam("dryRun"); v != "" {
+		dry, err := strconv.ParseBool(v)
+		if err != nil {
+			return respondError(c, http.StatusBadRequest, "dryRun must be true or false")
+		}
+		opts.DryRun = dry
+	}
+
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, maxImportSize+1<<20)
+
+	var (
+		r    io.ReaderAt
+		size int64
+	)
+	if strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		fh, err := c.FormFile("archive")
+		if err != nil {
+			return respondError(c, http.StatusBadRequest, "archive file is required")
+		}
+		if fh.Size > maxImportSize {
+			return respondError(c, http.StatusRequestEntityTooLarge, "archive is too large")
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return respondError(c, http.StatusBadRequest, "could not read archive")
+		}
+		defer f.Close()
+		r, size = f, fh.Size
+	} else {
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxImportSize+1))
+		if err != nil {
+			return respondError(c, http.StatusBadRequest, "could not read archive")
+		}
+		if len(body) > maxImportSize {
+			return respondError(c, http.StatusRequestEntityTooLarge, "archive is too large")
+		}
+		r, size = bytes.NewReader(body), int64(len(body))
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), 2*time.Minute)
+	defer cancel()
+
+	res, err := h.svc.Import(ctx, userID, r, size, opts)
+	if err != nil {
+		var ie *services.ImportError
+		if errors.As(err, &ie) {
+			return respondError(c, http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, services.ErrReplaceUnavailable) {
+			return respondError(c, http.StatusNotImplemented, err.Error())
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	status := http.StatusOK
+	if !opts.DryRun {
+		status = http.StatusCreated
+	}
+	return c.JSON(status, models.SingleResponse[*services.ImportResult]{Data: res})
+}
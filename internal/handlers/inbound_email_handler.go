@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+// InboundWebhookSecretHeader carries the shared secret on webhook calls.
+const InboundWebhookSecretHeader = "X-Webhook-Secret"
+
+type InboundEmailHandler struct {
+	svc     services.InboundEmailService
+	secret  string
+	maxSize int64
+}
+
+// NewInboundEmailHandler returns the handler. With an empty secret the
+// webhook refuses every call.
+func NewInboundEmailHandler(svc services.InboundEmailService, secret string, maxSize int64) *InboundEmailHandler {
+	return &InboundEmailHandler{svc: svc, secret: secret, maxSize: maxSize}
+}
+
+func inboundEmailError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInboundEmailNotFound), errors.Is(err, services.ErrInboundMailboxNotFound):
+		return respondError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInboundEmailTooLarge):
+		return respondError(c, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, services.ErrInvalidEmail):
+		return respondError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrInboundEmailDisabled):
+		return respondError(c, http.StatusNotImplemented, err.Error())
+	}
+	return respondError(c, http.StatusInternalServerError, err.Error())
+}
+
+// Webhook receives one email as raw RFC 5322 in the body, from a mail
+// provider's inbound routing. The recipients are taken from ?to= (comma
+// separated), or else from the message headers.
+func (h *InboundEmailHandler) Webhook(c echo.Context) error {
+	got := c.Request().Header.Get(InboundWebhookSecretHeader)
+	if h.secret == "" || subtle.ConstantTimeCompare([]byte(got), []byte(h.secret)) != 1 {
+		return respondError(c, http.StatusUnauthorized, "invalid webhook secret")
+	}
+	body := http.MaxBytesReader(c.Response(), c.Request().Body, h.maxSize)
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return inboundEmailError(c, services.ErrInboundEmailTooLarge)
+		}
+		return respondError(c, http.StatusBadRequest, "could not read body")
+	}
+	var to []string
+	for _, addr := range strings.Split(c.QueryParam("to"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			to = append(to, addr)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 2*time.Minute)
+	defer cancel()
+
+	n, err := h.svc.Receive(ctx, to, raw, services.InboundViaWebhook)
+	if err != nil {
+		if !errors.Is(err, services.ErrInboundMailboxNotFound) && !errors.Is(err, services.ErrInvalidEmail) {
+			log.Printf("inbound email: webhook: %v", err)
+		}
+		return inboundEmailError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, map[string]any{"data": map[string]int{"delivered": n}})
+}
+
+// Address returns the caller's address to forward receipts to.
+func (h *InboundEmailHandler) Address(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	addr, err := h.svc.Address(ctx, userID)
+	if err != nil {
+		return inboundEmailError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*services.InboundAddress]{Data: addr})
+}
+
+func (h *InboundEmailHandler) RotateAddress(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	addr, err := h.svc.RotateAddress(ctx, userID)
+	if err != nil {
+		return inboundEmailError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*services.InboundAddress]{Data: addr})
+}
+
+func (h *InboundEmailHandler) List(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	limit, offset := parsePagination(c, 20)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	emails, total, err := h.svc.List(ctx, userID, limit, offset)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, models.ListResponse[models.InboundEmail]{
+		Data:       emails,
+		Pagination: models.Pagination{Limit: limit, Offset: offset, Total: total},
+	})
+}
+
+func (h *InboundEmailHandler) Get(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	e, err := h.svc.Get(ctx, userID, c.Param("id"))
+	if err != nil {
+		return inboundEmailError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*models.InboundEmail]{Data: e})
+}
+
+// Raw downloads the email as it was received.
+func (h *InboundEmailHandler) Raw(c echo.Context) error {
+	return h.serve(c, -1)
+}
+
+// File downloads one of the email's attached files, by its index in files.
+func (h *InboundEmailHandler) File(c echo.Context) error {
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 {
+		return inboundEmailError(c, services.ErrInboundEmailNotFound)
+	}
+	return h.serve(c, index)
+}
+
+func (h *InboundEmailHandler) serve(c echo.Context, file int) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Minute)
+	defer cancel()
+
+	f, rc, err := h.svc.Open(ctx, userID, c.Param("id"), file)
+	if err != nil {
+		return inboundEmailError(c, err)
+	}
+	defer rc.Close()
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(f.Size, 10))
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": f.FileName}))
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	return c.Stream(http.StatusOK, f.ContentType, rc)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+type InsightHandler struct {
+	svc           services.InsightService
+	subscriptions services.SubscriptionService
+}
+
+func NewInsightHandler(svc services.InsightService, subscriptions services.SubscriptionService) *InsightHandler {
+	return &InsightHandler{
+		svc:           svc,
+		subscriptions: subscriptions,
+	}
+}
+
+func (h *InsightHandler) List(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	limit, offset := parsePagination(c, 20)
+
+	var kind *models.InsightKind
+	if kStr := c.QueryParam("kind"); kStr != "" {
+		k := models.InsightKind(kStr)
+		kind = &k
+	}
+
+	includeDismissed := false
+	if dStr := c.QueryParam("includeDismissed"); dStr != "" {
+		v, err := strconv.ParseBool(dStr)
+		if err != nil {
+			return respondError(c, http.StatusBadRequest, "invalid includeDismissed")
+		}
+		includeDismissed = v
+	}
+
+	filter := repositories.InsightFilter{
+		UserID:           userID,
+		Kind:             kind,
+		IncludeDismissed: includeDismissed,
+		Limit:            limit,
+		Offset:           offset,
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	insights, total, err := h.svc.List(ctx, filter)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, models.ListResponse[models.Insight]{
+		Data: insights,
+		Pagination: models.Pagination{
+			Limit:  limit,
+			Offset: offset,
+			Total:  total,
+		},
+	})
+}
+
+func (h *InsightHandler) Dismiss(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	id := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	found, err := h.svc.Dismiss(ctx, userID, id)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	if !found {
+		return respondError(c, http.StatusNotFound, "insight not found")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *InsightHandler) Refresh(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+	defer cancel()
+
+	if err := h.svc.Scan(ctx, userID); err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *InsightHandler) Subscriptions(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 15*time.Second)
+	defer cancel()
+
+	subs, err := h.subscriptions.Detect(ctx, userID)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"data": subs})
+}
+
+func (h *InsightHandler) ConfirmSubscription(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	key := c.Param("key")
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 15*time.Second)
+	defer cancel()
+
+	rem, err := h.subscriptions.Confirm(ctx, userID, key)
+	if err != nil {
+		if errors.Is(err, services.ErrSubscriptionNotFound) {
+			return respondError(c, http.StatusNotFound, err.Error())
+		}
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusCreated, models.SingleResponse[*models.Reminder]{Data: rem})
+}
+
+func (h *InsightHandler) DismissSubscription(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	key := c.Param("key")
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 15*time.Second)
+	defer cancel()
+
+	if err := h.subscriptions.Dismiss(ctx, userID, key); err != nil {
+		if errors.Is(err, services.ErrSubscriptionNotFound) {
+			return respondError(c, http.StatusNotFound, err.Error())
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+type mfaVerifyRequest struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"` // TOTP code or recovery code
+	DeviceName     string `json:"deviceName"`
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type mfaReauthRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// respondMFAError maps the second-factor errors shared by the MFA endpoints.
+func respondMFAError(c echo.Context, err error) error {
+	var locked *services.LoginLockedError
+	switch {
+	case errors.As(err, &locked):
+		secs := int64(math.Ceil(locked.RetryAfter.Seconds()))
+		c.Response().Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+		return respondError(c, http.StatusTooManyRequests, "too many failed login attempts; try again later")
+	case errors.Is(err, services.ErrInvalidCredentials), errors.Is(err, services.ErrInvalidMFACode),
+		errors.Is(err, services.ErrInvalidToken):
+		return respondError(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, services.ErrMFAAlreadyEnabled), errors.Is(err, services.ErrMFANotEnabled),
+		errors.Is(err, services.ErrNoPendingEnrolment):
+		return respondError(c, http.StatusConflict, err.Error())
+	default:
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (h *AuthHandler) VerifyMFA(c echo.Context) error {
+	var req mfaVerifyRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	if req.ChallengeToken == "" || req.Code == "" {
+		return respondError(c, http.StatusBadRequest, "challengeToken and code are required")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	user, tokens, err := h.svc.VerifyMFA(ctx, req.ChallengeToken, req.Code, sessionMeta(c, req.DeviceName))
+	if err != nil {
+		return respondMFAError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[map[string]any]{Data: authResponse(user, tokens)})
+}
+
+func (h *AuthHandler) MFAStatus(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	status, err := h.svc.MFAStatus(ctx, userID)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*services.MFAStatus]{Data: status})
+}
+
+func (h *AuthHandler) SetupTOTP(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	enrolment, err := h.svc.BeginTOTPEnrolment(ctx, userID)
+	if err != nil {
+		return respondMFAError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*services.TOTPEnrolment]{Data: enrolment})
+}
+
+func (h *AuthHandler) ConfirmTOTP(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	var req mfaCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	if req.Code == "" {
+		return respondError(c, http.StatusBadRequest, "code is required")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	codes, err := h.svc.ConfirmTOTPEnrolment(ctx, userID, req.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMFACode) {
+			return respondError(c, http.StatusBadRequest, err.Error())
+		}
+		return respondMFAError(c, err)
+	}
+	// Recovery codes are only ever shown here and on regeneration.
+	return c.JSON(http.StatusOK, models.SingleResponse[map[string]any]{Data: map[string]any{
+		"enabled":       true,
+		"recoveryCodes": codes,
+	}})
+}
+
+func (h *AuthHandler) DisableMFA(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	var req mfaReauthRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	if req.Password == "" || req.Code == "" {
+		return respondError(c, http.StatusBadRequest, "password and code are required")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.DisableMFA(ctx, userID, req.Password, req.Code); err != nil {
+		return respondMFAError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	var req mfaReauthRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	if req.Password == "" || req.Code == "" {
+		return respondError(c, http.StatusBadRequest, "password and code are required")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	codes, err := h.svc.RegenerateRecoveryCodes(ctx, userID, req.Password, req.Code)
+	if err != nil {
+		return respondMFAError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[map[string]any]{Data: map[string]any{
+		"recoveryCodes": codes,
+	}})
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/extract"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+type ReceiptHandler struct {
+	svc services.ReceiptService
+}
+
+func NewReceiptHandler(svc services.ReceiptService) *ReceiptHandler {
+	return &ReceiptHandler{svc: svc}
+}
+
+// Extract reads an attachment as a receipt and returns the field
+// candidates and a draft transaction for the user to confirm.
+func (h *ReceiptHandler) Extract(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+
+	// OCR of a large photo can take a while.
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 2*time.Minute)
+	defer cancel()
+
+	res, err := h.svc.Extract(ctx, scope, c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAttachmentNotFound):
+			return respondError(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, extract.ErrNoText), errors.Is(err, extract.ErrOCRUnavailable),
+			errors.Is(err, extract.ErrUnsupported), errors.Is(err, extract.ErrTooLarge):
+			return respondError(c, http.StatusUnprocessableEntity, err.Error())
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*services.ReceiptExtraction]{Data: res})
+}
@@ -14,23 +14,40 @@ import (
 )
 
 type ReminderHandler struct {
-	svc services.ReminderService
+	svc  services.ReminderService
+	tags services.TagService
 }
 
-func NewReminderHandler(svc services.ReminderService) *ReminderHandler {
-	return &ReminderHandler{svc: svc}
+func NewReminderHandler(svc services.ReminderService, tags services.TagService) *ReminderHandler {
+	return &ReminderHandler{svc: svc, tags: tags}
 }
 
 type reminderRequest struct {
-	Title          string  `json:"title"`
-	Description    *string `json:"description"`
-	DueAt          string  `json:"dueAt"`
-	RepeatInterval string  `json:"repeatInterval"`
-	IsActive       *bool   `json:"isActive"`
+	Title          string   `json:"title"`
+	Description    *string  `json:"description"`
+	DueAt          string   `json:"dueAt"`
+	RepeatInterval string   `json:"repeatInterval"`
+	IsActive       *bool    `json:"isActive"`
+	Amount         *float64 `json:"amount"`
+	Currency       *string  `json:"currency"`
+	Type           *string  `json:"type"`
+	CategoryID     *string  `json:"categoryId"`
+	TagIDs         []string `json:"tagIds"`
+}
+
+func (req reminderRequest) applyMoney(r *models.Reminder) {
+	r.Amount = req.Amount
+	r.Currency = req.Currency
+	r.CategoryID = req.CategoryID
+	r.TagIDs = req.TagIDs
+	if req.Type != nil {
+		t := models.TransactionType(*req.Type)
+		r.Type = &t
+	}
 }
 
 func (h *ReminderHandler) Create(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 	var req reminderRequest
 	if err := c.Bind(&req); err != nil {
 		return respondError(c, http.StatusBadRequest, "invalid payload")
@@ -44,7 +61,6 @@ func (h *ReminderHandler) Create(c echo.Context) error {
 	}
 
 	r := &models.Reminder{
-		UserID:      userID,
 		Title:       req.Title,
 		Description: req.Description,
 		DueAt:       dueAt,
@@ -52,11 +68,12 @@ func (h *ReminderHandler) Create(c echo.Context) error {
 	if req.RepeatInterval != "" {
 		r.RepeatInterval = models.RepeatInterval(req.RepeatInterval)
 	}
+	req.applyMoney(r)
 
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
-	created, err := h.svc.Create(ctx, r)
+	created, err := h.svc.Create(ctx, scope, r)
 	if err != nil {
 		return respondError(c, http.StatusBadRequest, err.Error())
 	}
@@ -64,7 +81,7 @@ func (h *ReminderHandler) Create(c echo.Context) error {
 }
 
 func (h *ReminderHandler) List(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 
 	isActiveParam := c.QueryParam("isActive")
 	var isActive *bool
@@ -84,16 +101,23 @@ func (h *ReminderHandler) List(c echo.Context) error {
 		return respondError(c, http.StatusBadRequest, "invalid to")
 	}
 
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	tagIDs, tagMatch, err := parseTagFilter(ctx, c, h.tags, scope)
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+
 	filter := repositories.ReminderFilter{
-		UserID:   userID,
+		Scope:    scope,
 		IsActive: isActive,
 		From:     from,
 		To:       to,
+		TagIDs:   tagIDs,
+		TagMatch: tagMatch,
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
-	defer cancel()
-
 	rems, err := h.svc.List(ctx, filter)
 	if err != nil {
 		return respondError(c, http.StatusInternalServerError, err.Error())
@@ -103,13 +127,13 @@ func (h *ReminderHandler) List(c echo.Context) error {
 }
 
 func (h *ReminderHandler) Get(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 	id := c.Param("id")
 
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
-	rem, err := h.svc.Get(ctx, userID, id)
+	rem, err := h.svc.Get(ctx, scope, id)
 	if err != nil {
 		return respondError(c, http.StatusInternalServerError, err.Error())
 	}
@@ -120,7 +144,7 @@ func (h *ReminderHandler) Get(c echo.Context) error {
 }
 
 func (h *ReminderHandler) Update(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 	id := c.Param("id")
 
 	var req reminderRequest
@@ -129,8 +153,7 @@ func (h *ReminderHandler) Update(c echo.Context) error {
 	}
 
 	r := &models.Reminder{
-		ID:     id,
-		UserID: userID,
+		ID: id,
 	}
 
 	if req.Title != "" {
@@ -152,11 +175,12 @@ func (h *ReminderHandler) Update(c echo.Context) error {
 	if req.IsActive != nil {
 		r.IsActive = *req.IsActive
 	}
+	req.applyMoney(r)
 
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
-	updated, err := h.svc.Update(ctx, userID, r)
+	updated, err := h.svc.Update(ctx, scope, r)
 	if err != nil {
 		return respondError(c, http.StatusBadRequest, err.Error())
 	}
@@ -164,13 +188,13 @@ func (h *ReminderHandler) Update(c echo.Context) error {
 }
 
 func (h *ReminderHandler) Delete(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 	id := c.Param("id")
 
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
-	if err := h.svc.Delete(ctx, userID, id); err != nil {
+	if err := h.svc.Delete(ctx, scope, id); err != nil {
 		return respondError(c, http.StatusInternalServerError, err.Error())
 	}
 	return c.NoContent(http.StatusNoContent)
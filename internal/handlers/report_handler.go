@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -12,15 +14,19 @@ import (
 )
 
 type ReportHandler struct {
-	svc services.ReportService
+	svc        services.ReportService
+	statements services.StatementService
 }
 
-func NewReportHandler(svc services.ReportService) *ReportHandler {
-	return &ReportHandler{svc: svc}
+func NewReportHandler(svc services.ReportService, statements services.StatementService) *ReportHandler {
+	return &ReportHandler{
+		svc:        svc,
+		statements: statements,
+	}
 }
 
 func (h *ReportHandler) Summary(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 
 	periodStr := c.QueryParam("period")
 	if periodStr == "" {
@@ -56,10 +62,58 @@ func (h *ReportHandler) Summary(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 15*time.Second)
 	defer cancel()
 
-	report, err := h.svc.GetSummary(ctx, userID, period, start, end, groupBy)
+	report, err := h.svc.GetSummary(ctx, scope, period, start, end, groupBy)
 	if err != nil {
 		return respondError(c, http.StatusBadRequest, err.Error())
 	}
 
 	return c.JSON(http.StatusOK, models.SingleResponse[*services.SummaryReport]{Data: report})
 }
+
+func (h *ReportHandler) Forecast(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+
+	days := services.DefaultForecastDays
+	if dStr := c.QueryParam("days"); dStr != "" {
+		v, err := strconv.Atoi(dStr)
+		if err != nil || v <= 0 || v > services.MaxForecastDays {
+			return respondError(c, http.StatusBadRequest, "days must be between 1 and 365")
+		}
+		days = v
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 15*time.Second)
+	defer cancel()
+
+	report, err := h.svc.GetForecast(ctx, scope, days)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, models.SingleResponse[*services.ForecastReport]{Data: report})
+}
+
+func (h *ReportHandler) Statement(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+
+	month := time.Now().UTC()
+	if mStr := c.QueryParam("month"); mStr != "" {
+		m, err := time.Parse("2006-01", mStr)
+		if err != nil {
+			return respondError(c, http.StatusBadRequest, "invalid month, expected YYYY-MM")
+		}
+		month = m
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+	defer cancel()
+
+	doc, err := h.statements.MonthlyPDF(ctx, scope, month)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		fmt.Sprintf(`attachment; filename="statement-%s.pdf"`, month.Format("2006-01")))
+	return c.Blob(http.StatusOK, "application/pdf", doc)
+}
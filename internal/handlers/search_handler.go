@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/search"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+const maxSearchLimit = 50
+
+type SearchHandler struct {
+	svc services.SearchService
+}
+
+func NewSearchHandler(svc services.SearchService) *SearchHandler {
+	return &SearchHandler{svc: svc}
+}
+
+// searchScopes is the read scope an API token needs for each result group.
+var searchScopes = []struct {
+	kind  search.Kind
+	scope string
+}{
+	{search.KindTransactions, models.ScopeTransactionsRead},
+	{search.KindReminders, models.ScopeRemindersRead},
+	{search.KindCategories, models.ScopeCategoriesRead},
+}
+
+// Search handles GET /search?q=&limit=. limit applies per result group.
+func (h *SearchHandler) Search(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+	q := c.QueryParam("q")
+	limit, _ := parsePagination(c, 10)
+	limit = min(limit, maxSearchLimit)
+
+	// A token only sees the groups it has read access to.
+	var allowed []search.Kind
+	for _, s := range searchScopes {
+		if middleware.HasScope(c, s.scope) {
+			allowed = append(allowed, s.kind)
+		}
+	}
+	if len(allowed) == 0 {
+		return respondError(c, http.StatusForbidden, "token has no read scope for search", "insufficient_scope")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	res, err := h.svc.Search(ctx, scope, q, allowed, limit)
+	if err != nil {
+		var perr *search.ParseError
+		if errors.As(err, &perr) || errors.Is(err, services.ErrEmptySearch) {
+			return respondError(c, http.StatusBadRequest, err.Error())
+		}
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*services.SearchResults]{Data: res})
+}
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+type SplitHandler struct {
+	svc services.SplitService
+}
+
+func NewSplitHandler(svc services.SplitService) *SplitHandler {
+	return &SplitHandler{svc: svc}
+}
+
+type contactRequest struct {
+	Name  string  `json:"name"`
+	Email *string `json:"email"`
+}
+
+type settlementRequest struct {
+	From     *services.PartyRef `json:"from"`
+	To       *services.PartyRef `json:"to"`
+	Amount   float64            `json:"amount"`
+	Currency string             `json:"currency"`
+	Note     *string            `json:"note"`
+	Date     string             `json:"date"`
+}
+
+func splitError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrContactNotFound),
+		errors.Is(err, services.ErrSplitNotFound),
+		errors.Is(err, services.ErrTransactionNotFound),
+		errors.Is(err, services.ErrCounterpartNotFound),
+		errors.Is(err, services.ErrContactLinkNotFound),
+		errors.Is(err, services.ErrSettlementNotFound):
+		return respondError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrContactHasBalance),
+		errors.Is(err, services.ErrContactLinkExists):
+		return respondError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrEmailNotVerified):
+		return respondError(c, http.StatusForbidden, err.Error())
+	}
+	return respondError(c, http.StatusBadRequest, err.Error())
+}
+
+func (h *SplitHandler) CreateContact(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req contactRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	contact, err := h.svc.CreateContact(ctx, userID, &models.Contact{Name: req.Name, Email: req.Email})
+	if err != nil {
+		return splitError(c, err)
+	}
+	return c.JSON(http.StatusCreated, models.SingleResponse[*models.Contact]{Data: contact})
+}
+
+func (h *SplitHandler) ListContacts(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	contacts, err := h.svc.ListContacts(ctx, userID)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"data": contacts})
+}
+
+func (h *SplitHandler) UpdateContact(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req contactRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	contact, err := h.svc.UpdateContact(ctx, userID, &models.Contact{ID: c.Param("id"), Name: req.Name, Email: req.Email})
+	if err != nil {
+		return splitError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*models.Contact]{Data: contact})
+}
+
+func (h *SplitHandler) DeleteContact(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.DeleteContact(ctx, userID, c.Param("id")); err != nil {
+		return splitError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *SplitHandler) RequestContactLink(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	link, err := h.svc.RequestContactLink(ctx, userID, req.Email)
+	if err != nil {
+		return splitError(c, err)
+	}
+	return c.JSON(http.StatusCreated, models.SingleResponse[*models.ContactLink]{Data: link})
+}
+
+func (h *SplitHandler) ListContactLinks(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	links, err := h.svc.ListContactLinks(ctx, userID)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"data": links})
+}
+
+func (h *SplitHandler) AcceptContactLink(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	link, err := h.svc.AcceptContactLink(ctx, userID, c.Param("id"))
+	if err != nil {
+		return splitError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*models.ContactLink]{Data: link})
+}
+
+func (h *SplitHandler) RemoveContactLink(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.RemoveContactLink(ctx, userID, c.Param("id")); err != nil {
+		return splitError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *SplitHandler) Split(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+	var req services.SplitRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	split, err := h.svc.Split(ctx, scope, c.Param("id"), req)
+	if err != nil {
+		return splitError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*models.ExpenseSplit]{Data: split})
+}
+
+func (h *SplitHandler) GetSplit(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	split, err := h.svc.GetSplit(ctx, scope, c.Param("id"))
+	if err != nil {
+		return splitError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*models.ExpenseSplit]{Data: split})
+}
+
+func (h *SplitHandler) RemoveSplit(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.RemoveSplit(ctx, scope, c.Param("id")); err != nil {
+		return splitError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *SplitHandler) Settle(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	var req settlementRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	date, err := parseBodyTime(req.Date)
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid date format")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	st, err := h.svc.Settle(ctx, userID, services.SettlementRequest{
+		From:     req.From,
+		To:       req.To,
+		Amount:   req.Amount,
+		Currency: req.Currency,
+		Note:     req.Note,
+		Date:     date,
+	})
+	if err != nil {
+		return splitError(c, err)
+	}
+	return c.JSON(http.StatusCreated, models.SingleResponse[*models.Settlement]{Data: st})
+}
+
+func (h *SplitHandler) ConfirmSettlement(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	st, err := h.svc.ConfirmSettlement(ctx, userID, c.Param("id"))
+	if err != nil {
+		return splitError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*models.Settlement]{Data: st})
+}
+
+func (h *SplitHandler) DeclineSettlement(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.svc.DeclineSettlement(ctx, userID, c.Param("id")); err != nil {
+		return splitError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *SplitHandler) ListSettlements(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	limit, offset := parsePagination(c, 20)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	settlements, total, err := h.svc.ListSettlements(ctx, userID, limit, offset)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, models.ListResponse[models.Settlement]{
+		Data:       settlements,
+		Pagination: models.Pagination{Limit: limit, Offset: offset, Total: total},
+	})
+}
+
+// Balances lists what each counterpart owes; ?simplify=true minimises the
+// payments needed between registered users.
+func (h *SplitHandler) Balances(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	simplify := false
+	if v := c.QueryParam("simplify"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return respondError(c, http.StatusBadRequest, "simplify must be true or false")
+		}
+		simplify = b
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	balances, err := h.svc.Balances(ctx, userID, simplify)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"data": balances})
+}
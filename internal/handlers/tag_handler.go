@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+	"github.com/ronak4195/personal-assistant/internal/services"
+)
+
+type TagHandler struct {
+	svc services.TagService
+}
+
+func NewTagHandler(svc services.TagService) *TagHandler {
+	return &TagHandler{svc: svc}
+}
+
+type tagRequest struct {
+	Name  *string `json:"name"`
+	Color *string `json:"color"`
+}
+
+func tagError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrTagNotFound):
+		return respondError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrTagExists):
+		return respondError(c, http.StatusConflict, err.Error())
+	}
+	return respondError(c, http.StatusBadRequest, err.Error())
+}
+
+func (h *TagHandler) Create(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+	var req tagRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+	if req.Name == nil {
+		return respondError(c, http.StatusBadRequest, "name is required")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	tag, err := h.svc.Create(ctx, scope, *req.Name, req.Color)
+	if err != nil {
+		return tagError(c, err)
+	}
+	return c.JSON(http.StatusCreated, models.SingleResponse[*models.Tag]{Data: tag})
+}
+
+func (h *TagHandler) List(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	tags, err := h.svc.List(ctx, scope)
+	if err != nil {
+		return respondError(c, http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"data": tags})
+}
+
+func (h *TagHandler) Update(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+	var req tagRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	tag, err := h.svc.Update(ctx, scope, c.Param("id"), req.Name, req.Color)
+	if err != nil {
+		return tagError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*models.Tag]{Data: tag})
+}
+
+func (h *TagHandler) Delete(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+	defer cancel()
+
+	if err := h.svc.Delete(ctx, scope, c.Param("id")); err != nil {
+		return tagError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *TagHandler) Bulk(c echo.Context) error {
+	scope, _ := middleware.GetWorkspace(c)
+	var req services.BulkTagRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, "invalid payload")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+	defer cancel()
+
+	res, err := h.svc.Bulk(ctx, scope, req)
+	if err != nil {
+		return tagError(c, err)
+	}
+	return c.JSON(http.StatusOK, models.SingleResponse[*services.BulkTagResult]{Data: res})
+}
+
+// parseTagFilter reads ?tags=a,b&tagMode=any|all. Tags may be given by ID or
+// name; an unknown tag is reported rather than silently matching nothing.
+func parseTagFilter(ctx context.Context, c echo.Context, tags services.TagService, scope repositories.Scope) ([]string, repositories.TagMatch, error) {
+	mode := repositories.TagMatch(c.QueryParam("tagMode"))
+	switch mode {
+	case "":
+		mode = repositories.TagMatchAny
+	case repositories.TagMatchAny, repositories.TagMatchAll:
+	default:
+		return nil, "", errors.New("tagMode must be any or all")
+	}
+
+	param := c.QueryParam("tags")
+	if param == "" {
+		return nil, mode, nil
+	}
+	ids, err := tags.Resolve(ctx, scope, strings.Split(param, ","))
+	if err != nil {
+		return nil, "", err
+	}
+	return ids, mode, nil
+}
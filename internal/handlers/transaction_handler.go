@@ -13,25 +13,27 @@ import (
 )
 
 type TransactionHandler struct {
-	svc services.TransactionService
+	svc  services.TransactionService
+	tags services.TagService
 }
 
-func NewTransactionHandler(svc services.TransactionService) *TransactionHandler {
-	return &TransactionHandler{svc: svc}
+func NewTransactionHandler(svc services.TransactionService, tags services.TagService) *TransactionHandler {
+	return &TransactionHandler{svc: svc, tags: tags}
 }
 
 type transactionCreateRequest struct {
-	Type          string  `json:"type"`
-	Amount        float64 `json:"amount"`
-	Currency      string  `json:"currency"`
-	CategoryID    *string `json:"categoryId"`
-	SubcategoryID *string `json:"subcategoryId"`
-	Description   *string `json:"description"`
-	Date          string  `json:"date"`
+	Type          string   `json:"type"`
+	Amount        float64  `json:"amount"`
+	Currency      string   `json:"currency"`
+	CategoryID    *string  `json:"categoryId"`
+	SubcategoryID *string  `json:"subcategoryId"`
+	Description   *string  `json:"description"`
+	TagIDs        []string `json:"tagIds"`
+	Date          string   `json:"date"`
 }
 
 func (h *TransactionHandler) Create(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 	var req transactionCreateRequest
 	if err := c.Bind(&req); err != nil {
 		return respondError(c, http.StatusBadRequest, "invalid payload")
@@ -50,20 +52,20 @@ func (h *TransactionHandler) Create(c echo.Context) error {
 	}
 
 	tx := &models.Transaction{
-		UserID:        userID,
 		Type:          models.TransactionType(req.Type),
 		Amount:        req.Amount,
 		Currency:      req.Currency,
 		CategoryID:    req.CategoryID,
 		SubcategoryID: req.SubcategoryID,
 		Description:   req.Description,
+		TagIDs:        req.TagIDs,
 		Date:          date,
 	}
 
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
-	created, err := h.svc.Create(ctx, tx)
+	created, err := h.svc.Create(ctx, scope, tx)
 	if err != nil {
 		return respondError(c, http.StatusBadRequest, err.Error())
 	}
@@ -72,7 +74,7 @@ func (h *TransactionHandler) Create(c echo.Context) error {
 }
 
 func (h *TransactionHandler) List(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 
 	limit, offset := parsePagination(c, 20)
 
@@ -105,21 +107,28 @@ func (h *TransactionHandler) List(c echo.Context) error {
 	sortParam := c.QueryParam("sort")
 	sortAsc := sortParam == "date_asc"
 
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	tagIDs, tagMatch, err := parseTagFilter(ctx, c, h.tags, scope)
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, err.Error())
+	}
+
 	filter := repositories.TransactionFilter{
-		UserID:        userID,
+		Scope:         scope,
 		Type:          ttype,
 		From:          from,
 		To:            to,
 		CategoryID:    catPtr,
 		SubcategoryID: subCatPtr,
+		TagIDs:        tagIDs,
+		TagMatch:      tagMatch,
 		Limit:         limit,
 		Offset:        offset,
 		SortDateAsc:   sortAsc,
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
-	defer cancel()
-
 	txs, total, err := h.svc.List(ctx, filter)
 	if err != nil {
 		return respondError(c, http.StatusInternalServerError, err.Error())
@@ -137,13 +146,13 @@ func (h *TransactionHandler) List(c echo.Context) error {
 }
 
 func (h *TransactionHandler) Get(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 	id := c.Param("id")
 
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
-	tx, err := h.svc.Get(ctx, userID, id)
+	tx, err := h.svc.Get(ctx, scope, id)
 	if err != nil {
 		return respondError(c, http.StatusInternalServerError, err.Error())
 	}
@@ -160,11 +169,12 @@ type transactionUpdateRequest struct {
 	CategoryID    *string  `json:"categoryId"`
 	SubcategoryID *string  `json:"subcategoryId"`
 	Description   *string  `json:"description"`
+	TagIDs        []string `json:"tagIds"` // replaces the tags; [] removes them all
 	Date          *string  `json:"date"`
 }
 
 func (h *TransactionHandler) Update(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 	id := c.Param("id")
 
 	var req transactionUpdateRequest
@@ -173,8 +183,7 @@ func (h *TransactionHandler) Update(c echo.Context) error {
 	}
 
 	tx := &models.Transaction{
-		ID:     id,
-		UserID: userID,
+		ID: id,
 	}
 
 	if req.Type != nil {
@@ -195,6 +204,7 @@ func (h *TransactionHandler) Update(c echo.Context) error {
 	if req.Description != nil {
 		tx.Description = req.Description
 	}
+	tx.TagIDs = req.TagIDs
 	if req.Date != nil && *req.Date != "" {
 		d, err := time.Parse(time.RFC3339, *req.Date)
 		if err != nil {
@@ -206,7 +216,7 @@ func (h *TransactionHandler) Update(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
-	updated, err := h.svc.Update(ctx, userID, tx)
+	updated, err := h.svc.Update(ctx, scope, tx)
 	if err != nil {
 		return respondError(c, http.StatusBadRequest, err.Error())
 	}
@@ -214,13 +224,13 @@ func (h *TransactionHandler) Update(c echo.Context) error {
 }
 
 func (h *TransactionHandler) Delete(c echo.Context) error {
-	userID := middleware.GetUserID(c)
+	scope, _ := middleware.GetWorkspace(c)
 	id := c.Param("id")
 
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
-	if err := h.svc.Delete(ctx, userID, id); err != nil {
+	if err := h.svc.Delete(ctx, scope, id); err != nil {
 		return respondError(c, http.StatusInternalServerError, err.Error())
 	}
 	return c.NoContent(http.StatusNoContent)
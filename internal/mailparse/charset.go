@@ -0,0 +1,67 @@
+package mailparse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// windows1252 maps the bytes 0x80-0x9F, where Windows-1252 differs from
+// ISO-8859-1. Unassigned bytes map to U+FFFD.
+var windows1252 = [32]rune{
+	'€', '�', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '�', 'Ž', '�',
+	'�', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '�', 'ž', 'Ÿ',
+}
+
+// singleByte reports whether charset is one of the Western single-byte
+// encodings. ISO-8859-1 is read as Windows-1252, as browsers do: senders
+// that declare it often use the Windows punctuation.
+func singleByte(charset string) bool {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "iso-8859-1", "iso8859-1", "latin1", "latin-1", "l1", "windows-1252", "cp1252", "iso-8859-15":
+		return true
+	}
+	return false
+}
+
+func decodeSingleByte(data []byte) string {
+	var b strings.Builder
+	b.Grow(len(data))
+	for _, c := range data {
+		switch {
+		case c < 0x80:
+			b.WriteByte(c)
+		case c < 0xA0:
+			b.WriteRune(windows1252[c-0x80])
+		default:
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
+
+// toUTF8 converts a body to UTF-8. Charsets other than UTF-8 and the
+// Western single-byte ones are passed through with invalid bytes replaced,
+// which keeps the ASCII amounts and dates readable.
+func toUTF8(data []byte, charset string) string {
+	if singleByte(charset) && !utf8.Valid(data) {
+		return decodeSingleByte(data)
+	}
+	return strings.ToValidUTF8(string(data), "�")
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case singleByte(charset):
+		return strings.NewReader(decodeSingleByte(data)), nil
+	case strings.EqualFold(charset, "us-ascii"):
+		return bytes.NewReader(data), nil
+	}
+	return nil, fmt.Errorf("mailparse: unsupported charset %q", charset)
+}
@@ -0,0 +1,43 @@
+package mailparse
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	invisibleRe = regexp.MustCompile(`(?is)<!--.*?-->|<(script|style|head|title)\b[^>]*>.*?</(script|style|head|title)\s*>`)
+	lineBreakRe = regexp.MustCompile(`(?i)<br\b[^>]*>|</?(p|div|tr|li|ul|ol|table|tbody|thead|tfoot|h[1-6]|section|article|header|footer|center|blockquote)\b[^>]*>`)
+	cellRe      = regexp.MustCompile(`(?i)</(td|th)\s*>`)
+	tagRe       = regexp.MustCompile(`(?s)<[^>]*>`)
+	spacesRe    = regexp.MustCompile(`[ \t\x{00A0}\x{200B}\x{200C}\x{FEFF}]+`)
+)
+
+// HTMLText turns an HTML body into plain text, one line per paragraph,
+// list item or table row. Table cells on a row are kept on one line, so a
+// label such as "Total" stays next to its amount.
+func HTMLText(s string) string {
+	s = invisibleRe.ReplaceAllString(s, " ")
+	s = lineBreakRe.ReplaceAllString(s, "\n")
+	s = cellRe.ReplaceAllString(s, "  ")
+	s = tagRe.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		if l = strings.TrimSpace(spacesRe.ReplaceAllString(l, " ")); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// BodyText returns the message's text: the plain text body, or the HTML
+// body converted when there is no plain one.
+func (m *Message) BodyText() string {
+	if strings.TrimSpace(m.Text) != "" {
+		return m.Text
+	}
+	return HTMLText(m.HTML)
+}
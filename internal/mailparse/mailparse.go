@@ -0,0 +1,212 @@
+// Package mailparse reads received email: the headers that matter here,
+// the text and HTML bodies, and any attached files, whatever MIME structure
+// the sender used.
+package mailparse
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+const (
+	maxParts = 100
+	maxDepth = 5
+)
+
+var ErrNoHeaders = errors.New("mailparse: message has no headers")
+
+type Attachment struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+type Message struct {
+	// From is nil when the header is missing or unreadable.
+	From    *mail.Address
+	Subject string
+	// Date is zero when the header is missing or unreadable.
+	Date      time.Time
+	MessageID string
+	// Recipients lists the addresses in Delivered-To, X-Original-To, To and
+	// Cc, in that order, without duplicates.
+	Recipients []string
+	// Text and HTML are the bodies, converted to UTF-8. Either may be empty.
+	Text        string
+	HTML        string
+	Attachments []Attachment
+}
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// Parse reads an RFC 5322 message.
+func Parse(raw []byte) (*Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("mailparse: %w", err)
+	}
+	if len(m.Header) == 0 {
+		return nil, ErrNoHeaders
+	}
+
+	msg := &Message{
+		Subject:   decodeHeader(m.Header.Get("Subject")),
+		MessageID: strings.Trim(strings.TrimSpace(m.Header.Get("Message-ID")), "<>"),
+	}
+	parser := mail.AddressParser{WordDecoder: wordDecoder}
+	if from, err := parser.Parse(m.Header.Get("From")); err == nil {
+		msg.From = from
+	}
+	if d, err := m.Header.Date(); err == nil {
+		msg.Date = d
+	}
+	seen := map[string]bool{}
+	for _, h := range []string{"Delivered-To", "X-Original-To", "To", "Cc"} {
+		for _, v := range m.Header[textproto.CanonicalMIMEHeaderKey(h)] {
+			list, err := parser.ParseList(v)
+			if err != nil {
+				continue
+			}
+			for _, a := range list {
+				addr := strings.ToLower(a.Address)
+				if !seen[addr] {
+					seen[addr] = true
+					msg.Recipients = append(msg.Recipients, addr)
+				}
+			}
+		}
+	}
+
+	w := &walker{msg: msg}
+	if err := w.part(textproto.MIMEHeader(m.Header), m.Body, 0); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+type walker struct {
+	msg   *Message
+	parts int
+}
+
+// part reads one MIME part, descending into multiparts and attached
+// messages.
+func (w *walker) part(h textproto.MIMEHeader, body io.Reader, depth int) error {
+	w.parts++
+	if w.parts > maxParts || depth > maxDepth {
+		return nil
+	}
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	data, err := io.ReadAll(decodeTransfer(h.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("mailparse: %w", err)
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if params["boundary"] == "" {
+			return nil
+		}
+		mr := multipart.NewReader(bytes.NewReader(data), params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				// A truncated multipart still has its earlier parts.
+				return nil
+			}
+			if err := w.part(p.Header, p, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	name := decodeHeader(dparams["filename"])
+	if name == "" {
+		name = decodeHeader(params["name"])
+	}
+
+	if mediaType == "message/rfc822" && disposition != "attachment" {
+		// A forwarded email: its bodies are what the user meant to send.
+		if inner, err := Parse(data); err == nil {
+			w.msg.Text = join(w.msg.Text, inner.Text)
+			w.msg.HTML = join(w.msg.HTML, inner.HTML)
+			w.msg.Attachments = append(w.msg.Attachments, inner.Attachments...)
+			return nil
+		}
+	}
+
+	isBody := disposition != "attachment" && name == ""
+	switch {
+	case isBody && mediaType == "text/plain":
+		w.msg.Text = join(w.msg.Text, toUTF8(data, params["charset"]))
+	case isBody && mediaType == "text/html":
+		w.msg.HTML = join(w.msg.HTML, toUTF8(data, params["charset"]))
+	case len(data) > 0:
+		w.msg.Attachments = append(w.msg.Attachments, Attachment{
+			FileName:    name,
+			ContentType: mediaType,
+			Data:        data,
+		})
+	}
+	return nil
+}
+
+func join(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return a + "\n" + b
+}
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		// The decoder skips line breaks but not other stray whitespace.
+		return base64.NewDecoder(base64.StdEncoding, &spaceSkipper{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+type spaceSkipper struct {
+	r io.Reader
+}
+
+func (s *spaceSkipper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	j := 0
+	for _, b := range p[:n] {
+		if b != ' ' && b != '\t' {
+			p[j] = b
+			j++
+		}
+	}
+	return j, err
+}
+
+func decodeHeader(s string) string {
+	if d, err := wordDecoder.DecodeHeader(s); err == nil {
+		s = d
+	}
+	return strings.TrimSpace(s)
+}
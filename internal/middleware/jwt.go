@@ -1,22 +1,48 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/requestctx"
 )
 
-const ContextUserIDKey = "userId"
+const (
+	ContextUserIDKey        = "userId"
+	ContextSessionIDKey     = "sessionId"
+	ContextEmailVerifiedKey = "emailVerified"
+	ContextAPITokenIDKey    = "apiTokenId"
+	ContextScopesKey        = "scopes"
+)
 
 type JWTClaims struct {
-	UserID string `json:"userId"`
+	UserID        string `json:"userId"`
+	SessionID     string `json:"sid"`
+	EmailVerified bool   `json:"ev"`
 	jwt.RegisteredClaims
 }
 
-func JWTAuth(secret string) echo.MiddlewareFunc {
+// SessionValidator reports whether the login session an access token was
+// issued for is still live (not logged out, revoked or expired).
+type SessionValidator interface {
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+// APITokenValidator resolves personal access tokens; it returns nil for
+// unknown, revoked or expired tokens.
+type APITokenValidator interface {
+	Authenticate(ctx context.Context, token, ip string) (*models.APIToken, error)
+}
+
+// JWTAuth authenticates a bearer token, which is either a session access
+// token (JWT) or a personal access token. Requests made with the latter
+// carry its scopes, checked per route by RequireScope.
+func JWTAuth(secret string, sessions SessionValidator, apiTokens APITokenValidator) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 
@@ -53,6 +79,32 @@ func JWTAuth(secret string) echo.MiddlewareFunc {
 			}
 
 			tokenStr := parts[1]
+			if strings.HasPrefix(tokenStr, models.APITokenPrefix) {
+				t, err := apiTokens.Authenticate(c.Request().Context(), tokenStr, c.RealIP())
+				if err != nil {
+					return c.JSON(http.StatusInternalServerError, map[string]any{
+						"error": map[string]any{
+							"message": "failed to validate token",
+						},
+					})
+				}
+				if t == nil {
+					return c.JSON(http.StatusUnauthorized, map[string]any{
+						"error": map[string]any{
+							"message": "invalid or expired token",
+						},
+					})
+				}
+				c.Set(ContextUserIDKey, t.UserID)
+				c.Set(ContextAPITokenIDKey, t.ID)
+				c.Set(ContextScopesKey, t.Scopes)
+				// Tokens can only be created from a session that already
+				// passed the verification check.
+				c.Set(ContextEmailVerifiedKey, true)
+				c.SetRequest(c.Request().WithContext(requestctx.WithAPIToken(c.Request().Context(), t.ID)))
+				return next(c)
+			}
+
 			claims := &JWTClaims{}
 			token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
 				return []byte(secret), nil
@@ -73,7 +125,32 @@ func JWTAuth(secret string) echo.MiddlewareFunc {
 				})
 			}
 
+			if claims.SessionID == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]any{
+					"error": map[string]any{
+						"message": "invalid or expired token",
+					},
+				})
+			}
+			active, err := sessions.IsSessionActive(c.Request().Context(), claims.SessionID)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]any{
+					"error": map[string]any{
+						"message": "failed to validate session",
+					},
+				})
+			}
+			if !active {
+				return c.JSON(http.StatusUnauthorized, map[string]any{
+					"error": map[string]any{
+						"message": "session revoked",
+					},
+				})
+			}
+
 			c.Set(ContextUserIDKey, claims.UserID)
+			c.Set(ContextSessionIDKey, claims.SessionID)
+			c.Set(ContextEmailVerifiedKey, claims.EmailVerified)
 
 			return next(c)
 		}
@@ -89,6 +166,98 @@ func GetUserID(c echo.Context) string {
 	return ""
 }
 
+// RequireVerifiedEmail rejects users who have not confirmed their email
+// address. It must run after JWTAuth; when enabled is false it is a no-op.
+func RequireVerifiedEmail(enabled bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !enabled {
+				return next(c)
+			}
+			if verified, _ := c.Get(ContextEmailVerifiedKey).(bool); !verified {
+				return c.JSON(http.StatusForbidden, map[string]any{
+					"error": map[string]any{
+						"message": "email address not verified",
+						"code":    "email_unverified",
+					},
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireScope restricts a route for personal access tokens to those granted
+// scope (or its "<resource>:*" wildcard). Session-authenticated requests are
+// not restricted.
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if HasScope(c, scope) {
+				return next(c)
+			}
+			return c.JSON(http.StatusForbidden, map[string]any{
+				"error": map[string]any{
+					"message": "token is missing scope " + scope,
+					"code":    "insufficient_scope",
+				},
+			})
+		}
+	}
+}
+
+// HasScope reports whether the request may use scope, for handlers whose
+// response depends on it. Session-authenticated requests hold every scope.
+func HasScope(c echo.Context, scope string) bool {
+	if GetAPITokenID(c) == "" {
+		return true
+	}
+	resource, _, _ := strings.Cut(scope, ":")
+	scopes, _ := c.Get(ContextScopesKey).([]string)
+	for _, s := range scopes {
+		if s == scope || s == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionOnly rejects personal access tokens, for account management routes
+// that scripts must not reach.
+func SessionOnly() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if GetAPITokenID(c) != "" {
+				return c.JSON(http.StatusForbidden, map[string]any{
+					"error": map[string]any{
+						"message": "this endpoint requires a login session",
+						"code":    "session_required",
+					},
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+func GetAPITokenID(c echo.Context) string {
+	if v := c.Get(ContextAPITokenIDKey); v != nil {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+func GetSessionID(c echo.Context) string {
+	if v := c.Get(ContextSessionIDKey); v != nil {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
 // package middleware
 
 // import (
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/models"
+)
+
+const testSecret = "test-secret"
+
+type stubSessions map[string]bool
+
+func (s stubSessions) IsSessionActive(_ context.Context, id string) (bool, error) {
+	return s[id], nil
+}
+
+type stubAPITokens map[string]*models.APIToken
+
+func (s stubAPITokens) Authenticate(_ context.Context, token, _ string) (*models.APIToken, error) {
+	return s[token], nil
+}
+
+func sessionToken(t *testing.T, sessionID string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userId": "user-1",
+		"sid":    sessionID,
+		"ev":     true,
+		"exp":    time.Now().Add(time.Minute).Unix(),
+	})
+	s, err := token.SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// newTestServer serves GET /x behind JWTAuth and the given middleware.
+func newTestServer(mw ...echo.MiddlewareFunc) *echo.Echo {
+	e := echo.New()
+	auth := JWTAuth(testSecret,
+		stubSessions{"live": true, "revoked": false},
+		stubAPITokens{
+			"pat_reader": {ID: "tok-1", UserID: "user-1", Scopes: []string{models.ScopeTransactionsRead}},
+			"pat_wild":   {ID: "tok-2", UserID: "user-1", Scopes: []string{"transactions:*"}},
+			"pat_none":   {ID: "tok-3", UserID: "user-1", Scopes: []string{}},
+		},
+	)
+	e.GET("/x", func(c echo.Context) error { return c.NoContent(http.StatusNoContent) }, append([]echo.MiddlewareFunc{auth}, mw...)...)
+	return e
+}
+
+func get(e *echo.Echo, bearer string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/x", nil)
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequireScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		scope  string
+		bearer string
+		want   int
+	}{
+		{"session holds every scope", models.ScopeTransactionsWrite, "session", http.StatusNoContent},
+		{"token with the scope", models.ScopeTransactionsRead, "pat_reader", http.StatusNoContent},
+		{"token without the scope", models.ScopeTransactionsWrite, "pat_reader", http.StatusForbidden},
+		{"token for another resource", models.ScopeTagsRead, "pat_reader", http.StatusForbidden},
+		{"wildcard covers the resource", models.ScopeTransactionsWrite, "pat_wild", http.StatusNoContent},
+		{"wildcard stops at the resource", models.ScopeCategoriesRead, "pat_wild", http.StatusForbidden},
+		{"token with no scopes", models.ScopeTransactionsRead, "pat_none", http.StatusForbidden},
+		{"unknown token", models.ScopeTransactionsRead, "pat_unknown", http.StatusUnauthorized},
+		{"no credentials", models.ScopeTransactionsRead, "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bearer := tt.bearer
+			if bearer == "session" {
+				bearer = sessionToken(t, "live")
+			}
+			rec := get(newTestServer(RequireScope(tt.scope)), bearer)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestSessionOnly(t *testing.T) {
+	e := newTestServer(SessionOnly())
+
+	if rec := get(e, sessionToken(t, "live")); rec.Code != http.StatusNoContent {
+		t.Errorf("session: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	// Even a token holding every scope cannot reach account routes.
+	if rec := get(e, "pat_wild"); rec.Code != http.StatusForbidden {
+		t.Errorf("token: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := get(e, sessionToken(t, "revoked")); rec.Code != http.StatusUnauthorized {
+		t.Errorf("revoked session: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
@@ -3,18 +3,28 @@ package middleware
 import (
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/requestctx"
 )
 
 const HeaderRequestID = "X-Request-ID"
 
+// RequestID tags each request with an ID and makes it, along with the
+// client's address and user agent, available to services through the
+// request context.
 func RequestID() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			reqID := c.Request().Header.Get(HeaderRequestID)
+			req := c.Request()
+			reqID := req.Header.Get(HeaderRequestID)
 			if reqID == "" {
 				reqID = uuid.NewString()
 			}
 			c.Response().Header().Set(HeaderRequestID, reqID)
+			c.SetRequest(req.WithContext(requestctx.With(req.Context(), requestctx.Meta{
+				RequestID: reqID,
+				IP:        c.RealIP(),
+				UserAgent: req.UserAgent(),
+			})))
 			return next(c)
 		}
 	}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+const (
+	// HeaderHouseholdID selects a household workspace. Without it, requests
+	// act on the caller's personal data.
+	HeaderHouseholdID = "X-Household-ID"
+
+	ContextScopeKey = "workspaceScope"
+	ContextRoleKey  = "workspaceRole"
+)
+
+// MembershipResolver looks up a user's role in a household.
+type MembershipResolver interface {
+	// MemberRole returns "" if the user is not a member.
+	MemberRole(ctx context.Context, householdID, userID string) (models.HouseholdRole, error)
+}
+
+// Workspace resolves the active workspace for data routes. It must run
+// after JWTAuth. Non-members get 404 so household IDs cannot be probed, and
+// viewers may only read.
+func Workspace(members MembershipResolver) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userID := GetUserID(c)
+			householdID := c.Request().Header.Get(HeaderHouseholdID)
+			if householdID == "" {
+				c.Set(ContextScopeKey, repositories.PersonalScope(userID))
+				c.Set(ContextRoleKey, models.RoleOwner)
+				return next(c)
+			}
+
+			role, err := members.MemberRole(c.Request().Context(), householdID, userID)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]any{
+					"error": map[string]any{
+						"message": "failed to resolve household",
+					},
+				})
+			}
+			if role == "" {
+				return c.JSON(http.StatusNotFound, map[string]any{
+					"error": map[string]any{
+						"message": "household not found",
+						"code":    "household_not_found",
+					},
+				})
+			}
+			if !role.CanWrite() && !isSafeMethod(c.Request().Method) {
+				return c.JSON(http.StatusForbidden, map[string]any{
+					"error": map[string]any{
+						"message": "viewers cannot make changes in this household",
+						"code":    "read_only_member",
+					},
+				})
+			}
+
+			c.Set(ContextScopeKey, repositories.HouseholdScope(userID, householdID))
+			c.Set(ContextRoleKey, role)
+			return next(c)
+		}
+	}
+}
+
+// GetWorkspace is the workspace-aware companion to GetUserID: it returns the
+// scope data queries must run in and the caller's role there. Outside the
+// Workspace middleware it falls back to the caller's personal data.
+func GetWorkspace(c echo.Context) (repositories.Scope, models.HouseholdRole) {
+	scope, ok := c.Get(ContextScopeKey).(repositories.Scope)
+	if !ok {
+		return repositories.PersonalScope(GetUserID(c)), models.RoleOwner
+	}
+	role, _ := c.Get(ContextRoleKey).(models.HouseholdRole)
+	return scope, role
+}
+
+func isSafeMethod(m string) bool {
+	return m == http.MethodGet || m == http.MethodHead || m == http.MethodOptions
+}
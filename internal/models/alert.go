@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AlertTemplate is a user's own pattern for reading a bank's alert
+// messages, tried before the built-in ones. See package bankalert for the
+// pattern syntax.
+type AlertTemplate struct {
+	ID         string          `bson:"_id,omitempty" json:"id"`
+	UserID     string          `bson:"userId" json:"userId"`
+	Name       string          `bson:"name" json:"name"`
+	Bank       string          `bson:"bank,omitempty" json:"bank,omitempty"`
+	Pattern    string          `bson:"pattern" json:"pattern"`
+	DateLayout string          `bson:"dateLayout,omitempty" json:"dateLayout,omitempty"`
+	Type       TransactionType `bson:"type,omitempty" json:"type,omitempty"`
+	Currency   string          `bson:"currency,omitempty" json:"currency,omitempty"`
+	CreatedAt  time.Time       `bson:"createdAt" json:"createdAt"`
+	UpdatedAt  time.Time       `bson:"updatedAt" json:"updatedAt"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// APITokenPrefix marks bearer tokens that are personal access tokens rather
+// than JWTs.
+const APITokenPrefix = "pat_"
+
+// Scopes that can be granted to an API token. A token may also hold a
+// "<resource>:*" wildcard, e.g. "reminders:*". Write does not imply read.
+const (
+	ScopeTransactionsRead  = "transactions:read"
+	ScopeTransactionsWrite = "transactions:write"
+	ScopeCategoriesRead    = "categories:read"
+	ScopeCategoriesWrite   = "categories:write"
+	ScopeRemindersRead     = "reminders:read"
+	ScopeRemindersWrite    = "reminders:write"
+	ScopeReportsRead       = "reports:read"
+	ScopeInsightsRead      = "insights:read"
+	ScopeInsightsWrite     = "insights:write"
+	ScopeDigestsRead       = "digests:read"
+	ScopeDigestsWrite      = "digests:write"
+	ScopeSplitsRead        = "splits:read"
+	ScopeSplitsWrite       = "splits:write"
+	ScopeTagsRead          = "tags:read"
+	ScopeTagsWrite         = "tags:write"
+)
+
+var AllScopes = []string{
+	ScopeTransactionsRead, ScopeTransactionsWrite,
+	ScopeCategoriesRead, ScopeCategoriesWrite,
+	ScopeRemindersRead, ScopeRemindersWrite,
+	ScopeReportsRead,
+	ScopeInsightsRead, ScopeInsightsWrite,
+	ScopeDigestsRead, ScopeDigestsWrite,
+	ScopeSplitsRead, ScopeSplitsWrite,
+	ScopeTagsRead, ScopeTagsWrite,
+}
+
+// APIToken is a personal access token for scripts and integrations. The
+// token itself is shown once on creation; only its hash is stored.
+type APIToken struct {
+	ID         string     `bson:"_id,omitempty" json:"id"`
+	UserID     string     `bson:"userId" json:"userId"`
+	Name       string     `bson:"name" json:"name"`
+	Prefix     string     `bson:"prefix" json:"prefix"` // first characters, to tell tokens apart
+	TokenHash  string     `bson:"tokenHash" json:"-"`
+	Scopes     []string   `bson:"scopes" json:"scopes"`
+	ExpiresAt  *time.Time `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+	LastUsedIP string     `bson:"lastUsedIp,omitempty" json:"lastUsedIp,omitempty"`
+	RevokedAt  *time.Time `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	CreatedAt  time.Time  `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Attachment is a file, such as a receipt, kept with a transaction. The
+// content lives in the file store under StorageKey.
+type Attachment struct {
+	ID            string    `bson:"_id,omitempty" json:"id"`
+	UserID        string    `bson:"userId" json:"userId"`
+	HouseholdID   string    `bson:"householdId,omitempty" json:"householdId,omitempty"`
+	TransactionID string    `bson:"transactionId" json:"transactionId"`
+	FileName      string    `bson:"fileName" json:"fileName"`
+	ContentType   string    `bson:"contentType" json:"contentType"` // sniffed from the content, not taken from the upload
+	Size          int64     `bson:"size" json:"size"`
+	SHA256        string    `bson:"sha256" json:"sha256"`
+	StorageKey    string    `bson:"storageKey" json:"-"`
+	ThumbnailKey  string    `bson:"thumbnailKey,omitempty" json:"-"`
+	HasThumbnail  bool      `bson:"hasThumbnail" json:"hasThumbnail"`
+	CreatedAt     time.Time `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditLogin           AuditAction = "auth.login"
+	AuditLoginFailed     AuditAction = "auth.login_failed"
+	AuditPasswordChanged AuditAction = "auth.password_changed"
+	AuditPasswordReset   AuditAction = "auth.password_reset"
+	AuditAPITokenCreated AuditAction = "api_token.created"
+	AuditCreated         AuditAction = "created"
+	AuditUpdated         AuditAction = "updated"
+	AuditDeleted         AuditAction = "deleted"
+)
+
+const (
+	AuditEntityUser        = "user"
+	AuditEntityAPIToken    = "api_token"
+	AuditEntityTransaction = "transaction"
+	AuditEntityCategory    = "category"
+	AuditEntityReminder    = "reminder"
+	AuditEntityTag         = "tag"
+	AuditEntityAttachment  = "attachment"
+)
+
+// AuditEvent is an append-only record of something done to or by an
+// account.
+type AuditEvent struct {
+	ID         string        `bson:"_id,omitempty" json:"id"`
+	UserID     string        `bson:"userId" json:"userId"`
+	Action     AuditAction   `bson:"action" json:"action"`
+	EntityType string        `bson:"entityType" json:"entityType"`
+	EntityID   string        `bson:"entityId,omitempty" json:"entityId,omitempty"`
+	Changes    []AuditChange `bson:"changes,omitempty" json:"changes,omitempty"`
+	RequestID  string        `bson:"requestId,omitempty" json:"requestId,omitempty"`
+	IP         string        `bson:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent  string        `bson:"userAgent,omitempty" json:"userAgent,omitempty"`
+	// APITokenID is set when the change was made with a personal access
+	// token rather than a session.
+	APITokenID string    `bson:"apiTokenId,omitempty" json:"apiTokenId,omitempty"`
+	CreatedAt  time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// AuditChange is one field's value before and after a change. Before is
+// absent for creations and After for deletions.
+type AuditChange struct {
+	Field  string `bson:"field" json:"field"`
+	Before any    `bson:"before,omitempty" json:"before,omitempty"`
+	After  any    `bson:"after,omitempty" json:"after,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// BotLink connects a chat with the bot to a user. Reminders are sent to the
+// chat and commands sent from it act on the user's personal data.
+type BotLink struct {
+	ID       string `bson:"_id,omitempty" json:"id"`
+	UserID   string `bson:"userId" json:"userId"`
+	ChatID   int64  `bson:"chatId" json:"chatId"`
+	Username string `bson:"username,omitempty" json:"username,omitempty"`
+	// Timezone and Currency come from the pairing code; they are used to
+	// read dates and amounts typed into the chat.
+	Timezone string `bson:"timezone" json:"timezone"`
+	Currency string `bson:"currency,omitempty" json:"currency,omitempty"`
+	// NotifiedThrough is the end of the last window of reminders sent.
+	NotifiedThrough time.Time `bson:"notifiedThrough" json:"-"`
+	CreatedAt       time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// BotPairing is a one-time code a user sends to the bot to link a chat.
+// Only the hash of the code is stored.
+type BotPairing struct {
+	ID        string    `bson:"_id,omitempty"`
+	UserID    string    `bson:"userId"`
+	CodeHash  string    `bson:"codeHash"`
+	Timezone  string    `bson:"timezone"`
+	Currency  string    `bson:"currency,omitempty"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
@@ -3,10 +3,11 @@ package models
 import "time"
 
 type Category struct {
-	ID        string    `bson:"_id,omitempty" json:"id"`
-	UserID    string    `bson:"userId" json:"userId"`
-	Name      string    `bson:"name" json:"name"`
-	ParentID  *string   `bson:"parentId,omitempty" json:"parentId,omitempty"`
-	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
-	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+	ID          string    `bson:"_id,omitempty" json:"id"`
+	UserID      string    `bson:"userId" json:"userId"`
+	HouseholdID string    `bson:"householdId,omitempty" json:"householdId,omitempty"`
+	Name        string    `bson:"name" json:"name"`
+	ParentID    *string   `bson:"parentId,omitempty" json:"parentId,omitempty"`
+	CreatedAt   time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time `bson:"updatedAt" json:"updatedAt"`
 }
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+type DigestFrequency string
+
+const (
+	DigestWeekly  DigestFrequency = "weekly"
+	DigestMonthly DigestFrequency = "monthly"
+)
+
+// DigestSubscription asks for a summary email every Monday (weekly) or on the
+// 1st of the month (monthly) at SendTime ("HH:MM") in Timezone.
+type DigestSubscription struct {
+	ID        string          `bson:"_id,omitempty" json:"id"`
+	UserID    string          `bson:"userId" json:"userId"`
+	Frequency DigestFrequency `bson:"frequency" json:"frequency"`
+	SendTime  string          `bson:"sendTime" json:"sendTime"`
+	Timezone  string          `bson:"timezone" json:"timezone"`
+	IsActive  bool            `bson:"isActive" json:"isActive"`
+	CreatedAt time.Time       `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time       `bson:"updatedAt" json:"updatedAt"`
+}
+
+type DigestDeliveryStatus string
+
+const (
+	DigestDeliveryPending DigestDeliveryStatus = "pending"
+	DigestDeliverySent    DigestDeliveryStatus = "sent"
+)
+
+// DigestDelivery is the per-period send record. (SubscriptionID, PeriodKey)
+// is unique, which is what makes sends idempotent across restarts and
+// replicas.
+type DigestDelivery struct {
+	ID             string               `bson:"_id,omitempty" json:"id"`
+	SubscriptionID string               `bson:"subscriptionId" json:"subscriptionId"`
+	UserID         string               `bson:"userId" json:"userId"`
+	PeriodKey      string               `bson:"periodKey" json:"periodKey"`
+	Status         DigestDeliveryStatus `bson:"status" json:"status"`
+	LeaseUntil     time.Time            `bson:"leaseUntil" json:"leaseUntil"`
+	SentAt         *time.Time           `bson:"sentAt,omitempty" json:"sentAt,omitempty"`
+	CreatedAt      time.Time            `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+type DraftStatus string
+
+const (
+	DraftPending  DraftStatus = "pending"
+	DraftApproved DraftStatus = "approved"
+	DraftRejected DraftStatus = "rejected"
+)
+
+type DraftSource string
+
+const (
+	DraftSourceAlert DraftSource = "alert"
+	DraftSourceEmail DraftSource = "email"
+)
+
+// TransactionDraft is a transaction captured automatically, waiting for the
+// user to approve it. Approved and rejected drafts are kept so the same
+// message is not captured twice.
+type TransactionDraft struct {
+	ID          string          `bson:"_id,omitempty" json:"id"`
+	UserID      string          `bson:"userId" json:"userId"`
+	HouseholdID string          `bson:"householdId,omitempty" json:"householdId,omitempty"`
+	Source      DraftSource     `bson:"source" json:"source"`
+	Status      DraftStatus     `bson:"status" json:"status"`
+	Type        TransactionType `bson:"type,omitempty" json:"type,omitempty"` // empty when the message did not say
+	Amount      float64         `bson:"amount" json:"amount"`
+	Currency    string          `bson:"currency,omitempty" json:"currency,omitempty"`
+	Date        time.Time       `bson:"date" json:"date"`
+	Description *string         `bson:"description,omitempty" json:"description,omitempty"`
+	Account     string          `bson:"account,omitempty" json:"account,omitempty"`
+	Reference   string          `bson:"reference,omitempty" json:"reference,omitempty"`
+	RawText     string          `bson:"rawText" json:"rawText"`
+	TextHash    string          `bson:"textHash" json:"-"`
+	// PossibleDuplicateOf is an existing transaction with the same amount
+	// and type on about the same day.
+	PossibleDuplicateOf string   `bson:"possibleDuplicateOf,omitempty" json:"possibleDuplicateOf,omitempty"`
+	Notes               []string `bson:"notes,omitempty" json:"notes,omitempty"`
+	TransactionID       string   `bson:"transactionId,omitempty" json:"transactionId,omitempty"`
+	// EmailID is the inbound email an emailed receipt came in.
+	EmailID   string    `bson:"emailId,omitempty" json:"emailId,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
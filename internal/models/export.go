@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+type ExportStatus string
+
+const (
+	ExportPending ExportStatus = "pending"
+	ExportRunning ExportStatus = "running"
+	ExportReady   ExportStatus = "ready"
+	ExportFailed  ExportStatus = "failed"
+)
+
+// Export is an asynchronous data-export job. The finished archive is kept
+// in GridFS until ExpiresAt.
+type Export struct {
+	ID          string         `bson:"_id,omitempty" json:"id"`
+	UserID      string         `bson:"userId" json:"userId"`
+	Status      ExportStatus   `bson:"status" json:"status"`
+	Error       string         `bson:"error,omitempty" json:"error,omitempty"`
+	FileID      string         `bson:"fileId,omitempty" json:"-"`
+	FileName    string         `bson:"fileName,omitempty" json:"fileName,omitempty"`
+	Size        int64          `bson:"size,omitempty" json:"size,omitempty"`
+	Counts      map[string]int `bson:"counts,omitempty" json:"counts,omitempty"`
+	LeaseUntil  *time.Time     `bson:"leaseUntil,omitempty" json:"-"`
+	CreatedAt   time.Time      `bson:"createdAt" json:"createdAt"`
+	CompletedAt *time.Time     `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time     `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+type HouseholdRole string
+
+const (
+	RoleOwner  HouseholdRole = "owner"
+	RoleEditor HouseholdRole = "editor"
+	RoleViewer HouseholdRole = "viewer"
+)
+
+func (r HouseholdRole) Valid() bool {
+	return r == RoleOwner || r == RoleEditor || r == RoleViewer
+}
+
+// CanWrite reports whether the role may change shared data.
+func (r HouseholdRole) CanWrite() bool {
+	return r == RoleOwner || r == RoleEditor
+}
+
+// Household is a shared workspace. Its categories, transactions and
+// reminders carry its ID and are visible to every member.
+type Household struct {
+	ID        string    `bson:"_id,omitempty" json:"id"`
+	Name      string    `bson:"name" json:"name"`
+	CreatedBy string    `bson:"createdBy" json:"createdBy"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+type HouseholdMember struct {
+	ID          string        `bson:"_id,omitempty" json:"id"`
+	HouseholdID string        `bson:"householdId" json:"householdId"`
+	UserID      string        `bson:"userId" json:"userId"`
+	Role        HouseholdRole `bson:"role" json:"role"`
+	JoinedAt    time.Time     `bson:"joinedAt" json:"joinedAt"`
+
+	// Filled in for API responses.
+	Name  string `bson:"-" json:"name,omitempty"`
+	Email string `bson:"-" json:"email,omitempty"`
+}
+
+type InvitationStatus string
+
+const (
+	InvitationPending  InvitationStatus = "pending"
+	InvitationAccepted InvitationStatus = "accepted"
+	InvitationDeclined InvitationStatus = "declined"
+	InvitationRevoked  InvitationStatus = "revoked"
+)
+
+// HouseholdInvitation invites an email address to join a household. The
+// invitee accepts or declines it once signed in with that address.
+type HouseholdInvitation struct {
+	ID            string           `bson:"_id,omitempty" json:"id"`
+	HouseholdID   string           `bson:"householdId" json:"householdId"`
+	HouseholdName string           `bson:"householdName" json:"householdName"`
+	Email         string           `bson:"email" json:"email"`
+	Role          HouseholdRole    `bson:"role" json:"role"`
+	InvitedBy     string           `bson:"invitedBy" json:"invitedBy"`
+	Status        InvitationStatus `bson:"status" json:"status"`
+	ExpiresAt     time.Time        `bson:"expiresAt" json:"expiresAt"`
+	RespondedAt   *time.Time       `bson:"respondedAt,omitempty" json:"respondedAt,omitempty"`
+	CreatedAt     time.Time        `bson:"createdAt" json:"createdAt"`
+}
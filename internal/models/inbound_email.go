@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// InboundMailbox gives a user an address to forward e-receipts to: Token
+// is its local part.
+type InboundMailbox struct {
+	ID        string    `bson:"_id,omitempty" json:"id"`
+	UserID    string    `bson:"userId" json:"userId"`
+	Token     string    `bson:"token" json:"-"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+type InboundEmailFile struct {
+	FileName    string `bson:"fileName" json:"fileName"`
+	ContentType string `bson:"contentType" json:"contentType"`
+	Size        int64  `bson:"size" json:"size"`
+	StorageKey  string `bson:"storageKey" json:"-"`
+}
+
+// InboundEmail is a received email. The message as sent and its attached
+// files are kept in the file store.
+type InboundEmail struct {
+	ID     string `bson:"_id,omitempty" json:"id"`
+	UserID string `bson:"userId" json:"userId"`
+	// Via is "smtp" or "webhook".
+	Via       string     `bson:"via" json:"via"`
+	From      string     `bson:"from" json:"from"`
+	Subject   string     `bson:"subject" json:"subject"`
+	MessageID string     `bson:"messageId,omitempty" json:"messageId,omitempty"`
+	Date      *time.Time `bson:"date,omitempty" json:"date,omitempty"`
+	// MessageKey identifies the message for spotting repeat deliveries.
+	MessageKey string             `bson:"messageKey" json:"-"`
+	Size       int64              `bson:"size" json:"size"`
+	StorageKey string             `bson:"storageKey" json:"-"`
+	Files      []InboundEmailFile `bson:"files" json:"files"`
+	DraftID    string             `bson:"draftId,omitempty" json:"draftId,omitempty"`
+	ReceivedAt time.Time          `bson:"receivedAt" json:"receivedAt"`
+}
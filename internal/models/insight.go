@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+type InsightKind string
+
+const (
+	InsightOutlier       InsightKind = "outlier"
+	InsightLargeDay      InsightKind = "large_day"
+	InsightCategoryTrend InsightKind = "category_trend"
+)
+
+type Insight struct {
+	ID            string      `bson:"_id,omitempty" json:"id"`
+	UserID        string      `bson:"userId" json:"userId"`
+	Kind          InsightKind `bson:"kind" json:"kind"`
+	Fingerprint   string      `bson:"fingerprint" json:"-"`
+	Title         string      `bson:"title" json:"title"`
+	Message       string      `bson:"message" json:"message"`
+	CategoryID    *string     `bson:"categoryId,omitempty" json:"categoryId,omitempty"`
+	TransactionID *string     `bson:"transactionId,omitempty" json:"transactionId,omitempty"`
+	Date          time.Time   `bson:"date" json:"date"`
+	Amount        float64     `bson:"amount" json:"amount"`
+	Baseline      float64     `bson:"baseline" json:"baseline"`
+	Score         float64     `bson:"score" json:"score"`
+	Dismissed     bool        `bson:"dismissed" json:"dismissed"`
+	DismissedAt   *time.Time  `bson:"dismissedAt,omitempty" json:"dismissedAt,omitempty"`
+	CreatedAt     time.Time   `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time   `bson:"updatedAt" json:"updatedAt"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// LoginThrottle counts recent failed logins for one key, either an email
+// address ("email:<address>") or a client IP ("ip:<address>").
+type LoginThrottle struct {
+	ID            string     `bson:"_id,omitempty" json:"id"`
+	Key           string     `bson:"key" json:"key"`
+	Failures      int        `bson:"failures" json:"failures"`
+	LastFailureAt time.Time  `bson:"lastFailureAt" json:"lastFailureAt"`
+	LockedUntil   *time.Time `bson:"lockedUntil,omitempty" json:"lockedUntil,omitempty"`
+	// ExpiresAt is when the counter is forgotten if no further failures occur.
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+}
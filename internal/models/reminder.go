@@ -9,16 +9,31 @@ const (
 	RepeatDaily   RepeatInterval = "daily"
 	RepeatWeekly  RepeatInterval = "weekly"
 	RepeatMonthly RepeatInterval = "monthly"
+	RepeatYearly  RepeatInterval = "yearly"
 )
 
+func (i RepeatInterval) Valid() bool {
+	switch i {
+	case RepeatNone, RepeatDaily, RepeatWeekly, RepeatMonthly, RepeatYearly:
+		return true
+	}
+	return false
+}
+
 type Reminder struct {
-	ID             string         `bson:"_id,omitempty" json:"id"`
-	UserID         string         `bson:"userId" json:"userId"`
-	Title          string         `bson:"title" json:"title"`
-	Description    *string        `bson:"description,omitempty" json:"description,omitempty"`
-	DueAt          time.Time      `bson:"dueAt" json:"dueAt"`
-	RepeatInterval RepeatInterval `bson:"repeatInterval" json:"repeatInterval"`
-	IsActive       bool           `bson:"isActive" json:"isActive"`
-	CreatedAt      time.Time      `bson:"createdAt" json:"createdAt"`
-	UpdatedAt      time.Time      `bson:"updatedAt" json:"updatedAt"`
+	ID             string           `bson:"_id,omitempty" json:"id"`
+	UserID         string           `bson:"userId" json:"userId"`
+	HouseholdID    string           `bson:"householdId,omitempty" json:"householdId,omitempty"`
+	Title          string           `bson:"title" json:"title"`
+	Description    *string          `bson:"description,omitempty" json:"description,omitempty"`
+	DueAt          time.Time        `bson:"dueAt" json:"dueAt"`
+	RepeatInterval RepeatInterval   `bson:"repeatInterval" json:"repeatInterval"`
+	IsActive       bool             `bson:"isActive" json:"isActive"`
+	Amount         *float64         `bson:"amount,omitempty" json:"amount,omitempty"`
+	Currency       *string          `bson:"currency,omitempty" json:"currency,omitempty"`
+	Type           *TransactionType `bson:"type,omitempty" json:"type,omitempty"` // defaults to expense when Amount is set
+	CategoryID     *string          `bson:"categoryId,omitempty" json:"categoryId,omitempty"`
+	TagIDs         []string         `bson:"tagIds,omitempty" json:"tagIds,omitempty"`
+	CreatedAt      time.Time        `bson:"createdAt" json:"createdAt"`
+	UpdatedAt      time.Time        `bson:"updatedAt" json:"updatedAt"`
 }
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Session is one login (a refresh-token family). Every refresh rotates
+// RefreshTokenHash and keeps the retired hashes so that replaying an old
+// token can be detected and the whole session revoked.
+type Session struct {
+	ID                  string     `bson:"_id,omitempty" json:"id"`
+	UserID              string     `bson:"userId" json:"userId"`
+	RefreshTokenHash    string     `bson:"refreshTokenHash" json:"-"`
+	PreviousTokenHashes []string   `bson:"previousTokenHashes" json:"-"`
+	DeviceName          string     `bson:"deviceName" json:"deviceName"`
+	IP                  string     `bson:"ip" json:"ip"`
+	UserAgent           string     `bson:"userAgent" json:"userAgent"`
+	CreatedAt           time.Time  `bson:"createdAt" json:"createdAt"`
+	LastUsedAt          time.Time  `bson:"lastUsedAt" json:"lastUsedAt"`
+	ExpiresAt           time.Time  `bson:"expiresAt" json:"expiresAt"`
+	RevokedAt           *time.Time `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	RevokedReason       string     `bson:"revokedReason,omitempty" json:"revokedReason,omitempty"`
+}
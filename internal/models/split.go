@@ -0,0 +1,152 @@
+package models
+
+import "time"
+
+type SplitMethod string
+
+const (
+	SplitEqual      SplitMethod = "equal"
+	SplitExact      SplitMethod = "exact"
+	SplitPercentage SplitMethod = "percentage"
+	SplitShares     SplitMethod = "shares"
+)
+
+func (m SplitMethod) Valid() bool {
+	switch m {
+	case SplitEqual, SplitExact, SplitPercentage, SplitShares:
+		return true
+	}
+	return false
+}
+
+// Party is one side of a debt: either a registered user or one of a user's
+// contacts. Exactly one of the IDs is set.
+type Party struct {
+	UserID    string `bson:"userId,omitempty" json:"userId,omitempty"`
+	ContactID string `bson:"contactId,omitempty" json:"contactId,omitempty"`
+}
+
+func UserParty(userID string) Party {
+	return Party{UserID: userID}
+}
+
+func (p Party) IsZero() bool {
+	return p.UserID == "" && p.ContactID == ""
+}
+
+// Key identifies the party in maps; user and contact IDs cannot collide.
+func (p Party) Key() string {
+	if p.ContactID != "" {
+		return "contact:" + p.ContactID
+	}
+	return "user:" + p.UserID
+}
+
+// Contact is someone without an account that a user shares expenses with.
+// Contacts are private to the user who created them.
+type Contact struct {
+	ID        string    `bson:"_id,omitempty" json:"id"`
+	UserID    string    `bson:"userId" json:"userId"`
+	Name      string    `bson:"name" json:"name"`
+	Email     *string   `bson:"email,omitempty" json:"email,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+type ContactLinkStatus string
+
+const (
+	ContactLinkPending  ContactLinkStatus = "pending"
+	ContactLinkAccepted ContactLinkStatus = "accepted"
+)
+
+// ContactLink connects two registered users so that either can name the
+// other in splits and settlements. It is requested by email address, like a
+// household invitation, and takes effect once the addressee accepts it.
+type ContactLink struct {
+	ID          string            `bson:"_id,omitempty" json:"id"`
+	RequesterID string            `bson:"requesterId" json:"requesterId"`
+	Email       string            `bson:"email" json:"email"`
+	AddresseeID string            `bson:"addresseeId,omitempty" json:"addresseeId,omitempty"`
+	Status      ContactLinkStatus `bson:"status" json:"status"`
+	CreatedAt   time.Time         `bson:"createdAt" json:"createdAt"`
+	AcceptedAt  *time.Time        `bson:"acceptedAt,omitempty" json:"acceptedAt,omitempty"`
+}
+
+// SplitShare is one participant's part of a split expense. Value is what was
+// asked for (an amount, a percentage or a number of shares, depending on the
+// method); Amount is what the participant owes as a result.
+type SplitShare struct {
+	Party  `bson:",inline"`
+	Value  float64 `bson:"value,omitempty" json:"value,omitempty"`
+	Amount float64 `bson:"amount" json:"amount"`
+}
+
+// ExpenseSplit divides an expense transaction between participants. Every
+// participant other than the payer owes the payer their share.
+type ExpenseSplit struct {
+	ID            string       `bson:"_id,omitempty" json:"id"`
+	TransactionID string       `bson:"transactionId" json:"transactionId"`
+	CreatedBy     string       `bson:"createdBy" json:"createdBy"`
+	PaidBy        string       `bson:"paidBy" json:"paidBy"`
+	Method        SplitMethod  `bson:"method" json:"method"`
+	Amount        float64      `bson:"amount" json:"amount"`
+	Currency      string       `bson:"currency" json:"currency"`
+	Description   *string      `bson:"description,omitempty" json:"description,omitempty"`
+	Shares        []SplitShare `bson:"shares" json:"shares"`
+	Date          time.Time    `bson:"date" json:"date"`
+	CreatedAt     time.Time    `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time    `bson:"updatedAt" json:"updatedAt"`
+}
+
+type SettlementStatus string
+
+const (
+	SettlementPending   SettlementStatus = "pending"
+	SettlementConfirmed SettlementStatus = "confirmed"
+	SettlementDeclined  SettlementStatus = "declined"
+)
+
+// Settlement records a payment that pays off debt between two parties. A
+// payment with another registered user stays pending until they confirm it;
+// one with a contact is confirmed straight away.
+type Settlement struct {
+	ID        string           `bson:"_id,omitempty" json:"id"`
+	CreatedBy string           `bson:"createdBy" json:"createdBy"`
+	From      Party            `bson:"from" json:"from"`
+	To        Party            `bson:"to" json:"to"`
+	Amount    float64          `bson:"amount" json:"amount"`
+	Currency  string           `bson:"currency" json:"currency"`
+	Note      *string          `bson:"note,omitempty" json:"note,omitempty"`
+	Date      time.Time        `bson:"date" json:"date"`
+	Status    SettlementStatus `bson:"status" json:"status"`
+	// TransactionIDs are the matching expense and income transactions
+	// recorded for the registered parties once the settlement is confirmed.
+	TransactionIDs []string   `bson:"transactionIds,omitempty" json:"transactionIds,omitempty"`
+	CreatedAt      time.Time  `bson:"createdAt" json:"createdAt"`
+	RespondedAt    *time.Time `bson:"respondedAt,omitempty" json:"respondedAt,omitempty"`
+}
+
+// Counterpart returns the party on the other side from userID.
+func (s *Settlement) Counterpart(userID string) Party {
+	if s.From.UserID == userID {
+		return s.To
+	}
+	return s.From
+}
+
+// LedgerEntry is one movement in the who-owes-whom ledger: Debtor owes
+// Creditor Amount more than before. Splits add one entry per participant; a
+// settlement adds one entry in the opposite direction.
+type LedgerEntry struct {
+	ID           string  `bson:"_id,omitempty" json:"id"`
+	SplitID      string  `bson:"splitId,omitempty" json:"splitId,omitempty"`
+	SettlementID string  `bson:"settlementId,omitempty" json:"settlementId,omitempty"`
+	Debtor       Party   `bson:"debtor" json:"debtor"`
+	Creditor     Party   `bson:"creditor" json:"creditor"`
+	Amount       float64 `bson:"amount" json:"amount"`
+	Currency     string  `bson:"currency" json:"currency"`
+	// Users lists the registered users involved, for lookups by user.
+	Users     []string  `bson:"users" json:"-"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
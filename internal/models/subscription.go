@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type SubscriptionDecisionStatus string
+
+const (
+	SubscriptionConfirmed SubscriptionDecisionStatus = "confirmed"
+	SubscriptionDismissed SubscriptionDecisionStatus = "dismissed"
+)
+
+// SubscriptionDecision records what the user did with a detected recurring
+// charge. Key identifies the detected series (see services.SubscriptionService).
+type SubscriptionDecision struct {
+	ID         string                     `bson:"_id,omitempty" json:"id"`
+	UserID     string                     `bson:"userId" json:"userId"`
+	Key        string                     `bson:"key" json:"key"`
+	Status     SubscriptionDecisionStatus `bson:"status" json:"status"`
+	ReminderID *string                    `bson:"reminderId,omitempty" json:"reminderId,omitempty"`
+	CreatedAt  time.Time                  `bson:"createdAt" json:"createdAt"`
+	UpdatedAt  time.Time                  `bson:"updatedAt" json:"updatedAt"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Tag is a free-form label. Unlike categories, a transaction or reminder can
+// carry any number of tags. Names are unique per workspace, ignoring case.
+type Tag struct {
+	ID          string    `bson:"_id,omitempty" json:"id"`
+	UserID      string    `bson:"userId" json:"userId"`
+	HouseholdID string    `bson:"householdId,omitempty" json:"householdId,omitempty"`
+	Name        string    `bson:"name" json:"name"`
+	Color       *string   `bson:"color,omitempty" json:"color,omitempty"`
+	CreatedAt   time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time `bson:"updatedAt" json:"updatedAt"`
+}
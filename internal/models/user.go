@@ -3,10 +3,28 @@ package models
 import "time"
 
 type User struct {
-	ID           string    `bson:"_id,omitempty" json:"id"`
-	Name         string    `bson:"name" json:"name"`
-	Email        string    `bson:"email" json:"email"`
-	PasswordHash string    `bson:"passwordHash" json:"-"`
-	CreatedAt    time.Time `bson:"createdAt" json:"createdAt"`
-	UpdatedAt    time.Time `bson:"updatedAt" json:"updatedAt"`
+	ID              string     `bson:"_id,omitempty" json:"id"`
+	Name            string     `bson:"name" json:"name"`
+	Email           string     `bson:"email" json:"email"`
+	PasswordHash    string     `bson:"passwordHash" json:"-"`
+	EmailVerified   bool       `bson:"emailVerified" json:"emailVerified"`
+	EmailVerifiedAt *time.Time `bson:"emailVerifiedAt,omitempty" json:"emailVerifiedAt,omitempty"`
+	MFA             UserMFA    `bson:"mfa,omitempty" json:"-"`
+	// DeletionScheduledFor is set while the account is waiting out the grace
+	// period before it and all its data are purged.
+	DeletionScheduledFor *time.Time `bson:"deletionScheduledFor,omitempty" json:"deletionScheduledFor,omitempty"`
+	CreatedAt            time.Time  `bson:"createdAt" json:"createdAt"`
+	UpdatedAt            time.Time  `bson:"updatedAt" json:"updatedAt"`
+}
+
+// UserMFA holds the TOTP second factor. PendingSecret is set between
+// enrolment and confirmation; LastStep is the last accepted TOTP step, kept
+// so a code cannot be replayed.
+type UserMFA struct {
+	Enabled            bool       `bson:"enabled,omitempty"`
+	TOTPSecret         string     `bson:"totpSecret,omitempty"`
+	PendingSecret      string     `bson:"pendingSecret,omitempty"`
+	LastStep           int64      `bson:"lastStep"`
+	RecoveryCodeHashes []string   `bson:"recoveryCodeHashes,omitempty"`
+	EnabledAt          *time.Time `bson:"enabledAt,omitempty"`
 }
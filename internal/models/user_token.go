@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type UserTokenPurpose string
+
+const (
+	TokenPasswordReset UserTokenPurpose = "password_reset"
+	TokenEmailVerify   UserTokenPurpose = "email_verify"
+	TokenMFAChallenge  UserTokenPurpose = "mfa_challenge"
+)
+
+// UserToken is a single-use, expiring token mailed or handed to the user.
+// Only its hash is stored.
+type UserToken struct {
+	ID        string           `bson:"_id,omitempty" json:"id"`
+	UserID    string           `bson:"userId" json:"userId"`
+	Purpose   UserTokenPurpose `bson:"purpose" json:"purpose"`
+	TokenHash string           `bson:"tokenHash" json:"-"`
+	Email     string           `bson:"email" json:"email"`
+	ExpiresAt time.Time        `bson:"expiresAt" json:"expiresAt"`
+	Attempts  int              `bson:"attempts,omitempty" json:"-"`
+	UsedAt    *time.Time       `bson:"usedAt,omitempty" json:"usedAt,omitempty"`
+	CreatedAt time.Time        `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AlertTemplateRepository holds users' own alert templates. They are
+// personal: a user's templates read every alert they send in, whichever
+// workspace it goes to.
+type AlertTemplateRepository interface {
+	Create(ctx context.Context, t *models.AlertTemplate) error
+	FindByID(ctx context.Context, id, userID string) (*models.AlertTemplate, error)
+	ListByUser(ctx context.Context, userID string) ([]models.AlertTemplate, error)
+	CountByUser(ctx context.Context, userID string) (int64, error)
+	Update(ctx context.Context, t *models.AlertTemplate) error
+	Delete(ctx context.Context, id, userID string) (bool, error)
+	EnsureIndexes(ctx context.Context) error
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+}
+
+type alertTemplateRepository struct {
+	col *mongo.Collection
+}
+
+func NewAlertTemplateRepository(db *mongo.Database) AlertTemplateRepository {
+	return &alertTemplateRepository{
+		col: db.Collection("alert_templates"),
+	}
+}
+
+func (r *alertTemplateRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: 1}},
+	})
+	return err
+}
+
+func (r *alertTemplateRepository) Create(ctx context.Context, t *models.AlertTemplate) error {
+	now := time.Now().UTC()
+	t.CreatedAt = now
+	t.UpdatedAt = now
+
+	res, err := r.col.InsertOne(ctx, t)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		t.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *alertTemplateRepository) FindByID(ctx context.Context, id, userID string) (*models.AlertTemplate, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	var t models.AlertTemplate
+	err = r.col.FindOne(ctx, bson.M{"_id": objectID, "userId": userID}).Decode(&t)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *alertTemplateRepository) ListByUser(ctx context.Context, userID string) ([]models.AlertTemplate, error) {
+	cur, err := r.col.Find(ctx, bson.M{"userId": userID}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	templates := []models.AlertTemplate{}
+	if err := cur.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (r *alertTemplateRepository) CountByUser(ctx context.Context, userID string) (int64, error) {
+	return r.col.CountDocuments(ctx, bson.M{"userId": userID})
+}
+
+func (r *alertTemplateRepository) Update(ctx context.Context, t *models.AlertTemplate) error {
+	objectID, err := primitive.ObjectIDFromHex(t.ID)
+	if err != nil {
+		return err
+	}
+	t.UpdatedAt = time.Now().UTC()
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": objectID, "userId": t.UserID}, bson.M{
+		"$set": bson.M{
+			"name":       t.Name,
+			"bank":       t.Bank,
+			"pattern":    t.Pattern,
+			"dateLayout": t.DateLayout,
+			"type":       t.Type,
+			"currency":   t.Currency,
+			"updatedAt":  t.UpdatedAt,
+		},
+	})
+	return err
+}
+
+func (r *alertTemplateRepository) Delete(ctx context.Context, id, userID string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": objectID, "userId": userID})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (r *alertTemplateRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
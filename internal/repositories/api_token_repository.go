@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type APITokenRepository interface {
+	Create(ctx context.Context, t *models.APIToken) error
+	FindByHash(ctx context.Context, hash string) (*models.APIToken, error)
+	// ListByUser returns the user's tokens that have not been revoked.
+	ListByUser(ctx context.Context, userID string) ([]models.APIToken, error)
+	Revoke(ctx context.Context, id, userID string) (bool, error)
+	// RevokeAllForUser revokes every live token of the user and returns how
+	// many there were.
+	RevokeAllForUser(ctx context.Context, userID string) (int64, error)
+	// TouchLastUsed records a use, at most once per interval per token.
+	TouchLastUsed(ctx context.Context, id, ip string, interval time.Duration) error
+	EnsureIndexes(ctx context.Context) error
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+}
+
+type apiTokenRepository struct {
+	col *mongo.Collection
+}
+
+func NewAPITokenRepository(db *mongo.Database) APITokenRepository {
+	return &apiTokenRepository{
+		col: db.Collection("api_tokens"),
+	}
+}
+
+func (r *apiTokenRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	return err
+}
+
+func (r *apiTokenRepository) Create(ctx context.Context, t *models.APIToken) error {
+	t.CreatedAt = time.Now().UTC()
+
+	res, err := r.col.InsertOne(ctx, t)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		t.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *apiTokenRepository) FindByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	var t models.APIToken
+	err := r.col.FindOne(ctx, bson.M{"tokenHash": hash}).Decode(&t)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *apiTokenRepository) ListByUser(ctx context.Context, userID string) ([]models.APIToken, error) {
+	cur, err := r.col.Find(ctx, bson.M{
+		"userId":    userID,
+		"revokedAt": nil,
+	}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	tokens := []models.APIToken{}
+	if err := cur.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *apiTokenRepository) Revoke(ctx context.Context, id, userID string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	res, err := r.col.UpdateOne(ctx, bson.M{
+		"_id":       objectID,
+		"userId":    userID,
+		"revokedAt": nil,
+	}, bson.M{
+		"$set": bson.M{"revokedAt": time.Now().UTC()},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *apiTokenRepository) RevokeAllForUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.col.UpdateMany(ctx, bson.M{
+		"userId":    userID,
+		"revokedAt": nil,
+	}, bson.M{
+		"$set": bson.M{"revokedAt": time.Now().UTC()},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func (r *apiTokenRepository) TouchLastUsed(ctx context.Context, id, ip string, interval time.Duration) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	_, err = r.col.UpdateOne(ctx, bson.M{
+		"_id": objectID,
+		"$or": bson.A{
+			bson.M{"lastUsedAt": nil},
+			bson.M{"lastUsedAt": bson.M{"$lt": now.Add(-interval)}},
+		},
+	}, bson.M{
+		"$set": bson.M{"lastUsedAt": now, "lastUsedIp": ip},
+	})
+	return err
+}
+
+func (r *apiTokenRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
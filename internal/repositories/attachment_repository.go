@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AttachmentRepository holds attachment metadata only; removing a document
+// does not remove its file.
+type AttachmentRepository interface {
+	Create(ctx context.Context, a *models.Attachment) error
+	FindByID(ctx context.Context, id string, scope Scope) (*models.Attachment, error)
+	ListByTransaction(ctx context.Context, scope Scope, transactionID string) ([]models.Attachment, error)
+	CountByTransaction(ctx context.Context, scope Scope, transactionID string) (int64, error)
+	Delete(ctx context.Context, id string, scope Scope) error
+	DeleteByTransaction(ctx context.Context, scope Scope, transactionID string) error
+	// ListAllByUser and ListAllByHousehold return the documents the
+	// matching DeleteAll methods remove, so their files can go first.
+	ListAllByUser(ctx context.Context, userID string) ([]models.Attachment, error)
+	ListAllByHousehold(ctx context.Context, householdID string) ([]models.Attachment, error)
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+	DeleteAllByHousehold(ctx context.Context, householdID string) (int64, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type attachmentRepository struct {
+	col *mongo.Collection
+}
+
+func NewAttachmentRepository(db *mongo.Database) AttachmentRepository {
+	return &attachmentRepository{
+		col: db.Collection("attachments"),
+	}
+}
+
+func (r *attachmentRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "transactionId", Value: 1}, {Key: "createdAt", Value: 1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+		{Keys: bson.D{{Key: "householdId", Value: 1}}},
+	})
+	return err
+}
+
+func (r *attachmentRepository) Create(ctx context.Context, a *models.Attachment) error {
+	a.CreatedAt = time.Now().UTC()
+
+	res, err := r.col.InsertOne(ctx, a)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		a.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *attachmentRepository) FindByID(ctx context.Context, id string, scope Scope) (*models.Attachment, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	filter := scope.filter()
+	filter["_id"] = objectID
+
+	var a models.Attachment
+	err = r.col.FindOne(ctx, filter).Decode(&a)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *attachmentRepository) ListByTransaction(ctx context.Context, scope Scope, transactionID string) ([]models.Attachment, error) {
+	filter := scope.filter()
+	filter["transactionId"] = transactionID
+	return r.list(ctx, filter)
+}
+
+func (r *attachmentRepository) CountByTransaction(ctx context.Context, scope Scope, transactionID string) (int64, error) {
+	filter := scope.filter()
+	filter["transactionId"] = transactionID
+	return r.col.CountDocuments(ctx, filter)
+}
+
+func (r *attachmentRepository) ListAllByUser(ctx context.Context, userID string) ([]models.Attachment, error) {
+	return r.list(ctx, personalFilter(userID))
+}
+
+func (r *attachmentRepository) ListAllByHousehold(ctx context.Context, householdID string) ([]models.Attachment, error) {
+	return r.list(ctx, bson.M{"householdId": householdID})
+}
+
+func (r *attachmentRepository) list(ctx context.Context, filter bson.M) ([]models.Attachment, error) {
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	attachments := []models.Attachment{}
+	if err := cur.All(ctx, &attachments); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (r *attachmentRepository) Delete(ctx context.Context, id string, scope Scope) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	filter := scope.filter()
+	filter["_id"] = objectID
+	_, err = r.col.DeleteOne(ctx, filter)
+	return err
+}
+
+func (r *attachmentRepository) DeleteByTransaction(ctx context.Context, scope Scope, transactionID string) error {
+	filter := scope.filter()
+	filter["transactionId"] = transactionID
+	_, err := r.col.DeleteMany(ctx, filter)
+	return err
+}
+
+func (r *attachmentRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, personalFilter(userID))
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (r *attachmentRepository) DeleteAllByHousehold(ctx context.Context, householdID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, bson.M{"householdId": householdID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
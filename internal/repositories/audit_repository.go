@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AuditFilter struct {
+	UserID     string
+	Actions    []models.AuditAction
+	EntityType string
+	EntityID   string
+	From       *time.Time
+	To         *time.Time
+	Limit      int64
+	Offset     int64
+}
+
+// AuditRepository is append-only: events are never updated, and only
+// removed together with the account they belong to.
+type AuditRepository interface {
+	Insert(ctx context.Context, e *models.AuditEvent) error
+	List(ctx context.Context, f AuditFilter) ([]models.AuditEvent, int64, error)
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type auditRepository struct {
+	col *mongo.Collection
+}
+
+func NewAuditRepository(db *mongo.Database) AuditRepository {
+	return &auditRepository{
+		col: db.Collection("audit_events"),
+	}
+}
+
+func (r *auditRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "entityType", Value: 1}, {Key: "entityId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	return err
+}
+
+func (r *auditRepository) Insert(ctx context.Context, e *models.AuditEvent) error {
+	e.CreatedAt = time.Now().UTC()
+
+	res, err := r.col.InsertOne(ctx, e)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		e.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *auditRepository) List(ctx context.Context, f AuditFilter) ([]models.AuditEvent, int64, error) {
+	filter := bson.M{"userId": f.UserID}
+	if len(f.Actions) > 0 {
+		filter["action"] = bson.M{"$in": f.Actions}
+	}
+	if f.EntityType != "" {
+		filter["entityType"] = f.EntityType
+	}
+	if f.EntityID != "" {
+		filter["entityId"] = f.EntityID
+	}
+	if f.From != nil || f.To != nil {
+		createdAt := bson.M{}
+		if f.From != nil {
+			createdAt["$gte"] = *f.From
+		}
+		if f.To != nil {
+			createdAt["$lte"] = *f.To
+		}
+		filter["createdAt"] = createdAt
+	}
+
+	count, err := r.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cur, err := r.col.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(f.Offset).
+		SetLimit(f.Limit))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+
+	events := []models.AuditEvent{}
+	if err := cur.All(ctx, &events); err != nil {
+		return nil, 0, err
+	}
+	return events, count, nil
+}
+
+func (r *auditRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
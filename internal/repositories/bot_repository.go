@@ -0,0 +1,199 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type BotRepository interface {
+	// CreatePairing stores a new code, replacing any the user had.
+	CreatePairing(ctx context.Context, p *models.BotPairing) error
+	// ConsumePairing deletes and returns the unexpired pairing with the
+	// hash, or nil.
+	ConsumePairing(ctx context.Context, codeHash string) (*models.BotPairing, error)
+
+	// Link links a chat to l.UserID. A chat already linked to someone is
+	// moved over.
+	Link(ctx context.Context, l *models.BotLink) error
+	FindByChatID(ctx context.Context, chatID int64) (*models.BotLink, error)
+	ListByUser(ctx context.Context, userID string) ([]models.BotLink, error)
+	ListAll(ctx context.Context) ([]models.BotLink, error)
+	Unlink(ctx context.Context, id, userID string) (bool, error)
+	UnlinkChat(ctx context.Context, chatID int64) (bool, error)
+	// ClaimNotifications moves a link's NotifiedThrough from from to to.
+	// It returns false when another worker got there first.
+	ClaimNotifications(ctx context.Context, id string, from, to time.Time) (bool, error)
+
+	EnsureIndexes(ctx context.Context) error
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+}
+
+type botRepository struct {
+	col      *mongo.Collection
+	pairings *mongo.Collection
+}
+
+func NewBotRepository(db *mongo.Database) BotRepository {
+	return &botRepository{
+		col:      db.Collection("bot_links"),
+		pairings: db.Collection("bot_pairings"),
+	}
+}
+
+func (r *botRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "chatId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.pairings.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "codeHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+func (r *botRepository) CreatePairing(ctx context.Context, p *models.BotPairing) error {
+	if _, err := r.pairings.DeleteMany(ctx, bson.M{"userId": p.UserID}); err != nil {
+		return err
+	}
+	p.CreatedAt = time.Now().UTC()
+
+	res, err := r.pairings.InsertOne(ctx, p)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		p.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *botRepository) ConsumePairing(ctx context.Context, codeHash string) (*models.BotPairing, error) {
+	var p models.BotPairing
+	err := r.pairings.FindOneAndDelete(ctx, bson.M{
+		"codeHash":  codeHash,
+		"expiresAt": bson.M{"$gt": time.Now().UTC()},
+	}).Decode(&p)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *botRepository) Link(ctx context.Context, l *models.BotLink) error {
+	now := time.Now().UTC()
+	l.CreatedAt = now
+	l.NotifiedThrough = now
+
+	var saved models.BotLink
+	err := r.col.FindOneAndUpdate(ctx, bson.M{"chatId": l.ChatID}, bson.M{
+		"$set": bson.M{
+			"userId":          l.UserID,
+			"username":        l.Username,
+			"timezone":        l.Timezone,
+			"currency":        l.Currency,
+			"notifiedThrough": l.NotifiedThrough,
+			"createdAt":       l.CreatedAt,
+		},
+	}, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&saved)
+	if err != nil {
+		return err
+	}
+	l.ID = saved.ID
+	return nil
+}
+
+func (r *botRepository) FindByChatID(ctx context.Context, chatID int64) (*models.BotLink, error) {
+	var l models.BotLink
+	err := r.col.FindOne(ctx, bson.M{"chatId": chatID}).Decode(&l)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *botRepository) ListByUser(ctx context.Context, userID string) ([]models.BotLink, error) {
+	return r.find(ctx, bson.M{"userId": userID})
+}
+
+func (r *botRepository) ListAll(ctx context.Context) ([]models.BotLink, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *botRepository) find(ctx context.Context, filter bson.M) ([]models.BotLink, error) {
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	links := []models.BotLink{}
+	if err := cur.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r *botRepository) Unlink(ctx context.Context, id, userID string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": objectID, "userId": userID})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (r *botRepository) UnlinkChat(ctx context.Context, chatID int64) (bool, error) {
+	res, err := r.col.DeleteOne(ctx, bson.M{"chatId": chatID})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (r *botRepository) ClaimNotifications(ctx context.Context, id string, from, to time.Time) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	res, err := r.col.UpdateOne(ctx, bson.M{
+		"_id":             objectID,
+		"notifiedThrough": from,
+	}, bson.M{
+		"$set": bson.M{"notifiedThrough": to},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+func (r *botRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	if _, err := r.pairings.DeleteMany(ctx, bson.M{"userId": userID}); err != nil {
+		return 0, err
+	}
+	res, err := r.col.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
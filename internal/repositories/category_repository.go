@@ -13,10 +13,13 @@ import (
 
 type CategoryRepository interface {
 	Create(ctx context.Context, c *models.Category) error
-	FindByID(ctx context.Context, id, userID string) (*models.Category, error)
-	List(ctx context.Context, userID string, parentID *string) ([]models.Category, error)
-	Update(ctx context.Context, c *models.Category) error
-	Delete(ctx context.Context, id, userID string) error
+	FindByID(ctx context.Context, id string, scope Scope) (*models.Category, error)
+	List(ctx context.Context, scope Scope, parentID *string) ([]models.Category, error)
+	Update(ctx context.Context, scope Scope, c *models.Category) error
+	Delete(ctx context.Context, id string, scope Scope) error
+	DeleteByIDs(ctx context.Context, userID string, ids []string) (int64, error)
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+	DeleteAllByHousehold(ctx context.Context, householdID string) (int64, error)
 }
 
 type categoryRepository struct {
@@ -45,19 +48,18 @@ func (r *categoryRepository) Create(ctx context.Context, c *models.Category) err
 	return nil
 }
 
-func (r *categoryRepository) FindByID(ctx context.Context, id, userID string) (*models.Category, error) {
+func (r *categoryRepository) FindByID(ctx context.Context, id string, scope Scope) (*models.Category, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err // invalid ID format
 	}
 
 	var cat models.Category
-	log.Default().Println("Finding category by ID:", id, "for user:", userID)
+	log.Default().Println("Finding category by ID:", id, "for user:", scope.UserID)
 
-	err = r.col.FindOne(ctx, bson.M{
-		"_id":    objectID,
-		"userId": userID,
-	}).Decode(&cat)
+	filter := scope.filter()
+	filter["_id"] = objectID
+	err = r.col.FindOne(ctx, filter).Decode(&cat)
 
 	if err == mongo.ErrNoDocuments {
 		return nil, nil
@@ -69,8 +71,8 @@ func (r *categoryRepository) FindByID(ctx context.Context, id, userID string) (*
 	return &cat, nil
 }
 
-func (r *categoryRepository) List(ctx context.Context, userID string, parentID *string) ([]models.Category, error) {
-	filter := bson.M{"userId": userID}
+func (r *categoryRepository) List(ctx context.Context, scope Scope, parentID *string) ([]models.Category, error) {
+	filter := scope.filter()
 	if parentID != nil {
 		filter["parentId"] = *parentID
 	}
@@ -88,12 +90,15 @@ func (r *categoryRepository) List(ctx context.Context, userID string, parentID *
 	return res, nil
 }
 
-func (r *categoryRepository) Update(ctx context.Context, c *models.Category) error {
+func (r *categoryRepository) Update(ctx context.Context, scope Scope, c *models.Category) error {
+	objectID, err := primitive.ObjectIDFromHex(c.ID)
+	if err != nil {
+		return err
+	}
 	c.UpdatedAt = time.Now().UTC()
-	_, err := r.col.UpdateOne(ctx, bson.M{
-		"_id":    c.ID,
-		"userId": c.UserID,
-	}, bson.M{
+	filter := scope.filter()
+	filter["_id"] = objectID
+	_, err = r.col.UpdateOne(ctx, filter, bson.M{
 		"$set": bson.M{
 			"name":      c.Name,
 			"parentId":  c.ParentID,
@@ -103,10 +108,33 @@ func (r *categoryRepository) Update(ctx context.Context, c *models.Category) err
 	return err
 }
 
-func (r *categoryRepository) Delete(ctx context.Context, id, userID string) error {
-	_, err := r.col.DeleteOne(ctx, bson.M{
-		"_id":    id,
-		"userId": userID,
-	})
+func (r *categoryRepository) Delete(ctx context.Context, id string, scope Scope) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	filter := scope.filter()
+	filter["_id"] = objectID
+	_, err = r.col.DeleteOne(ctx, filter)
 	return err
 }
+
+func (r *categoryRepository) DeleteByIDs(ctx context.Context, userID string, ids []string) (int64, error) {
+	return deleteByIDs(ctx, r.col, userID, ids)
+}
+
+func (r *categoryRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, personalFilter(userID))
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (r *categoryRepository) DeleteAllByHousehold(ctx context.Context, householdID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, bson.M{"householdId": householdID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
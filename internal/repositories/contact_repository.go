@@ -0,0 +1,268 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ContactRepository interface {
+	Create(ctx context.Context, c *models.Contact) error
+	FindByID(ctx context.Context, id, userID string) (*models.Contact, error)
+	FindByIDs(ctx context.Context, userID string, ids []string) ([]models.Contact, error)
+	List(ctx context.Context, userID string) ([]models.Contact, error)
+	Update(ctx context.Context, c *models.Contact) error
+	Delete(ctx context.Context, id, userID string) error
+
+	// CreateLink saves a pending link request. It reports false if the
+	// requester already asked that address.
+	CreateLink(ctx context.Context, l *models.ContactLink) (bool, error)
+	FindLink(ctx context.Context, id string) (*models.ContactLink, error)
+	// ListLinks lists the user's links and the pending requests addressed to
+	// their email.
+	ListLinks(ctx context.Context, userID, email string) ([]models.ContactLink, error)
+	// AcceptLink accepts a pending request addressed to email on behalf of
+	// userID. It reports false if the request was no longer pending.
+	AcceptLink(ctx context.Context, id, email, userID string) (bool, error)
+	DeleteLink(ctx context.Context, id string) error
+	// Linked reports whether the two users have an accepted link, whichever
+	// of them asked for it.
+	Linked(ctx context.Context, userID, otherID string) (bool, error)
+
+	// DeleteAllByUser removes the user's contacts and every link they are
+	// part of.
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type contactRepository struct {
+	col   *mongo.Collection
+	links *mongo.Collection
+}
+
+func NewContactRepository(db *mongo.Database) ContactRepository {
+	return &contactRepository{
+		col:   db.Collection("contacts"),
+		links: db.Collection("contact_links"),
+	}
+}
+
+func (r *contactRepository) EnsureIndexes(ctx context.Context) error {
+	if _, err := r.col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "name", Value: 1}},
+	}); err != nil {
+		return err
+	}
+	_, err := r.links.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "requesterId", Value: 1}, {Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "email", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "addresseeId", Value: 1}}},
+	})
+	return err
+}
+
+func (r *contactRepository) Create(ctx context.Context, c *models.Contact) error {
+	now := time.Now().UTC()
+	c.CreatedAt = now
+	c.UpdatedAt = now
+
+	res, err := r.col.InsertOne(ctx, c)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		c.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *contactRepository) FindByID(ctx context.Context, id, userID string) (*models.Contact, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	var c models.Contact
+	err = r.col.FindOne(ctx, bson.M{"_id": objectID, "userId": userID}).Decode(&c)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *contactRepository) FindByIDs(ctx context.Context, userID string, ids []string) ([]models.Contact, error) {
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			oids = append(oids, oid)
+		}
+	}
+	contacts := []models.Contact{}
+	if len(oids) == 0 {
+		return contacts, nil
+	}
+	cur, err := r.col.Find(ctx, bson.M{"_id": bson.M{"$in": oids}, "userId": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &contacts); err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
+
+func (r *contactRepository) List(ctx context.Context, userID string) ([]models.Contact, error) {
+	cur, err := r.col.Find(ctx, bson.M{"userId": userID},
+		options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	contacts := []models.Contact{}
+	if err := cur.All(ctx, &contacts); err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
+
+func (r *contactRepository) Update(ctx context.Context, c *models.Contact) error {
+	objectID, err := primitive.ObjectIDFromHex(c.ID)
+	if err != nil {
+		return err
+	}
+	c.UpdatedAt = time.Now().UTC()
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": objectID, "userId": c.UserID}, bson.M{
+		"$set": bson.M{"name": c.Name, "email": c.Email, "updatedAt": c.UpdatedAt},
+	})
+	return err
+}
+
+func (r *contactRepository) Delete(ctx context.Context, id, userID string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.col.DeleteOne(ctx, bson.M{"_id": objectID, "userId": userID})
+	return err
+}
+
+func (r *contactRepository) CreateLink(ctx context.Context, l *models.ContactLink) (bool, error) {
+	l.CreatedAt = time.Now().UTC()
+	l.Status = models.ContactLinkPending
+
+	res, err := r.links.InsertOne(ctx, l)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		l.ID = oid.Hex()
+	}
+	return true, nil
+}
+
+func (r *contactRepository) FindLink(ctx context.Context, id string) (*models.ContactLink, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	var l models.ContactLink
+	err = r.links.FindOne(ctx, bson.M{"_id": objectID}).Decode(&l)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *contactRepository) ListLinks(ctx context.Context, userID, email string) ([]models.ContactLink, error) {
+	cur, err := r.links.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"requesterId": userID},
+		bson.M{"addresseeId": userID},
+		bson.M{"email": email, "status": models.ContactLinkPending},
+	}}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	links := []models.ContactLink{}
+	if err := cur.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r *contactRepository) AcceptLink(ctx context.Context, id, email, userID string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	res, err := r.links.UpdateOne(ctx, bson.M{
+		"_id":    objectID,
+		"email":  email,
+		"status": models.ContactLinkPending,
+	}, bson.M{"$set": bson.M{
+		"status":      models.ContactLinkAccepted,
+		"addresseeId": userID,
+		"acceptedAt":  time.Now().UTC(),
+	}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+func (r *contactRepository) DeleteLink(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.links.DeleteOne(ctx, bson.M{"_id": objectID})
+	return err
+}
+
+func (r *contactRepository) Linked(ctx context.Context, userID, otherID string) (bool, error) {
+	n, err := r.links.CountDocuments(ctx, bson.M{
+		"status": models.ContactLinkAccepted,
+		"$or": bson.A{
+			bson.M{"requesterId": userID, "addresseeId": otherID},
+			bson.M{"requesterId": otherID, "addresseeId": userID},
+		},
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *contactRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+	total := res.DeletedCount
+
+	res, err = r.links.DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"requesterId": userID},
+		bson.M{"addresseeId": userID},
+	}})
+	if err != nil {
+		return total, err
+	}
+	return total + res.DeletedCount, nil
+}
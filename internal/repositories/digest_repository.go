@@ -0,0 +1,199 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type DigestRepository interface {
+	Create(ctx context.Context, d *models.DigestSubscription) error
+	FindByID(ctx context.Context, id, userID string) (*models.DigestSubscription, error)
+	ListByUser(ctx context.Context, userID string) ([]models.DigestSubscription, error)
+	ListActive(ctx context.Context) ([]models.DigestSubscription, error)
+	Update(ctx context.Context, d *models.DigestSubscription) error
+	Delete(ctx context.Context, id, userID string) error
+
+	EnsureIndexes(ctx context.Context) error
+	// ClaimDelivery takes the send lease for one subscription period. It
+	// returns false when the period was already sent or another worker holds
+	// an unexpired lease.
+	ClaimDelivery(ctx context.Context, sub *models.DigestSubscription, periodKey string, lease time.Duration) (bool, error)
+	MarkDelivered(ctx context.Context, subscriptionID, periodKey string) error
+	DeleteByIDs(ctx context.Context, userID string, ids []string) (int64, error)
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+}
+
+type digestRepository struct {
+	col        *mongo.Collection
+	deliveries *mongo.Collection
+}
+
+func NewDigestRepository(db *mongo.Database) DigestRepository {
+	return &digestRepository{
+		col:        db.Collection("digest_subscriptions"),
+		deliveries: db.Collection("digest_deliveries"),
+	}
+}
+
+func (r *digestRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.deliveries.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "subscriptionId", Value: 1}, {Key: "periodKey", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (r *digestRepository) Create(ctx context.Context, d *models.DigestSubscription) error {
+	now := time.Now().UTC()
+	d.CreatedAt = now
+	d.UpdatedAt = now
+
+	res, err := r.col.InsertOne(ctx, d)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		d.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *digestRepository) FindByID(ctx context.Context, id, userID string) (*models.DigestSubscription, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	var d models.DigestSubscription
+	err = r.col.FindOne(ctx, bson.M{
+		"_id":    objectID,
+		"userId": userID,
+	}).Decode(&d)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *digestRepository) ListByUser(ctx context.Context, userID string) ([]models.DigestSubscription, error) {
+	return r.find(ctx, bson.M{"userId": userID})
+}
+
+func (r *digestRepository) ListActive(ctx context.Context) ([]models.DigestSubscription, error) {
+	return r.find(ctx, bson.M{"isActive": true})
+}
+
+func (r *digestRepository) find(ctx context.Context, filter bson.M) ([]models.DigestSubscription, error) {
+	cursor, err := r.col.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var res []models.DigestSubscription
+	if err := cursor.All(ctx, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *digestRepository) Update(ctx context.Context, d *models.DigestSubscription) error {
+	objectID, err := primitive.ObjectIDFromHex(d.ID)
+	if err != nil {
+		return err
+	}
+
+	d.UpdatedAt = time.Now().UTC()
+	_, err = r.col.UpdateOne(ctx, bson.M{
+		"_id":    objectID,
+		"userId": d.UserID,
+	}, bson.M{
+		"$set": bson.M{
+			"frequency": d.Frequency,
+			"sendTime":  d.SendTime,
+			"timezone":  d.Timezone,
+			"isActive":  d.IsActive,
+			"updatedAt": d.UpdatedAt,
+		},
+	})
+	return err
+}
+
+func (r *digestRepository) Delete(ctx context.Context, id, userID string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil
+	}
+	_, err = r.col.DeleteOne(ctx, bson.M{
+		"_id":    objectID,
+		"userId": userID,
+	})
+	return err
+}
+
+func (r *digestRepository) ClaimDelivery(ctx context.Context, sub *models.DigestSubscription, periodKey string, lease time.Duration) (bool, error) {
+	now := time.Now().UTC()
+
+	// Matches only a pending record whose lease has lapsed. When nothing
+	// matches the upsert tries to insert, which the unique index rejects if
+	// the period is already sent or leased by someone else.
+	_, err := r.deliveries.UpdateOne(ctx, bson.M{
+		"subscriptionId": sub.ID,
+		"periodKey":      periodKey,
+		"status":         models.DigestDeliveryPending,
+		"leaseUntil":     bson.M{"$lt": now},
+	}, bson.M{
+		"$set": bson.M{
+			"leaseUntil": now.Add(lease),
+		},
+		"$setOnInsert": bson.M{
+			"userId":    sub.UserID,
+			"createdAt": now,
+		},
+	}, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *digestRepository) MarkDelivered(ctx context.Context, subscriptionID, periodKey string) error {
+	now := time.Now().UTC()
+	_, err := r.deliveries.UpdateOne(ctx, bson.M{
+		"subscriptionId": subscriptionID,
+		"periodKey":      periodKey,
+	}, bson.M{
+		"$set": bson.M{
+			"status": models.DigestDeliverySent,
+			"sentAt": now,
+		},
+	})
+	return err
+}
+
+func (r *digestRepository) DeleteByIDs(ctx context.Context, userID string, ids []string) (int64, error) {
+	return deleteByIDs(ctx, r.col, userID, ids)
+}
+
+func (r *digestRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	if _, err := r.deliveries.DeleteMany(ctx, bson.M{"userId": userID}); err != nil {
+		return 0, err
+	}
+	res, err := r.col.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
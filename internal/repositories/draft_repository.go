@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type DraftRepository interface {
+	// Create saves a draft. It reports false if the scope already has a
+	// draft with the same text hash.
+	Create(ctx context.Context, d *models.TransactionDraft) (bool, error)
+	FindByID(ctx context.Context, id string, scope Scope) (*models.TransactionDraft, error)
+	// List returns drafts newest first; an empty status lists them all.
+	List(ctx context.Context, scope Scope, status models.DraftStatus, limit, offset int64) ([]models.TransactionDraft, int64, error)
+	// FindDuplicate returns a draft, in any status, with the same text hash
+	// or, when reference is not empty, the same reference.
+	FindDuplicate(ctx context.Context, scope Scope, textHash, reference string) (*models.TransactionDraft, error)
+	FindByTransaction(ctx context.Context, scope Scope, transactionID string) (*models.TransactionDraft, error)
+	// Resolve moves a pending draft to status. It returns false when the
+	// draft is no longer pending.
+	Resolve(ctx context.Context, scope Scope, id string, status models.DraftStatus, transactionID string) (bool, error)
+	EnsureIndexes(ctx context.Context) error
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+	DeleteAllByHousehold(ctx context.Context, householdID string) (int64, error)
+}
+
+type draftRepository struct {
+	col *mongo.Collection
+}
+
+func NewDraftRepository(db *mongo.Database) DraftRepository {
+	return &draftRepository{
+		col: db.Collection("transaction_drafts"),
+	}
+}
+
+func (r *draftRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "status", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "householdId", Value: 1}, {Key: "status", Value: 1}, {Key: "createdAt", Value: -1}}},
+		// A text hash is unique within a scope, so that two deliveries of
+		// the same message cannot both become drafts. Personal drafts have
+		// no householdId, which the first index sees as null.
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "householdId", Value: 1}, {Key: "textHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{
+			Keys: bson.D{{Key: "householdId", Value: 1}, {Key: "textHash", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"householdId": bson.M{"$type": "string"}}),
+		},
+		{Keys: bson.D{{Key: "reference", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "transactionId", Value: 1}}, Options: options.Index().SetSparse(true)},
+	})
+	return err
+}
+
+func (r *draftRepository) Create(ctx context.Context, d *models.TransactionDraft) (bool, error) {
+	now := time.Now().UTC()
+	d.CreatedAt = now
+	d.UpdatedAt = now
+
+	res, err := r.col.InsertOne(ctx, d)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		d.ID = oid.Hex()
+	}
+	return true, nil
+}
+
+func (r *draftRepository) FindByID(ctx context.Context, id string, scope Scope) (*models.TransactionDraft, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	filter := scope.filter()
+	filter["_id"] = objectID
+	return r.findOne(ctx, filter)
+}
+
+func (r *draftRepository) findOne(ctx context.Context, filter bson.M) (*models.TransactionDraft, error) {
+	var d models.TransactionDraft
+	err := r.col.FindOne(ctx, filter).Decode(&d)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *draftRepository) List(ctx context.Context, scope Scope, status models.DraftStatus, limit, offset int64) ([]models.TransactionDraft, int64, error) {
+	filter := scope.filter()
+	if status != "" {
+		filter["status"] = status
+	}
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetLimit(limit).
+		SetSkip(offset)
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+
+	drafts := []models.TransactionDraft{}
+	if err := cur.All(ctx, &drafts); err != nil {
+		return nil, 0, err
+	}
+	return drafts, total, nil
+}
+
+func (r *draftRepository) FindDuplicate(ctx context.Context, scope Scope, textHash, reference string) (*models.TransactionDraft, error) {
+	or := bson.A{bson.M{"textHash": textHash}}
+	if reference != "" {
+		or = append(or, bson.M{"reference": reference})
+	}
+	filter := scope.filter()
+	filter["$or"] = or
+	return r.findOne(ctx, filter)
+}
+
+func (r *draftRepository) FindByTransaction(ctx context.Context, scope Scope, transactionID string) (*models.TransactionDraft, error) {
+	filter := scope.filter()
+	filter["transactionId"] = transactionID
+	return r.findOne(ctx, filter)
+}
+
+func (r *draftRepository) Resolve(ctx context.Context, scope Scope, id string, status models.DraftStatus, transactionID string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	filter := scope.filter()
+	filter["_id"] = objectID
+	filter["status"] = models.DraftPending
+
+	set := bson.M{"status": status, "updatedAt": time.Now().UTC()}
+	if transactionID != "" {
+		set["transactionId"] = transactionID
+	}
+	res, err := r.col.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+func (r *draftRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, personalFilter(userID))
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (r *draftRepository) DeleteAllByHousehold(ctx context.Context, householdID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, bson.M{"householdId": householdID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,279 @@
+package repositories
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ExportRepository interface {
+	Create(ctx context.Context, e *models.Export) error
+	FindByID(ctx context.Context, id, userID string) (*models.Export, error)
+	// FindActive returns the user's pending or running export, if any.
+	FindActive(ctx context.Context, userID string) (*models.Export, error)
+	ListByUser(ctx context.Context, userID string) ([]models.Export, error)
+	// ClaimNext leases the oldest pending export, or a running one whose
+	// worker's lease has lapsed, and marks it running.
+	ClaimNext(ctx context.Context, lease time.Duration) (*models.Export, error)
+	MarkReady(ctx context.Context, e *models.Export) error
+	MarkFailed(ctx context.Context, id, msg string) error
+	ListExpired(ctx context.Context, now time.Time) ([]models.Export, error)
+	Delete(ctx context.Context, e *models.Export) error
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+	EnsureIndexes(ctx context.Context) error
+
+	// CreateFile opens a GridFS upload stream for an archive.
+	CreateFile(ctx context.Context, name string) (*gridfs.UploadStream, error)
+	OpenFile(ctx context.Context, fileID string) (io.ReadCloser, error)
+	DeleteFile(ctx context.Context, fileID string) error
+}
+
+type exportRepository struct {
+	db  *mongo.Database
+	col *mongo.Collection
+}
+
+func NewExportRepository(db *mongo.Database) ExportRepository {
+	return &exportRepository{
+		db:  db,
+		col: db.Collection("exports"),
+	}
+}
+
+func (r *exportRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: 1}}},
+	})
+	return err
+}
+
+func (r *exportRepository) Create(ctx context.Context, e *models.Export) error {
+	e.CreatedAt = time.Now().UTC()
+
+	res, err := r.col.InsertOne(ctx, e)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		e.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *exportRepository) FindByID(ctx context.Context, id, userID string) (*models.Export, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	filter := bson.M{"_id": objectID}
+	if userID != "" {
+		filter["userId"] = userID
+	}
+
+	var e models.Export
+	err = r.col.FindOne(ctx, filter).Decode(&e)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *exportRepository) FindActive(ctx context.Context, userID string) (*models.Export, error) {
+	var e models.Export
+	err := r.col.FindOne(ctx, bson.M{
+		"userId": userID,
+		"status": bson.M{"$in": bson.A{models.ExportPending, models.ExportRunning}},
+	}).Decode(&e)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *exportRepository) ListByUser(ctx context.Context, userID string) ([]models.Export, error) {
+	cur, err := r.col.Find(ctx, bson.M{"userId": userID},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	exports := []models.Export{}
+	if err := cur.All(ctx, &exports); err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+func (r *exportRepository) ClaimNext(ctx context.Context, lease time.Duration) (*models.Export, error) {
+	now := time.Now().UTC()
+
+	var e models.Export
+	err := r.col.FindOneAndUpdate(ctx, bson.M{
+		"$or": bson.A{
+			bson.M{"status": models.ExportPending},
+			bson.M{"status": models.ExportRunning, "leaseUntil": bson.M{"$lt": now}},
+		},
+	}, bson.M{
+		"$set": bson.M{
+			"status":     models.ExportRunning,
+			"leaseUntil": now.Add(lease),
+		},
+	}, options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}}).
+		SetReturnDocument(options.After),
+	).Decode(&e)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *exportRepository) MarkReady(ctx context.Context, e *models.Export) error {
+	objectID, err := primitive.ObjectIDFromHex(e.ID)
+	if err != nil {
+		return err
+	}
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{
+			"status":      models.ExportReady,
+			"fileId":      e.FileID,
+			"fileName":    e.FileName,
+			"size":        e.Size,
+			"counts":      e.Counts,
+			"completedAt": e.CompletedAt,
+			"expiresAt":   e.ExpiresAt,
+		},
+		"$unset": bson.M{"leaseUntil": ""},
+	})
+	return err
+}
+
+func (r *exportRepository) MarkFailed(ctx context.Context, id, msg string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{
+			"status":      models.ExportFailed,
+			"error":       msg,
+			"completedAt": now,
+		},
+		"$unset": bson.M{"leaseUntil": ""},
+	})
+	return err
+}
+
+func (r *exportRepository) ListExpired(ctx context.Context, now time.Time) ([]models.Export, error) {
+	cur, err := r.col.Find(ctx, bson.M{"expiresAt": bson.M{"$lte": now}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var exports []models.Export
+	if err := cur.All(ctx, &exports); err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+func (r *exportRepository) Delete(ctx context.Context, e *models.Export) error {
+	if e.FileID != "" {
+		if err := r.DeleteFile(ctx, e.FileID); err != nil {
+			return err
+		}
+	}
+	objectID, err := primitive.ObjectIDFromHex(e.ID)
+	if err != nil {
+		return err
+	}
+	_, err = r.col.DeleteOne(ctx, bson.M{"_id": objectID})
+	return err
+}
+
+func (r *exportRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	exports, err := r.ListByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	for i := range exports {
+		if err := r.Delete(ctx, &exports[i]); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(exports)), nil
+}
+
+// bucket returns a GridFS bucket bound to ctx's deadline. Buckets carry
+// their deadline as state, so one is made per operation.
+func (r *exportRepository) bucket(ctx context.Context) (*gridfs.Bucket, error) {
+	b, err := gridfs.NewBucket(r.db, options.GridFSBucket().SetName("export_files"))
+	if err != nil {
+		return nil, err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		if err := b.SetReadDeadline(dl); err != nil {
+			return nil, err
+		}
+		if err := b.SetWriteDeadline(dl); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func (r *exportRepository) CreateFile(ctx context.Context, name string) (*gridfs.UploadStream, error) {
+	b, err := r.bucket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.OpenUploadStream(name)
+}
+
+func (r *exportRepository) OpenFile(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	objectID, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.bucket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b.OpenDownloadStream(objectID)
+}
+
+func (r *exportRepository) DeleteFile(ctx context.Context, fileID string) error {
+	objectID, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return err
+	}
+	b, err := r.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	err = b.DeleteContext(ctx, objectID)
+	if err == gridfs.ErrFileNotFound {
+		return nil
+	}
+	return err
+}
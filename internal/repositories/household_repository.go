@@ -0,0 +1,296 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type HouseholdRepository interface {
+	Create(ctx context.Context, h *models.Household) error
+	FindByID(ctx context.Context, id string) (*models.Household, error)
+	FindByIDs(ctx context.Context, ids []string) ([]models.Household, error)
+	Rename(ctx context.Context, id, name string) error
+	// Delete removes the household with its members and invitations. The
+	// shared data is removed separately.
+	Delete(ctx context.Context, id string) error
+
+	AddMember(ctx context.Context, m *models.HouseholdMember) error
+	FindMember(ctx context.Context, householdID, userID string) (*models.HouseholdMember, error)
+	ListMembers(ctx context.Context, householdID string) ([]models.HouseholdMember, error)
+	ListMemberships(ctx context.Context, userID string) ([]models.HouseholdMember, error)
+	UpdateMemberRole(ctx context.Context, householdID, userID string, role models.HouseholdRole) (bool, error)
+	RemoveMember(ctx context.Context, householdID, userID string) (bool, error)
+	CountOwners(ctx context.Context, householdID string) (int64, error)
+
+	CreateInvitation(ctx context.Context, inv *models.HouseholdInvitation) error
+	FindInvitation(ctx context.Context, id string) (*models.HouseholdInvitation, error)
+	// ListPendingInvitations lists a household's open invitations.
+	ListPendingInvitations(ctx context.Context, householdID string) ([]models.HouseholdInvitation, error)
+	// ListInvitationsForEmail lists open invitations addressed to email.
+	ListInvitationsForEmail(ctx context.Context, email string) ([]models.HouseholdInvitation, error)
+	// RespondInvitation moves a pending, unexpired invitation to status. It
+	// reports false if the invitation was no longer pending.
+	RespondInvitation(ctx context.Context, id string, status models.InvitationStatus) (bool, error)
+
+	EnsureIndexes(ctx context.Context) error
+}
+
+type householdRepository struct {
+	col         *mongo.Collection
+	members     *mongo.Collection
+	invitations *mongo.Collection
+}
+
+func NewHouseholdRepository(db *mongo.Database) HouseholdRepository {
+	return &householdRepository{
+		col:         db.Collection("households"),
+		members:     db.Collection("household_members"),
+		invitations: db.Collection("household_invitations"),
+	}
+}
+
+func (r *householdRepository) EnsureIndexes(ctx context.Context) error {
+	if _, err := r.members.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "householdId", Value: 1}, {Key: "userId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+	}); err != nil {
+		return err
+	}
+	_, err := r.invitations.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "householdId", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "email", Value: 1}, {Key: "status", Value: 1}}},
+	})
+	return err
+}
+
+func (r *householdRepository) Create(ctx context.Context, h *models.Household) error {
+	now := time.Now().UTC()
+	h.CreatedAt = now
+	h.UpdatedAt = now
+
+	res, err := r.col.InsertOne(ctx, h)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		h.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *householdRepository) FindByID(ctx context.Context, id string) (*models.Household, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	var h models.Household
+	err = r.col.FindOne(ctx, bson.M{"_id": objectID}).Decode(&h)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func (r *householdRepository) FindByIDs(ctx context.Context, ids []string) ([]models.Household, error) {
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			oids = append(oids, oid)
+		}
+	}
+	households := []models.Household{}
+	if len(oids) == 0 {
+		return households, nil
+	}
+	cur, err := r.col.Find(ctx, bson.M{"_id": bson.M{"$in": oids}},
+		options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &households); err != nil {
+		return nil, err
+	}
+	return households, nil
+}
+
+func (r *householdRepository) Rename(ctx context.Context, id, name string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{"name": name, "updatedAt": time.Now().UTC()},
+	})
+	return err
+}
+
+func (r *householdRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	// Members first, so a half-finished delete never leaves members of a
+	// household that no longer exists.
+	if _, err := r.members.DeleteMany(ctx, bson.M{"householdId": id}); err != nil {
+		return err
+	}
+	if _, err := r.invitations.DeleteMany(ctx, bson.M{"householdId": id}); err != nil {
+		return err
+	}
+	_, err = r.col.DeleteOne(ctx, bson.M{"_id": objectID})
+	return err
+}
+
+func (r *householdRepository) AddMember(ctx context.Context, m *models.HouseholdMember) error {
+	m.JoinedAt = time.Now().UTC()
+
+	res, err := r.members.InsertOne(ctx, m)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		m.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *householdRepository) FindMember(ctx context.Context, householdID, userID string) (*models.HouseholdMember, error) {
+	var m models.HouseholdMember
+	err := r.members.FindOne(ctx, bson.M{"householdId": householdID, "userId": userID}).Decode(&m)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *householdRepository) ListMembers(ctx context.Context, householdID string) ([]models.HouseholdMember, error) {
+	return r.findMembers(ctx, bson.M{"householdId": householdID})
+}
+
+func (r *householdRepository) ListMemberships(ctx context.Context, userID string) ([]models.HouseholdMember, error) {
+	return r.findMembers(ctx, bson.M{"userId": userID})
+}
+
+func (r *householdRepository) findMembers(ctx context.Context, filter bson.M) ([]models.HouseholdMember, error) {
+	cur, err := r.members.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "joinedAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	members := []models.HouseholdMember{}
+	if err := cur.All(ctx, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *householdRepository) UpdateMemberRole(ctx context.Context, householdID, userID string, role models.HouseholdRole) (bool, error) {
+	res, err := r.members.UpdateOne(ctx, bson.M{"householdId": householdID, "userId": userID}, bson.M{
+		"$set": bson.M{"role": role},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *householdRepository) RemoveMember(ctx context.Context, householdID, userID string) (bool, error) {
+	res, err := r.members.DeleteOne(ctx, bson.M{"householdId": householdID, "userId": userID})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (r *householdRepository) CountOwners(ctx context.Context, householdID string) (int64, error) {
+	return r.members.CountDocuments(ctx, bson.M{"householdId": householdID, "role": models.RoleOwner})
+}
+
+func (r *householdRepository) CreateInvitation(ctx context.Context, inv *models.HouseholdInvitation) error {
+	inv.CreatedAt = time.Now().UTC()
+	inv.Status = models.InvitationPending
+
+	res, err := r.invitations.InsertOne(ctx, inv)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		inv.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *householdRepository) FindInvitation(ctx context.Context, id string) (*models.HouseholdInvitation, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	var inv models.HouseholdInvitation
+	err = r.invitations.FindOne(ctx, bson.M{"_id": objectID}).Decode(&inv)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *householdRepository) ListPendingInvitations(ctx context.Context, householdID string) ([]models.HouseholdInvitation, error) {
+	return r.findInvitations(ctx, bson.M{"householdId": householdID})
+}
+
+func (r *householdRepository) ListInvitationsForEmail(ctx context.Context, email string) ([]models.HouseholdInvitation, error) {
+	return r.findInvitations(ctx, bson.M{"email": email})
+}
+
+func (r *householdRepository) findInvitations(ctx context.Context, filter bson.M) ([]models.HouseholdInvitation, error) {
+	filter["status"] = models.InvitationPending
+	filter["expiresAt"] = bson.M{"$gt": time.Now().UTC()}
+
+	cur, err := r.invitations.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	invitations := []models.HouseholdInvitation{}
+	if err := cur.All(ctx, &invitations); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (r *householdRepository) RespondInvitation(ctx context.Context, id string, status models.InvitationStatus) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	res, err := r.invitations.UpdateOne(ctx, bson.M{
+		"_id":       objectID,
+		"status":    models.InvitationPending,
+		"expiresAt": bson.M{"$gt": now},
+	}, bson.M{
+		"$set": bson.M{"status": status, "respondedAt": now},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
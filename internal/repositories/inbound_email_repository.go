@@ -0,0 +1,183 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InboundEmailRepository holds users' inbound mailboxes and the email
+// received in them. Removing an email document does not remove its files.
+type InboundEmailRepository interface {
+	FindMailboxByUser(ctx context.Context, userID string) (*models.InboundMailbox, error)
+	FindMailboxByToken(ctx context.Context, token string) (*models.InboundMailbox, error)
+	// SetMailbox gives the user the token, replacing any they had.
+	SetMailbox(ctx context.Context, userID, token string) (*models.InboundMailbox, error)
+
+	Create(ctx context.Context, e *models.InboundEmail) error
+	FindByID(ctx context.Context, id, userID string) (*models.InboundEmail, error)
+	FindByMessageKey(ctx context.Context, userID, key string) (*models.InboundEmail, error)
+	List(ctx context.Context, userID string, limit, offset int64) ([]models.InboundEmail, int64, error)
+	SetDraft(ctx context.Context, id, draftID string) error
+	// ListAllByUser returns the documents DeleteAllByUser removes, so
+	// their files can go first.
+	ListAllByUser(ctx context.Context, userID string) ([]models.InboundEmail, error)
+
+	EnsureIndexes(ctx context.Context) error
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+}
+
+type inboundEmailRepository struct {
+	col       *mongo.Collection
+	mailboxes *mongo.Collection
+}
+
+func NewInboundEmailRepository(db *mongo.Database) InboundEmailRepository {
+	return &inboundEmailRepository{
+		col:       db.Collection("inbound_emails"),
+		mailboxes: db.Collection("inbound_mailboxes"),
+	}
+}
+
+func (r *inboundEmailRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "receivedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "messageKey", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.mailboxes.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "token", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	return err
+}
+
+func (r *inboundEmailRepository) findMailbox(ctx context.Context, filter bson.M) (*models.InboundMailbox, error) {
+	var m models.InboundMailbox
+	err := r.mailboxes.FindOne(ctx, filter).Decode(&m)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *inboundEmailRepository) FindMailboxByUser(ctx context.Context, userID string) (*models.InboundMailbox, error) {
+	return r.findMailbox(ctx, bson.M{"userId": userID})
+}
+
+func (r *inboundEmailRepository) FindMailboxByToken(ctx context.Context, token string) (*models.InboundMailbox, error) {
+	return r.findMailbox(ctx, bson.M{"token": token})
+}
+
+func (r *inboundEmailRepository) SetMailbox(ctx context.Context, userID, token string) (*models.InboundMailbox, error) {
+	var m models.InboundMailbox
+	err := r.mailboxes.FindOneAndUpdate(ctx, bson.M{"userId": userID}, bson.M{
+		"$set": bson.M{"token": token, "createdAt": time.Now().UTC()},
+	}, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&m)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *inboundEmailRepository) Create(ctx context.Context, e *models.InboundEmail) error {
+	e.ReceivedAt = time.Now().UTC()
+
+	res, err := r.col.InsertOne(ctx, e)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		e.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *inboundEmailRepository) findOne(ctx context.Context, filter bson.M) (*models.InboundEmail, error) {
+	var e models.InboundEmail
+	err := r.col.FindOne(ctx, filter).Decode(&e)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *inboundEmailRepository) FindByID(ctx context.Context, id, userID string) (*models.InboundEmail, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	return r.findOne(ctx, bson.M{"_id": objectID, "userId": userID})
+}
+
+func (r *inboundEmailRepository) FindByMessageKey(ctx context.Context, userID, key string) (*models.InboundEmail, error) {
+	return r.findOne(ctx, bson.M{"userId": userID, "messageKey": key})
+}
+
+func (r *inboundEmailRepository) List(ctx context.Context, userID string, limit, offset int64) ([]models.InboundEmail, int64, error) {
+	filter := bson.M{"userId": userID}
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "receivedAt", Value: -1}}).
+		SetLimit(limit).
+		SetSkip(offset)
+	emails, err := r.find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return emails, total, nil
+}
+
+func (r *inboundEmailRepository) ListAllByUser(ctx context.Context, userID string) ([]models.InboundEmail, error) {
+	return r.find(ctx, bson.M{"userId": userID}, options.Find())
+}
+
+func (r *inboundEmailRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.InboundEmail, error) {
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	emails := []models.InboundEmail{}
+	if err := cur.All(ctx, &emails); err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
+
+func (r *inboundEmailRepository) SetDraft(ctx context.Context, id, draftID string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{"draftId": draftID}})
+	return err
+}
+
+func (r *inboundEmailRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	if _, err := r.mailboxes.DeleteMany(ctx, bson.M{"userId": userID}); err != nil {
+		return 0, err
+	}
+	res, err := r.col.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type InsightFilter struct {
+	UserID           string
+	Kind             *models.InsightKind
+	IncludeDismissed bool
+	Limit            int64
+	Offset           int64
+}
+
+type InsightRepository interface {
+	// Upsert stores an insight keyed by (userId, fingerprint). Re-detecting an
+	// existing insight refreshes its figures but never revives a dismissal.
+	Upsert(ctx context.Context, in *models.Insight) error
+	List(ctx context.Context, f InsightFilter) ([]models.Insight, int64, error)
+	Dismiss(ctx context.Context, id, userID string) (bool, error)
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+}
+
+type insightRepository struct {
+	col *mongo.Collection
+}
+
+func NewInsightRepository(db *mongo.Database) InsightRepository {
+	return &insightRepository{
+		col: db.Collection("insights"),
+	}
+}
+
+func (r *insightRepository) Upsert(ctx context.Context, in *models.Insight) error {
+	now := time.Now().UTC()
+	in.UpdatedAt = now
+
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)
+
+	return r.col.FindOneAndUpdate(ctx, bson.M{
+		"userId":      in.UserID,
+		"fingerprint": in.Fingerprint,
+	}, bson.M{
+		"$set": bson.M{
+			"kind":          in.Kind,
+			"title":         in.Title,
+			"message":       in.Message,
+			"categoryId":    in.CategoryID,
+			"transactionId": in.TransactionID,
+			"date":          in.Date,
+			"amount":        in.Amount,
+			"baseline":      in.Baseline,
+			"score":         in.Score,
+			"updatedAt":     in.UpdatedAt,
+		},
+		"$setOnInsert": bson.M{
+			"dismissed": false,
+			"createdAt": now,
+		},
+	}, opts).Decode(in)
+}
+
+func (r *insightRepository) List(ctx context.Context, f InsightFilter) ([]models.Insight, int64, error) {
+	filter := bson.M{"userId": f.UserID}
+	if f.Kind != nil {
+		filter["kind"] = *f.Kind
+	}
+	if !f.IncludeDismissed {
+		filter["dismissed"] = false
+	}
+
+	count, err := r.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOpts := options.Find().
+		SetSkip(f.Offset).
+		SetLimit(f.Limit).
+		SetSort(bson.D{{Key: "date", Value: -1}, {Key: "createdAt", Value: -1}})
+
+	cursor, err := r.col.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var res []models.Insight
+	if err := cursor.All(ctx, &res); err != nil {
+		return nil, 0, err
+	}
+	return res, count, nil
+}
+
+func (r *insightRepository) Dismiss(ctx context.Context, id, userID string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+
+	now := time.Now().UTC()
+	res, err := r.col.UpdateOne(ctx, bson.M{
+		"_id":    objectID,
+		"userId": userID,
+	}, bson.M{
+		"$set": bson.M{
+			"dismissed":   true,
+			"dismissedAt": now,
+			"updatedAt":   now,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *insightRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LoginThrottleRepository interface {
+	// Find returns the live counters for the given keys; keys without one
+	// are omitted.
+	Find(ctx context.Context, keys []string) ([]models.LoginThrottle, error)
+	// RecordFailure increments a key's counter, starting from zero if the
+	// previous one has expired, and keeps it for window after this failure.
+	RecordFailure(ctx context.Context, key string, window time.Duration) (*models.LoginThrottle, error)
+	// Lock extends a key's lockout to until. An existing later lockout is
+	// kept.
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, keys []string) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type loginThrottleRepository struct {
+	col *mongo.Collection
+}
+
+func NewLoginThrottleRepository(db *mongo.Database) LoginThrottleRepository {
+	return &loginThrottleRepository{
+		col: db.Collection("login_throttles"),
+	}
+}
+
+func (r *loginThrottleRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+func (r *loginThrottleRepository) Find(ctx context.Context, keys []string) ([]models.LoginThrottle, error) {
+	cur, err := r.col.Find(ctx, bson.M{
+		"key":       bson.M{"$in": keys},
+		"expiresAt": bson.M{"$gt": time.Now().UTC()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var res []models.LoginThrottle
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *loginThrottleRepository) RecordFailure(ctx context.Context, key string, window time.Duration) (*models.LoginThrottle, error) {
+	now := time.Now().UTC()
+	// A pipeline update so that concurrent failures from several replicas
+	// are all counted, and an expired counter the TTL monitor has not yet
+	// removed starts over.
+	expired := bson.M{"$lte": bson.A{bson.M{"$ifNull": bson.A{"$expiresAt", now}}, now}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"failures": bson.M{"$cond": bson.A{
+				expired, 1, bson.M{"$add": bson.A{"$failures", 1}},
+			}},
+			"lockedUntil": bson.M{"$cond": bson.A{expired, "$$REMOVE", "$lockedUntil"}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"lastFailureAt": now,
+			"expiresAt":     now.Add(window),
+		}}},
+	}
+
+	var t models.LoginThrottle
+	err := r.col.FindOneAndUpdate(ctx, bson.M{"key": key}, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&t)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *loginThrottleRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	_, err := r.col.UpdateOne(ctx, bson.M{"key": key}, bson.M{
+		"$max": bson.M{"lockedUntil": until, "expiresAt": until},
+	})
+	return err
+}
+
+func (r *loginThrottleRepository) Reset(ctx context.Context, keys []string) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{"key": bson.M{"$in": keys}})
+	return err
+}
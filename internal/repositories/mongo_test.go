@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/db"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// testDatabase connects to the server in MONGO_TEST_URI and returns a fresh
+// database that is dropped when the test ends. Tests that need one are
+// skipped when the variable is not set.
+func testDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	name := fmt.Sprintf("pa_test_%d", time.Now().UnixNano())
+	client, database, err := db.Connect(ctx, uri, name)
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		database.Drop(ctx)
+		client.Disconnect(ctx)
+	})
+	return database
+}
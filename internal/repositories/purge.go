@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UserDataPurger is implemented by every repository that holds documents
+// owned by a user, so that deleting an account can cascade to all of them.
+// Implementations must be idempotent: a purge interrupted half-way is simply
+// run again.
+type UserDataPurger interface {
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+}
+
+// deleteByIDs removes the given personal documents of one user. Invalid IDs
+// are ignored.
+func deleteByIDs(ctx context.Context, col *mongo.Collection, userID string, ids []string) (int64, error) {
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			oids = append(oids, oid)
+		}
+	}
+	if len(oids) == 0 {
+		return 0, nil
+	}
+	filter := personalFilter(userID)
+	filter["_id"] = bson.M{"$in": oids}
+	res, err := col.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -6,22 +6,34 @@ import (
 
 	"github.com/ronak4195/personal-assistant/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type ReminderFilter struct {
-	UserID   string
+	Scope    Scope
 	IsActive *bool
 	From     *time.Time
 	To       *time.Time
+	TagIDs   []string
+	TagMatch TagMatch
 }
 
 type ReminderRepository interface {
 	Create(ctx context.Context, r *models.Reminder) error
-	FindByID(ctx context.Context, id, userID string) (*models.Reminder, error)
+	FindByID(ctx context.Context, id string, scope Scope) (*models.Reminder, error)
 	List(ctx context.Context, f ReminderFilter) ([]models.Reminder, error)
-	Update(ctx context.Context, r *models.Reminder) error
-	Delete(ctx context.Context, id, userID string) error
+	Update(ctx context.Context, scope Scope, r *models.Reminder) error
+	Delete(ctx context.Context, id string, scope Scope) error
+	// AddTags and RemoveTags change the tags of the given documents and
+	// report how many were matched.
+	AddTags(ctx context.Context, scope Scope, ids, tagIDs []string) (int64, error)
+	RemoveTags(ctx context.Context, scope Scope, ids, tagIDs []string) (int64, error)
+	// UntagAll removes a tag from every document in scope.
+	UntagAll(ctx context.Context, scope Scope, tagID string) error
+	DeleteByIDs(ctx context.Context, userID string, ids []string) (int64, error)
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+	DeleteAllByHousehold(ctx context.Context, householdID string) (int64, error)
 }
 
 type reminderRepository struct {
@@ -49,12 +61,16 @@ func (r *reminderRepository) Create(ctx context.Context, rem *models.Reminder) e
 	return nil
 }
 
-func (r *reminderRepository) FindByID(ctx context.Context, id, userID string) (*models.Reminder, error) {
+func (r *reminderRepository) FindByID(ctx context.Context, id string, scope Scope) (*models.Reminder, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	filter := scope.filter()
+	filter["_id"] = objectID
+
 	var rem models.Reminder
-	err := r.col.FindOne(ctx, bson.M{
-		"_id":    id,
-		"userId": userID,
-	}).Decode(&rem)
+	err = r.col.FindOne(ctx, filter).Decode(&rem)
 	if err == mongo.ErrNoDocuments {
 		return nil, nil
 	}
@@ -65,7 +81,7 @@ func (r *reminderRepository) FindByID(ctx context.Context, id, userID string) (*
 }
 
 func (r *reminderRepository) List(ctx context.Context, f ReminderFilter) ([]models.Reminder, error) {
-	filter := bson.M{"userId": f.UserID}
+	filter := f.Scope.filter()
 	if f.IsActive != nil {
 		filter["isActive"] = *f.IsActive
 	}
@@ -79,6 +95,9 @@ func (r *reminderRepository) List(ctx context.Context, f ReminderFilter) ([]mode
 		}
 		filter["dueAt"] = due
 	}
+	if len(f.TagIDs) > 0 {
+		filter["tagIds"] = tagCondition(f.TagIDs, f.TagMatch)
+	}
 
 	cursor, err := r.col.Find(ctx, filter)
 	if err != nil {
@@ -93,21 +112,62 @@ func (r *reminderRepository) List(ctx context.Context, f ReminderFilter) ([]mode
 	return res, nil
 }
 
-func (r *reminderRepository) Update(ctx context.Context, rem *models.Reminder) error {
+func (r *reminderRepository) Update(ctx context.Context, scope Scope, rem *models.Reminder) error {
+	objectID, err := primitive.ObjectIDFromHex(rem.ID)
+	if err != nil {
+		return err
+	}
 	rem.UpdatedAt = time.Now().UTC()
-	_, err := r.col.UpdateOne(ctx, bson.M{
-		"_id":    rem.ID,
-		"userId": rem.UserID,
-	}, bson.M{
-		"$set": rem,
-	})
+	filter := scope.filter()
+	filter["_id"] = objectID
+
+	// _id is immutable, so it is left out of the $set document.
+	doc := *rem
+	doc.ID = ""
+	_, err = r.col.UpdateOne(ctx, filter, tagUpdate(doc, doc.TagIDs))
 	return err
 }
 
-func (r *reminderRepository) Delete(ctx context.Context, id, userID string) error {
-	_, err := r.col.DeleteOne(ctx, bson.M{
-		"_id":    id,
-		"userId": userID,
-	})
+func (r *reminderRepository) AddTags(ctx context.Context, scope Scope, ids, tagIDs []string) (int64, error) {
+	return setTags(ctx, r.col, scope, ids, addTagsUpdate(tagIDs))
+}
+
+func (r *reminderRepository) RemoveTags(ctx context.Context, scope Scope, ids, tagIDs []string) (int64, error) {
+	return setTags(ctx, r.col, scope, ids, removeTagsUpdate(tagIDs))
+}
+
+func (r *reminderRepository) UntagAll(ctx context.Context, scope Scope, tagID string) error {
+	_, err := setTags(ctx, r.col, scope, nil, removeTagsUpdate([]string{tagID}))
 	return err
 }
+
+func (r *reminderRepository) Delete(ctx context.Context, id string, scope Scope) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	filter := scope.filter()
+	filter["_id"] = objectID
+	_, err = r.col.DeleteOne(ctx, filter)
+	return err
+}
+
+func (r *reminderRepository) DeleteByIDs(ctx context.Context, userID string, ids []string) (int64, error) {
+	return deleteByIDs(ctx, r.col, userID, ids)
+}
+
+func (r *reminderRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, personalFilter(userID))
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (r *reminderRepository) DeleteAllByHousehold(ctx context.Context, householdID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, bson.M{"householdId": householdID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,40 @@
+package repositories
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// Scope selects the workspace a query runs in: a user's personal data, or
+// the shared data of a household the user belongs to. Household scopes are
+// only built after membership has been checked (see middleware.Workspace).
+type Scope struct {
+	UserID      string
+	HouseholdID string
+}
+
+func PersonalScope(userID string) Scope {
+	return Scope{UserID: userID}
+}
+
+func HouseholdScope(userID, householdID string) Scope {
+	return Scope{UserID: userID, HouseholdID: householdID}
+}
+
+func (s Scope) IsHousehold() bool {
+	return s.HouseholdID != ""
+}
+
+// filter matches the documents visible in the scope. Personal documents are
+// those without a household, so a member's own data never leaks into a
+// household they join, nor the household's into theirs.
+func (s Scope) filter() bson.M {
+	if s.HouseholdID != "" {
+		return bson.M{"householdId": s.HouseholdID}
+	}
+	return bson.M{"userId": s.UserID, "householdId": nil}
+}
+
+// personalFilter matches one user's personal documents, for account-level
+// operations such as purging. Household documents the user created belong
+// to the household and are left alone.
+func personalFilter(userID string) bson.M {
+	return PersonalScope(userID).filter()
+}
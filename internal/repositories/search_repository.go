@@ -0,0 +1,223 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SearchFilter narrows a search. Text uses MongoDB $text syntax; when it is
+// empty the other conditions alone select the results, newest first.
+type SearchFilter struct {
+	Scope                      Scope
+	Text                       string
+	MinAmount, MaxAmount       *float64
+	MinExclusive, MaxExclusive bool
+	From                       *time.Time // inclusive
+	Until                      *time.Time // exclusive
+	// CategoryIDs matches a transaction's category or subcategory, or a
+	// reminder's category. Nil matches any.
+	CategoryIDs []string
+	// TagIDs must all be present.
+	TagIDs   []string
+	Types    []models.TransactionType
+	Currency string
+	Limit    int64
+}
+
+// Scored is a search hit with its text relevance; Score is zero when the
+// search had no text.
+type Scored[T any] struct {
+	Doc   T       `bson:",inline"`
+	Score float64 `bson:"score"`
+}
+
+type SearchRepository interface {
+	Transactions(ctx context.Context, f SearchFilter) ([]Scored[models.Transaction], int64, error)
+	Reminders(ctx context.Context, f SearchFilter) ([]Scored[models.Reminder], int64, error)
+	// Categories only applies Text and CategoryIDs.
+	Categories(ctx context.Context, f SearchFilter) ([]Scored[models.Category], int64, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type searchRepository struct {
+	transactions *mongo.Collection
+	reminders    *mongo.Collection
+	categories   *mongo.Collection
+}
+
+func NewSearchRepository(db *mongo.Database) SearchRepository {
+	return &searchRepository{
+		transactions: db.Collection("transactions"),
+		reminders:    db.Collection("reminders"),
+		categories:   db.Collection("categories"),
+	}
+}
+
+// EnsureIndexes creates the text indexes. MongoDB allows one per collection.
+func (r *searchRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := []struct {
+		col   *mongo.Collection
+		model mongo.IndexModel
+	}{
+		{r.transactions, mongo.IndexModel{
+			Keys:    bson.D{{Key: "description", Value: "text"}},
+			Options: options.Index().SetName("search_text"),
+		}},
+		{r.reminders, mongo.IndexModel{
+			Keys: bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().SetName("search_text").
+				SetWeights(bson.D{{Key: "title", Value: 3}, {Key: "description", Value: 1}}),
+		}},
+		{r.categories, mongo.IndexModel{
+			Keys:    bson.D{{Key: "name", Value: "text"}},
+			Options: options.Index().SetName("search_text"),
+		}},
+	}
+	for _, idx := range indexes {
+		if _, err := idx.col.Indexes().CreateOne(ctx, idx.model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *searchRepository) Transactions(ctx context.Context, f SearchFilter) ([]Scored[models.Transaction], int64, error) {
+	filter := recordFilter(f, "date")
+	var and []bson.M
+	if f.CategoryIDs != nil {
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"categoryId": bson.M{"$in": f.CategoryIDs}},
+			bson.M{"subcategoryId": bson.M{"$in": f.CategoryIDs}},
+		}})
+	}
+	if len(f.Types) > 0 {
+		filter["type"] = bson.M{"$in": f.Types}
+	}
+	if len(and) > 0 {
+		filter["$and"] = and
+	}
+	return findScored[models.Transaction](ctx, r.transactions, f, filter, "date")
+}
+
+func (r *searchRepository) Reminders(ctx context.Context, f SearchFilter) ([]Scored[models.Reminder], int64, error) {
+	filter := recordFilter(f, "dueAt")
+	var and []bson.M
+	if f.CategoryIDs != nil {
+		filter["categoryId"] = bson.M{"$in": f.CategoryIDs}
+	}
+	if len(f.Types) > 0 {
+		types := bson.A{bson.M{"type": bson.M{"$in": f.Types}}}
+		for _, t := range f.Types {
+			if t == models.TransactionTypeExpense {
+				// A reminder with an amount and no type is an expense.
+				types = append(types, bson.M{"type": nil, "amount": bson.M{"$ne": nil}})
+			}
+		}
+		and = append(and, bson.M{"$or": types})
+	}
+	if len(and) > 0 {
+		filter["$and"] = and
+	}
+	return findScored[models.Reminder](ctx, r.reminders, f, filter, "dueAt")
+}
+
+func (r *searchRepository) Categories(ctx context.Context, f SearchFilter) ([]Scored[models.Category], int64, error) {
+	filter := f.Scope.filter()
+	if f.Text != "" {
+		filter["$text"] = bson.M{"$search": f.Text}
+	}
+	if f.CategoryIDs != nil {
+		oids := make([]primitive.ObjectID, 0, len(f.CategoryIDs))
+		for _, id := range f.CategoryIDs {
+			if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+				oids = append(oids, oid)
+			}
+		}
+		filter["_id"] = bson.M{"$in": oids}
+	}
+	return findScored[models.Category](ctx, r.categories, f, filter, "")
+}
+
+// recordFilter builds the conditions transactions and reminders share.
+func recordFilter(f SearchFilter, dateField string) bson.M {
+	filter := f.Scope.filter()
+	if f.Text != "" {
+		filter["$text"] = bson.M{"$search": f.Text}
+	}
+	if f.MinAmount != nil || f.MaxAmount != nil {
+		amount := bson.M{}
+		if f.MinAmount != nil {
+			op := "$gte"
+			if f.MinExclusive {
+				op = "$gt"
+			}
+			amount[op] = *f.MinAmount
+		}
+		if f.MaxAmount != nil {
+			op := "$lte"
+			if f.MaxExclusive {
+				op = "$lt"
+			}
+			amount[op] = *f.MaxAmount
+		}
+		filter["amount"] = amount
+	}
+	if f.From != nil || f.Until != nil {
+		dates := bson.M{}
+		if f.From != nil {
+			dates["$gte"] = *f.From
+		}
+		if f.Until != nil {
+			dates["$lt"] = *f.Until
+		}
+		filter[dateField] = dates
+	}
+	if len(f.TagIDs) > 0 {
+		filter["tagIds"] = tagCondition(f.TagIDs, TagMatchAll)
+	}
+	if f.Currency != "" {
+		filter["currency"] = f.Currency
+	}
+	return filter
+}
+
+// findScored runs a search query, ranking by text score when there is text
+// and then by dateField, newest first. Without a dateField ties sort by name.
+func findScored[T any](ctx context.Context, col *mongo.Collection, f SearchFilter, filter bson.M, dateField string) ([]Scored[T], int64, error) {
+	total, err := col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var sort bson.D
+	opts := options.Find().SetLimit(f.Limit)
+	if f.Text != "" {
+		score := bson.M{"$meta": "textScore"}
+		opts.SetProjection(bson.M{"score": score})
+		sort = append(sort, bson.E{Key: "score", Value: score})
+	}
+	if dateField != "" {
+		sort = append(sort, bson.E{Key: dateField, Value: -1})
+	} else {
+		sort = append(sort, bson.E{Key: "name", Value: 1})
+	}
+	opts.SetSort(sort)
+
+	cur, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+
+	hits := []Scored[T]{}
+	if err := cur.All(ctx, &hits); err != nil {
+		return nil, 0, err
+	}
+	return hits, total, nil
+}
@@ -0,0 +1,188 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, s *models.Session) error
+	FindByID(ctx context.Context, id string) (*models.Session, error)
+	// FindByRefreshHash looks the hash up among current and retired refresh
+	// tokens; reused reports a match on a retired one.
+	FindByRefreshHash(ctx context.Context, hash string) (s *models.Session, reused bool, err error)
+	// Rotate swaps the current refresh hash, but only if it still equals
+	// oldHash, so two concurrent refreshes cannot both succeed.
+	Rotate(ctx context.Context, id, oldHash, newHash, ip, userAgent string) (bool, error)
+	ListActiveByUser(ctx context.Context, userID string) ([]models.Session, error)
+	Revoke(ctx context.Context, id, userID, reason string) (bool, error)
+	RevokeAllForUser(ctx context.Context, userID, exceptID, reason string) error
+	EnsureIndexes(ctx context.Context) error
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+}
+
+type sessionRepository struct {
+	col *mongo.Collection
+}
+
+func NewSessionRepository(db *mongo.Database) SessionRepository {
+	return &sessionRepository{
+		col: db.Collection("sessions"),
+	}
+}
+
+func (r *sessionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "refreshTokenHash", Value: 1}}},
+		{Keys: bson.D{{Key: "previousTokenHashes", Value: 1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+		// Expired sessions are removed by Mongo's TTL monitor.
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+func (r *sessionRepository) Create(ctx context.Context, s *models.Session) error {
+	now := time.Now().UTC()
+	s.CreatedAt = now
+	s.LastUsedAt = now
+	if s.PreviousTokenHashes == nil {
+		s.PreviousTokenHashes = []string{}
+	}
+
+	res, err := r.col.InsertOne(ctx, s)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		s.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *sessionRepository) FindByID(ctx context.Context, id string) (*models.Session, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	return r.findOne(ctx, bson.M{"_id": objectID})
+}
+
+func (r *sessionRepository) FindByRefreshHash(ctx context.Context, hash string) (*models.Session, bool, error) {
+	s, err := r.findOne(ctx, bson.M{"$or": bson.A{
+		bson.M{"refreshTokenHash": hash},
+		bson.M{"previousTokenHashes": hash},
+	}})
+	if err != nil || s == nil {
+		return nil, false, err
+	}
+	return s, s.RefreshTokenHash != hash, nil
+}
+
+func (r *sessionRepository) findOne(ctx context.Context, filter bson.M) (*models.Session, error) {
+	var s models.Session
+	err := r.col.FindOne(ctx, filter).Decode(&s)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *sessionRepository) Rotate(ctx context.Context, id, oldHash, newHash, ip, userAgent string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	res, err := r.col.UpdateOne(ctx, bson.M{
+		"_id":              objectID,
+		"refreshTokenHash": oldHash,
+		"revokedAt":        nil,
+	}, bson.M{
+		"$set": bson.M{
+			"refreshTokenHash": newHash,
+			"lastUsedAt":       time.Now().UTC(),
+			"ip":               ip,
+			"userAgent":        userAgent,
+		},
+		"$push": bson.M{"previousTokenHashes": oldHash},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+func (r *sessionRepository) ListActiveByUser(ctx context.Context, userID string) ([]models.Session, error) {
+	cursor, err := r.col.Find(ctx, bson.M{
+		"userId":    userID,
+		"revokedAt": nil,
+		"expiresAt": bson.M{"$gt": time.Now().UTC()},
+	}, options.Find().SetSort(bson.D{{Key: "lastUsedAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var res []models.Session
+	if err := cursor.All(ctx, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *sessionRepository) Revoke(ctx context.Context, id, userID, reason string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	res, err := r.col.UpdateOne(ctx, bson.M{
+		"_id":       objectID,
+		"userId":    userID,
+		"revokedAt": nil,
+	}, bson.M{
+		"$set": bson.M{
+			"revokedAt":     time.Now().UTC(),
+			"revokedReason": reason,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *sessionRepository) RevokeAllForUser(ctx context.Context, userID, exceptID, reason string) error {
+	filter := bson.M{
+		"userId":    userID,
+		"revokedAt": nil,
+	}
+	if exceptID != "" {
+		if objectID, err := primitive.ObjectIDFromHex(exceptID); err == nil {
+			filter["_id"] = bson.M{"$ne": objectID}
+		}
+	}
+	_, err := r.col.UpdateMany(ctx, filter, bson.M{
+		"$set": bson.M{
+			"revokedAt":     time.Now().UTC(),
+			"revokedReason": reason,
+		},
+	})
+	return err
+}
+
+func (r *sessionRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+)
+
+func newTestSession(t *testing.T, repo SessionRepository, hash string) *models.Session {
+	t.Helper()
+	s := &models.Session{
+		UserID:           "user-1",
+		RefreshTokenHash: hash,
+		ExpiresAt:        time.Now().UTC().Add(time.Hour),
+	}
+	if err := repo.Create(context.Background(), s); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return s
+}
+
+func TestSessionRotate(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSessionRepository(testDatabase(t))
+	s := newTestSession(t, repo, "h1")
+
+	ok, err := repo.Rotate(ctx, s.ID, "h1", "h2", "10.0.0.1", "agent")
+	if err != nil || !ok {
+		t.Fatalf("Rotate(h1 -> h2) = %v, %v; want true", ok, err)
+	}
+
+	got, reused, err := repo.FindByRefreshHash(ctx, "h2")
+	if err != nil || got == nil || reused {
+		t.Fatalf("FindByRefreshHash(h2) = %v, %v, %v; want current session", got, reused, err)
+	}
+	if got.IP != "10.0.0.1" || got.UserAgent != "agent" {
+		t.Errorf("client details not updated: %q %q", got.IP, got.UserAgent)
+	}
+
+	got, reused, err = repo.FindByRefreshHash(ctx, "h1")
+	if err != nil || got == nil || !reused {
+		t.Fatalf("FindByRefreshHash(h1) = %v, %v, %v; want reuse of a retired hash", got, reused, err)
+	}
+	if got.ID != s.ID {
+		t.Errorf("retired hash found session %s, want %s", got.ID, s.ID)
+	}
+
+	// A retired hash can never be rotated again.
+	ok, err = repo.Rotate(ctx, s.ID, "h1", "h3", "", "")
+	if err != nil || ok {
+		t.Errorf("Rotate(retired h1) = %v, %v; want false", ok, err)
+	}
+
+	got, _, err = repo.FindByRefreshHash(ctx, "unknown")
+	if err != nil || got != nil {
+		t.Errorf("FindByRefreshHash(unknown) = %v, %v; want nil", got, err)
+	}
+}
+
+func TestSessionRotateRevoked(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSessionRepository(testDatabase(t))
+	s := newTestSession(t, repo, "h1")
+
+	if ok, err := repo.Revoke(ctx, s.ID, s.UserID, "logout"); err != nil || !ok {
+		t.Fatalf("Revoke = %v, %v", ok, err)
+	}
+	ok, err := repo.Rotate(ctx, s.ID, "h1", "h2", "", "")
+	if err != nil || ok {
+		t.Errorf("Rotate on a revoked session = %v, %v; want false", ok, err)
+	}
+}
+
+// Two refreshes presenting the same token race; exactly one may win.
+func TestSessionRotateConcurrent(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSessionRepository(testDatabase(t))
+	s := newTestSession(t, repo, "h1")
+
+	const n = 8
+	var wg sync.WaitGroup
+	results := make(chan bool, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ok, err := repo.Rotate(ctx, s.ID, "h1", "new-"+string(rune('a'+i)), "", "")
+			if err != nil {
+				t.Errorf("Rotate: %v", err)
+			}
+			results <- ok
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	won := 0
+	for ok := range results {
+		if ok {
+			won++
+		}
+	}
+	if won != 1 {
+		t.Errorf("%d concurrent rotations succeeded, want 1", won)
+	}
+}
@@ -0,0 +1,330 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SplitRepository stores split expenses, settlements and the ledger entries
+// both of them produce.
+type SplitRepository interface {
+	// CreateSplit saves a split together with its ledger entries.
+	CreateSplit(ctx context.Context, s *models.ExpenseSplit, entries []models.LedgerEntry) error
+	FindSplitByTransaction(ctx context.Context, transactionID string) (*models.ExpenseSplit, error)
+	// DeleteSplit removes a split and its ledger entries.
+	DeleteSplit(ctx context.Context, id string) error
+	DeleteByTransaction(ctx context.Context, transactionID string) error
+	// ListSplits returns the splits the user created or paid for.
+	ListSplits(ctx context.Context, userID string) ([]models.ExpenseSplit, error)
+
+	// CreateSettlement saves a settlement together with its ledger entries,
+	// of which a pending settlement has none.
+	CreateSettlement(ctx context.Context, s *models.Settlement, entries []models.LedgerEntry) error
+	FindSettlement(ctx context.Context, id string) (*models.Settlement, error)
+	// ConfirmSettlement marks a pending settlement confirmed with its
+	// transactions and adds its ledger entry. It reports false if the
+	// settlement was no longer pending.
+	ConfirmSettlement(ctx context.Context, s *models.Settlement, entry models.LedgerEntry) (bool, error)
+	// DeclineSettlement marks a pending settlement declined. It reports
+	// false if the settlement was no longer pending.
+	DeclineSettlement(ctx context.Context, id string) (bool, error)
+	// ListSettlements lists the user's settlements newest first; a limit of
+	// 0 lists them all.
+	ListSettlements(ctx context.Context, userID string, limit, offset int64) ([]models.Settlement, int64, error)
+
+	// ListEntries returns the ledger entries involving any of the users.
+	ListEntries(ctx context.Context, userIDs []string) ([]models.LedgerEntry, error)
+
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type splitRepository struct {
+	col         *mongo.Collection
+	ledger      *mongo.Collection
+	settlements *mongo.Collection
+}
+
+func NewSplitRepository(db *mongo.Database) SplitRepository {
+	return &splitRepository{
+		col:         db.Collection("expense_splits"),
+		ledger:      db.Collection("ledger_entries"),
+		settlements: db.Collection("settlements"),
+	}
+}
+
+func (r *splitRepository) EnsureIndexes(ctx context.Context) error {
+	if _, err := r.col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "transactionId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return err
+	}
+	if _, err := r.ledger.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "users", Value: 1}}},
+		{Keys: bson.D{{Key: "splitId", Value: 1}}},
+	}); err != nil {
+		return err
+	}
+	_, err := r.settlements.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "from.userId", Value: 1}, {Key: "date", Value: -1}}},
+		{Keys: bson.D{{Key: "to.userId", Value: 1}, {Key: "date", Value: -1}}},
+		{Keys: bson.D{{Key: "createdBy", Value: 1}, {Key: "date", Value: -1}}},
+	})
+	return err
+}
+
+func (r *splitRepository) CreateSplit(ctx context.Context, s *models.ExpenseSplit, entries []models.LedgerEntry) error {
+	now := time.Now().UTC()
+	s.CreatedAt = now
+	s.UpdatedAt = now
+
+	res, err := r.col.InsertOne(ctx, s)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		s.ID = oid.Hex()
+	}
+
+	for i := range entries {
+		entries[i].SplitID = s.ID
+	}
+	if err := r.insertEntries(ctx, entries, now); err != nil {
+		// Without its entries the split would not count towards balances.
+		_ = r.DeleteSplit(ctx, s.ID)
+		return err
+	}
+	return nil
+}
+
+func (r *splitRepository) insertEntries(ctx context.Context, entries []models.LedgerEntry, now time.Time) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	docs := make([]any, len(entries))
+	for i := range entries {
+		entries[i].CreatedAt = now
+		docs[i] = entries[i]
+	}
+	_, err := r.ledger.InsertMany(ctx, docs)
+	return err
+}
+
+func (r *splitRepository) FindSplitByTransaction(ctx context.Context, transactionID string) (*models.ExpenseSplit, error) {
+	var s models.ExpenseSplit
+	err := r.col.FindOne(ctx, bson.M{"transactionId": transactionID}).Decode(&s)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *splitRepository) DeleteSplit(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	if _, err := r.ledger.DeleteMany(ctx, bson.M{"splitId": id}); err != nil {
+		return err
+	}
+	_, err = r.col.DeleteOne(ctx, bson.M{"_id": objectID})
+	return err
+}
+
+func (r *splitRepository) DeleteByTransaction(ctx context.Context, transactionID string) error {
+	s, err := r.FindSplitByTransaction(ctx, transactionID)
+	if err != nil || s == nil {
+		return err
+	}
+	return r.DeleteSplit(ctx, s.ID)
+}
+
+func (r *splitRepository) CreateSettlement(ctx context.Context, s *models.Settlement, entries []models.LedgerEntry) error {
+	now := time.Now().UTC()
+	s.CreatedAt = now
+
+	res, err := r.settlements.InsertOne(ctx, s)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		s.ID = oid.Hex()
+	}
+
+	for i := range entries {
+		entries[i].SettlementID = s.ID
+	}
+	if err := r.insertEntries(ctx, entries, now); err != nil {
+		_, _ = r.settlements.DeleteOne(ctx, bson.M{"_id": res.InsertedID})
+		return err
+	}
+	return nil
+}
+
+func (r *splitRepository) FindSettlement(ctx context.Context, id string) (*models.Settlement, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	var s models.Settlement
+	err = r.settlements.FindOne(ctx, bson.M{"_id": objectID}).Decode(&s)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *splitRepository) ConfirmSettlement(ctx context.Context, s *models.Settlement, entry models.LedgerEntry) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(s.ID)
+	if err != nil {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	res, err := r.settlements.UpdateOne(ctx, bson.M{
+		"_id":    objectID,
+		"status": models.SettlementPending,
+	}, bson.M{"$set": bson.M{
+		"status":         models.SettlementConfirmed,
+		"transactionIds": s.TransactionIDs,
+		"respondedAt":    now,
+	}})
+	if err != nil {
+		return false, err
+	}
+	if res.ModifiedCount == 0 {
+		return false, nil
+	}
+
+	entry.SettlementID = s.ID
+	if err := r.insertEntries(ctx, []models.LedgerEntry{entry}, now); err != nil {
+		// Leave it pending so that the confirmation can be retried.
+		_, _ = r.settlements.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+			"$set":   bson.M{"status": models.SettlementPending},
+			"$unset": bson.M{"transactionIds": "", "respondedAt": ""},
+		})
+		return false, err
+	}
+	s.Status = models.SettlementConfirmed
+	s.RespondedAt = &now
+	return true, nil
+}
+
+func (r *splitRepository) DeclineSettlement(ctx context.Context, id string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	res, err := r.settlements.UpdateOne(ctx, bson.M{
+		"_id":    objectID,
+		"status": models.SettlementPending,
+	}, bson.M{"$set": bson.M{
+		"status":      models.SettlementDeclined,
+		"respondedAt": time.Now().UTC(),
+	}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+func settlementsOf(userID string) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"createdBy": userID},
+		bson.M{"from.userId": userID},
+		bson.M{"to.userId": userID},
+	}}
+}
+
+func (r *splitRepository) ListSettlements(ctx context.Context, userID string, limit, offset int64) ([]models.Settlement, int64, error) {
+	filter := settlementsOf(userID)
+	count, err := r.settlements.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cur, err := r.settlements.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "date", Value: -1}}).
+		SetSkip(offset).
+		SetLimit(limit))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+
+	settlements := []models.Settlement{}
+	if err := cur.All(ctx, &settlements); err != nil {
+		return nil, 0, err
+	}
+	return settlements, count, nil
+}
+
+func (r *splitRepository) ListSplits(ctx context.Context, userID string) ([]models.ExpenseSplit, error) {
+	cur, err := r.col.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"createdBy": userID},
+		bson.M{"paidBy": userID},
+	}}, options.Find().SetSort(bson.D{{Key: "date", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	splits := []models.ExpenseSplit{}
+	if err := cur.All(ctx, &splits); err != nil {
+		return nil, err
+	}
+	return splits, nil
+}
+
+func (r *splitRepository) ListEntries(ctx context.Context, userIDs []string) ([]models.LedgerEntry, error) {
+	cur, err := r.ledger.Find(ctx, bson.M{"users": bson.M{"$in": userIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var entries []models.LedgerEntry
+	if err := cur.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// DeleteAllByUser removes the user's splits and settlements and every ledger
+// entry they are part of, which also clears what others owed them or were
+// owed by them.
+func (r *splitRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	var total int64
+	res, err := r.ledger.DeleteMany(ctx, bson.M{"users": userID})
+	if err != nil {
+		return total, err
+	}
+	total += res.DeletedCount
+
+	res, err = r.col.DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"createdBy": userID},
+		bson.M{"paidBy": userID},
+	}})
+	if err != nil {
+		return total, err
+	}
+	total += res.DeletedCount
+
+	res, err = r.settlements.DeleteMany(ctx, settlementsOf(userID))
+	if err != nil {
+		return total, err
+	}
+	return total + res.DeletedCount, nil
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SubscriptionDecisionRepository interface {
+	Upsert(ctx context.Context, d *models.SubscriptionDecision) error
+	ListByUser(ctx context.Context, userID string) ([]models.SubscriptionDecision, error)
+	DeleteByIDs(ctx context.Context, userID string, ids []string) (int64, error)
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+}
+
+type subscriptionDecisionRepository struct {
+	col *mongo.Collection
+}
+
+func NewSubscriptionDecisionRepository(db *mongo.Database) SubscriptionDecisionRepository {
+	return &subscriptionDecisionRepository{
+		col: db.Collection("subscription_decisions"),
+	}
+}
+
+func (r *subscriptionDecisionRepository) Upsert(ctx context.Context, d *models.SubscriptionDecision) error {
+	now := time.Now().UTC()
+	d.UpdatedAt = now
+
+	set := bson.M{
+		"status":    d.Status,
+		"updatedAt": d.UpdatedAt,
+	}
+	if d.ReminderID != nil {
+		set["reminderId"] = *d.ReminderID
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)
+
+	return r.col.FindOneAndUpdate(ctx, bson.M{
+		"userId": d.UserID,
+		"key":    d.Key,
+	}, bson.M{
+		"$set":         set,
+		"$setOnInsert": bson.M{"createdAt": now},
+	}, opts).Decode(d)
+}
+
+func (r *subscriptionDecisionRepository) ListByUser(ctx context.Context, userID string) ([]models.SubscriptionDecision, error) {
+	cursor, err := r.col.Find(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var res []models.SubscriptionDecision
+	if err := cursor.All(ctx, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *subscriptionDecisionRepository) DeleteByIDs(ctx context.Context, userID string, ids []string) (int64, error) {
+	return deleteByIDs(ctx, r.col, userID, ids)
+}
+
+func (r *subscriptionDecisionRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,233 @@
+package repositories
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TagMatch says whether a tag filter matches documents with any or with all
+// of the given tags.
+type TagMatch string
+
+const (
+	TagMatchAny TagMatch = "any"
+	TagMatchAll TagMatch = "all"
+)
+
+type TagRepository interface {
+	Create(ctx context.Context, t *models.Tag) error
+	FindByID(ctx context.Context, id string, scope Scope) (*models.Tag, error)
+	// FindByName matches the name ignoring case.
+	FindByName(ctx context.Context, scope Scope, name string) (*models.Tag, error)
+	FindByIDs(ctx context.Context, scope Scope, ids []string) ([]models.Tag, error)
+	List(ctx context.Context, scope Scope) ([]models.Tag, error)
+	Update(ctx context.Context, scope Scope, t *models.Tag) error
+	Delete(ctx context.Context, id string, scope Scope) error
+	DeleteByIDs(ctx context.Context, userID string, ids []string) (int64, error)
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+	DeleteAllByHousehold(ctx context.Context, householdID string) (int64, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type tagRepository struct {
+	col *mongo.Collection
+}
+
+func NewTagRepository(db *mongo.Database) TagRepository {
+	return &tagRepository{
+		col: db.Collection("tags"),
+	}
+}
+
+func (r *tagRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "name", Value: 1}}},
+		{Keys: bson.D{{Key: "householdId", Value: 1}, {Key: "name", Value: 1}}},
+	})
+	return err
+}
+
+func (r *tagRepository) Create(ctx context.Context, t *models.Tag) error {
+	now := time.Now().UTC()
+	t.CreatedAt = now
+	t.UpdatedAt = now
+
+	res, err := r.col.InsertOne(ctx, t)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		t.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *tagRepository) FindByID(ctx context.Context, id string, scope Scope) (*models.Tag, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	filter := scope.filter()
+	filter["_id"] = objectID
+	return r.findOne(ctx, filter)
+}
+
+func (r *tagRepository) FindByName(ctx context.Context, scope Scope, name string) (*models.Tag, error) {
+	filter := scope.filter()
+	filter["name"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(name) + "$", Options: "i"}
+	return r.findOne(ctx, filter)
+}
+
+func (r *tagRepository) findOne(ctx context.Context, filter bson.M) (*models.Tag, error) {
+	var t models.Tag
+	err := r.col.FindOne(ctx, filter).Decode(&t)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *tagRepository) FindByIDs(ctx context.Context, scope Scope, ids []string) ([]models.Tag, error) {
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			oids = append(oids, oid)
+		}
+	}
+	tags := []models.Tag{}
+	if len(oids) == 0 {
+		return tags, nil
+	}
+	filter := scope.filter()
+	filter["_id"] = bson.M{"$in": oids}
+	cur, err := r.col.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *tagRepository) List(ctx context.Context, scope Scope) ([]models.Tag, error) {
+	cur, err := r.col.Find(ctx, scope.filter(), options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	tags := []models.Tag{}
+	if err := cur.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *tagRepository) Update(ctx context.Context, scope Scope, t *models.Tag) error {
+	objectID, err := primitive.ObjectIDFromHex(t.ID)
+	if err != nil {
+		return err
+	}
+	t.UpdatedAt = time.Now().UTC()
+	filter := scope.filter()
+	filter["_id"] = objectID
+	_, err = r.col.UpdateOne(ctx, filter, bson.M{
+		"$set": bson.M{"name": t.Name, "color": t.Color, "updatedAt": t.UpdatedAt},
+	})
+	return err
+}
+
+func (r *tagRepository) Delete(ctx context.Context, id string, scope Scope) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	filter := scope.filter()
+	filter["_id"] = objectID
+	_, err = r.col.DeleteOne(ctx, filter)
+	return err
+}
+
+func (r *tagRepository) DeleteByIDs(ctx context.Context, userID string, ids []string) (int64, error) {
+	return deleteByIDs(ctx, r.col, userID, ids)
+}
+
+func (r *tagRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, personalFilter(userID))
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (r *tagRepository) DeleteAllByHousehold(ctx context.Context, householdID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, bson.M{"householdId": householdID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// tagCondition is the filter on tagIds for a list query.
+func tagCondition(tagIDs []string, match TagMatch) bson.M {
+	if match == TagMatchAll {
+		return bson.M{"$all": tagIDs}
+	}
+	return bson.M{"$in": tagIDs}
+}
+
+// setTags applies a tag update to the documents with the given IDs in scope,
+// or to every document in scope when ids is nil.
+func setTags(ctx context.Context, col *mongo.Collection, scope Scope, ids []string, update bson.M) (int64, error) {
+	filter := scope.filter()
+	if ids != nil {
+		oids := make([]primitive.ObjectID, 0, len(ids))
+		for _, id := range ids {
+			if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+				oids = append(oids, oid)
+			}
+		}
+		if len(oids) == 0 {
+			return 0, nil
+		}
+		filter["_id"] = bson.M{"$in": oids}
+	}
+	update["$set"] = bson.M{"updatedAt": time.Now().UTC()}
+	res, err := col.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.MatchedCount, nil
+}
+
+func addTagsUpdate(tagIDs []string) bson.M {
+	return bson.M{"$addToSet": bson.M{"tagIds": bson.M{"$each": tagIDs}}}
+}
+
+func removeTagsUpdate(tagIDs []string) bson.M {
+	return bson.M{"$pull": bson.M{"tagIds": bson.M{"$in": tagIDs}}}
+}
+
+// tagUpdate returns the full-document update for doc, unsetting tagIds when
+// it is empty: the field is omitted from doc and $set alone would keep the
+// old tags.
+func tagUpdate(doc any, tagIDs []string) bson.M {
+	update := bson.M{"$set": doc}
+	if len(tagIDs) == 0 {
+		update["$unset"] = bson.M{"tagIds": ""}
+	}
+	return update
+}
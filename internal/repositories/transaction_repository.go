@@ -6,17 +6,20 @@ import (
 
 	"github.com/ronak4195/personal-assistant/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type TransactionFilter struct {
-	UserID        string
+	Scope         Scope
 	Type          *models.TransactionType
 	From          *time.Time
 	To            *time.Time
 	CategoryID    *string
 	SubcategoryID *string
+	TagIDs        []string
+	TagMatch      TagMatch
 	Limit         int64
 	Offset        int64
 	SortDateAsc   bool
@@ -24,11 +27,20 @@ type TransactionFilter struct {
 
 type TransactionRepository interface {
 	Create(ctx context.Context, t *models.Transaction) error
-	FindByID(ctx context.Context, id, userID string) (*models.Transaction, error)
+	FindByID(ctx context.Context, id string, scope Scope) (*models.Transaction, error)
 	List(ctx context.Context, f TransactionFilter) ([]models.Transaction, int64, error)
-	Update(ctx context.Context, t *models.Transaction) error
-	Delete(ctx context.Context, id, userID string) error
-	ListByDateRange(ctx context.Context, userID string, from, to time.Time) ([]models.Transaction, error)
+	Update(ctx context.Context, scope Scope, t *models.Transaction) error
+	Delete(ctx context.Context, id string, scope Scope) error
+	ListByDateRange(ctx context.Context, scope Scope, from, to time.Time) ([]models.Transaction, error)
+	// AddTags and RemoveTags change the tags of the given documents and
+	// report how many were matched.
+	AddTags(ctx context.Context, scope Scope, ids, tagIDs []string) (int64, error)
+	RemoveTags(ctx context.Context, scope Scope, ids, tagIDs []string) (int64, error)
+	// UntagAll removes a tag from every document in scope.
+	UntagAll(ctx context.Context, scope Scope, tagID string) error
+	DeleteByIDs(ctx context.Context, userID string, ids []string) (int64, error)
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+	DeleteAllByHousehold(ctx context.Context, householdID string) (int64, error)
 }
 
 type transactionRepository struct {
@@ -56,12 +68,16 @@ func (r *transactionRepository) Create(ctx context.Context, t *models.Transactio
 	return nil
 }
 
-func (r *transactionRepository) FindByID(ctx context.Context, id, userID string) (*models.Transaction, error) {
+func (r *transactionRepository) FindByID(ctx context.Context, id string, scope Scope) (*models.Transaction, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	filter := scope.filter()
+	filter["_id"] = objectID
+
 	var tx models.Transaction
-	err := r.col.FindOne(ctx, bson.M{
-		"_id":    id,
-		"userId": userID,
-	}).Decode(&tx)
+	err = r.col.FindOne(ctx, filter).Decode(&tx)
 	if err == mongo.ErrNoDocuments {
 		return nil, nil
 	}
@@ -72,7 +88,7 @@ func (r *transactionRepository) FindByID(ctx context.Context, id, userID string)
 }
 
 func (r *transactionRepository) List(ctx context.Context, f TransactionFilter) ([]models.Transaction, int64, error) {
-	filter := bson.M{"userId": f.UserID}
+	filter := f.Scope.filter()
 	if f.Type != nil {
 		filter["type"] = *f.Type
 	}
@@ -92,6 +108,9 @@ func (r *transactionRepository) List(ctx context.Context, f TransactionFilter) (
 	if f.SubcategoryID != nil {
 		filter["subcategoryId"] = *f.SubcategoryID
 	}
+	if len(f.TagIDs) > 0 {
+		filter["tagIds"] = tagCondition(f.TagIDs, f.TagMatch)
+	}
 
 	count, err := r.col.CountDocuments(ctx, filter)
 	if err != nil {
@@ -121,32 +140,51 @@ func (r *transactionRepository) List(ctx context.Context, f TransactionFilter) (
 	return res, count, nil
 }
 
-func (r *transactionRepository) Update(ctx context.Context, t *models.Transaction) error {
+func (r *transactionRepository) Update(ctx context.Context, scope Scope, t *models.Transaction) error {
+	objectID, err := primitive.ObjectIDFromHex(t.ID)
+	if err != nil {
+		return err
+	}
 	t.UpdatedAt = time.Now().UTC()
-	_, err := r.col.UpdateOne(ctx, bson.M{
-		"_id":    t.ID,
-		"userId": t.UserID,
-	}, bson.M{
-		"$set": t,
-	})
+	filter := scope.filter()
+	filter["_id"] = objectID
+
+	// _id is immutable, so it is left out of the $set document.
+	doc := *t
+	doc.ID = ""
+	_, err = r.col.UpdateOne(ctx, filter, tagUpdate(doc, doc.TagIDs))
 	return err
 }
 
-func (r *transactionRepository) Delete(ctx context.Context, id, userID string) error {
-	_, err := r.col.DeleteOne(ctx, bson.M{
-		"_id":    id,
-		"userId": userID,
-	})
+func (r *transactionRepository) AddTags(ctx context.Context, scope Scope, ids, tagIDs []string) (int64, error) {
+	return setTags(ctx, r.col, scope, ids, addTagsUpdate(tagIDs))
+}
+
+func (r *transactionRepository) RemoveTags(ctx context.Context, scope Scope, ids, tagIDs []string) (int64, error) {
+	return setTags(ctx, r.col, scope, ids, removeTagsUpdate(tagIDs))
+}
+
+func (r *transactionRepository) UntagAll(ctx context.Context, scope Scope, tagID string) error {
+	_, err := setTags(ctx, r.col, scope, nil, removeTagsUpdate([]string{tagID}))
 	return err
 }
 
-func (r *transactionRepository) ListByDateRange(ctx context.Context, userID string, from, to time.Time) ([]models.Transaction, error) {
-	filter := bson.M{
-		"userId": userID,
-		"date": bson.M{
-			"$gte": from,
-			"$lte": to,
-		},
+func (r *transactionRepository) Delete(ctx context.Context, id string, scope Scope) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	filter := scope.filter()
+	filter["_id"] = objectID
+	_, err = r.col.DeleteOne(ctx, filter)
+	return err
+}
+
+func (r *transactionRepository) ListByDateRange(ctx context.Context, scope Scope, from, to time.Time) ([]models.Transaction, error) {
+	filter := scope.filter()
+	filter["date"] = bson.M{
+		"$gte": from,
+		"$lte": to,
 	}
 	cursor, err := r.col.Find(ctx, filter)
 	if err != nil {
@@ -160,3 +198,23 @@ func (r *transactionRepository) ListByDateRange(ctx context.Context, userID stri
 	}
 	return res, nil
 }
+
+func (r *transactionRepository) DeleteByIDs(ctx context.Context, userID string, ids []string) (int64, error) {
+	return deleteByIDs(ctx, r.col, userID, ids)
+}
+
+func (r *transactionRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, personalFilter(userID))
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (r *transactionRepository) DeleteAllByHousehold(ctx context.Context, householdID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, bson.M{"householdId": householdID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
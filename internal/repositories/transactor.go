@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrTransactionsUnsupported means the database is a standalone server;
+// transactions need a replica set or a sharded cluster.
+var ErrTransactionsUnsupported = errors.New("database does not support transactions")
+
+// Transactor runs work in a database transaction. Repository methods take
+// part in it when called with the context fn receives.
+type Transactor interface {
+	// WithTransaction commits if fn returns nil and aborts otherwise. fn is
+	// run once; it is not retried.
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type transactor struct {
+	client *mongo.Client
+}
+
+func NewTransactor(client *mongo.Client) Transactor {
+	return &transactor{client: client}
+}
+
+func (t *transactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := t.checkSupport(ctx); err != nil {
+		return err
+	}
+	sess, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer sess.EndSession(context.WithoutCancel(ctx))
+
+	return mongo.WithSession(ctx, sess, func(sc mongo.SessionContext) error {
+		if err := sess.StartTransaction(); err != nil {
+			return err
+		}
+		if err := fn(sc); err != nil {
+			_ = sess.AbortTransaction(context.WithoutCancel(sc))
+			return err
+		}
+		return sess.CommitTransaction(sc)
+	})
+}
+
+// checkSupport asks the server what it is, as a standalone server only
+// rejects transactions at the first write.
+func (t *transactor) checkSupport(ctx context.Context) error {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := t.client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return err
+	}
+	if hello.SetName == "" && hello.Msg != "isdbgrid" {
+		return ErrTransactionsUnsupported
+	}
+	return nil
+}
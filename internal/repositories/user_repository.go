@@ -7,13 +7,38 @@ import (
 
 	"github.com/ronak4195/personal-assistant/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
 	FindByID(ctx context.Context, id string) (*models.User, error)
+	UpdatePasswordHash(ctx context.Context, id, hash string) error
+	MarkEmailVerified(ctx context.Context, id, email string) (bool, error)
+
+	SetPendingTOTP(ctx context.Context, id, secret string) error
+	// EnableTOTP promotes the pending secret to the active one, provided it
+	// is still the given secret.
+	EnableTOTP(ctx context.Context, id, secret string, step int64, recoveryHashes []string) (bool, error)
+	DisableMFA(ctx context.Context, id string) error
+	// UseTOTPStep records step as used; it reports false if that step (or a
+	// later one) has already been accepted.
+	UseTOTPStep(ctx context.Context, id string, step int64) (bool, error)
+	// UseRecoveryCode removes a recovery code, reporting whether it existed.
+	UseRecoveryCode(ctx context.Context, id, hash string) (bool, error)
+	SetRecoveryCodes(ctx context.Context, id string, hashes []string) error
+
+	// UpdateProfile changes the name and/or email. A new email address is
+	// unverified until confirmed again.
+	UpdateProfile(ctx context.Context, id string, name, email *string) error
+	ScheduleDeletion(ctx context.Context, id string, at time.Time) error
+	// CancelDeletion clears a deletion that is not yet due.
+	CancelDeletion(ctx context.Context, id string) (bool, error)
+	ListDueForDeletion(ctx context.Context, now time.Time, limit int64) ([]models.User, error)
+	Delete(ctx context.Context, id string) error
 }
 
 type userRepository struct {
@@ -58,8 +83,13 @@ func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models
 }
 
 func (r *userRepository) FindByID(ctx context.Context, id string) (*models.User, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
 	var u models.User
-	err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&u)
+	err = r.col.FindOne(ctx, bson.M{"_id": objectID}).Decode(&u)
 	if err == mongo.ErrNoDocuments {
 		return nil, nil
 	}
@@ -68,3 +98,222 @@ func (r *userRepository) FindByID(ctx context.Context, id string) (*models.User,
 	}
 	return &u, nil
 }
+
+func (r *userRepository) UpdatePasswordHash(ctx context.Context, id, hash string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{
+			"passwordHash": hash,
+			"updatedAt":    time.Now().UTC(),
+		},
+	})
+	return err
+}
+
+// MarkEmailVerified verifies the user's email, provided it is still the
+// address the verification was sent to.
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id, email string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	res, err := r.col.UpdateOne(ctx, bson.M{
+		"_id":   objectID,
+		"email": strings.ToLower(strings.TrimSpace(email)),
+	}, bson.M{
+		"$set": bson.M{
+			"emailVerified":   true,
+			"emailVerifiedAt": now,
+			"updatedAt":       now,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *userRepository) SetPendingTOTP(ctx context.Context, id, secret string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{
+			"mfa.pendingSecret": secret,
+			"updatedAt":         time.Now().UTC(),
+		},
+	})
+	return err
+}
+
+func (r *userRepository) EnableTOTP(ctx context.Context, id, secret string, step int64, recoveryHashes []string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	res, err := r.col.UpdateOne(ctx, bson.M{
+		"_id":               objectID,
+		"mfa.pendingSecret": secret,
+	}, bson.M{
+		"$set": bson.M{
+			"mfa": models.UserMFA{
+				Enabled:            true,
+				TOTPSecret:         secret,
+				LastStep:           step,
+				RecoveryCodeHashes: recoveryHashes,
+				EnabledAt:          &now,
+			},
+			"updatedAt": now,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *userRepository) DisableMFA(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$unset": bson.M{"mfa": ""},
+		"$set":   bson.M{"updatedAt": time.Now().UTC()},
+	})
+	return err
+}
+
+func (r *userRepository) UseTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	res, err := r.col.UpdateOne(ctx, bson.M{
+		"_id":          objectID,
+		"mfa.enabled":  true,
+		"mfa.lastStep": bson.M{"$lt": step},
+	}, bson.M{
+		"$set": bson.M{"mfa.lastStep": step},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *userRepository) UseRecoveryCode(ctx context.Context, id, hash string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	res, err := r.col.UpdateOne(ctx, bson.M{
+		"_id":                    objectID,
+		"mfa.enabled":            true,
+		"mfa.recoveryCodeHashes": hash,
+	}, bson.M{
+		"$pull": bson.M{"mfa.recoveryCodeHashes": hash},
+		"$set":  bson.M{"updatedAt": time.Now().UTC()},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+func (r *userRepository) SetRecoveryCodes(ctx context.Context, id string, hashes []string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": objectID, "mfa.enabled": true}, bson.M{
+		"$set": bson.M{
+			"mfa.recoveryCodeHashes": hashes,
+			"updatedAt":              time.Now().UTC(),
+		},
+	})
+	return err
+}
+
+func (r *userRepository) UpdateProfile(ctx context.Context, id string, name, email *string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	set := bson.M{"updatedAt": time.Now().UTC()}
+	update := bson.M{"$set": set}
+	if name != nil {
+		set["name"] = *name
+	}
+	if email != nil {
+		set["email"] = strings.ToLower(strings.TrimSpace(*email))
+		set["emailVerified"] = false
+		update["$unset"] = bson.M{"emailVerifiedAt": ""}
+	}
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+func (r *userRepository) ScheduleDeletion(ctx context.Context, id string, at time.Time) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{
+			"deletionScheduledFor": at,
+			"updatedAt":            time.Now().UTC(),
+		},
+	})
+	return err
+}
+
+func (r *userRepository) CancelDeletion(ctx context.Context, id string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	res, err := r.col.UpdateOne(ctx, bson.M{
+		"_id": objectID,
+		// Once due, the purge may already be under way.
+		"deletionScheduledFor": bson.M{"$gt": time.Now().UTC()},
+	}, bson.M{
+		"$unset": bson.M{"deletionScheduledFor": ""},
+		"$set":   bson.M{"updatedAt": time.Now().UTC()},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *userRepository) ListDueForDeletion(ctx context.Context, now time.Time, limit int64) ([]models.User, error) {
+	cur, err := r.col.Find(ctx, bson.M{
+		"deletionScheduledFor": bson.M{"$lte": now},
+	}, options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var users []models.User
+	if err := cur.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *userRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.col.DeleteOne(ctx, bson.M{"_id": objectID})
+	return err
+}
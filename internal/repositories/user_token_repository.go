@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UserTokenRepository interface {
+	Create(ctx context.Context, t *models.UserToken) error
+	// Consume atomically marks an unused, unexpired token as used and
+	// returns it, or returns nil if there is no such token.
+	Consume(ctx context.Context, purpose models.UserTokenPurpose, hash string) (*models.UserToken, error)
+	// FindActive returns an unused, unexpired token without consuming it.
+	FindActive(ctx context.Context, purpose models.UserTokenPurpose, hash string) (*models.UserToken, error)
+	// RecordFailure counts a failed attempt against a token and invalidates
+	// it once maxAttempts is reached.
+	RecordFailure(ctx context.Context, id string, maxAttempts int) error
+	// InvalidateAll marks every outstanding token of a purpose as used.
+	InvalidateAll(ctx context.Context, userID string, purpose models.UserTokenPurpose) error
+	EnsureIndexes(ctx context.Context) error
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+}
+
+type userTokenRepository struct {
+	col *mongo.Collection
+}
+
+func NewUserTokenRepository(db *mongo.Database) UserTokenRepository {
+	return &userTokenRepository{
+		col: db.Collection("user_tokens"),
+	}
+}
+
+func (r *userTokenRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+func (r *userTokenRepository) Create(ctx context.Context, t *models.UserToken) error {
+	t.CreatedAt = time.Now().UTC()
+
+	res, err := r.col.InsertOne(ctx, t)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(interface{ Hex() string }); ok {
+		t.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *userTokenRepository) Consume(ctx context.Context, purpose models.UserTokenPurpose, hash string) (*models.UserToken, error) {
+	now := time.Now().UTC()
+
+	var t models.UserToken
+	err := r.col.FindOneAndUpdate(ctx, bson.M{
+		"tokenHash": hash,
+		"purpose":   purpose,
+		"usedAt":    nil,
+		"expiresAt": bson.M{"$gt": now},
+	}, bson.M{
+		"$set": bson.M{"usedAt": now},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&t)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *userTokenRepository) InvalidateAll(ctx context.Context, userID string, purpose models.UserTokenPurpose) error {
+	_, err := r.col.UpdateMany(ctx, bson.M{
+		"userId":  userID,
+		"purpose": purpose,
+		"usedAt":  nil,
+	}, bson.M{
+		"$set": bson.M{"usedAt": time.Now().UTC()},
+	})
+	return err
+}
+
+func (r *userTokenRepository) FindActive(ctx context.Context, purpose models.UserTokenPurpose, hash string) (*models.UserToken, error) {
+	var t models.UserToken
+	err := r.col.FindOne(ctx, bson.M{
+		"tokenHash": hash,
+		"purpose":   purpose,
+		"usedAt":    nil,
+		"expiresAt": bson.M{"$gt": time.Now().UTC()},
+	}).Decode(&t)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *userTokenRepository) RecordFailure(ctx context.Context, id string, maxAttempts int) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	var t models.UserToken
+	err = r.col.FindOneAndUpdate(ctx, bson.M{"_id": objectID, "usedAt": nil},
+		bson.M{"$inc": bson.M{"attempts": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&t)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if t.Attempts < maxAttempts {
+		return nil
+	}
+	_, err = r.col.UpdateOne(ctx, bson.M{"_id": objectID, "usedAt": nil}, bson.M{
+		"$set": bson.M{"usedAt": time.Now().UTC()},
+	})
+	return err
+}
+
+func (r *userTokenRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.col.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
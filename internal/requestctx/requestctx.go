@@ -0,0 +1,34 @@
+// Package requestctx carries details of the HTTP request that started an
+// operation through to the services handling it.
+package requestctx
+
+import "context"
+
+type Meta struct {
+	RequestID string
+	IP        string
+	UserAgent string
+	// APITokenID is set when the request was authenticated with a personal
+	// access token.
+	APITokenID string
+}
+
+type ctxKey struct{}
+
+func With(ctx context.Context, m Meta) context.Context {
+	return context.WithValue(ctx, ctxKey{}, m)
+}
+
+// From returns the request details stored in ctx, or the zero Meta for work
+// that did not start from a request, such as scheduled jobs.
+func From(ctx context.Context) Meta {
+	m, _ := ctx.Value(ctxKey{}).(Meta)
+	return m
+}
+
+// WithAPIToken records that the request in ctx was made with an API token.
+func WithAPIToken(ctx context.Context, tokenID string) context.Context {
+	m := From(ctx)
+	m.APITokenID = tokenID
+	return With(ctx, m)
+}
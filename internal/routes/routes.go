@@ -3,6 +3,8 @@ package routes
 import (
 	"github.com/labstack/echo/v4"
 	"github.com/ronak4195/personal-assistant/internal/handlers"
+	appmw "github.com/ronak4195/personal-assistant/internal/middleware"
+	"github.com/ronak4195/personal-assistant/internal/models"
 )
 
 type Handlers struct {
@@ -11,9 +13,40 @@ type Handlers struct {
 	TransactionHandler *handlers.TransactionHandler
 	ReportHandler      *handlers.ReportHandler
 	ReminderHandler    *handlers.ReminderHandler
+	InsightHandler     *handlers.InsightHandler
+	DigestHandler      *handlers.DigestHandler
+	APITokenHandler    *handlers.APITokenHandler
+	ExportHandler      *handlers.ExportHandler
+	ImportHandler      *handlers.ImportHandler
+	AuditHandler       *handlers.AuditHandler
+	HouseholdHandler   *handlers.HouseholdHandler
+	SplitHandler       *handlers.SplitHandler
+	TagHandler         *handlers.TagHandler
+	SearchHandler      *handlers.SearchHandler
+	AttachmentHandler  *handlers.AttachmentHandler
+	ReceiptHandler     *handlers.ReceiptHandler
+	AssistantHandler   *handlers.AssistantHandler
+	AlertHandler       *handlers.AlertHandler
+	DraftHandler       *handlers.DraftHandler
+	// BotHandler is nil when no chat bot is configured.
+	BotHandler *handlers.BotHandler
+	// InboundEmailHandler is nil when no inbound email domain is configured.
+	InboundEmailHandler *handlers.InboundEmailHandler
 }
 
-func RegisterV1Routes(e *echo.Echo, h Handlers, jwtMiddleware echo.MiddlewareFunc) {
+type Middleware struct {
+	JWT           echo.MiddlewareFunc
+	VerifiedEmail echo.MiddlewareFunc
+	// Workspace resolves X-Household-ID for routes on shareable data.
+	Workspace echo.MiddlewareFunc
+}
+
+func RegisterV1Routes(e *echo.Echo, h Handlers, mw Middleware) {
+	// Account routes accept login sessions only, never API tokens.
+	session := []echo.MiddlewareFunc{mw.JWT, appmw.SessionOnly()}
+	scope := appmw.RequireScope
+	ws := mw.Workspace
+
 	healthHandler := handlers.NewHealthHandler()
 
 	v1 := e.Group("/api/v1")
@@ -21,36 +54,188 @@ func RegisterV1Routes(e *echo.Echo, h Handlers, jwtMiddleware echo.MiddlewareFun
 	// Health
 	v1.GET("/health", healthHandler.Health)
 
+	// Export downloads are authorised by the signed link itself.
+	v1.GET("/export/:id/download", h.ExportHandler.Download)
+
+	// Chat bot updates are authorised by the webhook secret.
+	if h.BotHandler != nil {
+		v1.POST("/bot/webhook", h.BotHandler.Webhook)
+	}
+
+	// Inbound email is authorised by the webhook secret.
+	if h.InboundEmailHandler != nil {
+		v1.POST("/inbound-email/webhook", h.InboundEmailHandler.Webhook)
+	}
+
 	// Auth (public)
 	auth := v1.Group("/auth")
 	auth.POST("/signup", h.AuthHandler.Signup)
 	auth.POST("/login", h.AuthHandler.Login)
-	auth.GET("/me", h.AuthHandler.Me, jwtMiddleware)
+	auth.POST("/refresh", h.AuthHandler.Refresh)
+	auth.GET("/me", h.AuthHandler.Me, session...)
+	auth.PUT("/me", h.AuthHandler.UpdateMe, session...)
+	auth.DELETE("/me", h.AuthHandler.DeleteMe, session...)
+	auth.POST("/me/password", h.AuthHandler.ChangePassword, session...)
+	auth.POST("/me/cancel-deletion", h.AuthHandler.CancelDeletion, session...)
+	auth.POST("/logout", h.AuthHandler.Logout, session...)
+	auth.GET("/sessions", h.AuthHandler.ListSessions, session...)
+	auth.DELETE("/sessions/:id", h.AuthHandler.RevokeSession, session...)
+	auth.POST("/password/forgot", h.AuthHandler.ForgotPassword)
+	auth.POST("/password/reset", h.AuthHandler.ResetPassword)
+	auth.POST("/email/verify", h.AuthHandler.VerifyEmail)
+	auth.POST("/email/verify/resend", h.AuthHandler.ResendVerification, session...)
+
+	// Two-factor authentication
+	auth.POST("/mfa/verify", h.AuthHandler.VerifyMFA)
+	auth.GET("/mfa", h.AuthHandler.MFAStatus, session...)
+	auth.POST("/mfa/totp/setup", h.AuthHandler.SetupTOTP, session...)
+	auth.POST("/mfa/totp/confirm", h.AuthHandler.ConfirmTOTP, session...)
+	auth.POST("/mfa/disable", h.AuthHandler.DisableMFA, session...)
+	auth.POST("/mfa/recovery-codes", h.AuthHandler.RegenerateRecoveryCodes, session...)
 
 	// Protected
-	api := v1.Group("", jwtMiddleware)
+	api := v1.Group("", mw.JWT, mw.VerifiedEmail)
 
 	// Categories
-	api.POST("/categories", h.CategoryHandler.Create)
-	api.GET("/categories", h.CategoryHandler.List)
-	api.GET("/categories/:id", h.CategoryHandler.Get)
-	api.PUT("/categories/:id", h.CategoryHandler.Update)
-	api.DELETE("/categories/:id", h.CategoryHandler.Delete)
+	api.POST("/categories", h.CategoryHandler.Create, ws, scope(models.ScopeCategoriesWrite))
+	api.GET("/categories", h.CategoryHandler.List, ws, scope(models.ScopeCategoriesRead))
+	api.GET("/categories/:id", h.CategoryHandler.Get, ws, scope(models.ScopeCategoriesRead))
+	api.PUT("/categories/:id", h.CategoryHandler.Update, ws, scope(models.ScopeCategoriesWrite))
+	api.DELETE("/categories/:id", h.CategoryHandler.Delete, ws, scope(models.ScopeCategoriesWrite))
 
 	// Transactions
-	api.POST("/transactions", h.TransactionHandler.Create)
-	api.GET("/transactions", h.TransactionHandler.List)
-	api.GET("/transactions/:id", h.TransactionHandler.Get)
-	api.PUT("/transactions/:id", h.TransactionHandler.Update)
-	api.DELETE("/transactions/:id", h.TransactionHandler.Delete)
+	api.POST("/transactions", h.TransactionHandler.Create, ws, scope(models.ScopeTransactionsWrite))
+	api.GET("/transactions", h.TransactionHandler.List, ws, scope(models.ScopeTransactionsRead))
+	api.GET("/transactions/:id", h.TransactionHandler.Get, ws, scope(models.ScopeTransactionsRead))
+	api.PUT("/transactions/:id", h.TransactionHandler.Update, ws, scope(models.ScopeTransactionsWrite))
+	api.DELETE("/transactions/:id", h.TransactionHandler.Delete, ws, scope(models.ScopeTransactionsWrite))
+
+	// Attachments
+	api.POST("/transactions/:id/attachments", h.AttachmentHandler.Upload, ws, scope(models.ScopeTransactionsWrite))
+	api.GET("/transactions/:id/attachments", h.AttachmentHandler.List, ws, scope(models.ScopeTransactionsRead))
+	api.GET("/attachments/:id", h.AttachmentHandler.Download, ws, scope(models.ScopeTransactionsRead))
+	api.GET("/attachments/:id/thumbnail", h.AttachmentHandler.Thumbnail, ws, scope(models.ScopeTransactionsRead))
+	api.DELETE("/attachments/:id", h.AttachmentHandler.Delete, ws, scope(models.ScopeTransactionsWrite))
+	api.POST("/attachments/:id/extract", h.ReceiptHandler.Extract, ws, scope(models.ScopeTransactionsRead))
+
+	// Assistant. The scope depends on what the text turns out to be, so the
+	// handler checks it.
+	api.POST("/assistant/parse", h.AssistantHandler.Parse, ws)
+	api.POST("/assistant/execute", h.AssistantHandler.Execute, ws)
+
+	// Bank alerts and the drafts they create. Templates are personal, like
+	// contacts; drafts belong to the workspace the alert was sent to.
+	api.POST("/alerts/ingest", h.AlertHandler.Ingest, ws, scope(models.ScopeTransactionsWrite))
+	api.GET("/alerts/templates", h.AlertHandler.ListTemplates, scope(models.ScopeTransactionsRead))
+	api.POST("/alerts/templates", h.AlertHandler.CreateTemplate, scope(models.ScopeTransactionsWrite))
+	api.POST("/alerts/templates/test", h.AlertHandler.TestTemplates, scope(models.ScopeTransactionsRead))
+	api.PUT("/alerts/templates/:id", h.AlertHandler.UpdateTemplate, scope(models.ScopeTransactionsWrite))
+	api.DELETE("/alerts/templates/:id", h.AlertHandler.DeleteTemplate, scope(models.ScopeTransactionsWrite))
+	api.GET("/drafts", h.DraftHandler.List, ws, scope(models.ScopeTransactionsRead))
+	api.GET("/drafts/:id", h.DraftHandler.Get, ws, scope(models.ScopeTransactionsRead))
+	api.POST("/drafts/:id/approve", h.DraftHandler.Approve, ws, scope(models.ScopeTransactionsWrite))
+	api.POST("/drafts/:id/reject", h.DraftHandler.Reject, ws, scope(models.ScopeTransactionsWrite))
+
+	// Tags
+	api.POST("/tags", h.TagHandler.Create, ws, scope(models.ScopeTagsWrite))
+	api.GET("/tags", h.TagHandler.List, ws, scope(models.ScopeTagsRead))
+	api.PUT("/tags/:id", h.TagHandler.Update, ws, scope(models.ScopeTagsWrite))
+	api.DELETE("/tags/:id", h.TagHandler.Delete, ws, scope(models.ScopeTagsWrite))
+	api.POST("/tags/bulk", h.TagHandler.Bulk, ws, scope(models.ScopeTagsWrite))
+
+	// Shared expenses
+	api.PUT("/transactions/:id/split", h.SplitHandler.Split, ws, scope(models.ScopeSplitsWrite))
+	api.GET("/transactions/:id/split", h.SplitHandler.GetSplit, ws, scope(models.ScopeSplitsRead))
+	api.DELETE("/transactions/:id/split", h.SplitHandler.RemoveSplit, ws, scope(models.ScopeSplitsWrite))
+	// Linking lets another account name you in splits, so like household
+	// invitations it needs a session.
+	api.POST("/contacts/links", h.SplitHandler.RequestContactLink, appmw.SessionOnly())
+	api.GET("/contacts/links", h.SplitHandler.ListContactLinks, appmw.SessionOnly())
+	api.POST("/contacts/links/:id/accept", h.SplitHandler.AcceptContactLink, appmw.SessionOnly())
+	api.DELETE("/contacts/links/:id", h.SplitHandler.RemoveContactLink, appmw.SessionOnly())
+	api.POST("/contacts", h.SplitHandler.CreateContact, scope(models.ScopeSplitsWrite))
+	api.GET("/contacts", h.SplitHandler.ListContacts, scope(models.ScopeSplitsRead))
+	api.PUT("/contacts/:id", h.SplitHandler.UpdateContact, scope(models.ScopeSplitsWrite))
+	api.DELETE("/contacts/:id", h.SplitHandler.DeleteContact, scope(models.ScopeSplitsWrite))
+	api.POST("/settlements", h.SplitHandler.Settle, scope(models.ScopeSplitsWrite))
+	api.GET("/settlements", h.SplitHandler.ListSettlements, scope(models.ScopeSplitsRead))
+	api.POST("/settlements/:id/confirm", h.SplitHandler.ConfirmSettlement, scope(models.ScopeSplitsWrite))
+	api.POST("/settlements/:id/decline", h.SplitHandler.DeclineSettlement, scope(models.ScopeSplitsWrite))
+	api.GET("/balances", h.SplitHandler.Balances, scope(models.ScopeSplitsRead))
 
 	// Reports
-	api.GET("/reports/summary", h.ReportHandler.Summary)
+	api.GET("/reports/summary", h.ReportHandler.Summary, ws, scope(models.ScopeReportsRead))
+	api.GET("/reports/forecast", h.ReportHandler.Forecast, ws, scope(models.ScopeReportsRead))
+	api.GET("/reports/statement.pdf", h.ReportHandler.Statement, ws, scope(models.ScopeReportsRead))
 
 	// Reminders
-	api.POST("/reminders", h.ReminderHandler.Create)
-	api.GET("/reminders", h.ReminderHandler.List)
-	api.GET("/reminders/:id", h.ReminderHandler.Get)
-	api.PUT("/reminders/:id", h.ReminderHandler.Update)
-	api.DELETE("/reminders/:id", h.ReminderHandler.Delete)
+	api.POST("/reminders", h.ReminderHandler.Create, ws, scope(models.ScopeRemindersWrite))
+	api.GET("/reminders", h.ReminderHandler.List, ws, scope(models.ScopeRemindersRead))
+	api.GET("/reminders/:id", h.ReminderHandler.Get, ws, scope(models.ScopeRemindersRead))
+	api.PUT("/reminders/:id", h.ReminderHandler.Update, ws, scope(models.ScopeRemindersWrite))
+	api.DELETE("/reminders/:id", h.ReminderHandler.Delete, ws, scope(models.ScopeRemindersWrite))
+
+	// Search checks each result group's read scope itself.
+	api.GET("/search", h.SearchHandler.Search, ws)
+
+	// Insights
+	api.GET("/insights", h.InsightHandler.List, scope(models.ScopeInsightsRead))
+	api.POST("/insights/refresh", h.InsightHandler.Refresh, scope(models.ScopeInsightsWrite))
+	api.POST("/insights/:id/dismiss", h.InsightHandler.Dismiss, scope(models.ScopeInsightsWrite))
+	api.GET("/insights/subscriptions", h.InsightHandler.Subscriptions, scope(models.ScopeInsightsRead))
+	// Confirming creates a reminder.
+	api.POST("/insights/subscriptions/:key/confirm", h.InsightHandler.ConfirmSubscription, scope(models.ScopeInsightsWrite), scope(models.ScopeRemindersWrite))
+	api.POST("/insights/subscriptions/:key/dismiss", h.InsightHandler.DismissSubscription, scope(models.ScopeInsightsWrite))
+
+	// Email digests
+	api.POST("/digests", h.DigestHandler.Create, scope(models.ScopeDigestsWrite))
+	api.GET("/digests", h.DigestHandler.List, scope(models.ScopeDigestsRead))
+	api.PUT("/digests/:id", h.DigestHandler.Update, scope(models.ScopeDigestsWrite))
+	api.DELETE("/digests/:id", h.DigestHandler.Delete, scope(models.ScopeDigestsWrite))
+
+	// Personal access tokens
+	api.POST("/tokens", h.APITokenHandler.Create, appmw.SessionOnly())
+	api.GET("/tokens", h.APITokenHandler.List, appmw.SessionOnly())
+	api.DELETE("/tokens/:id", h.APITokenHandler.Revoke, appmw.SessionOnly())
+
+	// Chat bot
+	if h.BotHandler != nil {
+		api.POST("/bot/pairing", h.BotHandler.CreatePairing, appmw.SessionOnly())
+		api.GET("/bot/links", h.BotHandler.ListLinks, appmw.SessionOnly())
+		api.DELETE("/bot/links/:id", h.BotHandler.Unlink, appmw.SessionOnly())
+	}
+
+	// Inbound email. Addresses are for the account holder only; the email
+	// received can be read like transactions.
+	if h.InboundEmailHandler != nil {
+		api.GET("/inbound-email/address", h.InboundEmailHandler.Address, appmw.SessionOnly())
+		api.POST("/inbound-email/address/rotate", h.InboundEmailHandler.RotateAddress, appmw.SessionOnly())
+		api.GET("/inbound-email/messages", h.InboundEmailHandler.List, scope(models.ScopeTransactionsRead))
+		api.GET("/inbound-email/messages/:id", h.InboundEmailHandler.Get, scope(models.ScopeTransactionsRead))
+		api.GET("/inbound-email/messages/:id/raw", h.InboundEmailHandler.Raw, scope(models.ScopeTransactionsRead))
+		api.GET("/inbound-email/messages/:id/files/:index", h.InboundEmailHandler.File, scope(models.ScopeTransactionsRead))
+	}
+
+	// Data export and import
+	api.POST("/export", h.ExportHandler.Create, appmw.SessionOnly())
+	api.GET("/export", h.ExportHandler.List, appmw.SessionOnly())
+	api.GET("/export/:id", h.ExportHandler.Get, appmw.SessionOnly())
+	api.POST("/import", h.ImportHandler.Import, appmw.SessionOnly())
+
+	// Audit log
+	api.GET("/audit", h.AuditHandler.List, appmw.SessionOnly())
+
+	// Households
+	api.POST("/households", h.HouseholdHandler.Create, appmw.SessionOnly())
+	api.GET("/households", h.HouseholdHandler.List, appmw.SessionOnly())
+	api.GET("/households/invitations", h.HouseholdHandler.MyInvitations, appmw.SessionOnly())
+	api.POST("/households/invitations/:id/accept", h.HouseholdHandler.AcceptInvitation, appmw.SessionOnly())
+	api.POST("/households/invitations/:id/decline", h.HouseholdHandler.DeclineInvitation, appmw.SessionOnly())
+	api.GET("/households/:id", h.HouseholdHandler.Get, appmw.SessionOnly())
+	api.PUT("/households/:id", h.HouseholdHandler.Update, appmw.SessionOnly())
+	api.DELETE("/households/:id", h.HouseholdHandler.Delete, appmw.SessionOnly())
+	api.POST("/households/:id/invitations", h.HouseholdHandler.Invite, appmw.SessionOnly())
+	api.DELETE("/households/:id/invitations/:invitationId", h.HouseholdHandler.RevokeInvitation, appmw.SessionOnly())
+	api.PUT("/households/:id/members/:userId", h.HouseholdHandler.UpdateMember, appmw.SessionOnly())
+	api.DELETE("/households/:id/members/:userId", h.HouseholdHandler.RemoveMember, appmw.SessionOnly())
 }
@@ -0,0 +1,68 @@
+// Package scheduler runs background jobs at fixed intervals for the lifetime
+// of a context. Jobs must be safe to run concurrently on several replicas;
+// the scheduler itself provides no cross-process locking.
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+type Job func(ctx context.Context) error
+
+type entry struct {
+	name     string
+	interval time.Duration
+	job      Job
+}
+
+type Scheduler struct {
+	entries []entry
+	wg      sync.WaitGroup
+}
+
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Every registers job to run once at start-up and then every interval.
+func (s *Scheduler) Every(name string, interval time.Duration, job Job) {
+	s.entries = append(s.entries, entry{name: name, interval: interval, job: job})
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		go func(e entry) {
+			defer s.wg.Done()
+			ticker := time.NewTicker(e.interval)
+			defer ticker.Stop()
+			for {
+				s.run(ctx, e)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(e)
+	}
+}
+
+// Wait blocks until every job loop has observed the context's cancellation.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run(ctx context.Context, e entry) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("scheduler: job %s panicked: %v", e.name, r)
+		}
+	}()
+	if err := e.job(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("scheduler: job %s: %v", e.name, err)
+	}
+}
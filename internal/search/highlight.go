@@ -0,0 +1,135 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MarkOpen  = "<mark>"
+	MarkClose = "</mark>"
+)
+
+// Highlight returns text with words matching terms wrapped in <mark> tags,
+// cut down to about width runes around the first match. The rest of the
+// text is HTML-escaped, so the snippet can be rendered as is. ok is false
+// when nothing matched.
+//
+// MongoDB matches stemmed words, so a term also marks words sharing its
+// stem: "groceries" marks "grocery".
+func Highlight(text string, terms []string, width int) (snippet string, ok bool) {
+	stems := make([]string, 0, len(terms))
+	for _, t := range terms {
+		if s := stem(t); s != "" {
+			stems = append(stems, s)
+		}
+	}
+	if len(stems) == 0 {
+		return "", false
+	}
+
+	type span struct{ start, end int }
+	var marks []span
+	for start := 0; start < len(text); {
+		r, size := utf8.DecodeRuneInString(text[start:])
+		if !isWordRune(r) {
+			start += size
+			continue
+		}
+		end := start
+		for end < len(text) {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			if !isWordRune(r) {
+				break
+			}
+			end += size
+		}
+		word := strings.ToLower(text[start:end])
+		for _, s := range stems {
+			if strings.HasPrefix(word, s) {
+				marks = append(marks, span{start, end})
+				break
+			}
+		}
+		start = end
+	}
+	if len(marks) == 0 {
+		return "", false
+	}
+
+	from, to := window(text, marks[0].start, width)
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, m := range marks {
+		if m.start < from || m.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:m.start]))
+		b.WriteString(MarkOpen)
+		b.WriteString(html.EscapeString(text[m.start:m.end]))
+		b.WriteString(MarkClose)
+		pos = m.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+// window picks byte offsets of about width runes around the match at
+// offset at, starting a little before it and on word boundaries.
+func window(text string, at, width int) (int, int) {
+	if width <= 0 || utf8.RuneCountInString(text) <= width {
+		return 0, len(text)
+	}
+	from := at
+	for lead := width / 4; lead > 0 && from > 0; lead-- {
+		_, size := utf8.DecodeLastRuneInString(text[:from])
+		from -= size
+	}
+	for from > 0 && from < at {
+		r, _ := utf8.DecodeLastRuneInString(text[:from])
+		if !isWordRune(r) {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(text[from:])
+		from += size
+	}
+
+	to := from
+	for n := 0; n < width && to < len(text); n++ {
+		_, size := utf8.DecodeRuneInString(text[to:])
+		to += size
+	}
+	for end := to; end > at && end < len(text); {
+		r, size := utf8.DecodeLastRuneInString(text[:end])
+		if !isWordRune(r) {
+			to = end - size
+			break
+		}
+		end -= size
+	}
+	return from, to
+}
+
+// stem crudely strips common English endings so a term marks the same word
+// forms the text index matched.
+func stem(term string) string {
+	w := strings.ToLower(strings.TrimFunc(term, func(r rune) bool { return !isWordRune(r) }))
+	for _, suffix := range []string{"ies", "ing", "es", "ed", "s", "y"} {
+		if rest, ok := strings.CutSuffix(w, suffix); ok && utf8.RuneCountInString(rest) >= 3 {
+			return rest
+		}
+	}
+	return w
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,279 @@
+// Package search parses the search box query language and highlights
+// matches in results.
+//
+// A query is free text plus optional field qualifiers:
+//
+//	coffee "corner shop" -starbucks amount:>5 category:food before:2026-01-01
+//
+// Free words match any of them, quoted phrases must appear as written, and
+// a leading minus excludes a word. Qualifiers narrow the results:
+//
+//	amount:>100 amount:<=20 amount:10..20 amount:42
+//	before:2026-01-01 after:2025-12-01 on:2025-12-24
+//	category:food tag:reimbursable type:income currency:EUR
+//	in:transactions (or reminders, categories)
+//
+// Qualifier values may be quoted: category:"eating out". Unknown qualifiers
+// are searched as text.
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+)
+
+type Kind string
+
+const (
+	KindTransactions Kind = "transactions"
+	KindReminders    Kind = "reminders"
+	KindCategories   Kind = "categories"
+)
+
+const dateLayout = "2006-01-02"
+
+// Range bounds a number. Nil ends are open.
+type Range struct {
+	Min, Max                   *float64
+	MinExclusive, MaxExclusive bool
+}
+
+type Query struct {
+	Words    []string
+	Phrases  []string
+	Excluded []string
+
+	Amount *Range
+	// From is inclusive and Until exclusive; both are UTC midnights.
+	From, Until *time.Time
+	Categories  []string
+	Tags        []string
+	Types       []models.TransactionType
+	Currency    string
+	In          []Kind
+}
+
+// ParseError reports a malformed query.
+type ParseError struct{ Err error }
+
+func (e *ParseError) Error() string { return e.Err.Error() }
+func (e *ParseError) Unwrap() error { return e.Err }
+
+func Parse(input string) (*Query, error) {
+	q := &Query{}
+	for _, tok := range tokenize(input) {
+		if err := q.add(tok); err != nil {
+			return nil, &ParseError{err}
+		}
+	}
+	return q, nil
+}
+
+type token struct {
+	key, value string
+	quoted     bool
+	negated    bool
+}
+
+func (q *Query) add(t token) error {
+	switch t.key {
+	case "":
+	case "amount":
+		r, err := parseRange(t.value)
+		if err != nil {
+			return err
+		}
+		q.Amount = r
+		return nil
+	case "before", "after", "on":
+		d, err := time.Parse(dateLayout, t.value)
+		if err != nil {
+			return fmt.Errorf("%s: dates are written as YYYY-MM-DD", t.key)
+		}
+		next := d.AddDate(0, 0, 1)
+		switch t.key {
+		case "before":
+			q.Until = &d
+		case "after":
+			q.From = &next
+		case "on":
+			q.From, q.Until = &d, &next
+		}
+		return nil
+	case "category":
+		q.Categories = append(q.Categories, t.value)
+		return nil
+	case "tag":
+		q.Tags = append(q.Tags, t.value)
+		return nil
+	case "type":
+		typ := models.TransactionType(strings.ToLower(t.value))
+		if typ != models.TransactionTypeIncome && typ != models.TransactionTypeExpense {
+			return errors.New("type: use income or expense")
+		}
+		q.Types = append(q.Types, typ)
+		return nil
+	case "currency":
+		q.Currency = strings.ToUpper(t.value)
+		return nil
+	case "in":
+		k := Kind(strings.ToLower(t.value))
+		if k != KindTransactions && k != KindReminders && k != KindCategories {
+			return errors.New("in: use transactions, reminders or categories")
+		}
+		q.In = append(q.In, k)
+		return nil
+	default:
+		// Not a qualifier after all, e.g. "re:invoice"; search it as text.
+		t.value = t.key + ":" + t.value
+	}
+
+	switch {
+	case t.value == "":
+	case t.negated:
+		q.Excluded = append(q.Excluded, t.value)
+	case t.quoted:
+		q.Phrases = append(q.Phrases, t.value)
+	default:
+		q.Words = append(q.Words, t.value)
+	}
+	return nil
+}
+
+// tokenize splits on whitespace outside quotes and separates "key:value".
+func tokenize(s string) []token {
+	var (
+		out []token
+		cur token
+		buf strings.Builder
+		in  bool // inside quotes
+		has bool // cur has content
+	)
+	flush := func() {
+		if has {
+			cur.value = buf.String()
+			out = append(out, cur)
+		}
+		cur, has = token{}, false
+		buf.Reset()
+	}
+	for _, r := range s {
+		switch {
+		case r == '"':
+			in = !in
+			cur.quoted = true
+			has = true
+		case unicode.IsSpace(r) && !in:
+			flush()
+		case r == '-' && !in && !has:
+			cur.negated = true
+		case r == ':' && !in && cur.key == "" && buf.Len() > 0 && !cur.quoted:
+			cur.key = strings.ToLower(buf.String())
+			buf.Reset()
+		default:
+			buf.WriteRune(r)
+			has = true
+		}
+	}
+	flush()
+	return out
+}
+
+func parseRange(v string) (*Range, error) {
+	bad := errors.New("amount: use a number, >n, >=n, <n, <=n or n..m")
+	num := func(s string) (*float64, error) {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, bad
+		}
+		return &f, nil
+	}
+
+	r := &Range{}
+	var err error
+	switch {
+	case strings.Contains(v, ".."):
+		lo, hi, _ := strings.Cut(v, "..")
+		if r.Min, err = num(lo); err != nil {
+			return nil, err
+		}
+		if r.Max, err = num(hi); err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(v, ">="):
+		r.Min, err = num(v[2:])
+	case strings.HasPrefix(v, ">"):
+		r.Min, err = num(v[1:])
+		r.MinExclusive = true
+	case strings.HasPrefix(v, "<="):
+		r.Max, err = num(v[2:])
+	case strings.HasPrefix(v, "<"):
+		r.Max, err = num(v[1:])
+		r.MaxExclusive = true
+	default:
+		r.Min, err = num(v)
+		r.Max = r.Min
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// HasText reports whether the query searches for any words or phrases.
+func (q *Query) HasText() bool {
+	return len(q.Words) > 0 || len(q.Phrases) > 0
+}
+
+// HasRecordFilters reports whether the query uses qualifiers that only
+// transactions and reminders have.
+func (q *Query) HasRecordFilters() bool {
+	return q.Amount != nil || q.From != nil || q.Until != nil ||
+		len(q.Tags) > 0 || len(q.Types) > 0 || q.Currency != ""
+}
+
+func (q *Query) IsEmpty() bool {
+	return !q.HasText() && !q.HasRecordFilters() && len(q.Categories) == 0
+}
+
+// Includes reports whether results of kind k were asked for.
+func (q *Query) Includes(k Kind) bool {
+	if len(q.In) == 0 {
+		return true
+	}
+	for _, in := range q.In {
+		if in == k {
+			return true
+		}
+	}
+	return false
+}
+
+// TextSearch renders the text part in MongoDB $text syntax.
+func (q *Query) TextSearch() string {
+	parts := make([]string, 0, len(q.Words)+len(q.Phrases)+len(q.Excluded))
+	for _, p := range q.Phrases {
+		parts = append(parts, `"`+strings.ReplaceAll(p, `"`, "")+`"`)
+	}
+	parts = append(parts, q.Words...)
+	for _, w := range q.Excluded {
+		parts = append(parts, "-"+w)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Terms are the words to highlight: the free words and the words of each
+// phrase.
+func (q *Query) Terms() []string {
+	terms := append([]string(nil), q.Words...)
+	for _, p := range q.Phrases {
+		terms = append(terms, strings.Fields(p)...)
+	}
+	return terms
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/mailer"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (s *authService) UpdateProfile(ctx context.Context, userID string, name, email *string, currentPassword string) (*models.User, error) {
+	u, err := s.requireUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != nil {
+		n := strings.TrimSpace(*name)
+		if len(n) > 100 {
+			return nil, errors.New("name must be at most 100 characters")
+		}
+		name = &n
+	}
+
+	if email != nil {
+		e := strings.ToLower(strings.TrimSpace(*email))
+		if e == u.Email {
+			email = nil
+		} else {
+			if _, err := mail.ParseAddress(e); err != nil || strings.ContainsAny(e, "<> ") {
+				return nil, errors.New("invalid email")
+			}
+			// Changing the login address needs the password, not just a
+			// (possibly stolen) access token.
+			if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(currentPassword)); err != nil {
+				return nil, ErrInvalidCredentials
+			}
+			existing, err := s.users.FindByEmail(ctx, e)
+			if err != nil {
+				return nil, err
+			}
+			if existing != nil {
+				return nil, ErrEmailTaken
+			}
+			email = &e
+		}
+	}
+
+	if name == nil && email == nil {
+		return u, nil
+	}
+	if err := s.users.UpdateProfile(ctx, u.ID, name, email); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.requireUser(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if email != nil {
+		if err := s.sendVerification(ctx, updated); err != nil {
+			log.Printf("auth: send verification to user %s: %v", u.ID, err)
+		}
+		s.notify(ctx, u.Email, "Your email address was changed", fmt.Sprintf(
+			"The email address on your account was changed to %s.\n\n"+
+				"If you did not do this, reset your password immediately.\n", updated.Email))
+	}
+	return updated, nil
+}
+
+func (s *authService) ChangePassword(ctx context.Context, userID, sessionID, currentPassword, newPassword string) error {
+	u, err := s.requireUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(currentPassword)); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.users.UpdatePasswordHash(ctx, u.ID, string(hash)); err != nil {
+		return err
+	}
+	if err := s.tokens.InvalidateAll(ctx, u.ID, models.TokenPasswordReset); err != nil {
+		return err
+	}
+	// Keep the session that made the change; sign out everywhere else.
+	// Access tokens were created under the old password, so they go too.
+	if err := s.sessions.RevokeAllForUser(ctx, u.ID, sessionID, "password changed"); err != nil {
+		return err
+	}
+	revoked, err := s.apiTokens.RevokeAllForUser(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	s.audit.Record(ctx, AuditEntry{UserID: u.ID, Action: models.AuditPasswordChanged, EntityType: models.AuditEntityUser, EntityID: u.ID})
+
+	text := "The password for your account was just changed and your other devices were signed out.\n\n"
+	if revoked > 0 {
+		text += "Your personal access tokens were revoked as well; create new ones for any scripts that still need access.\n\n"
+	}
+	s.notify(ctx, u.Email, "Your password was changed", text+
+		"If you did not do this, reset your password immediately.\n")
+	return nil
+}
+
+func (s *authService) ScheduleDeletion(ctx context.Context, userID, sessionID, password, code string) (time.Time, error) {
+	u, err := s.requireUser(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+		return time.Time{}, ErrInvalidCredentials
+	}
+	if u.MFA.Enabled {
+		ok, err := s.checkSecondFactor(ctx, u, code)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !ok {
+			return time.Time{}, ErrInvalidMFACode
+		}
+	}
+
+	at := time.Now().UTC().Add(s.graceTTL)
+	if u.DeletionScheduledFor != nil {
+		// Asking twice does not extend the grace period.
+		at = *u.DeletionScheduledFor
+	}
+	if err := s.users.ScheduleDeletion(ctx, u.ID, at); err != nil {
+		return time.Time{}, err
+	}
+	if err := s.sessions.RevokeAllForUser(ctx, u.ID, sessionID, "account deletion requested"); err != nil {
+		return time.Time{}, err
+	}
+	if _, err := s.apiTokens.RevokeAllForUser(ctx, u.ID); err != nil {
+		return time.Time{}, err
+	}
+
+	s.notify(ctx, u.Email, "Your account is scheduled for deletion", fmt.Sprintf(
+		"Your account and all of its data will be permanently deleted on %s.\n\n"+
+			"Changed your mind? Sign in before then and cancel the deletion.\n",
+		at.Format("2 January 2006 15:04 MST")))
+	return at, nil
+}
+
+func (s *authService) CancelDeletion(ctx context.Context, userID string) error {
+	ok, err := s.users.CancelDeletion(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNoDeletionScheduled
+	}
+	return nil
+}
+
+// notify sends a best-effort security notice.
+func (s *authService) notify(ctx context.Context, to, subject, text string) {
+	if err := s.mailer.Send(ctx, mailer.Message{To: to, Subject: subject, Text: text}); err != nil {
+		log.Printf("auth: send %q to %s: %v", subject, to, err)
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+const purgeBatchSize = 20
+
+// AccountPurgeService permanently removes accounts whose deletion grace
+// period has passed. It is run by the scheduler; each run picks up accounts
+// a previous, interrupted run left behind, because the user document is
+// only deleted after all of its data.
+type AccountPurgeService interface {
+	PurgeDue(ctx context.Context) error
+}
+
+type accountPurgeService struct {
+	users   repositories.UserRepository
+	purgers []repositories.UserDataPurger
+	now     func() time.Time
+}
+
+func NewAccountPurgeService(users repositories.UserRepository, purgers ...repositories.UserDataPurger) AccountPurgeService {
+	return &accountPurgeService{
+		users:   users,
+		purgers: purgers,
+		now:     time.Now,
+	}
+}
+
+func (s *accountPurgeService) PurgeDue(ctx context.Context) error {
+	due, err := s.users.ListDueForDeletion(ctx, s.now().UTC(), purgeBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, u := range due {
+		if err := s.purge(ctx, u.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *accountPurgeService) purge(ctx context.Context, userID string) error {
+	var total int64
+	for _, p := range s.purgers {
+		n, err := p.DeleteAllByUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		total += n
+	}
+	if err := s.users.Delete(ctx, userID); err != nil {
+		return err
+	}
+	log.Printf("account purge: deleted user %s and %d documents", userID, total)
+	return nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newAccountTestService(t *testing.T) (*authService, *models.User, *memAPITokens) {
+	t.Helper()
+	svc, u := newTestAuthService(newMemSessions())
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.PasswordHash = string(hash)
+
+	tokens := svc.apiTokens.(*memAPITokens)
+	for _, owner := range []string{u.ID, u.ID, "someone-else"} {
+		if err := tokens.Create(context.Background(), &models.APIToken{UserID: owner}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return svc, u, tokens
+}
+
+func liveTokens(tokens *memAPITokens, userID string) int {
+	n := 0
+	for _, t := range tokens.byID {
+		if t.UserID == userID && t.RevokedAt == nil {
+			n++
+		}
+	}
+	return n
+}
+
+func TestChangePasswordRevokesAPITokens(t *testing.T) {
+	svc, u, tokens := newAccountTestService(t)
+	if err := svc.ChangePassword(context.Background(), u.ID, "", "old-password", "new-password"); err != nil {
+		t.Fatal(err)
+	}
+	if n := liveTokens(tokens, u.ID); n != 0 {
+		t.Errorf("%d tokens still live after a password change", n)
+	}
+	if n := liveTokens(tokens, "someone-else"); n != 1 {
+		t.Errorf("another user's tokens were revoked")
+	}
+	if sent := *svc.mailer.(*sentMail); len(sent) != 1 {
+		t.Errorf("sent %d notices, want 1", len(sent))
+	}
+}
+
+func TestScheduleDeletionRevokesAPITokens(t *testing.T) {
+	svc, u, tokens := newAccountTestService(t)
+	if _, err := svc.ScheduleDeletion(context.Background(), u.ID, "", "old-password", ""); err != nil {
+		t.Fatal(err)
+	}
+	if n := liveTokens(tokens, u.ID); n != 0 {
+		t.Errorf("%d tokens still live after deletion was scheduled", n)
+	}
+}
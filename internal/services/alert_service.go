@@ -0,0 +1,392 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/bankalert"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+const (
+	maxAlertTemplates     = 50
+	maxAlertPatternLength = 1000
+)
+
+var (
+	ErrAlertTemplateNotFound  = errors.New("alert template not found")
+	ErrTooManyAlertTemplates  = fmt.Errorf("at most %d alert templates are allowed", maxAlertTemplates)
+	ErrAlertUnmatched         = errors.New("the message did not match any alert template")
+	ErrAlertTemplateIsBuiltIn = errors.New("built-in templates cannot be changed")
+)
+
+// AlertTemplateInput holds the fields of a template to create or change.
+// On update, nil fields are left as they are.
+type AlertTemplateInput struct {
+	Name       *string                 `json:"name"`
+	Bank       *string                 `json:"bank"`
+	Pattern    *string                 `json:"pattern"`
+	DateLayout *string                 `json:"dateLayout"`
+	Type       *models.TransactionType `json:"type"`
+	Currency   *string                 `json:"currency"`
+}
+
+type AlertIngestOptions struct {
+	// AutoCreate records a transaction straight away when the message says
+	// everything needed and does not look like one already recorded.
+	// Otherwise the alert is kept as a draft to approve.
+	AutoCreate bool
+	// Currency is used when neither the message nor the template gives one.
+	Currency string
+	// Location is the time zone of dates in the message; UTC when nil.
+	Location *time.Location
+}
+
+// Ingest results.
+const (
+	AlertCreated   = "created"
+	AlertDrafted   = "draft"
+	AlertDuplicate = "duplicate"
+)
+
+type AlertIngestResult struct {
+	Status      string                   `json:"status"`
+	Alert       *bankalert.Alert         `json:"alert"`
+	Draft       *models.TransactionDraft `json:"draft,omitempty"`
+	Transaction *models.Transaction      `json:"transaction,omitempty"`
+	// DuplicateOf is the earlier draft for the same message.
+	DuplicateOf *models.TransactionDraft `json:"duplicateOf,omitempty"`
+}
+
+type AlertTestResult struct {
+	Matched bool             `json:"matched"`
+	Alert   *bankalert.Alert `json:"alert,omitempty"`
+}
+
+type AlertService interface {
+	// ListTemplates returns the user's templates followed by the built-in
+	// ones, in the order messages are matched against them.
+	ListTemplates(ctx context.Context, userID string) ([]bankalert.Template, error)
+	CreateTemplate(ctx context.Context, userID string, in AlertTemplateInput) (*bankalert.Template, error)
+	UpdateTemplate(ctx context.Context, userID, id string, in AlertTemplateInput) (*bankalert.Template, error)
+	DeleteTemplate(ctx context.Context, userID, id string) error
+	// TestTemplate parses text without saving anything. With a template it
+	// tries that one alone; otherwise every template the user has.
+	TestTemplate(ctx context.Context, userID, text string, t *AlertTemplateInput, loc *time.Location) (*AlertTestResult, error)
+	// Ingest reads a forwarded alert message and records it as a draft or
+	// a transaction, unless it has been seen before.
+	Ingest(ctx context.Context, scope repositories.Scope, text string, opts AlertIngestOptions) (*AlertIngestResult, error)
+}
+
+type alertService struct {
+	templates    repositories.AlertTemplateRepository
+	drafts       repositories.DraftRepository
+	transactions TransactionService
+	now          func() time.Time
+}
+
+func NewAlertService(templates repositories.AlertTemplateRepository, drafts repositories.DraftRepository, transactions TransactionService) AlertService {
+	return &alertService{
+		templates:    templates,
+		drafts:       drafts,
+		transactions: transactions,
+		now:          time.Now,
+	}
+}
+
+func alertTemplate(t models.AlertTemplate) bankalert.Template {
+	return bankalert.Template{
+		ID:         t.ID,
+		Name:       t.Name,
+		Bank:       t.Bank,
+		Pattern:    t.Pattern,
+		DateLayout: t.DateLayout,
+		Type:       t.Type,
+		Currency:   t.Currency,
+	}
+}
+
+func (s *alertService) ListTemplates(ctx context.Context, userID string) ([]bankalert.Template, error) {
+	own, err := s.templates.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]bankalert.Template, 0, len(own)+len(bankalert.BuiltInMatchers))
+	for _, t := range own {
+		res = append(res, alertTemplate(t))
+	}
+	for _, m := range bankalert.BuiltInMatchers {
+		res = append(res, m.Template)
+	}
+	return res, nil
+}
+
+// matchers compiles the user's templates ahead of the built-in ones. A
+// stored template that no longer compiles is skipped rather than failing
+// every message.
+func (s *alertService) matchers(ctx context.Context, userID string) ([]*bankalert.Matcher, error) {
+	own, err := s.templates.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*bankalert.Matcher, 0, len(own)+len(bankalert.BuiltInMatchers))
+	for _, t := range own {
+		m, err := bankalert.Compile(alertTemplate(t))
+		if err != nil {
+			log.Printf("alerts: template %s: %v", t.ID, err)
+			continue
+		}
+		res = append(res, m)
+	}
+	return append(res, bankalert.BuiltInMatchers...), nil
+}
+
+// apply copies the set fields of in to t and checks the result.
+func (in AlertTemplateInput) apply(t *models.AlertTemplate) error {
+	if in.Name != nil {
+		t.Name = strings.TrimSpace(*in.Name)
+	}
+	if in.Bank != nil {
+		t.Bank = strings.TrimSpace(*in.Bank)
+	}
+	if in.Pattern != nil {
+		t.Pattern = *in.Pattern
+	}
+	if in.DateLayout != nil {
+		t.DateLayout = strings.TrimSpace(*in.DateLayout)
+	}
+	if in.Type != nil {
+		t.Type = *in.Type
+	}
+	if in.Currency != nil {
+		t.Currency = strings.ToUpper(strings.TrimSpace(*in.Currency))
+	}
+
+	switch {
+	case t.Name == "":
+		return errors.New("name is required")
+	case len(t.Name) > 100 || len(t.Bank) > 100:
+		return errors.New("name and bank must be at most 100 characters")
+	case strings.TrimSpace(t.Pattern) == "":
+		return errors.New("pattern is required")
+	case len(t.Pattern) > maxAlertPatternLength:
+		return fmt.Errorf("pattern must be at most %d characters", maxAlertPatternLength)
+	case len(t.DateLayout) > 50:
+		return errors.New("dateLayout must be at most 50 characters")
+	case t.Currency != "" && len(t.Currency) != 3:
+		return errors.New("currency must be a 3-letter code")
+	}
+	_, err := bankalert.Compile(alertTemplate(*t))
+	return err
+}
+
+func (s *alertService) CreateTemplate(ctx context.Context, userID string, in AlertTemplateInput) (*bankalert.Template, error) {
+	t := &models.AlertTemplate{UserID: userID}
+	if err := in.apply(t); err != nil {
+		return nil, err
+	}
+	n, err := s.templates.CountByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if n >= maxAlertTemplates {
+		return nil, ErrTooManyAlertTemplates
+	}
+	if err := s.templates.Create(ctx, t); err != nil {
+		return nil, err
+	}
+	res := alertTemplate(*t)
+	return &res, nil
+}
+
+func (s *alertService) UpdateTemplate(ctx context.Context, userID, id string, in AlertTemplateInput) (*bankalert.Template, error) {
+	if strings.HasPrefix(id, "builtin-") {
+		return nil, ErrAlertTemplateIsBuiltIn
+	}
+	t, err := s.templates.FindByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrAlertTemplateNotFound
+	}
+	if err := in.apply(t); err != nil {
+		return nil, err
+	}
+	if err := s.templates.Update(ctx, t); err != nil {
+		return nil, err
+	}
+	res := alertTemplate(*t)
+	return &res, nil
+}
+
+func (s *alertService) DeleteTemplate(ctx context.Context, userID, id string) error {
+	if strings.HasPrefix(id, "builtin-") {
+		return ErrAlertTemplateIsBuiltIn
+	}
+	deleted, err := s.templates.Delete(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAlertTemplateNotFound
+	}
+	return nil
+}
+
+func (s *alertService) TestTemplate(ctx context.Context, userID, text string, in *AlertTemplateInput, loc *time.Location) (*AlertTestResult, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	var matchers []*bankalert.Matcher
+	if in != nil {
+		t := &models.AlertTemplate{Name: "test"}
+		if err := in.apply(t); err != nil {
+			return nil, err
+		}
+		m, err := bankalert.Compile(alertTemplate(*t))
+		if err != nil {
+			return nil, err
+		}
+		matchers = []*bankalert.Matcher{m}
+	} else {
+		var err error
+		if matchers, err = s.matchers(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	a, ok := bankalert.Parse(text, matchers, loc)
+	return &AlertTestResult{Matched: ok, Alert: a}, nil
+}
+
+// alertTextHash identifies a message regardless of spacing and case, which
+// forwarding apps do not always keep.
+func alertTextHash(text string) string {
+	return hashToken(strings.ToLower(strings.Join(strings.Fields(text), " ")))
+}
+
+func (s *alertService) Ingest(ctx context.Context, scope repositories.Scope, text string, opts AlertIngestOptions) (*AlertIngestResult, error) {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	matchers, err := s.matchers(ctx, scope.UserID)
+	if err != nil {
+		return nil, err
+	}
+	a, ok := bankalert.Parse(text, matchers, loc)
+	if !ok {
+		return nil, ErrAlertUnmatched
+	}
+	res := &AlertIngestResult{Alert: a}
+
+	hash := alertTextHash(text)
+	dup, err := s.drafts.FindDuplicate(ctx, scope, hash, a.Reference)
+	if err != nil {
+		return nil, err
+	}
+	if dup != nil {
+		res.Status = AlertDuplicate
+		res.DuplicateOf = dup
+		return res, nil
+	}
+
+	d := &models.TransactionDraft{
+		UserID:      scope.UserID,
+		HouseholdID: scope.HouseholdID,
+		Source:      models.DraftSourceAlert,
+		Status:      models.DraftPending,
+		Type:        a.Type,
+		Amount:      a.Amount,
+		Currency:    a.Currency,
+		Account:     a.Account,
+		Reference:   a.Reference,
+		RawText:     text,
+		TextHash:    hash,
+		Notes:       append([]string(nil), a.Warnings...),
+	}
+	if a.Merchant != "" {
+		merchant := a.Merchant
+		d.Description = &merchant
+	}
+	if d.Currency == "" && opts.Currency != "" {
+		d.Currency = opts.Currency
+	}
+	if a.Date != nil {
+		d.Date = a.Date.UTC()
+	} else {
+		d.Date = s.now().UTC()
+		d.Notes = append(d.Notes, "the message has no date; the time it arrived was used")
+	}
+	if d.Type == "" {
+		d.Notes = append(d.Notes, "the message does not say whether money went out or came in")
+	}
+	if d.Currency == "" {
+		d.Notes = append(d.Notes, "the message has no currency")
+	}
+
+	if d.Type != "" {
+		match, err := findSimilarTransaction(ctx, s.transactions, s.drafts, scope, d)
+		if err != nil {
+			return nil, err
+		}
+		if match != nil {
+			d.PossibleDuplicateOf = match.ID
+			d.Notes = append(d.Notes, "a transaction with the same amount is already recorded around this date")
+		}
+	}
+
+	// The draft is saved before any transaction: its text hash is unique in
+	// the scope, so when the same alert arrives twice at once only one of
+	// them gets past this point.
+	created, err := s.drafts.Create(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		dup, err := s.drafts.FindDuplicate(ctx, scope, hash, a.Reference)
+		if err != nil {
+			return nil, err
+		}
+		res.Status = AlertDuplicate
+		res.DuplicateOf = dup
+		return res, nil
+	}
+	res.Draft = d
+	res.Status = AlertDrafted
+
+	if opts.AutoCreate && d.Type != "" && d.Currency != "" && d.PossibleDuplicateOf == "" {
+		// If this fails the draft stays pending and can be approved by hand.
+		tx, err := s.transactions.Create(ctx, scope, &models.Transaction{
+			Type:        d.Type,
+			Amount:      d.Amount,
+			Currency:    d.Currency,
+			Description: d.Description,
+			Date:        d.Date,
+		})
+		if err != nil {
+			return nil, err
+		}
+		ok, err := s.drafts.Resolve(ctx, scope, d.ID, models.DraftApproved, tx.ID)
+		if err == nil && !ok {
+			err = ErrDraftResolved
+		}
+		if err != nil {
+			if derr := s.transactions.Delete(ctx, scope, tx.ID); derr != nil {
+				log.Printf("alerts: undo transaction %s for draft %s: %v", tx.ID, d.ID, derr)
+			}
+			return nil, err
+		}
+		d.Status = models.DraftApproved
+		d.TransactionID = tx.ID
+		res.Transaction = tx
+		res.Status = AlertCreated
+	}
+	return res, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+type memAlertTemplates struct {
+	repositories.AlertTemplateRepository
+}
+
+func (memAlertTemplates) ListByUser(context.Context, string) ([]models.AlertTemplate, error) {
+	return nil, nil
+}
+
+// memDrafts enforces the unique text hash like the Mongo index does.
+// blind makes FindDuplicate miss, as when two deliveries check at the same
+// time before either is saved.
+type memDrafts struct {
+	repositories.DraftRepository
+	byHash map[string]*models.TransactionDraft
+	blind  bool
+}
+
+func (m *memDrafts) Create(_ context.Context, d *models.TransactionDraft) (bool, error) {
+	if _, ok := m.byHash[d.TextHash]; ok {
+		return false, nil
+	}
+	d.ID = "draft-" + d.TextHash[:8]
+	m.byHash[d.TextHash] = d
+	return true, nil
+}
+
+func (m *memDrafts) FindDuplicate(_ context.Context, _ repositories.Scope, textHash, _ string) (*models.TransactionDraft, error) {
+	if m.blind {
+		return nil, nil
+	}
+	return m.byHash[textHash], nil
+}
+
+func (m *memDrafts) FindByTransaction(context.Context, repositories.Scope, string) (*models.TransactionDraft, error) {
+	return nil, nil
+}
+
+func (m *memDrafts) Resolve(_ context.Context, _ repositories.Scope, id string, status models.DraftStatus, transactionID string) (bool, error) {
+	for _, d := range m.byHash {
+		if d.ID == id && d.Status == models.DraftPending {
+			d.Status = status
+			d.TransactionID = transactionID
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memTransactions) List(context.Context, repositories.TransactionFilter) ([]models.Transaction, int64, error) {
+	return nil, 0, nil
+}
+
+const testAlert = "Rs 450.00 spent on HDFC Bank Card x1234 at SWIGGY on 2026-10-01"
+
+func TestIngestConcurrentDuplicate(t *testing.T) {
+	ctx := context.Background()
+	drafts := &memDrafts{byHash: map[string]*models.TransactionDraft{}}
+	transactions := &memTransactions{created: map[string]*models.Transaction{}}
+	svc := NewAlertService(memAlertTemplates{}, drafts, transactions)
+	scope := repositories.PersonalScope("user-1")
+	opts := AlertIngestOptions{AutoCreate: true}
+
+	first, err := svc.Ingest(ctx, scope, testAlert, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Status != AlertCreated {
+		t.Fatalf("first ingest: status = %s, want %s", first.Status, AlertCreated)
+	}
+
+	// The second delivery does not see the first in its duplicate check,
+	// but saving the draft still fails, before any transaction is made.
+	drafts.blind = true
+	second, err := svc.Ingest(ctx, scope, "  "+testAlert+"\n", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Status != AlertDuplicate {
+		t.Errorf("second ingest: status = %s, want %s", second.Status, AlertDuplicate)
+	}
+	if len(transactions.created) != 1 {
+		t.Errorf("%d transactions created, want 1", len(transactions.created))
+	}
+}
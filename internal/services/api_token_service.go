@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+// lastUsedResolution bounds how often a token's last-used time is written.
+const lastUsedResolution = time.Minute
+
+var ErrAPITokenNotFound = errors.New("api token not found")
+
+type APITokenService interface {
+	// Create stores a new token and returns it with the plaintext token,
+	// which is not retrievable afterwards.
+	Create(ctx context.Context, userID, name string, scopes []string, expiresAt *time.Time) (*models.APIToken, string, error)
+	List(ctx context.Context, userID string) ([]models.APIToken, error)
+	Revoke(ctx context.Context, userID, id string) error
+	// Authenticate resolves a presented token, returning nil if it is
+	// unknown, revoked or expired, or its account is gone or being deleted.
+	Authenticate(ctx context.Context, token, ip string) (*models.APIToken, error)
+}
+
+type apiTokenService struct {
+	repo  repositories.APITokenRepository
+	users repositories.UserRepository
+	audit AuditService
+}
+
+func NewAPITokenService(repo repositories.APITokenRepository, users repositories.UserRepository, audit AuditService) APITokenService {
+	return &apiTokenService{repo: repo, users: users, audit: audit}
+}
+
+func (s *apiTokenService) Create(ctx context.Context, userID, name string, scopes []string, expiresAt *time.Time) (*models.APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return nil, "", errors.New("name is required (max 100 characters)")
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if expiresAt != nil {
+		if !expiresAt.After(time.Now()) {
+			return nil, "", errors.New("expiresAt must be in the future")
+		}
+		utc := expiresAt.UTC()
+		expiresAt = &utc
+	}
+
+	token, hash, err := newOpaqueToken(models.APITokenPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+	t := &models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    token[:len(models.APITokenPrefix)+6],
+		TokenHash: hash,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.Create(ctx, t); err != nil {
+		return nil, "", err
+	}
+	s.audit.Record(ctx, AuditEntry{
+		UserID:     userID,
+		Action:     models.AuditAPITokenCreated,
+		EntityType: models.AuditEntityAPIToken,
+		EntityID:   t.ID,
+		After:      t,
+	})
+	return t, token, nil
+}
+
+func (s *apiTokenService) List(ctx context.Context, userID string) ([]models.APIToken, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+func (s *apiTokenService) Revoke(ctx context.Context, userID, id string) error {
+	found, err := s.repo.Revoke(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+func (s *apiTokenService) Authenticate(ctx context.Context, token, ip string) (*models.APIToken, error) {
+	if !strings.HasPrefix(token, models.APITokenPrefix) {
+		return nil, nil
+	}
+	t, err := s.repo.FindByHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || t.RevokedAt != nil || (t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt)) {
+		return nil, nil
+	}
+	u, err := s.users.FindByID(ctx, t.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.DeletionScheduledFor != nil {
+		return nil, nil
+	}
+	if err := s.repo.TouchLastUsed(ctx, t.ID, ip, lastUsedResolution); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// normalizeScopes validates requested scopes (allowing "<resource>:*") and
+// returns them sorted and de-duplicated.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	out := make([]string, 0, len(scopes))
+	for _, sc := range scopes {
+		sc = strings.ToLower(strings.TrimSpace(sc))
+		if !knownScope(sc) {
+			return nil, fmt.Errorf("unknown scope %q", sc)
+		}
+		out = append(out, sc)
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+func knownScope(scope string) bool {
+	if slices.Contains(models.AllScopes, scope) {
+		return true
+	}
+	resource, ok := strings.CutSuffix(scope, ":*")
+	if !ok {
+		return false
+	}
+	return slices.ContainsFunc(models.AllScopes, func(s string) bool {
+		return strings.HasPrefix(s, resource+":")
+	})
+}
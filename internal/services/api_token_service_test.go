@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+type memAPITokens struct {
+	repositories.APITokenRepository
+	byID   map[string]*models.APIToken
+	nextID int
+}
+
+func newMemAPITokens() *memAPITokens {
+	return &memAPITokens{byID: map[string]*models.APIToken{}}
+}
+
+func (m *memAPITokens) Create(_ context.Context, t *models.APIToken) error {
+	m.nextID++
+	t.ID = strconv.Itoa(m.nextID)
+	t.CreatedAt = time.Now().UTC()
+	cp := *t
+	m.byID[t.ID] = &cp
+	return nil
+}
+
+func (m *memAPITokens) FindByHash(_ context.Context, hash string) (*models.APIToken, error) {
+	for _, t := range m.byID {
+		if t.TokenHash == hash {
+			cp := *t
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memAPITokens) RevokeAllForUser(_ context.Context, userID string) (int64, error) {
+	var n int64
+	now := time.Now().UTC()
+	for _, t := range m.byID {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memAPITokens) TouchLastUsed(context.Context, string, string, time.Duration) error {
+	return nil
+}
+
+func TestAPITokenAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	u := &models.User{ID: "user-1"}
+	users := &memUsers{byID: map[string]*models.User{u.ID: u}}
+	tokens := newMemAPITokens()
+	svc := NewAPITokenService(tokens, users, nopAudit{})
+
+	_, token, err := svc.Create(ctx, u.ID, "script", []string{models.ScopeTransactionsRead}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := svc.Authenticate(ctx, token, ""); err != nil || got == nil {
+		t.Fatalf("Authenticate = %v, %v; want the token", got, err)
+	}
+	if got, _ := svc.Authenticate(ctx, token+"x", ""); got != nil {
+		t.Error("an unknown token was accepted")
+	}
+
+	at := time.Now().Add(time.Hour)
+	u.DeletionScheduledFor = &at
+	if got, _ := svc.Authenticate(ctx, token, ""); got != nil {
+		t.Error("token accepted for an account scheduled for deletion")
+	}
+
+	delete(users.byID, u.ID)
+	if got, _ := svc.Authenticate(ctx, token, ""); got != nil {
+		t.Error("token accepted for an account that no longer exists")
+	}
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/assistant"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+var (
+	ErrAssistantAmbiguous = errors.New("the text needs clarifying before it can be saved")
+	ErrCategoryNotFound   = errors.New("category not found")
+)
+
+// AssistantOptions fill in what a sentence usually leaves out.
+type AssistantOptions struct {
+	// Currency is used when the text names none.
+	Currency string
+	// Location is the user's time zone for words like "yesterday".
+	Location *time.Location
+	// CategoryID overrides the category matched from the text.
+	CategoryID string
+}
+
+// AssistantProposal is what a sentence would create. After Execute the
+// transaction or reminder is the saved one.
+type AssistantProposal struct {
+	Interpretation  *assistant.Intent         `json:"interpretation"`
+	CategoryMatches []assistant.CategoryMatch `json:"categoryMatches"`
+	Transaction     *models.Transaction       `json:"transaction,omitempty"`
+	Reminder        *models.Reminder          `json:"reminder,omitempty"`
+	Created         bool                      `json:"created"`
+}
+
+type AssistantService interface {
+	// Parse reads text without saving anything.
+	Parse(ctx context.Context, scope repositories.Scope, text string, opts AssistantOptions) (*AssistantProposal, error)
+	// Execute saves a proposal from Parse. It returns ErrAssistantAmbiguous
+	// while a blocking ambiguity is left.
+	Execute(ctx context.Context, scope repositories.Scope, p *AssistantProposal) (*AssistantProposal, error)
+}
+
+type assistantService struct {
+	categories   repositories.CategoryRepository
+	transactions TransactionService
+	reminders    ReminderService
+}
+
+func NewAssistantService(categories repositories.CategoryRepository, transactions TransactionService, reminders ReminderService) AssistantService {
+	return &assistantService{categories: categories, transactions: transactions, reminders: reminders}
+}
+
+func (s *assistantService) Parse(ctx context.Context, scope repositories.Scope, text string, opts AssistantOptions) (*AssistantProposal, error) {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	in := assistant.Parse(text, time.Now().In(loc))
+	p := &AssistantProposal{Interpretation: in, CategoryMatches: []assistant.CategoryMatch{}}
+	if in.Kind == "" {
+		return p, nil
+	}
+
+	if in.Currency == "" {
+		in.Currency = opts.Currency
+		if in.Currency == "" && (in.Kind == assistant.KindTransaction || in.Amount != nil) {
+			in.Ambiguities = append(in.Ambiguities, assistant.Ambiguity{
+				Field: "currency", Message: "no currency given", Blocking: true,
+			})
+		}
+	}
+
+	category, err := s.category(ctx, scope, in, opts.CategoryID, p)
+	if err != nil {
+		return nil, err
+	}
+
+	switch in.Kind {
+	case assistant.KindTransaction:
+		tx := &models.Transaction{
+			Type:     in.Type,
+			Currency: in.Currency,
+			Date:     in.Date.UTC(),
+		}
+		if in.Amount != nil {
+			tx.Amount = *in.Amount
+		}
+		if category != nil {
+			tx.CategoryID = &category.ID
+			if category.ParentID != nil {
+				tx.CategoryID, tx.SubcategoryID = category.ParentID, &category.ID
+			}
+		}
+		if desc := in.Title; desc != "" {
+			if in.Payment != "" {
+				desc += " (" + in.Payment + ")"
+			}
+			tx.Description = &desc
+		}
+		p.Transaction = tx
+	case assistant.KindReminder:
+		r := &models.Reminder{
+			Title:          in.Title,
+			DueAt:          in.Date.UTC(),
+			RepeatInterval: in.Repeat,
+			Amount:         in.Amount,
+		}
+		if in.Amount != nil {
+			typ := in.Type
+			r.Type = &typ
+			if in.Currency != "" {
+				currency := in.Currency
+				r.Currency = &currency
+			}
+		}
+		if category != nil {
+			r.CategoryID = &category.ID
+		}
+		p.Reminder = r
+	}
+	return p, nil
+}
+
+// category picks the category for the intent, noting on it when the choice
+// was unclear.
+func (s *assistantService) category(ctx context.Context, scope repositories.Scope, in *assistant.Intent, override string, p *AssistantProposal) (*models.Category, error) {
+	if override != "" {
+		c, err := s.categories.FindByID(ctx, override, scope)
+		if err != nil {
+			return nil, ErrCategoryNotFound
+		}
+		return c, nil
+	}
+	if in.Category == "" {
+		return nil, nil
+	}
+	all, err := s.categories.List(ctx, scope, nil)
+	if err != nil {
+		return nil, err
+	}
+	matches := assistant.MatchCategories(in.Category, all)
+	if matches != nil {
+		p.CategoryMatches = matches
+	}
+	switch {
+	case len(matches) == 0:
+		in.Ambiguities = append(in.Ambiguities, assistant.Ambiguity{
+			Field:   "category",
+			Message: fmt.Sprintf("no category matches %q", in.Category),
+		})
+		return nil, nil
+	case len(matches) > 1 && matches[0].Score-matches[1].Score < 0.05:
+		var names []string
+		for _, m := range matches {
+			if matches[0].Score-m.Score < 0.05 {
+				names = append(names, m.Category.Name)
+			}
+		}
+		in.Ambiguities = append(in.Ambiguities, assistant.Ambiguity{
+			Field:   "category",
+			Message: fmt.Sprintf("%q matches several categories; picked %s", in.Category, matches[0].Category.Name),
+			Options: names,
+		})
+	}
+	return &matches[0].Category, nil
+}
+
+func (s *assistantService) Execute(ctx context.Context, scope repositories.Scope, p *AssistantProposal) (*AssistantProposal, error) {
+	if p.Interpretation.Blocked() {
+		return nil, ErrAssistantAmbiguous
+	}
+	var err error
+	switch {
+	case p.Transaction != nil:
+		if p.Transaction, err = s.transactions.Create(ctx, scope, p.Transaction); err != nil {
+			return nil, err
+		}
+	case p.Reminder != nil:
+		if p.Reminder, err = s.reminders.Create(ctx, scope, p.Reminder); err != nil {
+			return nil, err
+		}
+	}
+	p.Created = true
+	return p, nil
+}
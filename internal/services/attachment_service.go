@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+	"github.com/ronak4195/personal-assistant/internal/storage"
+)
+
+const (
+	maxAttachmentsPerTransaction = 20
+	maxAttachmentNameLength      = 200
+)
+
+var (
+	ErrAttachmentNotFound  = errors.New("attachment not found")
+	ErrAttachmentTooLarge  = errors.New("file is too large")
+	ErrEmptyFile           = errors.New("file is empty")
+	ErrUnsupportedFileType = errors.New("only JPEG, PNG, GIF, WebP images and PDF documents can be attached")
+	ErrTooManyAttachments  = fmt.Errorf("a transaction can have at most %d attachments", maxAttachmentsPerTransaction)
+	ErrNoThumbnail         = errors.New("attachment has no thumbnail")
+)
+
+// attachmentTypes are the content types accepted, as reported by
+// http.DetectContentType.
+var attachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+type AttachmentService interface {
+	// Upload attaches data to a transaction. The type is sniffed from the
+	// content; the file name is only kept for downloads.
+	Upload(ctx context.Context, scope repositories.Scope, transactionID, fileName string, data []byte) (*models.Attachment, error)
+	List(ctx context.Context, scope repositories.Scope, transactionID string) ([]models.Attachment, error)
+	// Open returns the attachment's content, or its JPEG thumbnail. The
+	// caller closes the reader.
+	Open(ctx context.Context, scope repositories.Scope, id string, thumbnail bool) (*models.Attachment, io.ReadCloser, error)
+	Delete(ctx context.Context, scope repositories.Scope, id string) error
+	// DeleteByTransaction removes every attachment of a transaction that is
+	// being deleted.
+	DeleteByTransaction(ctx context.Context, scope repositories.Scope, transactionID string) error
+	// DeleteAllByUser and DeleteAllByHousehold remove the files along with
+	// the documents, for account and household deletion.
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+	DeleteAllByHousehold(ctx context.Context, householdID string) (int64, error)
+	// RemoveFiles deletes the stored files of attachments whose documents
+	// are gone, or were never committed.
+	RemoveFiles(ctx context.Context, attachments []models.Attachment)
+}
+
+type attachmentService struct {
+	repo         repositories.AttachmentRepository
+	transactions repositories.TransactionRepository
+	store        storage.Store
+	maxSize      int64
+	audit        AuditService
+}
+
+func NewAttachmentService(repo repositories.AttachmentRepository, transactions repositories.TransactionRepository, store storage.Store, maxSize int64, audit AuditService) AttachmentService {
+	return &attachmentService{
+		repo:         repo,
+		transactions: transactions,
+		store:        store,
+		maxSize:      maxSize,
+		audit:        audit,
+	}
+}
+
+func (s *attachmentService) Upload(ctx context.Context, scope repositories.Scope, transactionID, fileName string, data []byte) (*models.Attachment, error) {
+	if int64(len(data)) > s.maxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+	if len(data) == 0 {
+		return nil, ErrEmptyFile
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	if !attachmentTypes[contentType] {
+		return nil, ErrUnsupportedFileType
+	}
+
+	tx, err := s.transactions.FindByID(ctx, transactionID, scope)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, ErrTransactionNotFound
+	}
+	n, err := s.repo.CountByTransaction(ctx, scope, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if n >= maxAttachmentsPerTransaction {
+		return nil, ErrTooManyAttachments
+	}
+
+	key, err := newStorageKey()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	a := &models.Attachment{
+		UserID:        scope.UserID,
+		HouseholdID:   scope.HouseholdID,
+		TransactionID: transactionID,
+		FileName:      cleanFileName(fileName),
+		ContentType:   contentType,
+		Size:          int64(len(data)),
+		SHA256:        hex.EncodeToString(sum[:]),
+		StorageKey:    key,
+	}
+
+	if err := s.store.Put(ctx, key, data, contentType); err != nil {
+		return nil, err
+	}
+	if thumb, ok := makeThumbnail(data); ok {
+		thumbKey := key + ".thumb.jpg"
+		if err := s.store.Put(ctx, thumbKey, thumb, "image/jpeg"); err != nil {
+			log.Printf("attachments: store thumbnail %s: %v", thumbKey, err)
+		} else {
+			a.ThumbnailKey = thumbKey
+			a.HasThumbnail = true
+		}
+	}
+	if err := s.repo.Create(ctx, a); err != nil {
+		s.removeFiles(ctx, *a)
+		return nil, err
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		UserID:     scope.UserID,
+		Action:     models.AuditCreated,
+		EntityType: models.AuditEntityAttachment,
+		EntityID:   a.ID,
+		After:      a,
+	})
+	return a, nil
+}
+
+func (s *attachmentService) List(ctx context.Context, scope repositories.Scope, transactionID string) ([]models.Attachment, error) {
+	tx, err := s.transactions.FindByID(ctx, transactionID, scope)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, ErrTransactionNotFound
+	}
+	return s.repo.ListByTransaction(ctx, scope, transactionID)
+}
+
+func (s *attachmentService) Open(ctx context.Context, scope repositories.Scope, id string, thumbnail bool) (*models.Attachment, io.ReadCloser, error) {
+	a, err := s.repo.FindByID(ctx, id, scope)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a == nil {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	key := a.StorageKey
+	if thumbnail {
+		if !a.HasThumbnail {
+			return nil, nil, ErrNoThumbnail
+		}
+		key = a.ThumbnailKey
+	}
+	rc, err := s.store.Open(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, rc, nil
+}
+
+func (s *attachmentService) Delete(ctx context.Context, scope repositories.Scope, id string) error {
+	a, err := s.repo.FindByID(ctx, id, scope)
+	if err != nil {
+		return err
+	}
+	if a == nil {
+		return ErrAttachmentNotFound
+	}
+	if err := s.repo.Delete(ctx, id, scope); err != nil {
+		return err
+	}
+	s.removeFiles(ctx, *a)
+	s.audit.Record(ctx, AuditEntry{
+		UserID:     scope.UserID,
+		Action:     models.AuditDeleted,
+		EntityType: models.AuditEntityAttachment,
+		EntityID:   id,
+		Before:     a,
+	})
+	return nil
+}
+
+func (s *attachmentService) DeleteByTransaction(ctx context.Context, scope repositories.Scope, transactionID string) error {
+	attachments, err := s.repo.ListByTransaction(ctx, scope, transactionID)
+	if err != nil {
+		return err
+	}
+	if len(attachments) == 0 {
+		return nil
+	}
+	if err := s.repo.DeleteByTransaction(ctx, scope, transactionID); err != nil {
+		return err
+	}
+	for _, a := range attachments {
+		s.removeFiles(ctx, a)
+	}
+	return nil
+}
+
+// DeleteAllByUser removes the files before the documents: a purge that
+// fails half-way is run again, and would not find the keys otherwise.
+func (s *attachmentService) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	attachments, err := s.repo.ListAllByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.deleteFiles(ctx, attachments); err != nil {
+		return 0, err
+	}
+	return s.repo.DeleteAllByUser(ctx, userID)
+}
+
+func (s *attachmentService) DeleteAllByHousehold(ctx context.Context, householdID string) (int64, error) {
+	attachments, err := s.repo.ListAllByHousehold(ctx, householdID)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.deleteFiles(ctx, attachments); err != nil {
+		return 0, err
+	}
+	return s.repo.DeleteAllByHousehold(ctx, householdID)
+}
+
+func (s *attachmentService) deleteFiles(ctx context.Context, attachments []models.Attachment) error {
+	for _, a := range attachments {
+		for _, key := range []string{a.StorageKey, a.ThumbnailKey} {
+			if key == "" {
+				continue
+			}
+			if err := s.store.Delete(ctx, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *attachmentService) RemoveFiles(ctx context.Context, attachments []models.Attachment) {
+	for _, a := range attachments {
+		s.removeFiles(ctx, a)
+	}
+}
+
+// removeFiles deletes an attachment's files once its document is gone. A
+// failure only leaves an unreachable file behind, so it is logged.
+func (s *attachmentService) removeFiles(ctx context.Context, a models.Attachment) {
+	if err := s.deleteFiles(ctx, []models.Attachment{a}); err != nil {
+		log.Printf("attachments: remove files of %s: %v", a.StorageKey, err)
+	}
+}
+
+func newStorageKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "attachments/" + hex.EncodeToString(b), nil
+}
+
+// cleanFileName keeps the base name of an uploaded file without control
+// characters, for the Content-Disposition of downloads.
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if r := []rune(name); len(r) > maxAttachmentNameLength {
+		name = string(r[:maxAttachmentNameLength])
+	}
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"sort"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+	"github.com/ronak4195/personal-assistant/internal/requestctx"
+)
+
+// AuditEntry describes an event to record. Before and After are the entity
+// as the API returns it; either may be nil.
+type AuditEntry struct {
+	UserID     string
+	Action     models.AuditAction
+	EntityType string
+	EntityID   string
+	Before     any
+	After      any
+}
+
+type AuditService interface {
+	// Record stores an event along with the request details in ctx. It is
+	// best effort: a failure is logged rather than failing the operation
+	// being audited.
+	Record(ctx context.Context, e AuditEntry)
+	List(ctx context.Context, f repositories.AuditFilter) ([]models.AuditEvent, int64, error)
+}
+
+type auditService struct {
+	repo repositories.AuditRepository
+}
+
+func NewAuditService(repo repositories.AuditRepository) AuditService {
+	return &auditService{repo: repo}
+}
+
+func (s *auditService) Record(ctx context.Context, e AuditEntry) {
+	if e.UserID == "" {
+		return
+	}
+	meta := requestctx.From(ctx)
+	ev := &models.AuditEvent{
+		UserID:     e.UserID,
+		Action:     e.Action,
+		EntityType: e.EntityType,
+		EntityID:   e.EntityID,
+		Changes:    auditDiff(e.Before, e.After),
+		RequestID:  meta.RequestID,
+		IP:         meta.IP,
+		UserAgent:  meta.UserAgent,
+		APITokenID: meta.APITokenID,
+	}
+	// The event is written even if the request is cancelled right after
+	// the change it describes.
+	if err := s.repo.Insert(context.WithoutCancel(ctx), ev); err != nil {
+		log.Printf("audit: record %s %s for user %s: %v", e.Action, e.EntityType, e.UserID, err)
+	}
+}
+
+func (s *auditService) List(ctx context.Context, f repositories.AuditFilter) ([]models.AuditEvent, int64, error) {
+	return s.repo.List(ctx, f)
+}
+
+// auditIgnoredFields change on every write or never, and say nothing about
+// what the user changed.
+var auditIgnoredFields = map[string]bool{
+	"id":        true,
+	"userId":    true,
+	"createdAt": true,
+	"updatedAt": true,
+}
+
+// auditDiff compares the JSON forms of before and after field by field.
+func auditDiff(before, after any) []models.AuditChange {
+	b, a := auditFields(before), auditFields(after)
+	if b == nil && a == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range b {
+		keys = append(keys, k)
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []models.AuditChange
+	for _, k := range keys {
+		if auditIgnoredFields[k] {
+			continue
+		}
+		bv, av := b[k], a[k]
+		if reflect.DeepEqual(bv, av) {
+			continue
+		}
+		changes = append(changes, models.AuditChange{Field: k, Before: bv, After: av})
+	}
+	return changes
+}
+
+func auditFields(v any) map[string]any {
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil() {
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil
+	}
+	return m
+}
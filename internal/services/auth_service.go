@@ -3,44 +3,146 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/ronak4195/personal-assistant/internal/mailer"
 	"github.com/ronak4195/personal-assistant/internal/models"
 	"github.com/ronak4195/personal-assistant/internal/repositories"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected; session revoked")
+	ErrInvalidToken        = errors.New("invalid or expired token")
+	ErrAlreadyVerified     = errors.New("email already verified")
+	ErrInvalidMFACode      = errors.New("invalid authentication code")
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrNoPendingEnrolment  = errors.New("no two-factor enrolment in progress")
+	ErrEmailTaken          = errors.New("email already exists")
+	ErrNoDeletionScheduled = errors.New("no account deletion is pending")
+)
+
+const (
+	passwordResetTTL = time.Hour
+	emailVerifyTTL   = 48 * time.Hour
+)
+
+type AuthConfig struct {
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// AppURL is the front-end base URL that emailed links point to.
+	AppURL string
+	// DeletionGrace is how long a deleted account can still be restored.
+	DeletionGrace time.Duration
+	LoginThrottle LoginThrottleConfig
+}
+
+// SessionMeta describes the client a session is opened from.
+type SessionMeta struct {
+	DeviceName string
+	IP         string
+	UserAgent  string
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"` // access token lifetime in seconds
+	SessionID    string `json:"sessionId"`
+}
+
+// LoginResult carries either the session tokens or, for accounts with
+// two-factor authentication, the challenge that must be answered first.
+type LoginResult struct {
+	User      *models.User
+	Tokens    *TokenPair
+	Challenge *MFAChallenge
+}
+
 type AuthService interface {
-	Signup(ctx context.Context, name, email, password string) (*models.User, string, error)
-	Login(ctx context.Context, email, password string) (*models.User, string, error)
+	Signup(ctx context.Context, name, email, password string, meta SessionMeta) (*models.User, *TokenPair, error)
+	Login(ctx context.Context, email, password string, meta SessionMeta) (*LoginResult, error)
+	Refresh(ctx context.Context, refreshToken string, meta SessionMeta) (*TokenPair, error)
+	Logout(ctx context.Context, userID, sessionID string) error
 	GetUser(ctx context.Context, id string) (*models.User, error)
+
+	ListSessions(ctx context.Context, userID string) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) (bool, error)
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	SendVerification(ctx context.Context, userID string) error
+	VerifyEmail(ctx context.Context, token string) error
+
+	VerifyMFA(ctx context.Context, challengeToken, code string, meta SessionMeta) (*models.User, *TokenPair, error)
+	MFAStatus(ctx context.Context, userID string) (*MFAStatus, error)
+	BeginTOTPEnrolment(ctx context.Context, userID string) (*TOTPEnrolment, error)
+	ConfirmTOTPEnrolment(ctx context.Context, userID, code string) ([]string, error)
+	DisableMFA(ctx context.Context, userID, password, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID, password, code string) ([]string, error)
+
+	UpdateProfile(ctx context.Context, userID string, name, email *string, currentPassword string) (*models.User, error)
+	ChangePassword(ctx context.Context, userID, sessionID, currentPassword, newPassword string) error
+	ScheduleDeletion(ctx context.Context, userID, sessionID, password, code string) (time.Time, error)
+	CancelDeletion(ctx context.Context, userID string) error
 }
 
 type authService struct {
-	users     repositories.UserRepository
-	jwtSecret string
+	users          repositories.UserRepository
+	sessions       repositories.SessionRepository
+	apiTokens      repositories.APITokenRepository
+	tokens         repositories.UserTokenRepository
+	loginThrottles repositories.LoginThrottleRepository
+	audit          AuditService
+	mailer         mailer.Mailer
+	jwtSecret      string
+	accessTTL      time.Duration
+	refreshTTL     time.Duration
+	appURL         string
+	graceTTL       time.Duration
+	throttle       LoginThrottleConfig
 }
 
-func NewAuthService(userRepo repositories.UserRepository, jwtSecret string) AuthService {
+func NewAuthService(userRepo repositories.UserRepository, sessionRepo repositories.SessionRepository, apiTokenRepo repositories.APITokenRepository, tokenRepo repositories.UserTokenRepository, throttleRepo repositories.LoginThrottleRepository, audit AuditService, m mailer.Mailer, cfg AuthConfig) AuthService {
 	return &authService{
-		users:     userRepo,
-		jwtSecret: jwtSecret,
+		users:          userRepo,
+		sessions:       sessionRepo,
+		apiTokens:      apiTokenRepo,
+		tokens:         tokenRepo,
+		loginThrottles: throttleRepo,
+		audit:          audit,
+		mailer:         m,
+		jwtSecret:      cfg.JWTSecret,
+		accessTTL:      cfg.AccessTokenTTL,
+		refreshTTL:     cfg.RefreshTokenTTL,
+		appURL:         strings.TrimRight(cfg.AppURL, "/"),
+		graceTTL:       cfg.DeletionGrace,
+		throttle:       cfg.LoginThrottle,
 	}
 }
 
-func (s *authService) Signup(ctx context.Context, name, email, password string) (*models.User, string, error) {
+func (s *authService) Signup(ctx context.Context, name, email, password string, meta SessionMeta) (*models.User, *TokenPair, error) {
 	existing, err := s.users.FindByEmail(ctx, email)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 	if existing != nil {
-		return nil, "", errors.New("email already exists")
+		return nil, nil, ErrEmailTaken
 	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
 	u := &models.User{
@@ -50,48 +152,304 @@ func (s *authService) Signup(ctx context.Context, name, email, password string)
 	}
 
 	if err := s.users.Create(ctx, u); err != nil {
-		return nil, "", err
+		return nil, nil, err
+	}
+
+	// Signup succeeds even if the mail cannot go out; the user can resend.
+	if err := s.sendVerification(ctx, u); err != nil {
+		log.Printf("auth: send verification to user %s: %v", u.ID, err)
 	}
 
-	token, err := s.generateToken(u.ID)
+	tokens, err := s.startSession(ctx, u, meta)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	return u, token, nil
+	return u, tokens, nil
 }
 
-func (s *authService) Login(ctx context.Context, email, password string) (*models.User, string, error) {
+func (s *authService) Login(ctx context.Context, email, password string, meta SessionMeta) (*LoginResult, error) {
+	keys := newLoginKeys(email, meta.IP)
+	if err := s.checkLoginThrottle(ctx, keys); err != nil {
+		return nil, err
+	}
+
 	u, err := s.users.FindByEmail(ctx, email)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 	if u == nil {
-		return nil, "", errors.New("invalid credentials")
+		return nil, s.loginFailed(ctx, keys, nil, meta.IP)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
-		return nil, "", errors.New("invalid credentials")
+		s.audit.Record(ctx, AuditEntry{UserID: u.ID, Action: models.AuditLoginFailed, EntityType: models.AuditEntityUser, EntityID: u.ID})
+		return nil, s.loginFailed(ctx, keys, u, meta.IP)
+	}
+	// With MFA the failures are only cleared once the second factor passes;
+	// otherwise each new challenge would bring fresh guesses at the code.
+	if u.MFA.Enabled {
+		challenge, err := s.issueMFAChallenge(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{User: u, Challenge: challenge}, nil
+	}
+	s.loginSucceeded(ctx, keys)
+
+	tokens, err := s.startSession(ctx, u, meta)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, AuditEntry{UserID: u.ID, Action: models.AuditLogin, EntityType: models.AuditEntityUser, EntityID: u.ID})
+
+	return &LoginResult{User: u, Tokens: tokens}, nil
+}
+
+func (s *authService) Refresh(ctx context.Context, refreshToken string, meta SessionMeta) (*TokenPair, error) {
+	oldHash := hashToken(refreshToken)
+	sess, reused, err := s.sessions.FindByRefreshHash(ctx, oldHash)
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil || sess.RevokedAt != nil || time.Now().After(sess.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if reused {
+		if _, err := s.sessions.Revoke(ctx, sess.ID, sess.UserID, "refresh token reuse"); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	u, err := s.users.FindByID(ctx, sess.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	newToken, newHash, err := newOpaqueToken("")
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.sessions.Rotate(ctx, sess.ID, oldHash, newHash, meta.IP, meta.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Lost a race with another refresh of the same token: that is reuse.
+		if _, err := s.sessions.Revoke(ctx, sess.ID, sess.UserID, "refresh token reuse"); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
 	}
 
-	token, err := s.generateToken(u.ID)
+	access, err := s.generateToken(u, sess.ID)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: newToken,
+		ExpiresIn:    int64(s.accessTTL.Seconds()),
+		SessionID:    sess.ID,
+	}, nil
+}
 
-	return u, token, nil
+func (s *authService) Logout(ctx context.Context, userID, sessionID string) error {
+	_, err := s.sessions.Revoke(ctx, sessionID, userID, "logout")
+	return err
 }
 
 func (s *authService) GetUser(ctx context.Context, id string) (*models.User, error) {
 	return s.users.FindByID(ctx, id)
 }
 
-func (s *authService) generateToken(userID string) (string, error) {
+func (s *authService) ListSessions(ctx context.Context, userID string) ([]models.Session, error) {
+	return s.sessions.ListActiveByUser(ctx, userID)
+}
+
+func (s *authService) RevokeSession(ctx context.Context, userID, sessionID string) (bool, error) {
+	return s.sessions.Revoke(ctx, sessionID, userID, "revoked by user")
+}
+
+func (s *authService) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	sess, err := s.sessions.FindByID(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	return sess != nil && sess.RevokedAt == nil && time.Now().Before(sess.ExpiresAt), nil
+}
+
+func (s *authService) startSession(ctx context.Context, u *models.User, meta SessionMeta) (*TokenPair, error) {
+	refresh, hash, err := newOpaqueToken("")
+	if err != nil {
+		return nil, err
+	}
+
+	sess := &models.Session{
+		UserID:           u.ID,
+		RefreshTokenHash: hash,
+		DeviceName:       meta.DeviceName,
+		IP:               meta.IP,
+		UserAgent:        meta.UserAgent,
+		ExpiresAt:        time.Now().UTC().Add(s.refreshTTL),
+	}
+	if err := s.sessions.Create(ctx, sess); err != nil {
+		return nil, err
+	}
+
+	access, err := s.generateToken(u, sess.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int64(s.accessTTL.Seconds()),
+		SessionID:    sess.ID,
+	}, nil
+}
+
+func (s *authService) generateToken(u *models.User, sessionID string) (string, error) {
 	claims := jwt.MapClaims{
-		"userId": userID,
-		"exp":    time.Now().Add(24 * time.Hour).Unix(),
+		"userId": u.ID,
+		"sid":    sessionID,
+		"ev":     u.EmailVerified,
+		"exp":    time.Now().Add(s.accessTTL).Unix(),
 		"iat":    time.Now().Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(s.jwtSecret))
 }
+
+func (s *authService) ForgotPassword(ctx context.Context, email string) error {
+	u, err := s.users.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		// Do not reveal whether the address has an account.
+		return nil
+	}
+
+	link, err := s.issueToken(ctx, u, models.TokenPasswordReset, passwordResetTTL, "/reset-password")
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Text: fmt.Sprintf("Someone asked to reset the password for your account.\n\n"+
+			"Use this link within the next hour to choose a new password:\n%s\n\n"+
+			"If this wasn't you, you can ignore this email.\n", link),
+	})
+}
+
+func (s *authService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	t, err := s.tokens.Consume(ctx, models.TokenPasswordReset, hashToken(token))
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return ErrInvalidToken
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.users.UpdatePasswordHash(ctx, t.UserID, string(hash)); err != nil {
+		return err
+	}
+	if err := s.tokens.InvalidateAll(ctx, t.UserID, models.TokenPasswordReset); err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeAllForUser(ctx, t.UserID, "", "password reset"); err != nil {
+		return err
+	}
+	if _, err := s.apiTokens.RevokeAllForUser(ctx, t.UserID); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, AuditEntry{UserID: t.UserID, Action: models.AuditPasswordReset, EntityType: models.AuditEntityUser, EntityID: t.UserID})
+	// The reset link reached the inbox, which proves ownership as well.
+	_, err = s.users.MarkEmailVerified(ctx, t.UserID, t.Email)
+	return err
+}
+
+func (s *authService) SendVerification(ctx context.Context, userID string) error {
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return errors.New("user not found")
+	}
+	if u.EmailVerified {
+		return ErrAlreadyVerified
+	}
+	return s.sendVerification(ctx, u)
+}
+
+func (s *authService) VerifyEmail(ctx context.Context, token string) error {
+	t, err := s.tokens.Consume(ctx, models.TokenEmailVerify, hashToken(token))
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return ErrInvalidToken
+	}
+	ok, err := s.users.MarkEmailVerified(ctx, t.UserID, t.Email)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// The address changed after the link was sent.
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func (s *authService) sendVerification(ctx context.Context, u *models.User) error {
+	link, err := s.issueToken(ctx, u, models.TokenEmailVerify, emailVerifyTTL, "/verify-email")
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      u.Email,
+		Subject: "Confirm your email address",
+		Text: fmt.Sprintf("Welcome%s!\n\nPlease confirm your email address by opening this link:\n%s\n\n"+
+			"The link expires in 48 hours.\n", greetingName(u.Name), link),
+	})
+}
+
+// issueToken invalidates earlier tokens of the same purpose, stores a new one
+// and returns the front-end link that carries it.
+func (s *authService) issueToken(ctx context.Context, u *models.User, purpose models.UserTokenPurpose, ttl time.Duration, path string) (string, error) {
+	if err := s.tokens.InvalidateAll(ctx, u.ID, purpose); err != nil {
+		return "", err
+	}
+	token, hash, err := newOpaqueToken("")
+	if err != nil {
+		return "", err
+	}
+	if err := s.tokens.Create(ctx, &models.UserToken{
+		UserID:    u.ID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		Email:     u.Email,
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	}); err != nil {
+		return "", err
+	}
+	return s.appURL + path + "?token=" + url.QueryEscape(token), nil
+}
+
+func greetingName(name string) string {
+	if name == "" {
+		return ""
+	}
+	return ", " + name
+}
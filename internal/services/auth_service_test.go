@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/mailer"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+// memSessions keeps sessions in memory with the same compare-and-swap
+// semantics as the Mongo repository. Methods the tests do not use panic
+// through the embedded nil interface.
+type memSessions struct {
+	repositories.SessionRepository
+	mu     sync.Mutex
+	byID   map[string]*models.Session
+	nextID int
+	// loseRace makes the next Rotate fail as if another refresh won.
+	loseRace bool
+}
+
+func newMemSessions() *memSessions {
+	return &memSessions{byID: map[string]*models.Session{}}
+}
+
+func (m *memSessions) Create(_ context.Context, s *models.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	s.ID = strconv.Itoa(m.nextID)
+	cp := *s
+	m.byID[s.ID] = &cp
+	return nil
+}
+
+func (m *memSessions) FindByID(_ context.Context, id string) (*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.byID[id]; ok {
+		cp := *s
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (m *memSessions) FindByRefreshHash(_ context.Context, hash string) (*models.Session, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.byID {
+		if s.RefreshTokenHash == hash {
+			cp := *s
+			return &cp, false, nil
+		}
+		for _, h := range s.PreviousTokenHashes {
+			if h == hash {
+				cp := *s
+				return &cp, true, nil
+			}
+		}
+	}
+	return nil, false, nil
+}
+
+func (m *memSessions) Rotate(_ context.Context, id, oldHash, newHash, ip, userAgent string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.loseRace {
+		m.loseRace = false
+		return false, nil
+	}
+	s, ok := m.byID[id]
+	if !ok || s.RevokedAt != nil || s.RefreshTokenHash != oldHash {
+		return false, nil
+	}
+	s.PreviousTokenHashes = append(s.PreviousTokenHashes, oldHash)
+	s.RefreshTokenHash = newHash
+	s.IP, s.UserAgent = ip, userAgent
+	return true, nil
+}
+
+func (m *memSessions) Revoke(_ context.Context, id, userID, reason string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.byID[id]
+	if !ok || s.UserID != userID || s.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	s.RevokedAt = &now
+	s.RevokedReason = reason
+	return true, nil
+}
+
+func (m *memSessions) RevokeAllForUser(_ context.Context, userID, exceptID, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UTC()
+	for id, s := range m.byID {
+		if s.UserID == userID && id != exceptID && s.RevokedAt == nil {
+			s.RevokedAt = &now
+			s.RevokedReason = reason
+		}
+	}
+	return nil
+}
+
+type memUsers struct {
+	repositories.UserRepository
+	byID map[string]*models.User
+}
+
+func (m *memUsers) FindByID(_ context.Context, id string) (*models.User, error) {
+	return m.byID[id], nil
+}
+
+func (m *memUsers) FindByEmail(_ context.Context, email string) (*models.User, error) {
+	for _, u := range m.byID {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memUsers) UseTOTPStep(_ context.Context, id string, step int64) (bool, error) {
+	u := m.byID[id]
+	if u == nil || step <= u.MFA.LastStep {
+		return false, nil
+	}
+	u.MFA.LastStep = step
+	return true, nil
+}
+
+func (m *memUsers) UseRecoveryCode(_ context.Context, id, hash string) (bool, error) {
+	u := m.byID[id]
+	if u == nil {
+		return false, nil
+	}
+	for i, h := range u.MFA.RecoveryCodeHashes {
+		if h == hash {
+			u.MFA.RecoveryCodeHashes = append(u.MFA.RecoveryCodeHashes[:i], u.MFA.RecoveryCodeHashes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memUsers) UpdatePasswordHash(_ context.Context, id, hash string) error {
+	if u := m.byID[id]; u != nil {
+		u.PasswordHash = hash
+	}
+	return nil
+}
+
+func (m *memUsers) ScheduleDeletion(_ context.Context, id string, at time.Time) error {
+	if u := m.byID[id]; u != nil {
+		u.DeletionScheduledFor = &at
+	}
+	return nil
+}
+
+type memUserTokens struct {
+	repositories.UserTokenRepository
+}
+
+func (memUserTokens) InvalidateAll(context.Context, string, models.UserTokenPurpose) error {
+	return nil
+}
+
+type nopAudit struct {
+	AuditService
+}
+
+func (nopAudit) Record(context.Context, AuditEntry) {}
+
+type sentMail []mailer.Message
+
+func (m *sentMail) Send(_ context.Context, msg mailer.Message) error {
+	*m = append(*m, msg)
+	return nil
+}
+
+func newTestAuthService(sessions *memSessions) (*authService, *models.User) {
+	u := &models.User{ID: "user-1", Email: "a@example.com"}
+	return &authService{
+		users:      &memUsers{byID: map[string]*models.User{u.ID: u}},
+		sessions:   sessions,
+		apiTokens:  newMemAPITokens(),
+		tokens:     memUserTokens{},
+		audit:      nopAudit{},
+		mailer:     &sentMail{},
+		jwtSecret:  "test-secret",
+		accessTTL:  time.Minute,
+		refreshTTL: time.Hour,
+	}, u
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	ctx := context.Background()
+	sessions := newMemSessions()
+	svc, u := newTestAuthService(sessions)
+
+	first, err := svc.startSession(ctx, u, SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := svc.Refresh(ctx, first.RefreshToken, SessionMeta{IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("refresh token was not rotated")
+	}
+	if second.SessionID != first.SessionID {
+		t.Errorf("session changed from %s to %s", first.SessionID, second.SessionID)
+	}
+	if _, err := svc.Refresh(ctx, second.RefreshToken, SessionMeta{}); err != nil {
+		t.Errorf("Refresh with the rotated token: %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	ctx := context.Background()
+	sessions := newMemSessions()
+	svc, u := newTestAuthService(sessions)
+
+	first, err := svc.startSession(ctx, u, SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := svc.Refresh(ctx, first.RefreshToken, SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replaying the retired token means it leaked: the whole session goes.
+	if _, err := svc.Refresh(ctx, first.RefreshToken, SessionMeta{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replayed token: err = %v, want ErrRefreshTokenReused", err)
+	}
+	if active, _ := svc.IsSessionActive(ctx, first.SessionID); active {
+		t.Error("session still active after reuse")
+	}
+	if _, err := svc.Refresh(ctx, second.RefreshToken, SessionMeta{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("current token after reuse: err = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRefreshLostRaceIsReuse(t *testing.T) {
+	ctx := context.Background()
+	sessions := newMemSessions()
+	svc, u := newTestAuthService(sessions)
+
+	first, err := svc.startSession(ctx, u, SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions.loseRace = true
+	if _, err := svc.Refresh(ctx, first.RefreshToken, SessionMeta{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("err = %v, want ErrRefreshTokenReused", err)
+	}
+	if active, _ := svc.IsSessionActive(ctx, first.SessionID); active {
+		t.Error("session still active after a lost rotation race")
+	}
+}
+
+func TestRefreshUnknownOrExpired(t *testing.T) {
+	ctx := context.Background()
+	sessions := newMemSessions()
+	svc, u := newTestAuthService(sessions)
+
+	if _, err := svc.Refresh(ctx, "nonsense", SessionMeta{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("unknown token: err = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	tokens, err := svc.startSession(ctx, u, SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions.byID[tokens.SessionID].ExpiresAt = time.Now().Add(-time.Minute)
+	if _, err := svc.Refresh(ctx, tokens.RefreshToken, SessionMeta{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expired session: err = %v, want ErrInvalidRefreshToken", err)
+	}
+}
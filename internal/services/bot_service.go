@@ -0,0 +1,389 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/assistant"
+	"github.com/ronak4195/personal-assistant/internal/bot"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+const (
+	botPairingTTL      = 15 * time.Minute
+	botPairingAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	botPairingLength   = 8
+	// botCatchUp bounds how far back reminders are sent after the job has
+	// not run for a while, so a long outage does not flood the chat.
+	botCatchUp = 24 * time.Hour
+)
+
+var (
+	ErrBotLinkNotFound = errors.New("bot link not found")
+	ErrInvalidTimezone = errors.New("invalid timezone")
+)
+
+const botHelp = `Commands:
+/add <text> – record a transaction, e.g. /add spent 450 on groceries yesterday
+/remind <text> – add a reminder, e.g. /remind pay rent every month on the 1st at 9am
+/balance – income and spending this month
+/today – today's transactions and reminders
+/unlink – stop using this chat`
+
+type BotPairingCode struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type BotService interface {
+	// CreatePairingCode returns a one-time code to send to the bot as
+	// "/start <code>". timezone and currency are used for what is typed in
+	// the chat.
+	CreatePairingCode(ctx context.Context, userID, timezone, currency string) (*BotPairingCode, error)
+	ListLinks(ctx context.Context, userID string) ([]models.BotLink, error)
+	Unlink(ctx context.Context, userID, id string) error
+	// HandleUpdate answers one message sent to the bot.
+	HandleUpdate(ctx context.Context, u bot.Update) error
+	// SendDueReminders sends each linked chat the reminders that fell due
+	// since the last run.
+	SendDueReminders(ctx context.Context) error
+}
+
+type botService struct {
+	repo         repositories.BotRepository
+	client       bot.Client
+	assistant    AssistantService
+	reports      ReportService
+	transactions TransactionService
+	reminders    ReminderService
+	now          func() time.Time
+}
+
+func NewBotService(repo repositories.BotRepository, client bot.Client, assistant AssistantService, reports ReportService,
+	transactions TransactionService, reminders ReminderService) BotService {
+	return &botService{
+		repo:         repo,
+		client:       client,
+		assistant:    assistant,
+		reports:      reports,
+		transactions: transactions,
+		reminders:    reminders,
+		now:          time.Now,
+	}
+}
+
+func (s *botService) CreatePairingCode(ctx context.Context, userID, timezone, currency string) (*BotPairingCode, error) {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	b := make([]byte, botPairingLength)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	for i := range b {
+		b[i] = botPairingAlphabet[int(b[i])%len(botPairingAlphabet)]
+	}
+	code := string(b)
+
+	p := &models.BotPairing{
+		UserID:    userID,
+		CodeHash:  hashToken(code),
+		Timezone:  timezone,
+		Currency:  strings.ToUpper(strings.TrimSpace(currency)),
+		ExpiresAt: s.now().UTC().Add(botPairingTTL),
+	}
+	if err := s.repo.CreatePairing(ctx, p); err != nil {
+		return nil, err
+	}
+	return &BotPairingCode{Code: code, ExpiresAt: p.ExpiresAt}, nil
+}
+
+func (s *botService) ListLinks(ctx context.Context, userID string) ([]models.BotLink, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+func (s *botService) Unlink(ctx context.Context, userID, id string) error {
+	ok, err := s.repo.Unlink(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrBotLinkNotFound
+	}
+	return nil
+}
+
+func (s *botService) HandleUpdate(ctx context.Context, u bot.Update) error {
+	m := u.Message
+	if m == nil || m.Text == "" {
+		return nil
+	}
+	reply, err := s.answer(ctx, m)
+	if err != nil {
+		return err
+	}
+	return s.client.SendMessage(ctx, m.Chat.ID, reply)
+}
+
+func (s *botService) answer(ctx context.Context, m *bot.Message) (string, error) {
+	cmd, args, isCommand := bot.Command(m.Text)
+	if cmd == "start" || cmd == "link" {
+		if args == "" {
+			return "Open the app and create a pairing code, then send /start <code> here.\n\n" + botHelp, nil
+		}
+		return s.pair(ctx, m, args)
+	}
+
+	link, err := s.repo.FindByChatID(ctx, m.Chat.ID)
+	if err != nil {
+		return "", err
+	}
+	if link == nil {
+		return "This chat is not linked yet. Create a pairing code in the app and send /start <code> here.", nil
+	}
+	scope := repositories.PersonalScope(link.UserID)
+	loc, err := time.LoadLocation(link.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	if !isCommand {
+		// Plain text is the most common way to log something.
+		cmd, args = "add", strings.TrimSpace(m.Text)
+	}
+	switch cmd {
+	case "add":
+		return s.execute(ctx, scope, link, loc, args, assistant.KindTransaction)
+	case "remind":
+		if args != "" && !strings.HasPrefix(strings.ToLower(args), "remind") {
+			args = "remind me to " + args
+		}
+		return s.execute(ctx, scope, link, loc, args, assistant.KindReminder)
+	case "balance":
+		return s.balance(ctx, scope, loc)
+	case "today":
+		return s.today(ctx, scope, loc)
+	case "unlink":
+		if _, err := s.repo.UnlinkChat(ctx, m.Chat.ID); err != nil {
+			return "", err
+		}
+		return "This chat is no longer linked. You will not get reminders here.", nil
+	}
+	return botHelp, nil
+}
+
+func (s *botService) pair(ctx context.Context, m *bot.Message, code string) (string, error) {
+	p, err := s.repo.ConsumePairing(ctx, hashToken(strings.ToUpper(strings.TrimSpace(code))))
+	if err != nil {
+		return "", err
+	}
+	if p == nil {
+		return "That code is not valid or has expired. Create a new one in the app.", nil
+	}
+	l := &models.BotLink{
+		UserID:   p.UserID,
+		ChatID:   m.Chat.ID,
+		Timezone: p.Timezone,
+		Currency: p.Currency,
+	}
+	if m.From != nil {
+		l.Username = m.From.Username
+	}
+	if err := s.repo.Link(ctx, l); err != nil {
+		return "", err
+	}
+	return "Linked. Reminders will be sent here.\n\n" + botHelp, nil
+}
+
+func (s *botService) execute(ctx context.Context, scope repositories.Scope, link *models.BotLink, loc *time.Location, text string, want assistant.Kind) (string, error) {
+	if text == "" {
+		return botHelp, nil
+	}
+	p, err := s.assistant.Parse(ctx, scope, text, AssistantOptions{Currency: link.Currency, Location: loc})
+	if err != nil {
+		return "", err
+	}
+	in := p.Interpretation
+	if in.Kind != "" && in.Kind != want {
+		if want == assistant.KindReminder {
+			return "That reads like a transaction; use /add for it.", nil
+		}
+		return "That reads like a reminder; use /remind for it.", nil
+	}
+	if in.Blocked() {
+		var b strings.Builder
+		b.WriteString("Not saved:")
+		for _, a := range in.Ambiguities {
+			if a.Blocking {
+				b.WriteString("\n- " + a.Message)
+			}
+		}
+		return b.String(), nil
+	}
+
+	p, err = s.assistant.Execute(ctx, scope, p)
+	if err != nil {
+		return "Not saved: " + err.Error(), nil
+	}
+	var b strings.Builder
+	switch {
+	case p.Transaction != nil:
+		tx := p.Transaction
+		verb := "Spent"
+		if tx.Type == models.TransactionTypeIncome {
+			verb = "Received"
+		}
+		fmt.Fprintf(&b, "%s %s %s", verb, money(tx.Amount), tx.Currency)
+		if tx.Description != nil {
+			fmt.Fprintf(&b, " – %s", *tx.Description)
+		}
+		fmt.Fprintf(&b, " on %s", tx.Date.In(loc).Format("Mon 2 Jan"))
+	case p.Reminder != nil:
+		r := p.Reminder
+		fmt.Fprintf(&b, "Reminder set: %s, %s", r.Title, r.DueAt.In(loc).Format("Mon 2 Jan 15:04"))
+		if r.RepeatInterval != models.RepeatNone {
+			fmt.Fprintf(&b, ", repeating %s", r.RepeatInterval)
+		}
+	}
+	if len(p.CategoryMatches) > 0 {
+		fmt.Fprintf(&b, "\nCategory: %s", p.CategoryMatches[0].Category.Name)
+	}
+	for _, a := range in.Ambiguities {
+		b.WriteString("\nNote: " + a.Message)
+	}
+	return b.String(), nil
+}
+
+func (s *botService) balance(ctx context.Context, scope repositories.Scope, loc *time.Location) (string, error) {
+	now := s.now().In(loc)
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 1, 0).Add(-time.Nanosecond)
+	rep, err := s.reports.GetSummary(ctx, scope, PeriodCustom, &from, &to, GroupNone)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\nIncome: %s\nSpent: %s\nBalance: %s",
+		from.Format("January 2006"), money(rep.Totals.Income), money(rep.Totals.Expenses), money(rep.Totals.Savings)), nil
+}
+
+func (s *botService) today(ctx context.Context, scope repositories.Scope, loc *time.Location) (string, error) {
+	now := s.now().In(loc)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 0, 1)
+
+	end := to.Add(-time.Nanosecond)
+	txs, total, err := s.transactions.List(ctx, repositories.TransactionFilter{Scope: scope, From: &from, To: &end, Limit: 20, SortDateAsc: true})
+	if err != nil {
+		return "", err
+	}
+	occ, err := s.dueReminders(ctx, scope, from, to)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(from.Format("Monday 2 January"))
+	if len(txs) == 0 {
+		b.WriteString("\nNo transactions.")
+	}
+	for _, tx := range txs {
+		sign := "-"
+		if tx.Type == models.TransactionTypeIncome {
+			sign = "+"
+		}
+		fmt.Fprintf(&b, "\n%s%s %s %s", sign, money(tx.Amount), tx.Currency, describe(tx))
+	}
+	if more := total - int64(len(txs)); more > 0 {
+		fmt.Fprintf(&b, "\n…and %d more", more)
+	}
+	if len(occ) > 0 {
+		b.WriteString("\n\nReminders:")
+		for _, o := range occ {
+			fmt.Fprintf(&b, "\n%s %s", o.at.In(loc).Format("15:04"), o.reminder.Title)
+		}
+	}
+	return b.String(), nil
+}
+
+func (s *botService) dueReminders(ctx context.Context, scope repositories.Scope, from, to time.Time) ([]reminderOccurrence, error) {
+	active := true
+	rems, err := s.reminders.List(ctx, repositories.ReminderFilter{Scope: scope, IsActive: &active})
+	if err != nil {
+		return nil, err
+	}
+	var res []reminderOccurrence
+	for _, r := range rems {
+		for _, at := range occurrencesBetween(r.DueAt, r.RepeatInterval, from, to) {
+			res = append(res, reminderOccurrence{at: at, reminder: r})
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].at.Before(res[j].at) })
+	return res, nil
+}
+
+func (s *botService) SendDueReminders(ctx context.Context) error {
+	links, err := s.repo.ListAll(ctx)
+	if err != nil {
+		return err
+	}
+	// Mongo keeps milliseconds; the claim compares against what is stored.
+	now := s.now().UTC().Truncate(time.Millisecond)
+	var errs []error
+	for _, l := range links {
+		if err := s.notify(ctx, l, now); err != nil {
+			errs = append(errs, fmt.Errorf("chat %d: %w", l.ChatID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *botService) notify(ctx context.Context, l models.BotLink, now time.Time) error {
+	if !now.After(l.NotifiedThrough) {
+		return nil
+	}
+	// Claim the window first so that replicas running the job at the same
+	// time do not both send it. A failed send is not retried.
+	ok, err := s.repo.ClaimNotifications(ctx, l.ID, l.NotifiedThrough, now)
+	if err != nil || !ok {
+		return err
+	}
+	from := l.NotifiedThrough
+	if now.Sub(from) > botCatchUp {
+		from = now.Add(-botCatchUp)
+	}
+	occ, err := s.dueReminders(ctx, repositories.PersonalScope(l.UserID), from, now)
+	if err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(l.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	for _, o := range occ {
+		text := "⏰ " + o.reminder.Title
+		if o.reminder.Amount != nil {
+			text += " – " + money(*o.reminder.Amount)
+			if o.reminder.Currency != nil {
+				text += " " + *o.reminder.Currency
+			}
+		}
+		if o.reminder.Description != nil && *o.reminder.Description != "" {
+			text += "\n" + *o.reminder.Description
+		}
+		text += "\n" + o.at.In(loc).Format("Mon 2 Jan 15:04")
+		if err := s.client.SendMessage(ctx, l.ChatID, text); err != nil {
+			log.Printf("bot: reminder %s to chat %d: %v", o.reminder.ID, l.ChatID, err)
+		}
+	}
+	return nil
+}
@@ -9,24 +9,25 @@ import (
 )
 
 type CategoryService interface {
-	Create(ctx context.Context, userID, name string, parentID *string) (*models.Category, error)
-	List(ctx context.Context, userID string, parentID *string) ([]models.Category, error)
-	Get(ctx context.Context, userID, id string) (*models.Category, error)
-	Update(ctx context.Context, userID, id, name string, parentID *string) (*models.Category, error)
-	Delete(ctx context.Context, userID, id string) error
+	Create(ctx context.Context, scope repositories.Scope, name string, parentID *string) (*models.Category, error)
+	List(ctx context.Context, scope repositories.Scope, parentID *string) ([]models.Category, error)
+	Get(ctx context.Context, scope repositories.Scope, id string) (*models.Category, error)
+	Update(ctx context.Context, scope repositories.Scope, id, name string, parentID *string) (*models.Category, error)
+	Delete(ctx context.Context, scope repositories.Scope, id string) error
 }
 
 type categoryService struct {
-	repo repositories.CategoryRepository
+	repo  repositories.CategoryRepository
+	audit AuditService
 }
 
-func NewCategoryService(repo repositories.CategoryRepository) CategoryService {
-	return &categoryService{repo: repo}
+func NewCategoryService(repo repositories.CategoryRepository, audit AuditService) CategoryService {
+	return &categoryService{repo: repo, audit: audit}
 }
 
-func (s *categoryService) Create(ctx context.Context, userID, name string, parentID *string) (*models.Category, error) {
+func (s *categoryService) Create(ctx context.Context, scope repositories.Scope, name string, parentID *string) (*models.Category, error) {
 	if parentID != nil {
-		parent, err := s.repo.FindByID(ctx, *parentID, userID)
+		parent, err := s.repo.FindByID(ctx, *parentID, scope)
 		if err != nil {
 			return nil, err
 		}
@@ -36,26 +37,34 @@ func (s *categoryService) Create(ctx context.Context, userID, name string, paren
 	}
 
 	cat := &models.Category{
-		UserID:   userID,
-		Name:     name,
-		ParentID: parentID,
+		UserID:      scope.UserID,
+		HouseholdID: scope.HouseholdID,
+		Name:        name,
+		ParentID:    parentID,
 	}
 	if err := s.repo.Create(ctx, cat); err != nil {
 		return nil, err
 	}
+	s.audit.Record(ctx, AuditEntry{
+		UserID:     scope.UserID,
+		Action:     models.AuditCreated,
+		EntityType: models.AuditEntityCategory,
+		EntityID:   cat.ID,
+		After:      cat,
+	})
 	return cat, nil
 }
 
-func (s *categoryService) List(ctx context.Context, userID string, parentID *string) ([]models.Category, error) {
-	return s.repo.List(ctx, userID, parentID)
+func (s *categoryService) List(ctx context.Context, scope repositories.Scope, parentID *string) ([]models.Category, error) {
+	return s.repo.List(ctx, scope, parentID)
 }
 
-func (s *categoryService) Get(ctx context.Context, userID, id string) (*models.Category, error) {
-	return s.repo.FindByID(ctx, id, userID)
+func (s *categoryService) Get(ctx context.Context, scope repositories.Scope, id string) (*models.Category, error) {
+	return s.repo.FindByID(ctx, id, scope)
 }
 
-func (s *categoryService) Update(ctx context.Context, userID, id, name string, parentID *string) (*models.Category, error) {
-	cat, err := s.repo.FindByID(ctx, id, userID)
+func (s *categoryService) Update(ctx context.Context, scope repositories.Scope, id, name string, parentID *string) (*models.Category, error) {
+	cat, err := s.repo.FindByID(ctx, id, scope)
 	if err != nil {
 		return nil, err
 	}
@@ -64,7 +73,7 @@ func (s *categoryService) Update(ctx context.Context, userID, id, name string, p
 	}
 
 	if parentID != nil {
-		parent, err := s.repo.FindByID(ctx, *parentID, userID)
+		parent, err := s.repo.FindByID(ctx, *parentID, scope)
 		if err != nil {
 			return nil, err
 		}
@@ -73,16 +82,55 @@ func (s *categoryService) Update(ctx context.Context, userID, id, name string, p
 		}
 	}
 
+	before := *cat
 	cat.Name = name
 	cat.ParentID = parentID
 
-	if err := s.repo.Update(ctx, cat); err != nil {
+	if err := s.repo.Update(ctx, scope, cat); err != nil {
 		return nil, err
 	}
+	s.audit.Record(ctx, AuditEntry{
+		UserID:     scope.UserID,
+		Action:     models.AuditUpdated,
+		EntityType: models.AuditEntityCategory,
+		EntityID:   cat.ID,
+		Before:     &before,
+		After:      cat,
+	})
 	return cat, nil
 }
 
-func (s *categoryService) Delete(ctx context.Context, userID, id string) error {
+func (s *categoryService) Delete(ctx context.Context, scope repositories.Scope, id string) error {
 	// NOTE: here you could check for transactions referencing this category and either block or nullify.
-	return s.repo.Delete(ctx, id, userID)
+	existing, err := s.repo.FindByID(ctx, id, scope)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id, scope); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, AuditEntry{
+		UserID:     scope.UserID,
+		Action:     models.AuditDeleted,
+		EntityType: models.AuditEntityCategory,
+		EntityID:   id,
+		Before:     existing,
+	})
+	return nil
+}
+
+// checkCategoryID makes sure id, when set, names a category in the scope;
+// another member's personal categories are not in a household's scope.
+func checkCategoryID(ctx context.Context, categories repositories.CategoryRepository, scope repositories.Scope, id *string) error {
+	if id == nil {
+		return nil
+	}
+	c, err := categories.FindByID(ctx, *id, scope)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return ErrCategoryNotFound
+	}
+	return nil
 }
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/mailer"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+const (
+	// How long a worker may hold a period before another one retries it.
+	digestLease = 10 * time.Minute
+	// Periods whose send time passed longer ago than this (e.g. while the
+	// server was down) are skipped rather than sent late.
+	digestCatchUp         = 48 * time.Hour
+	digestMaxCategories   = 5
+	digestDefaultSendTime = "08:00"
+)
+
+var ErrDigestNotFound = errors.New("digest subscription not found")
+
+type DigestPatch struct {
+	Frequency *models.DigestFrequency
+	SendTime  *string
+	Timezone  *string
+	IsActive  *bool
+}
+
+type DigestService interface {
+	Create(ctx context.Context, d *models.DigestSubscription) (*models.DigestSubscription, error)
+	List(ctx context.Context, userID string) ([]models.DigestSubscription, error)
+	Update(ctx context.Context, userID, id string, patch DigestPatch) (*models.DigestSubscription, error)
+	Delete(ctx context.Context, userID, id string) error
+	// SendDue sends every digest whose current period is due and not yet
+	// delivered. It is meant to be run periodically by the scheduler.
+	SendDue(ctx context.Context) error
+}
+
+type digestService struct {
+	repo      repositories.DigestRepository
+	users     repositories.UserRepository
+	reports   ReportService
+	reminders repositories.ReminderRepository
+	mailer    mailer.Mailer
+	now       func() time.Time
+}
+
+func NewDigestService(repo repositories.DigestRepository, users repositories.UserRepository, reports ReportService, reminders repositories.ReminderRepository, m mailer.Mailer) DigestService {
+	return &digestService{
+		repo:      repo,
+		users:     users,
+		reports:   reports,
+		reminders: reminders,
+		mailer:    m,
+		now:       time.Now,
+	}
+}
+
+func (s *digestService) Create(ctx context.Context, d *models.DigestSubscription) (*models.DigestSubscription, error) {
+	if d.SendTime == "" {
+		d.SendTime = digestDefaultSendTime
+	}
+	if d.Timezone == "" {
+		d.Timezone = "UTC"
+	}
+	d.IsActive = true
+	if err := validateDigest(d); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *digestService) List(ctx context.Context, userID string) ([]models.DigestSubscription, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+func (s *digestService) Update(ctx context.Context, userID, id string, patch DigestPatch) (*models.DigestSubscription, error) {
+	existing, err := s.repo.FindByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrDigestNotFound
+	}
+
+	if patch.Frequency != nil {
+		existing.Frequency = *patch.Frequency
+	}
+	if patch.SendTime != nil {
+		existing.SendTime = *patch.SendTime
+	}
+	if patch.Timezone != nil {
+		existing.Timezone = *patch.Timezone
+	}
+	if patch.IsActive != nil {
+		existing.IsActive = *patch.IsActive
+	}
+	if err := validateDigest(existing); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (s *digestService) Delete(ctx context.Context, userID, id string) error {
+	return s.repo.Delete(ctx, id, userID)
+}
+
+func validateDigest(d *models.DigestSubscription) error {
+	if d.Frequency != models.DigestWeekly && d.Frequency != models.DigestMonthly {
+		return errors.New("frequency must be weekly or monthly")
+	}
+	if _, _, err := parseClock(d.SendTime); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(d.Timezone); err != nil {
+		return errors.New("invalid timezone")
+	}
+	return nil
+}
+
+func parseClock(s string) (int, int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, errors.New("sendTime must be HH:MM")
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+type digestPeriod struct {
+	key         string
+	start       time.Time // inclusive, in the subscriber's timezone
+	end         time.Time // exclusive
+	scheduledAt time.Time
+}
+
+// currentDigestPeriod returns the most recent period whose send time is not
+// in the future. Weekly digests go out on Mondays and cover the previous
+// Monday-Sunday; monthly digests go out on the 1st and cover the previous
+// calendar month.
+func currentDigestPeriod(d models.DigestSubscription, now time.Time) (digestPeriod, error) {
+	loc, err := time.LoadLocation(d.Timezone)
+	if err != nil {
+		return digestPeriod{}, err
+	}
+	hour, minute, err := parseClock(d.SendTime)
+	if err != nil {
+		return digestPeriod{}, err
+	}
+	local := now.In(loc)
+
+	var p digestPeriod
+	switch d.Frequency {
+	case models.DigestWeekly:
+		sinceMonday := (int(local.Weekday()) + 6) % 7
+		send := time.Date(local.Year(), local.Month(), local.Day()-sinceMonday, hour, minute, 0, 0, loc)
+		if send.After(local) {
+			send = send.AddDate(0, 0, -7)
+		}
+		p.scheduledAt = send
+		p.end = time.Date(send.Year(), send.Month(), send.Day(), 0, 0, 0, 0, loc)
+		p.start = p.end.AddDate(0, 0, -7)
+		p.key = "weekly:" + p.start.Format("2006-01-02")
+	case models.DigestMonthly:
+		send := time.Date(local.Year(), local.Month(), 1, hour, minute, 0, 0, loc)
+		if send.After(local) {
+			send = send.AddDate(0, -1, 0)
+		}
+		p.scheduledAt = send
+		p.end = time.Date(send.Year(), send.Month(), 1, 0, 0, 0, 0, loc)
+		p.start = p.end.AddDate(0, -1, 0)
+		p.key = "monthly:" + p.start.Format("2006-01")
+	default:
+		return digestPeriod{}, fmt.Errorf("unknown frequency %q", d.Frequency)
+	}
+	return p, nil
+}
+
+func (s *digestService) SendDue(ctx context.Context) error {
+	subs, err := s.repo.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	for i := range subs {
+		sub := &subs[i]
+		period, err := currentDigestPeriod(*sub, now)
+		if err != nil {
+			log.Printf("digests: subscription %s: %v", sub.ID, err)
+			continue
+		}
+		if period.scheduledAt.Before(sub.CreatedAt) || now.Sub(period.scheduledAt) > digestCatchUp {
+			continue
+		}
+
+		claimed, err := s.repo.ClaimDelivery(ctx, sub, period.key, digestLease)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		// On failure the lease simply lapses and a later run retries.
+		if err := s.send(ctx, sub, period); err != nil {
+			log.Printf("digests: subscription %s period %s: %v", sub.ID, period.key, err)
+			continue
+		}
+		if err := s.repo.MarkDelivered(ctx, sub.ID, period.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *digestService) send(ctx context.Context, sub *models.DigestSubscription, period digestPeriod) error {
+	user, err := s.users.FindByID(ctx, sub.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	msg, err := s.build(ctx, sub, user, period)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, *msg)
+}
+
+func (s *digestService) build(ctx context.Context, sub *models.DigestSubscription, user *models.User, period digestPeriod) (*mailer.Message, error) {
+	from := period.start.UTC()
+	to := period.end.UTC().Add(-time.Nanosecond)
+	summary, err := s.reports.GetSummary(ctx, repositories.PersonalScope(sub.UserID), PeriodCustom, &from, &to, GroupCategory)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := period.start.Location()
+	view := digestView{
+		Name:      user.Name,
+		Frequency: string(sub.Frequency),
+		Income:    money(summary.Totals.Income),
+		Expenses:  money(summary.Totals.Expenses),
+		Savings:   money(summary.Totals.Savings),
+	}
+
+	lastDay := period.end.AddDate(0, 0, -1)
+	horizonEnd := period.scheduledAt.AddDate(0, 0, 7)
+	view.Horizon = "next 7 days"
+	subject := fmt.Sprintf("Your weekly summary: %s - %s", period.start.Format("2 Jan"), lastDay.Format("2 Jan 2006"))
+	view.PeriodLabel = fmt.Sprintf("%s - %s", period.start.Format("Mon 2 Jan"), lastDay.Format("Mon 2 Jan 2006"))
+	if sub.Frequency == models.DigestMonthly {
+		horizonEnd = period.scheduledAt.AddDate(0, 1, 0)
+		view.Horizon = "coming month"
+		view.PeriodLabel = period.start.Format("January 2006")
+		subject = "Your monthly summary: " + view.PeriodLabel
+	}
+
+	cats := append([]CategorySummary(nil), summary.ByCategory...)
+	sort.Slice(cats, func(i, j int) bool { return cats[i].Expenses > cats[j].Expenses })
+	for _, c := range cats {
+		if len(view.Categories) == digestMaxCategories || c.Expenses <= 0 {
+			break
+		}
+		view.Categories = append(view.Categories, digestCategoryView{Name: c.CategoryName, Expenses: money(c.Expenses)})
+	}
+
+	upcoming, err := s.upcomingReminders(ctx, sub.UserID, period.scheduledAt, horizonEnd)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range upcoming {
+		v := digestReminderView{
+			When:  u.at.In(loc).Format("Mon 2 Jan 15:04"),
+			Title: u.reminder.Title,
+		}
+		if u.reminder.Amount != nil {
+			v.Amount = money(*u.reminder.Amount)
+			if u.reminder.Currency != nil {
+				v.Amount += " " + *u.reminder.Currency
+			}
+		}
+		view.Upcoming = append(view.Upcoming, v)
+	}
+
+	var text, html bytes.Buffer
+	if err := digestTextTemplate.Execute(&text, view); err != nil {
+		return nil, err
+	}
+	if err := digestHTMLTemplate.Execute(&html, view); err != nil {
+		return nil, err
+	}
+
+	return &mailer.Message{
+		To:      user.Email,
+		Subject: subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+type reminderOccurrence struct {
+	at       time.Time
+	reminder models.Reminder
+}
+
+func (s *digestService) upcomingReminders(ctx context.Context, userID string, from, to time.Time) ([]reminderOccurrence, error) {
+	active := true
+	rems, err := s.reminders.List(ctx, repositories.ReminderFilter{Scope: repositories.PersonalScope(userID), IsActive: &active})
+	if err != nil {
+		return nil, err
+	}
+
+	var res []reminderOccurrence
+	for _, r := range rems {
+		for _, at := range occurrencesBetween(r.DueAt, r.RepeatInterval, from, to) {
+			res = append(res, reminderOccurrence{at: at, reminder: r})
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].at.Before(res[j].at) })
+	return res, nil
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+func TestCurrentDigestPeriod(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no timezone database:", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no timezone database:", err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("no timezone database:", err)
+	}
+	at := func(loc *time.Location, y int, m time.Month, d, hour, minute int) time.Time {
+		return time.Date(y, m, d, hour, minute, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name      string
+		frequency models.DigestFrequency
+		timezone  string
+		now       time.Time
+		key       string
+		start     time.Time
+		end       time.Time
+		scheduled time.Time
+	}{
+		{
+			name:      "weekly mid-week",
+			frequency: models.DigestWeekly,
+			timezone:  "UTC",
+			now:       at(time.UTC, 2026, 10, 21, 10, 0),
+			key:       "weekly:2026-10-12",
+			start:     at(time.UTC, 2026, 10, 12, 0, 0),
+			end:       at(time.UTC, 2026, 10, 19, 0, 0),
+			scheduled: at(time.UTC, 2026, 10, 19, 8, 0),
+		},
+		{
+			name:      "weekly before Monday's send time",
+			frequency: models.DigestWeekly,
+			timezone:  "UTC",
+			now:       at(time.UTC, 2026, 10, 19, 7, 59),
+			key:       "weekly:2026-10-05",
+			start:     at(time.UTC, 2026, 10, 5, 0, 0),
+			end:       at(time.UTC, 2026, 10, 12, 0, 0),
+			scheduled: at(time.UTC, 2026, 10, 12, 8, 0),
+		},
+		{
+			name:      "weekly on Monday already in the subscriber's timezone",
+			frequency: models.DigestWeekly,
+			timezone:  "Asia/Tokyo",
+			now:       at(time.UTC, 2026, 10, 18, 23, 30),
+			key:       "weekly:2026-10-12",
+			start:     at(tokyo, 2026, 10, 12, 0, 0),
+			end:       at(tokyo, 2026, 10, 19, 0, 0),
+			scheduled: at(tokyo, 2026, 10, 19, 8, 0),
+		},
+		{
+			name:      "weekly across the end of summer time",
+			frequency: models.DigestWeekly,
+			timezone:  "Europe/Berlin",
+			now:       at(time.UTC, 2026, 10, 26, 7, 30),
+			key:       "weekly:2026-10-19",
+			start:     at(berlin, 2026, 10, 19, 0, 0),
+			end:       at(berlin, 2026, 10, 26, 0, 0),
+			scheduled: at(berlin, 2026, 10, 26, 8, 0),
+		},
+		{
+			name:      "monthly",
+			frequency: models.DigestMonthly,
+			timezone:  "UTC",
+			now:       at(time.UTC, 2026, 10, 19, 12, 0),
+			key:       "monthly:2026-09",
+			start:     at(time.UTC, 2026, 9, 1, 0, 0),
+			end:       at(time.UTC, 2026, 10, 1, 0, 0),
+			scheduled: at(time.UTC, 2026, 10, 1, 8, 0),
+		},
+		{
+			name:      "monthly before the 1st's send time",
+			frequency: models.DigestMonthly,
+			timezone:  "UTC",
+			now:       at(time.UTC, 2026, 1, 1, 7, 59),
+			key:       "monthly:2025-11",
+			start:     at(time.UTC, 2025, 11, 1, 0, 0),
+			end:       at(time.UTC, 2025, 12, 1, 0, 0),
+			scheduled: at(time.UTC, 2025, 12, 1, 8, 0),
+		},
+		{
+			name:      "monthly across the start of summer time",
+			frequency: models.DigestMonthly,
+			timezone:  "America/New_York",
+			now:       at(time.UTC, 2026, 4, 1, 13, 0),
+			key:       "monthly:2026-03",
+			start:     at(newYork, 2026, 3, 1, 0, 0),
+			end:       at(newYork, 2026, 4, 1, 0, 0),
+			scheduled: at(newYork, 2026, 4, 1, 8, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := models.DigestSubscription{Frequency: tt.frequency, SendTime: "08:00", Timezone: tt.timezone}
+			p, err := currentDigestPeriod(d, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.key != tt.key {
+				t.Errorf("key = %s, want %s", p.key, tt.key)
+			}
+			if !p.start.Equal(tt.start) || !p.end.Equal(tt.end) {
+				t.Errorf("period = %s - %s, want %s - %s", p.start, p.end, tt.start, tt.end)
+			}
+			if !p.scheduledAt.Equal(tt.scheduled) {
+				t.Errorf("scheduledAt = %s, want %s", p.scheduledAt, tt.scheduled)
+			}
+		})
+	}
+}
+
+// memDigests hands out each subscription period once, like the unique index
+// on the deliveries collection.
+type memDigests struct {
+	repositories.DigestRepository
+	subs      []models.DigestSubscription
+	claimed   map[string]bool
+	delivered map[string]bool
+}
+
+func (m *memDigests) ListActive(context.Context) ([]models.DigestSubscription, error) {
+	return m.subs, nil
+}
+
+func (m *memDigests) ClaimDelivery(_ context.Context, sub *models.DigestSubscription, periodKey string, _ time.Duration) (bool, error) {
+	key := sub.ID + "/" + periodKey
+	if m.claimed[key] {
+		return false, nil
+	}
+	m.claimed[key] = true
+	return true, nil
+}
+
+func (m *memDigests) MarkDelivered(_ context.Context, subscriptionID, periodKey string) error {
+	m.delivered[subscriptionID+"/"+periodKey] = true
+	return nil
+}
+
+type noReminders struct {
+	repositories.ReminderRepository
+}
+
+func (noReminders) List(context.Context, repositories.ReminderFilter) ([]models.Reminder, error) {
+	return nil, nil
+}
+
+func TestSendDueSendsEachPeriodOnce(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	digests := &memDigests{
+		subs: []models.DigestSubscription{{
+			ID: "digest-1", UserID: "user-1", Frequency: models.DigestWeekly,
+			SendTime: "08:00", Timezone: "UTC", IsActive: true, CreatedAt: now.AddDate(0, -1, 0),
+		}},
+		claimed:   map[string]bool{},
+		delivered: map[string]bool{},
+	}
+	mail := &sentMail{}
+	svc := &digestService{
+		repo:      digests,
+		users:     &memUsers{byID: map[string]*models.User{"user-1": {ID: "user-1", Email: "a@example.com"}}},
+		reports:   fixedSummary{},
+		reminders: noReminders{},
+		mailer:    mail,
+		now:       func() time.Time { return now },
+	}
+
+	if err := svc.SendDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(*mail) != 1 || (*mail)[0].To != "a@example.com" {
+		t.Fatalf("sent %+v, want one digest to a@example.com", *mail)
+	}
+	if !digests.delivered["digest-1/weekly:2026-10-12"] {
+		t.Errorf("delivered = %v, want the week of 12 Oct marked", digests.delivered)
+	}
+
+	now = now.Add(3 * time.Hour)
+	if err := svc.SendDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(*mail) != 1 {
+		t.Errorf("second run in the same period sent %d more", len(*mail)-1)
+	}
+
+	now = now.AddDate(0, 0, 7)
+	if err := svc.SendDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(*mail) != 2 {
+		t.Errorf("sent %d digests after a week, want 2", len(*mail))
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+type digestView struct {
+	Name        string
+	Frequency   string
+	PeriodLabel string
+	Income      string
+	Expenses    string
+	Savings     string
+	Categories  []digestCategoryView
+	Upcoming    []digestReminderView
+	Horizon     string
+}
+
+type digestCategoryView struct {
+	Name     string
+	Expenses string
+}
+
+type digestReminderView struct {
+	When   string
+	Title  string
+	Amount string
+}
+
+var digestTextTemplate = texttemplate.Must(texttemplate.New("digest.txt").Parse(`Hi {{if .Name}}{{.Name}}{{else}}there{{end}},
+
+Here is your {{.Frequency}} summary for {{.PeriodLabel}}.
+
+Income:      {{.Income}}
+Expenses:    {{.Expenses}}
+Net savings: {{.Savings}}
+{{if .Categories}}
+Where the money went:
+{{range .Categories}}  - {{.Name}}: {{.Expenses}}
+{{end}}{{end}}
+{{if .Upcoming}}Coming up in the {{.Horizon}}:
+{{range .Upcoming}}  - {{.When}}  {{.Title}}{{if .Amount}} ({{.Amount}}){{end}}
+{{end}}{{else}}Nothing scheduled for the {{.Horizon}}.
+{{end}}
+You are receiving this because you subscribed to {{.Frequency}} digests.
+`))
+
+var digestHTMLTemplate = htmltemplate.Must(htmltemplate.New("digest.html").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: Helvetica, Arial, sans-serif; color: #222; max-width: 560px;">
+<p>Hi {{if .Name}}{{.Name}}{{else}}there{{end}},</p>
+<p>Here is your {{.Frequency}} summary for <strong>{{.PeriodLabel}}</strong>.</p>
+<table cellpadding="4" style="border-collapse: collapse;">
+<tr><td>Income</td><td align="right">{{.Income}}</td></tr>
+<tr><td>Expenses</td><td align="right">{{.Expenses}}</td></tr>
+<tr><td><strong>Net savings</strong></td><td align="right"><strong>{{.Savings}}</strong></td></tr>
+</table>
+{{if .Categories}}
+<h3>Where the money went</h3>
+<table cellpadding="4" style="border-collapse: collapse;">
+{{range .Categories}}<tr><td>{{.Name}}</td><td align="right">{{.Expenses}}</td></tr>
+{{end}}</table>
+{{end}}
+<h3>Coming up in the {{.Horizon}}</h3>
+{{if .Upcoming}}<ul>
+{{range .Upcoming}}<li>{{.When}} &mdash; {{.Title}}{{if .Amount}} ({{.Amount}}){{end}}</li>
+{{end}}</ul>{{else}}<p>Nothing scheduled.</p>{{end}}
+<p style="color: #888; font-size: 12px;">You are receiving this because you subscribed to {{.Frequency}} digests.</p>
+</body>
+</html>
+`))
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+// draftDuplicateWindow is how far apart in time a draft and an existing
+// transaction can be and still look like the same one. Banks often date an
+// alert by when it was posted rather than when the card was used.
+const draftDuplicateWindow = 24 * time.Hour
+
+var (
+	ErrDraftNotFound = errors.New("draft not found")
+	ErrDraftResolved = errors.New("draft has already been approved or rejected")
+)
+
+// DraftApproval holds the changes to make to a draft as it becomes a
+// transaction. Nil fields keep what the draft has.
+type DraftApproval struct {
+	Type          *models.TransactionType `json:"type"`
+	Amount        *float64                `json:"amount"`
+	Currency      *string                 `json:"currency"`
+	Date          *time.Time              `json:"date"`
+	Description   *string                 `json:"description"`
+	CategoryID    *string                 `json:"categoryId"`
+	SubcategoryID *string                 `json:"subcategoryId"`
+	TagIDs        []string                `json:"tagIds"`
+}
+
+type DraftService interface {
+	List(ctx context.Context, scope repositories.Scope, status models.DraftStatus, limit, offset int64) ([]models.TransactionDraft, int64, error)
+	Get(ctx context.Context, scope repositories.Scope, id string) (*models.TransactionDraft, error)
+	// Approve records the draft as a transaction. Receipt files of an
+	// emailed draft are attached to it.
+	Approve(ctx context.Context, scope repositories.Scope, id string, a DraftApproval) (*models.TransactionDraft, error)
+	// Reject marks the draft rejected. It is kept so the same message is
+	// recognised if it comes in again.
+	Reject(ctx context.Context, scope repositories.Scope, id string) error
+}
+
+type draftService struct {
+	repo         repositories.DraftRepository
+	transactions TransactionService
+	emails       InboundEmailService
+}
+
+func NewDraftService(repo repositories.DraftRepository, transactions TransactionService, emails InboundEmailService) DraftService {
+	return &draftService{repo: repo, transactions: transactions, emails: emails}
+}
+
+func (s *draftService) List(ctx context.Context, scope repositories.Scope, status models.DraftStatus, limit, offset int64) ([]models.TransactionDraft, int64, error) {
+	return s.repo.List(ctx, scope, status, limit, offset)
+}
+
+func (s *draftService) Get(ctx context.Context, scope repositories.Scope, id string) (*models.TransactionDraft, error) {
+	d, err := s.repo.FindByID(ctx, id, scope)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, ErrDraftNotFound
+	}
+	return d, nil
+}
+
+func (s *draftService) Approve(ctx context.Context, scope repositories.Scope, id string, a DraftApproval) (*models.TransactionDraft, error) {
+	d, err := s.Get(ctx, scope, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Status != models.DraftPending {
+		return nil, ErrDraftResolved
+	}
+
+	tx := &models.Transaction{
+		Type:          d.Type,
+		Amount:        d.Amount,
+		Currency:      d.Currency,
+		Date:          d.Date,
+		Description:   d.Description,
+		CategoryID:    a.CategoryID,
+		SubcategoryID: a.SubcategoryID,
+		TagIDs:        a.TagIDs,
+	}
+	if a.Type != nil {
+		tx.Type = *a.Type
+	}
+	if a.Amount != nil {
+		tx.Amount = *a.Amount
+	}
+	if a.Currency != nil {
+		tx.Currency = strings.ToUpper(strings.TrimSpace(*a.Currency))
+	}
+	if a.Date != nil {
+		tx.Date = a.Date.UTC()
+	}
+	if a.Description != nil {
+		tx.Description = a.Description
+	}
+	if tx.Type == "" {
+		return nil, errors.New("type is required: the message did not say")
+	}
+	if tx.Currency == "" {
+		return nil, errors.New("currency is required: the message did not say")
+	}
+
+	// The transaction is created first so a failure leaves the draft
+	// pending. If someone else resolved the draft meanwhile, it is undone.
+	created, err := s.transactions.Create(ctx, scope, tx)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.Resolve(ctx, scope, d.ID, models.DraftApproved, created.ID)
+	if err == nil && !ok {
+		err = ErrDraftResolved
+	}
+	if err != nil {
+		if derr := s.transactions.Delete(ctx, scope, created.ID); derr != nil {
+			log.Printf("drafts: undo transaction %s for draft %s: %v", created.ID, d.ID, derr)
+		}
+		return nil, err
+	}
+	if d.EmailID != "" {
+		// The transaction stands without its files; they can still be
+		// downloaded from the email.
+		if err := s.emails.AttachToTransaction(ctx, scope, d.EmailID, created.ID); err != nil {
+			log.Printf("drafts: attach email %s to transaction %s: %v", d.EmailID, created.ID, err)
+		}
+	}
+	d.Status = models.DraftApproved
+	d.TransactionID = created.ID
+	d.UpdatedAt = time.Now().UTC()
+	return d, nil
+}
+
+func (s *draftService) Reject(ctx context.Context, scope repositories.Scope, id string) error {
+	d, err := s.Get(ctx, scope, id)
+	if err != nil {
+		return err
+	}
+	ok, err := s.repo.Resolve(ctx, scope, d.ID, models.DraftRejected, "")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDraftResolved
+	}
+	return nil
+}
+
+// findSimilarTransaction returns a recorded transaction that may be the one
+// the draft describes: same type, amount and currency within
+// draftDuplicateWindow. Transactions approved from other drafts are
+// skipped; those came from different messages, so were different payments.
+func findSimilarTransaction(ctx context.Context, transactions TransactionService, drafts repositories.DraftRepository,
+	scope repositories.Scope, d *models.TransactionDraft) (*models.Transaction, error) {
+	from := d.Date.Add(-draftDuplicateWindow)
+	to := d.Date.Add(draftDuplicateWindow)
+	txs, _, err := transactions.List(ctx, repositories.TransactionFilter{
+		Scope: scope,
+		Type:  &d.Type,
+		From:  &from,
+		To:    &to,
+		Limit: 200,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range txs {
+		tx := &txs[i]
+		if math.Abs(tx.Amount-d.Amount) >= 0.005 {
+			continue
+		}
+		if d.Currency != "" && !strings.EqualFold(tx.Currency, d.Currency) {
+			continue
+		}
+		from, err := drafts.FindByTransaction(ctx, scope, tx.ID)
+		if err != nil {
+			return nil, err
+		}
+		if from == nil {
+			return tx, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,388 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/archive"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	exportLease         = 10 * time.Minute
+	exportRetention     = 7 * 24 * time.Hour
+	exportLinkTTL       = 15 * time.Minute
+	exportBatchSize     = 5
+	exportBuildDeadline = 5 * time.Minute
+)
+
+var (
+	ErrExportNotFound      = errors.New("export not found")
+	ErrExportNotReady      = errors.New("export is not ready")
+	ErrInvalidDownloadLink = errors.New("invalid or expired download link")
+)
+
+// AccountRepositories are the user-scoped repositories that exports read
+// from and imports write to.
+type AccountRepositories struct {
+	Users                 repositories.UserRepository
+	Categories            repositories.CategoryRepository
+	Tags                  repositories.TagRepository
+	Transactions          repositories.TransactionRepository
+	Reminders             repositories.ReminderRepository
+	SubscriptionDecisions repositories.SubscriptionDecisionRepository
+	Insights              repositories.InsightRepository
+	Digests               repositories.DigestRepository
+	APITokens             repositories.APITokenRepository
+	Contacts              repositories.ContactRepository
+	Splits                repositories.SplitRepository
+	Households            repositories.HouseholdRepository
+	Attachments           repositories.AttachmentRepository
+	AlertTemplates        repositories.AlertTemplateRepository
+	Drafts                repositories.DraftRepository
+	InboundEmails         repositories.InboundEmailRepository
+}
+
+type ExportService interface {
+	// Request queues an export, or returns the one already in progress.
+	Request(ctx context.Context, userID string) (*models.Export, error)
+	Get(ctx context.Context, userID, id string) (*models.Export, error)
+	List(ctx context.Context, userID string) ([]models.Export, error)
+	// DownloadURL returns a signed, time-limited path for a ready export.
+	DownloadURL(e *models.Export) (string, time.Time)
+	// OpenDownload checks a signed link and opens the archive.
+	OpenDownload(ctx context.Context, id string, expires int64, sig string) (*models.Export, io.ReadCloser, error)
+	// ProcessPending builds queued exports and removes expired ones. It is
+	// run by the scheduler.
+	ProcessPending(ctx context.Context) error
+	// Collect gathers everything the archive holds for a user.
+	Collect(ctx context.Context, userID string) (*archive.Contents, error)
+}
+
+type exportService struct {
+	repo        repositories.ExportRepository
+	src         AccountRepositories
+	attachments AttachmentService
+	emails      InboundEmailService
+	signKey     []byte
+	now         func() time.Time
+}
+
+func NewExportService(repo repositories.ExportRepository, src AccountRepositories, attachments AttachmentService, emails InboundEmailService, secret string) ExportService {
+	key := sha256.Sum256([]byte("export-download\x00" + secret))
+	return &exportService{
+		repo:        repo,
+		src:         src,
+		attachments: attachments,
+		emails:      emails,
+		signKey:     key[:],
+		now:         time.Now,
+	}
+}
+
+func (s *exportService) Request(ctx context.Context, userID string) (*models.Export, error) {
+	active, err := s.repo.FindActive(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return active, nil
+	}
+	e := &models.Export{UserID: userID, Status: models.ExportPending}
+	if err := s.repo.Create(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (s *exportService) Get(ctx context.Context, userID, id string) (*models.Export, error) {
+	e, err := s.repo.FindByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, ErrExportNotFound
+	}
+	return e, nil
+}
+
+func (s *exportService) List(ctx context.Context, userID string) ([]models.Export, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+func (s *exportService) DownloadURL(e *models.Export) (string, time.Time) {
+	expires := s.now().Add(exportLinkTTL)
+	if e.ExpiresAt != nil && e.ExpiresAt.Before(expires) {
+		expires = *e.ExpiresAt
+	}
+	exp := expires.Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(exp, 10))
+	q.Set("sig", s.sign(e.ID, exp))
+	return "/api/v1/export/" + e.ID + "/download?" + q.Encode(), expires
+}
+
+func (s *exportService) OpenDownload(ctx context.Context, id string, expires int64, sig string) (*models.Export, io.ReadCloser, error) {
+	if s.now().Unix() > expires || !hmac.Equal([]byte(sig), []byte(s.sign(id, expires))) {
+		return nil, nil, ErrInvalidDownloadLink
+	}
+	e, err := s.repo.FindByID(ctx, id, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if e == nil {
+		return nil, nil, ErrExportNotFound
+	}
+	if e.Status != models.ExportReady || e.FileID == "" {
+		return nil, nil, ErrExportNotReady
+	}
+	rc, err := s.repo.OpenFile(ctx, e.FileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, rc, nil
+}
+
+func (s *exportService) sign(id string, expires int64) string {
+	mac := hmac.New(sha256.New, s.signKey)
+	fmt.Fprintf(mac, "%s.%d", id, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *exportService) ProcessPending(ctx context.Context) error {
+	expired, err := s.repo.ListExpired(ctx, s.now().UTC())
+	if err != nil {
+		return err
+	}
+	for i := range expired {
+		if err := s.repo.Delete(ctx, &expired[i]); err != nil {
+			return err
+		}
+	}
+
+	for range exportBatchSize {
+		e, err := s.repo.ClaimNext(ctx, exportLease)
+		if err != nil {
+			return err
+		}
+		if e == nil {
+			return nil
+		}
+		if err := s.build(ctx, e); err != nil {
+			log.Printf("export: build %s for user %s: %v", e.ID, e.UserID, err)
+			if err := s.repo.MarkFailed(ctx, e.ID, "export failed"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *exportService) build(ctx context.Context, e *models.Export) error {
+	ctx, cancel := context.WithTimeout(ctx, exportBuildDeadline)
+	defer cancel()
+
+	contents, err := s.Collect(ctx, e.UserID)
+	if err != nil {
+		return err
+	}
+
+	now := s.now().UTC()
+	name := fmt.Sprintf("personal-assistant-export-%s.zip", now.Format("20060102-150405"))
+	file, err := s.repo.CreateFile(ctx, name)
+	if err != nil {
+		return err
+	}
+	cw := &countingWriter{w: file}
+	manifest, err := archive.Write(cw, contents, now)
+	if err != nil {
+		_ = file.Abort()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	fileID, _ := file.FileID.(primitive.ObjectID)
+	expires := now.Add(exportRetention)
+	e.FileID = fileID.Hex()
+	e.FileName = name
+	e.Size = cw.n
+	e.Counts = manifest.Counts
+	e.CompletedAt = &now
+	e.ExpiresAt = &expires
+	return s.repo.MarkReady(ctx, e)
+}
+
+func (s *exportService) Collect(ctx context.Context, userID string) (*archive.Contents, error) {
+	u, err := s.src.Users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errors.New("user not found")
+	}
+	c := &archive.Contents{
+		UserID: u.ID,
+		Profile: archive.Profile{
+			Name:          u.Name,
+			Email:         u.Email,
+			EmailVerified: u.EmailVerified,
+			CreatedAt:     u.CreatedAt,
+		},
+	}
+
+	// Exports cover personal data; household data belongs to every member.
+	scope := repositories.PersonalScope(userID)
+	if c.Categories, err = s.src.Categories.List(ctx, scope, nil); err != nil {
+		return nil, err
+	}
+	if c.Tags, err = s.src.Tags.List(ctx, scope); err != nil {
+		return nil, err
+	}
+	if c.Transactions, _, err = s.src.Transactions.List(ctx, repositories.TransactionFilter{
+		Scope:       scope,
+		SortDateAsc: true,
+	}); err != nil {
+		return nil, err
+	}
+	if c.Reminders, err = s.src.Reminders.List(ctx, repositories.ReminderFilter{Scope: scope}); err != nil {
+		return nil, err
+	}
+	if c.SubscriptionDecisions, err = s.src.SubscriptionDecisions.ListByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if c.Insights, _, err = s.src.Insights.List(ctx, repositories.InsightFilter{
+		UserID:           userID,
+		IncludeDismissed: true,
+	}); err != nil {
+		return nil, err
+	}
+	if c.Digests, err = s.src.Digests.ListByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if c.APITokens, err = s.src.APITokens.ListByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if c.AlertTemplates, err = s.src.AlertTemplates.ListByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if c.Drafts, _, err = s.src.Drafts.List(ctx, scope, "", 0, 0); err != nil {
+		return nil, err
+	}
+	if err := s.collectSplits(ctx, c, u); err != nil {
+		return nil, err
+	}
+	if err := s.collectFiles(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// collectSplits adds the user's contacts, shared expenses and household
+// memberships. Splits and settlements with other users are included from
+// both sides.
+func (s *exportService) collectSplits(ctx context.Context, c *archive.Contents, u *models.User) error {
+	var err error
+	if c.Contacts, err = s.src.Contacts.List(ctx, u.ID); err != nil {
+		return err
+	}
+	if c.ContactLinks, err = s.src.Contacts.ListLinks(ctx, u.ID, u.Email); err != nil {
+		return err
+	}
+	if c.Splits, err = s.src.Splits.ListSplits(ctx, u.ID); err != nil {
+		return err
+	}
+	if c.Settlements, _, err = s.src.Splits.ListSettlements(ctx, u.ID, 0, 0); err != nil {
+		return err
+	}
+	if c.LedgerEntries, err = s.src.Splits.ListEntries(ctx, []string{u.ID}); err != nil {
+		return err
+	}
+
+	memberships, err := s.src.Households.ListMemberships(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, len(memberships))
+	for i, m := range memberships {
+		ids[i] = m.HouseholdID
+	}
+	households, err := s.src.Households.FindByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	names := map[string]string{}
+	for _, h := range households {
+		names[h.ID] = h.Name
+	}
+	for _, m := range memberships {
+		c.Households = append(c.Households, archive.Membership{
+			HouseholdID: m.HouseholdID,
+			Name:        names[m.HouseholdID],
+			Role:        m.Role,
+			JoinedAt:    m.JoinedAt,
+		})
+	}
+	return nil
+}
+
+// collectFiles adds attachment and inbound email metadata. The stored
+// files are only read while the archive is written.
+func (s *exportService) collectFiles(ctx context.Context, c *archive.Contents) error {
+	var err error
+	if c.Attachments, err = s.src.Attachments.ListAllByUser(ctx, c.UserID); err != nil {
+		return err
+	}
+	scope := repositories.PersonalScope(c.UserID)
+	for _, a := range c.Attachments {
+		id := a.ID
+		c.Blobs = append(c.Blobs, archive.Blob{
+			Path: archive.AttachmentPath(id),
+			Open: func() (io.ReadCloser, error) {
+				_, rc, err := s.attachments.Open(ctx, scope, id, false)
+				return rc, err
+			},
+		})
+	}
+
+	if c.InboundEmails, err = s.src.InboundEmails.ListAllByUser(ctx, c.UserID); err != nil {
+		return err
+	}
+	for _, e := range c.InboundEmails {
+		id := e.ID
+		open := func(file int) func() (io.ReadCloser, error) {
+			return func() (io.ReadCloser, error) {
+				_, rc, err := s.emails.Open(ctx, c.UserID, id, file)
+				return rc, err
+			}
+		}
+		c.Blobs = append(c.Blobs, archive.Blob{Path: archive.InboundEmailPath(id), Open: open(-1)})
+		for i := range e.Files {
+			c.Blobs = append(c.Blobs, archive.Blob{Path: archive.InboundEmailFilePath(id, i), Open: open(i)})
+		}
+	}
+	return nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
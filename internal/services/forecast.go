@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+const (
+	DefaultForecastDays = 90
+	MaxForecastDays     = 365
+
+	// Discretionary spending is estimated from this many days of history.
+	forecastLookbackDays = 90
+)
+
+type ForecastItem struct {
+	Date       time.Time              `json:"date"`
+	Source     string                 `json:"source"` // "reminder" or "transaction"
+	SourceID   string                 `json:"sourceId"`
+	Title      string                 `json:"title"`
+	Type       models.TransactionType `json:"type"`
+	Amount     float64                `json:"amount"`
+	CategoryID *string                `json:"categoryId,omitempty"`
+}
+
+type ForecastBaseline struct {
+	CategoryID   string  `json:"categoryId"`
+	CategoryName string  `json:"categoryName"`
+	DailyAverage float64 `json:"dailyAverage"`
+}
+
+type ForecastPoint struct {
+	Date      time.Time `json:"date"`
+	Scheduled float64   `json:"scheduled"` // net of known items for the day
+	Baseline  float64   `json:"baseline"`
+	Balance   float64   `json:"balance"`
+}
+
+type ForecastReport struct {
+	Period struct {
+		Start time.Time `json:"start"`
+		End   time.Time `json:"end"`
+	} `json:"period"`
+	StartingBalance   float64            `json:"startingBalance"`
+	DailyBaseline     float64            `json:"dailyBaseline"`
+	Baseline          []ForecastBaseline `json:"baseline"`
+	Items             []ForecastItem     `json:"items"`
+	Points            []ForecastPoint    `json:"points"`
+	Lowest            ForecastPoint      `json:"lowest"`
+	FirstNegativeDate *time.Time         `json:"firstNegativeDate,omitempty"`
+}
+
+// GetForecast projects the end-of-day balance for each of the next `days`
+// days, starting tomorrow (UTC). The starting balance is the net of every
+// transaction before that; known future items come from transactions dated
+// in the window and active reminders carrying an amount. Discretionary
+// spending is the per-category daily average of the last 90 days, skipping
+// categories already covered by an amount-bearing reminder so they are not
+// counted twice.
+func (s *reportService) GetForecast(ctx context.Context, scope repositories.Scope, days int) (*ForecastReport, error) {
+	if days <= 0 || days > MaxForecastDays {
+		return nil, errors.New("days must be between 1 and 365")
+	}
+
+	now := s.now().UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	end := start.AddDate(0, 0, days)
+
+	history, err := s.txRepo.ListByDateRange(ctx, scope, time.Time{}, start.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+	scheduled, err := s.txRepo.ListByDateRange(ctx, scope, start, end.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+	active := true
+	reminders, err := s.reminderRepo.List(ctx, repositories.ReminderFilter{Scope: scope, IsActive: &active})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ForecastReport{}
+	report.Period.Start = start
+	report.Period.End = end.Add(-time.Nanosecond)
+
+	for _, tx := range history {
+		report.StartingBalance += signedAmount(tx.Type, tx.Amount)
+	}
+
+	coveredCats := map[string]bool{}
+	for _, r := range reminders {
+		if r.Amount == nil || *r.Amount <= 0 {
+			continue
+		}
+		typ := models.TransactionTypeExpense
+		if r.Type != nil {
+			typ = *r.Type
+		}
+		if r.CategoryID != nil {
+			coveredCats[*r.CategoryID] = true
+		}
+		for _, at := range occurrencesBetween(r.DueAt.UTC(), r.RepeatInterval, start, end) {
+			report.Items = append(report.Items, ForecastItem{
+				Date:       at,
+				Source:     "reminder",
+				SourceID:   r.ID,
+				Title:      r.Title,
+				Type:       typ,
+				Amount:     *r.Amount,
+				CategoryID: r.CategoryID,
+			})
+		}
+	}
+	for _, tx := range scheduled {
+		title := ""
+		if tx.Description != nil {
+			title = *tx.Description
+		}
+		report.Items = append(report.Items, ForecastItem{
+			Date:       tx.Date.UTC(),
+			Source:     "transaction",
+			SourceID:   tx.ID,
+			Title:      title,
+			Type:       tx.Type,
+			Amount:     tx.Amount,
+			CategoryID: tx.CategoryID,
+		})
+	}
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		if !report.Items[i].Date.Equal(report.Items[j].Date) {
+			return report.Items[i].Date.Before(report.Items[j].Date)
+		}
+		return report.Items[i].SourceID < report.Items[j].SourceID
+	})
+
+	baseline, err := s.discretionaryBaseline(ctx, scope, history, start, coveredCats)
+	if err != nil {
+		return nil, err
+	}
+	report.Baseline = baseline
+	for _, b := range baseline {
+		report.DailyBaseline += b.DailyAverage
+	}
+
+	balance := report.StartingBalance
+	next := 0
+	for d := 0; d < days; d++ {
+		dayStart := start.AddDate(0, 0, d)
+		dayEnd := dayStart.AddDate(0, 0, 1)
+
+		point := ForecastPoint{Date: dayStart, Baseline: report.DailyBaseline}
+		for next < len(report.Items) && report.Items[next].Date.Before(dayEnd) {
+			point.Scheduled += signedAmount(report.Items[next].Type, report.Items[next].Amount)
+			next++
+		}
+		balance += point.Scheduled - point.Baseline
+		point.Balance = balance
+		report.Points = append(report.Points, point)
+
+		if d == 0 || point.Balance < report.Lowest.Balance {
+			report.Lowest = point
+		}
+		if point.Balance < 0 && report.FirstNegativeDate == nil {
+			date := point.Date
+			report.FirstNegativeDate = &date
+		}
+	}
+
+	return report, nil
+}
+
+func (s *reportService) discretionaryBaseline(ctx context.Context, scope repositories.Scope, history []models.Transaction, start time.Time, covered map[string]bool) ([]ForecastBaseline, error) {
+	lookbackFrom := start.AddDate(0, 0, -forecastLookbackDays)
+
+	sums := map[string]float64{}
+	for _, tx := range history {
+		if tx.Type != models.TransactionTypeExpense || tx.Date.Before(lookbackFrom) {
+			continue
+		}
+		id := ""
+		if tx.CategoryID != nil {
+			id = *tx.CategoryID
+		}
+		if covered[id] {
+			continue
+		}
+		sums[id] += tx.Amount
+	}
+	if len(sums) == 0 {
+		return []ForecastBaseline{}, nil
+	}
+
+	cats, err := s.catRepo.List(ctx, scope, nil)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]string{"": uncategorisedName}
+	for _, c := range cats {
+		names[c.ID] = c.Name
+	}
+
+	res := make([]ForecastBaseline, 0, len(sums))
+	for id, sum := range sums {
+		res = append(res, ForecastBaseline{
+			CategoryID:   id,
+			CategoryName: names[id],
+			DailyAverage: sum / forecastLookbackDays,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].DailyAverage != res[j].DailyAverage {
+			return res[i].DailyAverage > res[j].DailyAverage
+		}
+		return res[i].CategoryID < res[j].CategoryID
+	})
+	return res, nil
+}
+
+func signedAmount(t models.TransactionType, amount float64) float64 {
+	switch t {
+	case models.TransactionTypeIncome:
+		return amount
+	case models.TransactionTypeExpense:
+		return -amount
+	}
+	return 0
+}
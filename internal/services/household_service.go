@@ -0,0 +1,489 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/mailer"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+const householdInvitationTTL = 7 * 24 * time.Hour
+
+var (
+	ErrHouseholdNotFound  = errors.New("household not found")
+	ErrNotHouseholdOwner  = errors.New("only household owners can do this")
+	ErrInvitationNotFound = errors.New("invitation not found or no longer open")
+	ErrAlreadyMember      = errors.New("already a member of this household")
+	ErrLastOwner          = errors.New("a household needs at least one owner; make another member an owner first")
+	ErrEmailNotVerified   = errors.New("verify your email address before accepting invitations")
+)
+
+// HouseholdData are the repositories holding data that can be shared in a
+// household, so it can be removed with the household.
+type HouseholdData interface {
+	DeleteAllByHousehold(ctx context.Context, householdID string) (int64, error)
+}
+
+type HouseholdSummary struct {
+	models.Household
+	Role models.HouseholdRole `json:"role"`
+}
+
+type HouseholdDetails struct {
+	models.Household
+	Role        models.HouseholdRole         `json:"role"`
+	Members     []models.HouseholdMember     `json:"members"`
+	Invitations []models.HouseholdInvitation `json:"invitations,omitempty"` // owners only
+}
+
+type HouseholdService interface {
+	Create(ctx context.Context, userID, name string) (*models.Household, error)
+	List(ctx context.Context, userID string) ([]HouseholdSummary, error)
+	Get(ctx context.Context, userID, id string) (*HouseholdDetails, error)
+	Rename(ctx context.Context, userID, id, name string) (*models.Household, error)
+	// Delete removes the household and all of its shared data.
+	Delete(ctx context.Context, userID, id string) error
+
+	Invite(ctx context.Context, userID, id, email string, role models.HouseholdRole) (*models.HouseholdInvitation, error)
+	RevokeInvitation(ctx context.Context, userID, id, invitationID string) error
+	// PendingInvitations lists open invitations to the user's email address.
+	PendingInvitations(ctx context.Context, userID string) ([]models.HouseholdInvitation, error)
+	AcceptInvitation(ctx context.Context, userID, invitationID string) (*models.Household, error)
+	DeclineInvitation(ctx context.Context, userID, invitationID string) error
+
+	UpdateMemberRole(ctx context.Context, userID, id, memberID string, role models.HouseholdRole) error
+	// RemoveMember removes another member (owners only) or, when memberID
+	// is the caller, leaves the household.
+	RemoveMember(ctx context.Context, userID, id, memberID string) error
+
+	// MemberRole returns the user's role in a household, or "" if they are
+	// not a member. It backs the workspace middleware.
+	MemberRole(ctx context.Context, householdID, userID string) (models.HouseholdRole, error)
+	// DeleteAllByUser removes the user from every household, deleting the
+	// ones they are the last owner of. It runs when an account is purged.
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+}
+
+type householdService struct {
+	repo   repositories.HouseholdRepository
+	users  repositories.UserRepository
+	data   []HouseholdData
+	mailer mailer.Mailer
+	appURL string
+}
+
+func NewHouseholdService(repo repositories.HouseholdRepository, users repositories.UserRepository, m mailer.Mailer, appURL string, data ...HouseholdData) HouseholdService {
+	return &householdService{
+		repo:   repo,
+		users:  users,
+		data:   data,
+		mailer: m,
+		appURL: strings.TrimRight(appURL, "/"),
+	}
+}
+
+func (s *householdService) Create(ctx context.Context, userID, name string) (*models.Household, error) {
+	name, err := validHouseholdName(name)
+	if err != nil {
+		return nil, err
+	}
+	h := &models.Household{Name: name, CreatedBy: userID}
+	if err := s.repo.Create(ctx, h); err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddMember(ctx, &models.HouseholdMember{
+		HouseholdID: h.ID,
+		UserID:      userID,
+		Role:        models.RoleOwner,
+	}); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (s *householdService) List(ctx context.Context, userID string) ([]HouseholdSummary, error) {
+	memberships, err := s.repo.ListMemberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	roles := make(map[string]models.HouseholdRole, len(memberships))
+	ids := make([]string, 0, len(memberships))
+	for _, m := range memberships {
+		roles[m.HouseholdID] = m.Role
+		ids = append(ids, m.HouseholdID)
+	}
+	households, err := s.repo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]HouseholdSummary, 0, len(households))
+	for _, h := range households {
+		res = append(res, HouseholdSummary{Household: h, Role: roles[h.ID]})
+	}
+	return res, nil
+}
+
+func (s *householdService) Get(ctx context.Context, userID, id string) (*HouseholdDetails, error) {
+	h, role, err := s.load(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.repo.ListMembers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range members {
+		u, err := s.users.FindByID(ctx, members[i].UserID)
+		if err != nil {
+			return nil, err
+		}
+		if u != nil {
+			members[i].Name = u.Name
+			members[i].Email = u.Email
+		}
+	}
+	d := &HouseholdDetails{Household: *h, Role: role, Members: members}
+	if role == models.RoleOwner {
+		if d.Invitations, err = s.repo.ListPendingInvitations(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (s *householdService) Rename(ctx context.Context, userID, id, name string) (*models.Household, error) {
+	name, err := validHouseholdName(name)
+	if err != nil {
+		return nil, err
+	}
+	h, err := s.requireOwner(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Rename(ctx, id, name); err != nil {
+		return nil, err
+	}
+	h.Name = name
+	return h, nil
+}
+
+func (s *householdService) Delete(ctx context.Context, userID, id string) error {
+	if _, err := s.requireOwner(ctx, id, userID); err != nil {
+		return err
+	}
+	return s.deleteHousehold(ctx, id)
+}
+
+func (s *householdService) deleteHousehold(ctx context.Context, id string) error {
+	for _, d := range s.data {
+		if _, err := d.DeleteAllByHousehold(ctx, id); err != nil {
+			return err
+		}
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *householdService) Invite(ctx context.Context, userID, id, email string, role models.HouseholdRole) (*models.HouseholdInvitation, error) {
+	h, err := s.requireOwner(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.Valid() {
+		return nil, errors.New("role must be owner, editor or viewer")
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if _, err := mail.ParseAddress(email); err != nil || strings.ContainsAny(email, "<> ") {
+		return nil, errors.New("invalid email")
+	}
+
+	if invitee, err := s.users.FindByEmail(ctx, email); err != nil {
+		return nil, err
+	} else if invitee != nil {
+		m, err := s.repo.FindMember(ctx, id, invitee.ID)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			return nil, ErrAlreadyMember
+		}
+	}
+
+	inviter, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	inv := &models.HouseholdInvitation{
+		HouseholdID:   id,
+		HouseholdName: h.Name,
+		Email:         email,
+		Role:          role,
+		InvitedBy:     userID,
+		ExpiresAt:     time.Now().UTC().Add(householdInvitationTTL),
+	}
+	if err := s.repo.CreateInvitation(ctx, inv); err != nil {
+		return nil, err
+	}
+
+	from := "Someone"
+	if inviter != nil {
+		from = inviter.Name
+	}
+	msg := mailer.Message{
+		To:      email,
+		Subject: fmt.Sprintf("You're invited to join %s", h.Name),
+		Text: fmt.Sprintf(
+			"%s invited you to share finances in the household %q as %s.\n\n"+
+				"Sign in (or create an account with this address) to accept or decline:\n%s\n\n"+
+				"The invitation expires on %s.\n",
+			from, h.Name, role, s.appURL+"/households/invitations", inv.ExpiresAt.Format("2 Jan 2006")),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("household: send invitation %s: %v", inv.ID, err)
+	}
+	return inv, nil
+}
+
+func (s *householdService) RevokeInvitation(ctx context.Context, userID, id, invitationID string) error {
+	if _, err := s.requireOwner(ctx, id, userID); err != nil {
+		return err
+	}
+	inv, err := s.repo.FindInvitation(ctx, invitationID)
+	if err != nil {
+		return err
+	}
+	if inv == nil || inv.HouseholdID != id {
+		return ErrInvitationNotFound
+	}
+	ok, err := s.repo.RespondInvitation(ctx, invitationID, models.InvitationRevoked)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+func (s *householdService) PendingInvitations(ctx context.Context, userID string) ([]models.HouseholdInvitation, error) {
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errors.New("user not found")
+	}
+	return s.repo.ListInvitationsForEmail(ctx, u.Email)
+}
+
+// invitationFor returns an open invitation addressed to the user.
+func (s *householdService) invitationFor(ctx context.Context, userID, invitationID string) (*models.User, *models.HouseholdInvitation, error) {
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u == nil {
+		return nil, nil, errors.New("user not found")
+	}
+	inv, err := s.repo.FindInvitation(ctx, invitationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if inv == nil || inv.Email != u.Email || inv.Status != models.InvitationPending || !inv.ExpiresAt.After(time.Now()) {
+		return nil, nil, ErrInvitationNotFound
+	}
+	return u, inv, nil
+}
+
+func (s *householdService) AcceptInvitation(ctx context.Context, userID, invitationID string) (*models.Household, error) {
+	u, inv, err := s.invitationFor(ctx, userID, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	// Otherwise anyone could sign up with the invited address and join.
+	if !u.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+	h, err := s.repo.FindByID(ctx, inv.HouseholdID)
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		return nil, ErrInvitationNotFound
+	}
+
+	ok, err := s.repo.RespondInvitation(ctx, inv.ID, models.InvitationAccepted)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvitationNotFound
+	}
+	existing, err := s.repo.FindMember(ctx, h.ID, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return h, nil
+	}
+	if err := s.repo.AddMember(ctx, &models.HouseholdMember{
+		HouseholdID: h.ID,
+		UserID:      u.ID,
+		Role:        inv.Role,
+	}); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (s *householdService) DeclineInvitation(ctx context.Context, userID, invitationID string) error {
+	_, inv, err := s.invitationFor(ctx, userID, invitationID)
+	if err != nil {
+		return err
+	}
+	ok, err := s.repo.RespondInvitation(ctx, inv.ID, models.InvitationDeclined)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+func (s *householdService) UpdateMemberRole(ctx context.Context, userID, id, memberID string, role models.HouseholdRole) error {
+	if _, err := s.requireOwner(ctx, id, userID); err != nil {
+		return err
+	}
+	if !role.Valid() {
+		return errors.New("role must be owner, editor or viewer")
+	}
+	m, err := s.repo.FindMember(ctx, id, memberID)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return errors.New("member not found")
+	}
+	if m.Role == models.RoleOwner && role != models.RoleOwner {
+		if err := s.keepAnOwner(ctx, id); err != nil {
+			return err
+		}
+	}
+	_, err = s.repo.UpdateMemberRole(ctx, id, memberID, role)
+	return err
+}
+
+func (s *householdService) RemoveMember(ctx context.Context, userID, id, memberID string) error {
+	if memberID != userID {
+		if _, err := s.requireOwner(ctx, id, userID); err != nil {
+			return err
+		}
+	}
+	m, err := s.repo.FindMember(ctx, id, memberID)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		if memberID == userID {
+			return ErrHouseholdNotFound
+		}
+		return errors.New("member not found")
+	}
+	if m.Role == models.RoleOwner {
+		if err := s.keepAnOwner(ctx, id); err != nil {
+			return err
+		}
+	}
+	_, err = s.repo.RemoveMember(ctx, id, memberID)
+	return err
+}
+
+func (s *householdService) keepAnOwner(ctx context.Context, id string) error {
+	owners, err := s.repo.CountOwners(ctx, id)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func (s *householdService) MemberRole(ctx context.Context, householdID, userID string) (models.HouseholdRole, error) {
+	m, err := s.repo.FindMember(ctx, householdID, userID)
+	if err != nil || m == nil {
+		return "", err
+	}
+	return m.Role, nil
+}
+
+func (s *householdService) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	memberships, err := s.repo.ListMemberships(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range memberships {
+		if m.Role == models.RoleOwner {
+			owners, err := s.repo.CountOwners(ctx, m.HouseholdID)
+			if err != nil {
+				return 0, err
+			}
+			if owners <= 1 {
+				if err := s.deleteHousehold(ctx, m.HouseholdID); err != nil {
+					return 0, err
+				}
+				continue
+			}
+		}
+		if _, err := s.repo.RemoveMember(ctx, m.HouseholdID, userID); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(memberships)), nil
+}
+
+// load returns a household along with the caller's role in it. Households
+// the caller does not belong to are reported as not found.
+func (s *householdService) load(ctx context.Context, id, userID string) (*models.Household, models.HouseholdRole, error) {
+	role, err := s.MemberRole(ctx, id, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if role == "" {
+		return nil, "", ErrHouseholdNotFound
+	}
+	h, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if h == nil {
+		return nil, "", ErrHouseholdNotFound
+	}
+	return h, role, nil
+}
+
+func (s *householdService) requireOwner(ctx context.Context, id, userID string) (*models.Household, error) {
+	h, role, err := s.load(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role != models.RoleOwner {
+		return nil, ErrNotHouseholdOwner
+	}
+	return h, nil
+}
+
+func validHouseholdName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return "", errors.New("name is required (max 100 characters)")
+	}
+	return name, nil
+}
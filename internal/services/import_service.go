@@ -0,0 +1,707 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/archive"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+// maxImportFileBytes bounds how much of one stored file is read from an
+// archive; the attachment service applies the configured limit after.
+const maxImportFileBytes = 64 << 20
+
+type ImportMode string
+
+const (
+	// ImportMerge adds the archive to the account, reusing categories that
+	// already exist under the same path.
+	ImportMerge ImportMode = "merge"
+	// ImportReplace swaps the account's data for the archive's, in one
+	// database transaction.
+	ImportReplace ImportMode = "replace"
+)
+
+var ErrReplaceUnavailable = errors.New("replace imports need a database that supports transactions")
+
+type ImportOptions struct {
+	Mode   ImportMode
+	DryRun bool
+}
+
+type ImportCounts struct {
+	Created int `json:"created"`
+	Reused  int `json:"reused,omitempty"`
+	Removed int `json:"removed,omitempty"` // existing documents dropped by replace
+}
+
+type ImportResult struct {
+	Mode                  ImportMode     `json:"mode"`
+	DryRun                bool           `json:"dryRun"`
+	FormatVersion         int            `json:"formatVersion"`
+	ExportedAt            time.Time      `json:"exportedAt"`
+	Categories            ImportCounts   `json:"categories"`
+	Tags                  ImportCounts   `json:"tags"`
+	Transactions          ImportCounts   `json:"transactions"`
+	Reminders             ImportCounts   `json:"reminders"`
+	SubscriptionDecisions ImportCounts   `json:"subscriptionDecisions"`
+	Digests               ImportCounts   `json:"digests"`
+	AlertTemplates        ImportCounts   `json:"alertTemplates"`
+	Attachments           ImportCounts   `json:"attachments"`
+	Ignored               map[string]int `json:"ignored,omitempty"`
+	Warnings              []string       `json:"warnings"`
+}
+
+// ImportError reports an archive that cannot be imported as given.
+type ImportError struct{ Err error }
+
+func (e *ImportError) Error() string { return e.Err.Error() }
+func (e *ImportError) Unwrap() error { return e.Err }
+
+type ImportService interface {
+	// Import restores an export archive into the user's account. With
+	// DryRun it only reports what would happen. A failed import undoes
+	// everything it wrote.
+	Import(ctx context.Context, userID string, r io.ReaderAt, size int64, opts ImportOptions) (*ImportResult, error)
+}
+
+type importService struct {
+	exports     ExportService
+	src         AccountRepositories
+	attachments AttachmentService
+	tx          repositories.Transactor
+}
+
+func NewImportService(exports ExportService, src AccountRepositories, attachments AttachmentService, tx repositories.Transactor) ImportService {
+	return &importService{exports: exports, src: src, attachments: attachments, tx: tx}
+}
+
+// importJournal records what an import wrote so it can be undone.
+type importJournal struct {
+	categories   []string
+	tags         []string
+	transactions []string
+	reminders    []string
+	digests      []string
+	templates    []string
+	attachments  []models.Attachment
+	decisions    []string                      // newly created decision IDs
+	overwritten  []models.SubscriptionDecision // originals of upserted decisions
+}
+
+func (s *importService) Import(ctx context.Context, userID string, r io.ReaderAt, size int64, opts ImportOptions) (*ImportResult, error) {
+	if opts.Mode != ImportMerge && opts.Mode != ImportReplace {
+		return nil, &ImportError{errors.New("mode must be merge or replace")}
+	}
+
+	manifest, in, err := archive.Read(r, size)
+	if err != nil {
+		return nil, &ImportError{err}
+	}
+	if opts.Mode == ImportReplace && !opts.DryRun {
+		return s.replace(ctx, userID, manifest, in)
+	}
+	existing, err := s.exports.Collect(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := newImportResult(manifest, in, existing, opts)
+	j := &importJournal{}
+	if err := s.apply(ctx, userID, in, existing, opts, res, j); err != nil {
+		if !opts.DryRun {
+			if rbErr := s.rollback(context.WithoutCancel(ctx), userID, j); rbErr != nil {
+				log.Printf("import: rollback for user %s: %v", userID, rbErr)
+				return nil, fmt.Errorf("import failed (%v) and could not be fully undone: %w", err, rbErr)
+			}
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// replace reads the account, writes the archive and removes what it
+// replaces in one transaction, so a failure at any point leaves the account
+// as it was. Stored files are outside the database: those uploaded are
+// removed again if the transaction fails, and those replaced only once it
+// has committed.
+func (s *importService) replace(ctx context.Context, userID string, manifest *archive.Manifest, in *archive.Contents) (*ImportResult, error) {
+	opts := ImportOptions{Mode: ImportReplace}
+	var res *ImportResult
+	var existing *archive.Contents
+	j := &importJournal{}
+	err := s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if existing, err = s.exports.Collect(ctx, userID); err != nil {
+			return err
+		}
+		res = newImportResult(manifest, in, existing, opts)
+		if err := s.apply(ctx, userID, in, existing, opts, res, j); err != nil {
+			return err
+		}
+		return s.removeReplaced(ctx, userID, existing, in)
+	})
+	if err != nil {
+		s.attachments.RemoveFiles(context.WithoutCancel(ctx), j.attachments)
+		if errors.Is(err, repositories.ErrTransactionsUnsupported) {
+			return nil, ErrReplaceUnavailable
+		}
+		return nil, err
+	}
+	s.attachments.RemoveFiles(ctx, existing.Attachments)
+	return res, nil
+}
+
+func newImportResult(manifest *archive.Manifest, in, existing *archive.Contents, opts ImportOptions) *ImportResult {
+	res := &ImportResult{
+		Mode:          opts.Mode,
+		DryRun:        opts.DryRun,
+		FormatVersion: manifest.Version,
+		ExportedAt:    manifest.CreatedAt,
+		Warnings:      []string{},
+	}
+	// See docs/export-format.md for why these are not restored.
+	for name, n := range map[string]int{
+		"insights":      len(in.Insights),
+		"apiTokens":     len(in.APITokens),
+		"contacts":      len(in.Contacts),
+		"contactLinks":  len(in.ContactLinks),
+		"splits":        len(in.Splits),
+		"settlements":   len(in.Settlements),
+		"ledgerEntries": len(in.LedgerEntries),
+		"households":    len(in.Households),
+		"drafts":        len(in.Drafts),
+		"inboundEmails": len(in.InboundEmails),
+	} {
+		if n == 0 {
+			continue
+		}
+		if res.Ignored == nil {
+			res.Ignored = map[string]int{}
+		}
+		res.Ignored[name] = n
+	}
+	if opts.Mode == ImportReplace {
+		res.Categories.Removed = len(existing.Categories)
+		res.Tags.Removed = len(existing.Tags)
+		res.Transactions.Removed = len(existing.Transactions)
+		res.Reminders.Removed = len(existing.Reminders)
+		res.Digests.Removed = len(existing.Digests)
+		res.AlertTemplates.Removed = len(existing.AlertTemplates)
+		res.Attachments.Removed = len(existing.Attachments)
+	}
+
+	return res
+}
+
+func (s *importService) apply(ctx context.Context, userID string, in, existing *archive.Contents, opts ImportOptions, res *ImportResult, j *importJournal) error {
+	write := !opts.DryRun
+
+	// Categories, parents before children, so ParentID can be remapped.
+	catIDs := map[string]string{}
+	var existingPaths map[string]string
+	if opts.Mode == ImportMerge {
+		existingPaths = pathIndex(existing.Categories)
+	}
+	ordered, broken := orderCategories(in.Categories)
+	if broken > 0 {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("%d categories had a missing or circular parent and were imported at the top level", broken))
+	}
+	inPaths := archive.CategoryPaths(ordered)
+	for _, c := range ordered {
+		if id, ok := existingPaths[strings.ToLower(inPaths[c.ID])]; ok {
+			catIDs[c.ID] = id
+			res.Categories.Reused++
+			continue
+		}
+		oldID := c.ID
+		c.ID = ""
+		c.UserID = userID
+		c.HouseholdID = ""
+		if c.ParentID != nil {
+			if p, ok := catIDs[*c.ParentID]; ok {
+				c.ParentID = &p
+			} else {
+				c.ParentID = nil
+			}
+		}
+		if write {
+			if err := s.src.Categories.Create(ctx, &c); err != nil {
+				return err
+			}
+			j.categories = append(j.categories, c.ID)
+		} else {
+			c.ID = "new:" + oldID
+		}
+		catIDs[oldID] = c.ID
+		res.Categories.Created++
+	}
+
+	// Tags are matched by name, like categories by path.
+	tagIDs := map[string]string{}
+	existingTags := map[string]string{}
+	if opts.Mode == ImportMerge {
+		for _, t := range existing.Tags {
+			existingTags[strings.ToLower(t.Name)] = t.ID
+		}
+	}
+	for _, t := range in.Tags {
+		key := strings.ToLower(t.Name)
+		if id, ok := existingTags[key]; ok {
+			tagIDs[t.ID] = id
+			res.Tags.Reused++
+			continue
+		}
+		oldID := t.ID
+		t.ID = ""
+		t.UserID = userID
+		t.HouseholdID = ""
+		if write {
+			if err := s.src.Tags.Create(ctx, &t); err != nil {
+				return err
+			}
+			j.tags = append(j.tags, t.ID)
+		} else {
+			t.ID = "new:" + oldID
+		}
+		tagIDs[oldID] = t.ID
+		existingTags[key] = t.ID
+		res.Tags.Created++
+	}
+	danglingTags := 0
+	remapTags := func(ids []string) []string {
+		var out []string
+		for _, id := range ids {
+			if n, ok := tagIDs[id]; ok {
+				out = append(out, n)
+			} else {
+				danglingTags++
+			}
+		}
+		return out
+	}
+
+	remap := func(id *string, dangling *int) *string {
+		if id == nil {
+			return nil
+		}
+		if n, ok := catIDs[*id]; ok {
+			return &n
+		}
+		*dangling++
+		return nil
+	}
+
+	dangling := 0
+	txIDs := map[string]string{}
+	invalid := &invalidRecords{}
+	for _, t := range in.Transactions {
+		if err := validateTransaction(&t); err != nil {
+			invalid.add("transactions", err)
+			continue
+		}
+		oldID := t.ID
+		t.ID = ""
+		t.UserID = userID
+		t.HouseholdID = ""
+		t.CategoryID = remap(t.CategoryID, &dangling)
+		t.SubcategoryID = remap(t.SubcategoryID, &dangling)
+		t.TagIDs = remapTags(t.TagIDs)
+		if write {
+			if err := s.src.Transactions.Create(ctx, &t); err != nil {
+				return err
+			}
+			j.transactions = append(j.transactions, t.ID)
+		} else {
+			t.ID = "new:" + oldID
+		}
+		txIDs[oldID] = t.ID
+		res.Transactions.Created++
+	}
+
+	reminderIDs := map[string]string{}
+	for _, rem := range in.Reminders {
+		if rem.RepeatInterval == "" {
+			rem.RepeatInterval = models.RepeatNone
+		}
+		if err := validateReminder(&rem); err != nil {
+			invalid.add("reminders", err)
+			continue
+		}
+		oldID := rem.ID
+		rem.ID = ""
+		rem.UserID = userID
+		rem.HouseholdID = ""
+		rem.CategoryID = remap(rem.CategoryID, &dangling)
+		rem.TagIDs = remapTags(rem.TagIDs)
+		if write {
+			if err := s.src.Reminders.Create(ctx, &rem); err != nil {
+				return err
+			}
+			j.reminders = append(j.reminders, rem.ID)
+			reminderIDs[oldID] = rem.ID
+		}
+		res.Reminders.Created++
+	}
+	if dangling > 0 {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("%d references to categories missing from the archive were cleared", dangling))
+	}
+	if danglingTags > 0 {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("%d references to tags missing from the archive were cleared", danglingTags))
+	}
+
+	// Decisions are keyed per user; importing one overwrites a local
+	// decision about the same recurring charge.
+	current := map[string]models.SubscriptionDecision{}
+	for _, d := range existing.SubscriptionDecisions {
+		current[d.Key] = d
+	}
+	for _, d := range in.SubscriptionDecisions {
+		prev, had := current[d.Key]
+		d.ID = ""
+		d.UserID = userID
+		if d.ReminderID != nil {
+			if id, ok := reminderIDs[*d.ReminderID]; ok {
+				d.ReminderID = &id
+			} else {
+				d.ReminderID = nil
+			}
+		}
+		if write {
+			if err := s.src.SubscriptionDecisions.Upsert(ctx, &d); err != nil {
+				return err
+			}
+			if had {
+				j.overwritten = append(j.overwritten, prev)
+			} else {
+				j.decisions = append(j.decisions, d.ID)
+			}
+		}
+		if had && opts.Mode == ImportMerge {
+			res.SubscriptionDecisions.Reused++
+		} else {
+			res.SubscriptionDecisions.Created++
+		}
+	}
+
+	for _, d := range in.Digests {
+		if opts.Mode == ImportMerge && hasEquivalentDigest(existing.Digests, d) {
+			res.Digests.Reused++
+			continue
+		}
+		if err := validateDigest(&d); err != nil {
+			invalid.add("digests", err)
+			continue
+		}
+		d.ID = ""
+		d.UserID = userID
+		if write {
+			if err := s.src.Digests.Create(ctx, &d); err != nil {
+				return err
+			}
+			j.digests = append(j.digests, d.ID)
+		}
+		res.Digests.Created++
+	}
+	res.Warnings = append(res.Warnings, invalid.warnings()...)
+
+	if err := s.applyAlertTemplates(ctx, userID, in, existing, opts, res, j); err != nil {
+		return err
+	}
+	return s.applyAttachments(ctx, userID, in, txIDs, opts, res, j)
+}
+
+// applyAlertTemplates restores templates that still compile, up to the
+// per-user limit. Merge reuses a template with the same name and pattern.
+func (s *importService) applyAlertTemplates(ctx context.Context, userID string, in, existing *archive.Contents, opts ImportOptions, res *ImportResult, j *importJournal) error {
+	have := map[string]bool{}
+	room := maxAlertTemplates
+	if opts.Mode == ImportMerge {
+		for _, t := range existing.AlertTemplates {
+			have[strings.ToLower(t.Name)+"\x00"+t.Pattern] = true
+		}
+		room -= len(existing.AlertTemplates)
+	}
+	invalid, over := 0, 0
+	for _, a := range in.AlertTemplates {
+		key := strings.ToLower(a.Name) + "\x00" + a.Pattern
+		if have[key] {
+			res.AlertTemplates.Reused++
+			continue
+		}
+		t := models.AlertTemplate{UserID: userID}
+		if err := (AlertTemplateInput{
+			Name:       &a.Name,
+			Bank:       &a.Bank,
+			Pattern:    &a.Pattern,
+			DateLayout: &a.DateLayout,
+			Type:       &a.Type,
+			Currency:   &a.Currency,
+		}).apply(&t); err != nil {
+			invalid++
+			continue
+		}
+		if room <= 0 {
+			over++
+			continue
+		}
+		if !opts.DryRun {
+			if err := s.src.AlertTemplates.Create(ctx, &t); err != nil {
+				return err
+			}
+			j.templates = append(j.templates, t.ID)
+		}
+		have[key] = true
+		room--
+		res.AlertTemplates.Created++
+	}
+	if invalid > 0 {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("%d invalid alert templates were skipped", invalid))
+	}
+	if over > 0 {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("%d alert templates were skipped; at most %d are allowed", over, maxAlertTemplates))
+	}
+	return nil
+}
+
+// applyAttachments uploads the archived files again, onto the imported
+// transactions, so they get the same checks as any other upload.
+func (s *importService) applyAttachments(ctx context.Context, userID string, in *archive.Contents, txIDs map[string]string, opts ImportOptions, res *ImportResult, j *importJournal) error {
+	scope := repositories.PersonalScope(userID)
+	missing, rejected := 0, 0
+	for _, a := range in.Attachments {
+		txID, ok := txIDs[a.TransactionID]
+		blob, hasFile := in.Blob(archive.AttachmentPath(a.ID))
+		if !ok || !hasFile {
+			missing++
+			continue
+		}
+		if opts.DryRun {
+			res.Attachments.Created++
+			continue
+		}
+		data, err := readBlob(blob)
+		if err != nil {
+			return &ImportError{fmt.Errorf("%s: %w", blob.Path, err)}
+		}
+		created, err := s.attachments.Upload(ctx, scope, txID, a.FileName, data)
+		switch {
+		case errors.Is(err, ErrAttachmentTooLarge), errors.Is(err, ErrEmptyFile),
+			errors.Is(err, ErrUnsupportedFileType), errors.Is(err, ErrTooManyAttachments):
+			rejected++
+			continue
+		case err != nil:
+			return err
+		}
+		j.attachments = append(j.attachments, *created)
+		res.Attachments.Created++
+	}
+	if missing > 0 {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("%d attachments without their file or transaction in the archive were skipped", missing))
+	}
+	if rejected > 0 {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("%d attachments were rejected by the upload checks", rejected))
+	}
+	return nil
+}
+
+// invalidRecords counts archived records that fail the checks the services
+// apply, per collection, keeping the first reason for the warning.
+type invalidRecords struct {
+	order []string
+	count map[string]int
+	first map[string]error
+}
+
+func (v *invalidRecords) add(collection string, err error) {
+	if v.count == nil {
+		v.count, v.first = map[string]int{}, map[string]error{}
+	}
+	if v.count[collection] == 0 {
+		v.order = append(v.order, collection)
+		v.first[collection] = err
+	}
+	v.count[collection]++
+}
+
+func (v *invalidRecords) warnings() []string {
+	var out []string
+	for _, c := range v.order {
+		out = append(out, fmt.Sprintf("%d invalid %s were skipped (first: %v)", v.count[c], c, v.first[c]))
+	}
+	return out
+}
+
+func readBlob(b archive.Blob) ([]byte, error) {
+	rc, err := b.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, maxImportFileBytes+1))
+}
+
+func (s *importService) rollback(ctx context.Context, userID string, j *importJournal) error {
+	var errs []error
+	del := func(f func(context.Context, string, []string) (int64, error), ids []string) {
+		if _, err := f(ctx, userID, ids); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	scope := repositories.PersonalScope(userID)
+	for _, a := range j.attachments {
+		if err := s.attachments.Delete(ctx, scope, a.ID); err != nil && !errors.Is(err, ErrAttachmentNotFound) {
+			errs = append(errs, err)
+		}
+	}
+	for _, id := range j.templates {
+		if _, err := s.src.AlertTemplates.Delete(ctx, id, userID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	del(s.src.Transactions.DeleteByIDs, j.transactions)
+	del(s.src.Reminders.DeleteByIDs, j.reminders)
+	del(s.src.Categories.DeleteByIDs, j.categories)
+	del(s.src.Tags.DeleteByIDs, j.tags)
+	del(s.src.Digests.DeleteByIDs, j.digests)
+	del(s.src.SubscriptionDecisions.DeleteByIDs, j.decisions)
+	for i := range j.overwritten {
+		if err := s.src.SubscriptionDecisions.Upsert(ctx, &j.overwritten[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *importService) removeReplaced(ctx context.Context, userID string, existing, in *archive.Contents) error {
+	ids := func(n int, id func(int) string) []string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = id(i)
+		}
+		return out
+	}
+	// The files of removed attachments are deleted after the commit.
+	scope := repositories.PersonalScope(userID)
+	for _, a := range existing.Attachments {
+		if err := s.src.Attachments.Delete(ctx, a.ID, scope); err != nil {
+			return err
+		}
+	}
+	for _, t := range existing.AlertTemplates {
+		if _, err := s.src.AlertTemplates.Delete(ctx, t.ID, userID); err != nil {
+			return err
+		}
+	}
+	if _, err := s.src.Transactions.DeleteByIDs(ctx, userID, ids(len(existing.Transactions), func(i int) string { return existing.Transactions[i].ID })); err != nil {
+		return err
+	}
+	if _, err := s.src.Reminders.DeleteByIDs(ctx, userID, ids(len(existing.Reminders), func(i int) string { return existing.Reminders[i].ID })); err != nil {
+		return err
+	}
+	if _, err := s.src.Categories.DeleteByIDs(ctx, userID, ids(len(existing.Categories), func(i int) string { return existing.Categories[i].ID })); err != nil {
+		return err
+	}
+	if _, err := s.src.Tags.DeleteByIDs(ctx, userID, ids(len(existing.Tags), func(i int) string { return existing.Tags[i].ID })); err != nil {
+		return err
+	}
+	if _, err := s.src.Digests.DeleteByIDs(ctx, userID, ids(len(existing.Digests), func(i int) string { return existing.Digests[i].ID })); err != nil {
+		return err
+	}
+
+	keep := map[string]bool{}
+	for _, d := range in.SubscriptionDecisions {
+		keep[d.Key] = true
+	}
+	var stale []string
+	for _, d := range existing.SubscriptionDecisions {
+		if !keep[d.Key] {
+			stale = append(stale, d.ID)
+		}
+	}
+	if _, err := s.src.SubscriptionDecisions.DeleteByIDs(ctx, userID, stale); err != nil {
+		return err
+	}
+
+	// Insights point at the transactions that were just replaced; they are
+	// regenerated from the new data.
+	_, err := s.src.Insights.DeleteAllByUser(ctx, userID)
+	return err
+}
+
+// orderCategories returns categories with every parent before its children.
+// Categories whose parent is missing or part of a cycle are made top-level;
+// broken counts them.
+func orderCategories(in []models.Category) (ordered []models.Category, broken int) {
+	byID := make(map[string]models.Category, len(in))
+	children := map[string][]string{}
+	for _, c := range in {
+		byID[c.ID] = c
+	}
+	var roots []string
+	for _, c := range in {
+		if c.ParentID != nil {
+			if _, ok := byID[*c.ParentID]; ok && *c.ParentID != c.ID {
+				children[*c.ParentID] = append(children[*c.ParentID], c.ID)
+				continue
+			}
+		}
+		roots = append(roots, c.ID)
+	}
+
+	placed := map[string]bool{}
+	var visit func(id string)
+	visit = func(id string) {
+		placed[id] = true
+		ordered = append(ordered, byID[id])
+		for _, child := range children[id] {
+			if !placed[child] {
+				visit(child)
+			}
+		}
+	}
+	for _, id := range roots {
+		if byID[id].ParentID != nil {
+			broken++
+			c := byID[id]
+			c.ParentID = nil
+			byID[id] = c
+		}
+		visit(id)
+	}
+	// Whatever is left is only reachable through a cycle.
+	for _, c := range in {
+		if !placed[c.ID] {
+			broken++
+			c.ParentID = nil
+			byID[c.ID] = c
+			visit(c.ID)
+		}
+	}
+	return ordered, broken
+}
+
+// pathIndex maps lower-cased "Parent / Child" paths to category IDs.
+func pathIndex(categories []models.Category) map[string]string {
+	out := map[string]string{}
+	for id, path := range archive.CategoryPaths(categories) {
+		out[strings.ToLower(path)] = id
+	}
+	return out
+}
+
+func hasEquivalentDigest(existing []models.DigestSubscription, d models.DigestSubscription) bool {
+	for _, e := range existing {
+		if e.Frequency == d.Frequency && e.SendTime == d.SendTime && e.Timezone == d.Timezone {
+			return true
+		}
+	}
+	return false
+}
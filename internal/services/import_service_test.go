@@ -0,0 +1,160 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/archive"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+type fakeTransactor struct {
+	err     error // returned without running fn
+	aborted bool
+}
+
+func (f *fakeTransactor) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
+	if f.err != nil {
+		return f.err
+	}
+	err := fn(ctx)
+	f.aborted = err != nil
+	return err
+}
+
+type fakeExports struct {
+	ExportService
+}
+
+func (fakeExports) Collect(_ context.Context, userID string) (*archive.Contents, error) {
+	return &archive.Contents{UserID: userID}, nil
+}
+
+type memTransactionRepo struct {
+	repositories.TransactionRepository
+	created   int
+	deleteErr error
+}
+
+func (m *memTransactionRepo) Create(_ context.Context, t *models.Transaction) error {
+	m.created++
+	t.ID = "imported-tx"
+	return nil
+}
+
+func (m *memTransactionRepo) DeleteByIDs(context.Context, string, []string) (int64, error) {
+	return 0, m.deleteErr
+}
+
+type memAttachmentFiles struct {
+	AttachmentService
+	uploaded []models.Attachment
+	removed  []models.Attachment
+}
+
+func (m *memAttachmentFiles) Upload(_ context.Context, scope repositories.Scope, txID, name string, _ []byte) (*models.Attachment, error) {
+	a := models.Attachment{ID: "att-new", UserID: scope.UserID, TransactionID: txID, FileName: name, StorageKey: "attachments/new"}
+	m.uploaded = append(m.uploaded, a)
+	return &a, nil
+}
+
+func (m *memAttachmentFiles) RemoveFiles(_ context.Context, attachments []models.Attachment) {
+	m.removed = append(m.removed, attachments...)
+}
+
+func writeArchive(t *testing.T, c *archive.Contents) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := archive.Write(&buf, c, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func testArchive(t *testing.T) *bytes.Reader {
+	return writeArchive(t, &archive.Contents{
+		UserID:       "old-user",
+		Transactions: []models.Transaction{{ID: "tx-1", Amount: 12.5, Currency: "EUR", Type: models.TransactionTypeExpense}},
+		Attachments:  []models.Attachment{{ID: "att-1", TransactionID: "tx-1", FileName: "receipt.pdf"}},
+		Blobs: []archive.Blob{{
+			Path: archive.AttachmentPath("att-1"),
+			Open: func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("%PDF-1.4")), nil },
+		}},
+	})
+}
+
+func TestReplaceNeedsTransactions(t *testing.T) {
+	svc := NewImportService(fakeExports{}, AccountRepositories{}, &memAttachmentFiles{},
+		&fakeTransactor{err: repositories.ErrTransactionsUnsupported})
+	r := testArchive(t)
+	_, err := svc.Import(context.Background(), "user-1", r, r.Size(), ImportOptions{Mode: ImportReplace})
+	if !errors.Is(err, ErrReplaceUnavailable) {
+		t.Errorf("err = %v, want ErrReplaceUnavailable", err)
+	}
+}
+
+func TestReplaceFailureRemovesUploadedFiles(t *testing.T) {
+	removeErr := errors.New("delete failed")
+	transactions := &memTransactionRepo{deleteErr: removeErr}
+	files := &memAttachmentFiles{}
+	tx := &fakeTransactor{}
+	svc := NewImportService(fakeExports{}, AccountRepositories{Transactions: transactions}, files, tx)
+
+	r := testArchive(t)
+	_, err := svc.Import(context.Background(), "user-1", r, r.Size(), ImportOptions{Mode: ImportReplace})
+	if !errors.Is(err, removeErr) {
+		t.Fatalf("err = %v, want the removal error", err)
+	}
+	if !tx.aborted {
+		t.Error("transaction was not aborted")
+	}
+	if transactions.created != 1 || len(files.uploaded) != 1 || files.uploaded[0].TransactionID != "imported-tx" {
+		t.Fatalf("created %d transactions and uploaded %+v", transactions.created, files.uploaded)
+	}
+	if len(files.removed) != 1 || files.removed[0].ID != "att-new" {
+		t.Errorf("removed files of %+v, want the uploaded attachment", files.removed)
+	}
+}
+
+// Records the services would refuse are skipped with a warning. The
+// reminder and digest repositories are nil, so writing either would panic.
+func TestImportSkipsInvalidRecords(t *testing.T) {
+	transactions := &memTransactionRepo{}
+	svc := NewImportService(fakeExports{}, AccountRepositories{Transactions: transactions}, &memAttachmentFiles{}, &fakeTransactor{})
+	r := writeArchive(t, &archive.Contents{
+		UserID: "old-user",
+		Transactions: []models.Transaction{
+			{ID: "tx-1", Amount: 12.5, Currency: "EUR", Type: models.TransactionTypeExpense},
+			{ID: "tx-2", Amount: 0, Currency: "EUR", Type: models.TransactionTypeExpense},
+			{ID: "tx-3", Amount: 3, Currency: "EUR", Type: "transfer"},
+		},
+		Reminders: []models.Reminder{{ID: "r-1", Title: "Rent", RepeatInterval: "biweekly"}},
+		Digests:   []models.DigestSubscription{{ID: "d-1", Frequency: models.DigestWeekly, SendTime: "25:00", Timezone: "UTC"}},
+	})
+
+	res, err := svc.Import(context.Background(), "user-1", r, r.Size(), ImportOptions{Mode: ImportMerge})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Transactions.Created != 1 || transactions.created != 1 {
+		t.Errorf("created %d transactions (%d written), want 1", res.Transactions.Created, transactions.created)
+	}
+	if res.Reminders.Created != 0 || res.Digests.Created != 0 {
+		t.Errorf("created %d reminders and %d digests, want none", res.Reminders.Created, res.Digests.Created)
+	}
+	want := []string{"2 invalid transactions", "1 invalid reminders", "1 invalid digests"}
+	if len(res.Warnings) != len(want) {
+		t.Fatalf("warnings = %q", res.Warnings)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(res.Warnings[i], w) {
+			t.Errorf("warning %d = %q, want it to start with %q", i, res.Warnings[i], w)
+		}
+	}
+}
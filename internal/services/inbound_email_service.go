@@ -0,0 +1,502 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ronak4195/personal-assistant/internal/extract"
+	"github.com/ronak4195/personal-assistant/internal/mailparse"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+	"github.com/ronak4195/personal-assistant/internal/storage"
+)
+
+const (
+	inboundTokenAlphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+	inboundTokenLength   = 12
+	// maxDraftText bounds the email text kept on a draft; the whole
+	// message is in the file store.
+	maxDraftText = 4000
+)
+
+// How an email arrived.
+const (
+	InboundViaSMTP    = "smtp"
+	InboundViaWebhook = "webhook"
+)
+
+var (
+	ErrInboundEmailDisabled   = errors.New("inbound email is not configured")
+	ErrInboundMailboxNotFound = errors.New("no mailbox has this address")
+	ErrInboundEmailNotFound   = errors.New("email not found")
+	ErrInboundEmailTooLarge   = errors.New("email is too large")
+	ErrInvalidEmail           = errors.New("the message is not a valid email")
+)
+
+type InboundAddress struct {
+	Address   string    `json:"address"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type InboundEmailService interface {
+	// Address returns the user's address, creating it on first use.
+	Address(ctx context.Context, userID string) (*InboundAddress, error)
+	// RotateAddress replaces the user's address, e.g. after it leaked.
+	// Mail to the old one is refused from then on.
+	RotateAddress(ctx context.Context, userID string) (*InboundAddress, error)
+	// Accepts reports whether addr belongs to a user.
+	Accepts(ctx context.Context, addr string) (bool, error)
+	// Receive keeps an email and makes a draft transaction from it for
+	// each user it is addressed to. When recipients is empty they are read
+	// from the message headers. It returns how many users got it.
+	Receive(ctx context.Context, recipients []string, raw []byte, via string) (int, error)
+	List(ctx context.Context, userID string, limit, offset int64) ([]models.InboundEmail, int64, error)
+	Get(ctx context.Context, userID, id string) (*models.InboundEmail, error)
+	// Open returns one of the email's files, or the message as received
+	// when file is negative.
+	Open(ctx context.Context, userID, id string, file int) (*models.InboundEmailFile, io.ReadCloser, error)
+	// AttachToTransaction copies the email's receipt files to a
+	// transaction made from it.
+	AttachToTransaction(ctx context.Context, scope repositories.Scope, emailID, transactionID string) error
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+}
+
+type inboundEmailService struct {
+	repo         repositories.InboundEmailRepository
+	drafts       repositories.DraftRepository
+	transactions TransactionService
+	attachments  AttachmentService
+	store        storage.Store
+	extractor    extract.Extractor
+	domain       string
+	maxSize      int64
+	now          func() time.Time
+}
+
+// NewInboundEmailService returns the service for addresses at domain. With
+// an empty domain no addresses are handed out, but email already received
+// can still be read and purged.
+func NewInboundEmailService(repo repositories.InboundEmailRepository, drafts repositories.DraftRepository,
+	transactions TransactionService, attachments AttachmentService, store storage.Store, extractor extract.Extractor,
+	domain string, maxSize int64) InboundEmailService {
+	return &inboundEmailService{
+		repo:         repo,
+		drafts:       drafts,
+		transactions: transactions,
+		attachments:  attachments,
+		store:        store,
+		extractor:    extractor,
+		domain:       strings.ToLower(domain),
+		maxSize:      maxSize,
+		now:          time.Now,
+	}
+}
+
+func newInboundToken() (string, error) {
+	b := make([]byte, inboundTokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = inboundTokenAlphabet[int(b[i])%len(inboundTokenAlphabet)]
+	}
+	return string(b), nil
+}
+
+func (s *inboundEmailService) address(m *models.InboundMailbox) *InboundAddress {
+	return &InboundAddress{Address: m.Token + "@" + s.domain, CreatedAt: m.CreatedAt}
+}
+
+func (s *inboundEmailService) Address(ctx context.Context, userID string) (*InboundAddress, error) {
+	if s.domain == "" {
+		return nil, ErrInboundEmailDisabled
+	}
+	m, err := s.repo.FindMailboxByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil {
+		return s.address(m), nil
+	}
+	return s.RotateAddress(ctx, userID)
+}
+
+func (s *inboundEmailService) RotateAddress(ctx context.Context, userID string) (*InboundAddress, error) {
+	if s.domain == "" {
+		return nil, ErrInboundEmailDisabled
+	}
+	token, err := newInboundToken()
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.repo.SetMailbox(ctx, userID, token)
+	if err != nil {
+		return nil, err
+	}
+	return s.address(m), nil
+}
+
+// token returns the mailbox token in addr, or "" for an address at another
+// domain. A "+suffix" is ignored, so users can tag what they forward.
+func (s *inboundEmailService) token(addr string) string {
+	i := strings.LastIndexByte(addr, '@')
+	if s.domain == "" || i < 0 || !strings.EqualFold(addr[i+1:], s.domain) {
+		return ""
+	}
+	local, _, _ := strings.Cut(addr[:i], "+")
+	return strings.ToLower(local)
+}
+
+func (s *inboundEmailService) mailbox(ctx context.Context, addr string) (*models.InboundMailbox, error) {
+	token := s.token(addr)
+	if token == "" {
+		return nil, nil
+	}
+	return s.repo.FindMailboxByToken(ctx, token)
+}
+
+func (s *inboundEmailService) Accepts(ctx context.Context, addr string) (bool, error) {
+	m, err := s.mailbox(ctx, addr)
+	return m != nil, err
+}
+
+func (s *inboundEmailService) Receive(ctx context.Context, recipients []string, raw []byte, via string) (int, error) {
+	if int64(len(raw)) > s.maxSize {
+		return 0, ErrInboundEmailTooLarge
+	}
+	msg, err := mailparse.Parse(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidEmail, err)
+	}
+	if len(recipients) == 0 {
+		recipients = msg.Recipients
+	}
+
+	seen := map[string]bool{}
+	delivered := 0
+	for _, addr := range recipients {
+		m, err := s.mailbox(ctx, addr)
+		if err != nil {
+			return delivered, err
+		}
+		if m == nil || seen[m.UserID] {
+			continue
+		}
+		seen[m.UserID] = true
+		if err := s.receive(ctx, m.UserID, msg, raw, via); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	if delivered == 0 {
+		return 0, ErrInboundMailboxNotFound
+	}
+	return delivered, nil
+}
+
+// receive keeps the email for one user. A message already received, such
+// as one the sending server retried, is skipped.
+func (s *inboundEmailService) receive(ctx context.Context, userID string, msg *mailparse.Message, raw []byte, via string) error {
+	key := msg.MessageID
+	if key == "" {
+		key = string(raw)
+	}
+	key = hashToken(key)
+	existing, err := s.repo.FindByMessageKey(ctx, userID, key)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return nil
+	}
+
+	e := &models.InboundEmail{
+		UserID:     userID,
+		Via:        via,
+		Subject:    msg.Subject,
+		MessageID:  msg.MessageID,
+		MessageKey: key,
+		Size:       int64(len(raw)),
+	}
+	if msg.From != nil {
+		// Not mail.Address.String, which would encode a non-ASCII name.
+		e.From = msg.From.Address
+		if msg.From.Name != "" {
+			e.From = fmt.Sprintf("%s <%s>", msg.From.Name, msg.From.Address)
+		}
+	}
+	if !msg.Date.IsZero() {
+		d := msg.Date.UTC()
+		e.Date = &d
+	}
+	if err := s.storeFiles(ctx, e, msg, raw); err != nil {
+		return err
+	}
+	if err := s.repo.Create(ctx, e); err != nil {
+		s.removeFiles(ctx, *e)
+		return err
+	}
+
+	d, err := s.draft(ctx, e, msg)
+	if err != nil {
+		return err
+	}
+	created, err := s.drafts.Create(ctx, d)
+	if err != nil {
+		return err
+	}
+	if !created {
+		// A concurrent delivery of the same message drafted it already.
+		return nil
+	}
+	e.DraftID = d.ID
+	return s.repo.SetDraft(ctx, e.ID, d.ID)
+}
+
+func (s *inboundEmailService) storeFiles(ctx context.Context, e *models.InboundEmail, msg *mailparse.Message, raw []byte) error {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	prefix := "inbound/" + hex.EncodeToString(b)
+
+	e.StorageKey = prefix + "/message.eml"
+	if err := s.store.Put(ctx, e.StorageKey, raw, "message/rfc822"); err != nil {
+		return err
+	}
+	e.Files = []models.InboundEmailFile{}
+	for i, a := range msg.Attachments {
+		f := models.InboundEmailFile{
+			FileName:    cleanFileName(a.FileName),
+			ContentType: a.ContentType,
+			Size:        int64(len(a.Data)),
+			StorageKey:  fmt.Sprintf("%s/%d", prefix, i),
+		}
+		if f.FileName == "" || f.FileName == "." {
+			f.FileName = fmt.Sprintf("attachment-%d", i+1)
+		}
+		if err := s.store.Put(ctx, f.StorageKey, a.Data, a.ContentType); err != nil {
+			s.removeFiles(ctx, *e)
+			return err
+		}
+		e.Files = append(e.Files, f)
+	}
+	return nil
+}
+
+// receiptTypes are the attachments worth reading when the body has no
+// total, and worth copying to the transaction.
+var receiptTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+}
+
+// draft reads the receipt in an email. The body is read first; an attached
+// PDF or image only when the body has no total, since OCR is slow.
+func (s *inboundEmailService) draft(ctx context.Context, e *models.InboundEmail, msg *mailparse.Message) (*models.TransactionDraft, error) {
+	now := s.now()
+	text := strings.TrimSpace(msg.BodyText())
+	fields := extract.ParseReceipt(msg.Subject+"\n"+text, now)
+
+	if len(fields.Total) == 0 && s.extractor != nil {
+		for _, a := range msg.Attachments {
+			if !receiptTypes[a.ContentType] {
+				continue
+			}
+			res, err := s.extractor.Extract(ctx, a.ContentType, a.Data, now)
+			if err != nil {
+				continue
+			}
+			if len(res.Fields.Total) > 0 {
+				fields = res.Fields
+				break
+			}
+		}
+	}
+
+	scope := repositories.PersonalScope(e.UserID)
+	d := &models.TransactionDraft{
+		UserID:   e.UserID,
+		Source:   models.DraftSourceEmail,
+		Status:   models.DraftPending,
+		Type:     models.TransactionTypeExpense,
+		RawText:  truncateRunes(msg.Subject+"\n\n"+text, maxDraftText),
+		TextHash: e.MessageKey,
+		EmailID:  e.ID,
+	}
+	if len(fields.Total) > 0 {
+		d.Amount = fields.Total[0].Value
+	} else {
+		d.Notes = append(d.Notes, "no total was found; enter the amount when approving")
+	}
+	if len(fields.Currency) > 0 {
+		d.Currency = fields.Currency[0].Value
+	} else {
+		d.Notes = append(d.Notes, "no currency was found")
+	}
+	switch {
+	case len(fields.Date) > 0:
+		d.Date = fields.Date[0].Value.UTC()
+	case e.Date != nil:
+		d.Date = *e.Date
+		d.Notes = append(d.Notes, "no purchase date was found; the date the email was sent was used")
+	default:
+		d.Date = now.UTC()
+		d.Notes = append(d.Notes, "no date was found; the time the email arrived was used")
+	}
+	if merchant := emailMerchant(fields, msg.From); merchant != "" {
+		d.Description = &merchant
+	}
+
+	if d.Amount > 0 {
+		match, err := findSimilarTransaction(ctx, s.transactions, s.drafts, scope, d)
+		if err != nil {
+			return nil, err
+		}
+		if match != nil {
+			d.PossibleDuplicateOf = match.ID
+			d.Notes = append(d.Notes, "a transaction with the same amount is already recorded around this date")
+		}
+	}
+	return d, nil
+}
+
+// emailMerchant picks the merchant: a confident match in the receipt, or
+// else the sender's name, which for receipts is usually the shop.
+func emailMerchant(fields extract.Fields, from *mail.Address) string {
+	if len(fields.Merchant) > 0 && fields.Merchant[0].Confidence >= 0.6 {
+		return fields.Merchant[0].Value
+	}
+	if from != nil && from.Name != "" {
+		return from.Name
+	}
+	if len(fields.Merchant) > 0 {
+		return fields.Merchant[0].Value
+	}
+	if from != nil {
+		if _, domain, ok := strings.Cut(from.Address, "@"); ok {
+			return domain
+		}
+	}
+	return ""
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
+
+func (s *inboundEmailService) List(ctx context.Context, userID string, limit, offset int64) ([]models.InboundEmail, int64, error) {
+	return s.repo.List(ctx, userID, limit, offset)
+}
+
+func (s *inboundEmailService) Get(ctx context.Context, userID, id string) (*models.InboundEmail, error) {
+	e, err := s.repo.FindByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, ErrInboundEmailNotFound
+	}
+	return e, nil
+}
+
+func (s *inboundEmailService) Open(ctx context.Context, userID, id string, file int) (*models.InboundEmailFile, io.ReadCloser, error) {
+	e, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	f := &models.InboundEmailFile{
+		FileName:    "message.eml",
+		ContentType: "message/rfc822",
+		Size:        e.Size,
+		StorageKey:  e.StorageKey,
+	}
+	if file >= 0 {
+		if file >= len(e.Files) {
+			return nil, nil, ErrInboundEmailNotFound
+		}
+		f = &e.Files[file]
+	}
+	rc, err := s.store.Open(ctx, f.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrInboundEmailNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, rc, nil
+}
+
+func (s *inboundEmailService) AttachToTransaction(ctx context.Context, scope repositories.Scope, emailID, transactionID string) error {
+	e, err := s.Get(ctx, scope.UserID, emailID)
+	if err != nil {
+		return err
+	}
+	for _, f := range e.Files {
+		if !receiptTypes[f.ContentType] {
+			continue
+		}
+		rc, err := s.store.Open(ctx, f.StorageKey)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if _, err := s.attachments.Upload(ctx, scope, transactionID, f.FileName, data); err != nil {
+			// Files over the attachment limits stay with the email.
+			log.Printf("inbound email: attach %s of %s: %v", f.FileName, e.ID, err)
+		}
+	}
+	return nil
+}
+
+// DeleteAllByUser deletes the files first: the documents are how a purge
+// run again after a failure would find them.
+func (s *inboundEmailService) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	emails, err := s.repo.ListAllByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range emails {
+		for _, key := range inboundKeys(e) {
+			if err := s.store.Delete(ctx, key); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return s.repo.DeleteAllByUser(ctx, userID)
+}
+
+func inboundKeys(e models.InboundEmail) []string {
+	keys := []string{e.StorageKey}
+	for _, f := range e.Files {
+		keys = append(keys, f.StorageKey)
+	}
+	return keys
+}
+
+// removeFiles cleans up after a failed receive. A failure only leaves an
+// unreachable file behind, so it is logged.
+func (s *inboundEmailService) removeFiles(ctx context.Context, e models.InboundEmail) {
+	for _, key := range inboundKeys(e) {
+		if err := s.store.Delete(ctx, key); err != nil {
+			log.Printf("inbound email: remove %s: %v", key, err)
+		}
+	}
+}
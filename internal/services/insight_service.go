@@ -0,0 +1,342 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+const (
+	insightOutlierLookbackDays = 180
+	insightOutlierMinSamples   = 8
+	// Modified z-score threshold (Iglewicz & Hoaglin).
+	insightOutlierThreshold = 3.5
+
+	insightDayLookbackDays = 90
+	insightDayMinActive    = 14
+	insightDayThreshold    = 3.0
+
+	insightTrendMonths   = 3
+	insightTrendMinRatio = 1.25
+)
+
+type InsightService interface {
+	// Analyze evaluates a freshly created transaction against the user's
+	// history and stores any resulting insights.
+	Analyze(ctx context.Context, tx *models.Transaction) error
+	// Scan re-evaluates the user's recent history in full.
+	Scan(ctx context.Context, userID string) error
+	List(ctx context.Context, f repositories.InsightFilter) ([]models.Insight, int64, error)
+	Dismiss(ctx context.Context, userID, id string) (bool, error)
+}
+
+type insightService struct {
+	repo    repositories.InsightRepository
+	txRepo  repositories.TransactionRepository
+	catRepo repositories.CategoryRepository
+	now     func() time.Time
+}
+
+func NewInsightService(repo repositories.InsightRepository, txRepo repositories.TransactionRepository, catRepo repositories.CategoryRepository) InsightService {
+	return &insightService{
+		repo:    repo,
+		txRepo:  txRepo,
+		catRepo: catRepo,
+		now:     time.Now,
+	}
+}
+
+func (s *insightService) Analyze(ctx context.Context, tx *models.Transaction) error {
+	if tx.Type != models.TransactionTypeExpense {
+		return nil
+	}
+
+	day := startOfDayUTC(tx.Date)
+	from := day.AddDate(0, 0, -insightOutlierLookbackDays)
+	if m := startOfMonthUTC(day).AddDate(0, -insightTrendMonths, 0); m.Before(from) {
+		from = m
+	}
+	txs, err := s.txRepo.ListByDateRange(ctx, repositories.PersonalScope(tx.UserID), from, day.AddDate(0, 0, 1).Add(-time.Nanosecond))
+	if err != nil {
+		return err
+	}
+	expenses := filterExpenses(txs)
+
+	names := newCategoryNames(s.catRepo, tx.UserID)
+	var found []*models.Insight
+	if in := outlierInsight(ctx, *tx, expenses, names); in != nil {
+		found = append(found, in)
+	}
+	if in := largeDayInsight(tx.UserID, day, expenses); in != nil {
+		found = append(found, in)
+	}
+	if in := trendInsight(ctx, tx.UserID, tx.CategoryID, startOfMonthUTC(day), expenses, names); in != nil {
+		found = append(found, in)
+	}
+	return s.store(ctx, found)
+}
+
+func (s *insightService) Scan(ctx context.Context, userID string) error {
+	now := s.now().UTC()
+	today := startOfDayUTC(now)
+	from := today.AddDate(0, 0, -2*insightOutlierLookbackDays)
+	txs, err := s.txRepo.ListByDateRange(ctx, repositories.PersonalScope(userID), from, today.AddDate(0, 0, 1).Add(-time.Nanosecond))
+	if err != nil {
+		return err
+	}
+	expenses := filterExpenses(txs)
+	names := newCategoryNames(s.catRepo, userID)
+
+	var found []*models.Insight
+	scanFrom := today.AddDate(0, 0, -insightOutlierLookbackDays)
+	days := map[time.Time]bool{}
+	cats := map[string]*string{}
+	for _, tx := range expenses {
+		if tx.Date.Before(scanFrom) {
+			continue
+		}
+		if in := outlierInsight(ctx, tx, expenses, names); in != nil {
+			found = append(found, in)
+		}
+		days[startOfDayUTC(tx.Date)] = true
+		cats[categoryKey(tx.CategoryID)] = tx.CategoryID
+	}
+	for day := range days {
+		if in := largeDayInsight(userID, day, expenses); in != nil {
+			found = append(found, in)
+		}
+	}
+	for _, catID := range cats {
+		if in := trendInsight(ctx, userID, catID, startOfMonthUTC(today), expenses, names); in != nil {
+			found = append(found, in)
+		}
+	}
+	return s.store(ctx, found)
+}
+
+func (s *insightService) List(ctx context.Context, f repositories.InsightFilter) ([]models.Insight, int64, error) {
+	return s.repo.List(ctx, f)
+}
+
+func (s *insightService) Dismiss(ctx context.Context, userID, id string) (bool, error) {
+	return s.repo.Dismiss(ctx, id, userID)
+}
+
+func (s *insightService) store(ctx context.Context, found []*models.Insight) error {
+	for _, in := range found {
+		if err := s.repo.Upsert(ctx, in); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outlierInsight flags an expense whose amount is far above what the user
+// usually spends in the same category, using the median absolute deviation
+// so that a few earlier large purchases do not mask new ones.
+func outlierInsight(ctx context.Context, tx models.Transaction, expenses []models.Transaction, names *categoryNames) *models.Insight {
+	key := categoryKey(tx.CategoryID)
+	from := tx.Date.AddDate(0, 0, -insightOutlierLookbackDays)
+
+	var samples []float64
+	for _, h := range expenses {
+		if h.ID == tx.ID || categoryKey(h.CategoryID) != key || !h.Date.Before(tx.Date) || h.Date.Before(from) {
+			continue
+		}
+		samples = append(samples, h.Amount)
+	}
+	if len(samples) < insightOutlierMinSamples {
+		return nil
+	}
+
+	med := median(samples)
+	deviations := make([]float64, len(samples))
+	for i, v := range samples {
+		deviations[i] = math.Abs(v - med)
+	}
+	mad := median(deviations)
+
+	var score float64
+	if mad > 0 {
+		score = 0.6745 * (tx.Amount - med) / mad
+	} else if med > 0 && tx.Amount > 3*med {
+		// Perfectly regular history: anything three times the norm stands out.
+		score = tx.Amount / med
+	}
+	if score <= insightOutlierThreshold {
+		return nil
+	}
+
+	name := names.get(ctx, tx.CategoryID)
+	id := tx.ID
+	return &models.Insight{
+		UserID:        tx.UserID,
+		Kind:          models.InsightOutlier,
+		Fingerprint:   "outlier:" + tx.ID,
+		Title:         fmt.Sprintf("Unusually large %s expense", name),
+		Message:       fmt.Sprintf("%.2f %s is well above your typical %s expense of %.2f.", tx.Amount, tx.Currency, name, med),
+		CategoryID:    tx.CategoryID,
+		TransactionID: &id,
+		Date:          tx.Date,
+		Amount:        tx.Amount,
+		Baseline:      med,
+		Score:         round2(score),
+	}
+}
+
+// largeDayInsight compares the day's total spend with the daily totals of the
+// preceding 90 days (including days with no spending).
+func largeDayInsight(userID string, day time.Time, expenses []models.Transaction) *models.Insight {
+	from := day.AddDate(0, 0, -insightDayLookbackDays)
+	totals := make(map[time.Time]float64, insightDayLookbackDays)
+	var today float64
+	for _, tx := range expenses {
+		d := startOfDayUTC(tx.Date)
+		if d.Equal(day) {
+			today += tx.Amount
+		} else if !d.Before(from) && d.Before(day) {
+			totals[d] += tx.Amount
+		}
+	}
+	if len(totals) < insightDayMinActive || today == 0 {
+		return nil
+	}
+
+	var sum, sumSq float64
+	for _, v := range totals {
+		sum += v
+		sumSq += v * v
+	}
+	n := float64(insightDayLookbackDays)
+	mean := sum / n
+	std := math.Sqrt(math.Max(sumSq/n-mean*mean, 0))
+	if std == 0 {
+		return nil
+	}
+	z := (today - mean) / std
+	if z <= insightDayThreshold || today < 2*mean {
+		return nil
+	}
+
+	return &models.Insight{
+		UserID:      userID,
+		Kind:        models.InsightLargeDay,
+		Fingerprint: "large_day:" + day.Format("2006-01-02"),
+		Title:       "Unusually high spending day",
+		Message:     fmt.Sprintf("You spent %.2f on %s, against a daily average of %.2f.", today, day.Format("Jan 2"), mean),
+		Date:        day,
+		Amount:      today,
+		Baseline:    round2(mean),
+		Score:       round2(z),
+	}
+}
+
+// trendInsight flags a category whose spend grew every month over the last
+// three complete months before `month`, by at least 25% overall.
+func trendInsight(ctx context.Context, userID string, catID *string, month time.Time, expenses []models.Transaction, names *categoryNames) *models.Insight {
+	key := categoryKey(catID)
+	first := month.AddDate(0, -insightTrendMonths, 0)
+
+	monthly := make([]float64, insightTrendMonths)
+	for _, tx := range expenses {
+		if categoryKey(tx.CategoryID) != key || tx.Date.Before(first) || !tx.Date.Before(month) {
+			continue
+		}
+		m := startOfMonthUTC(tx.Date)
+		idx := (m.Year()-first.Year())*12 + int(m.Month()) - int(first.Month())
+		monthly[idx] += tx.Amount
+	}
+	if monthly[0] <= 0 {
+		return nil
+	}
+	for i := 1; i < len(monthly); i++ {
+		if monthly[i] <= monthly[i-1] {
+			return nil
+		}
+	}
+	last := monthly[len(monthly)-1]
+	ratio := last / monthly[0]
+	if ratio < insightTrendMinRatio {
+		return nil
+	}
+
+	name := names.get(ctx, catID)
+	lastMonth := month.AddDate(0, -1, 0)
+	return &models.Insight{
+		UserID:      userID,
+		Kind:        models.InsightCategoryTrend,
+		Fingerprint: "trend:" + key + ":" + lastMonth.Format("2006-01"),
+		Title:       fmt.Sprintf("%s spending is trending up", name),
+		Message: fmt.Sprintf("%s spending rose for %d months in a row, from %.2f in %s to %.2f in %s.",
+			name, insightTrendMonths, monthly[0], first.Format("January"), last, lastMonth.Format("January")),
+		CategoryID: catID,
+		Date:       lastMonth,
+		Amount:     last,
+		Baseline:   monthly[0],
+		Score:      round2(ratio),
+	}
+}
+
+// categoryNames resolves category names lazily, caching lookups for the
+// duration of one analysis run.
+type categoryNames struct {
+	repo   repositories.CategoryRepository
+	userID string
+	cache  map[string]string
+}
+
+func newCategoryNames(repo repositories.CategoryRepository, userID string) *categoryNames {
+	return &categoryNames{repo: repo, userID: userID, cache: map[string]string{}}
+}
+
+func (n *categoryNames) get(ctx context.Context, id *string) string {
+	if id == nil {
+		return uncategorisedName
+	}
+	if name, ok := n.cache[*id]; ok {
+		return name
+	}
+	name := uncategorisedName
+	if c, _ := n.repo.FindByID(ctx, *id, repositories.PersonalScope(n.userID)); c != nil {
+		name = c.Name
+	}
+	n.cache[*id] = name
+	return name
+}
+
+func filterExpenses(txs []models.Transaction) []models.Transaction {
+	res := make([]models.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if tx.Type == models.TransactionTypeExpense {
+			res = append(res, tx)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Date.Before(res[j].Date) })
+	return res
+}
+
+func categoryKey(id *string) string {
+	if id == nil {
+		return ""
+	}
+	return *id
+}
+
+func startOfDayUTC(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func startOfMonthUTC(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
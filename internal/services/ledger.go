@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+)
+
+// Ledger arithmetic is done in integer cents so that shares always add up to
+// the amount that was split.
+
+func toCents(v float64) int64 {
+	return int64(math.Round(v * 100))
+}
+
+func fromCents(c int64) float64 {
+	return float64(c) / 100
+}
+
+// allocate divides total in proportion to weights. Cents lost to rounding go
+// to the parts with the largest remainders, earliest first.
+func allocate(total int64, weights []float64) []int64 {
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+	parts := make([]int64, len(weights))
+	if sum <= 0 {
+		return parts
+	}
+
+	rem := make([]float64, len(weights))
+	left := total
+	for i, w := range weights {
+		exact := float64(total) * w / sum
+		parts[i] = int64(math.Floor(exact))
+		rem[i] = exact - float64(parts[i])
+		left -= parts[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return rem[order[a]] > rem[order[b]] })
+	for i := 0; left > 0; i = (i + 1) % len(order) {
+		parts[order[i]]++
+		left--
+	}
+	return parts
+}
+
+// splitAmounts works out what each participant owes of total under method.
+// values holds the per-participant input and is ignored for equal splits.
+func splitAmounts(method models.SplitMethod, total int64, values []float64) ([]int64, error) {
+	for _, v := range values {
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, errors.New("split values must not be negative")
+		}
+	}
+
+	switch method {
+	case models.SplitEqual:
+		weights := make([]float64, len(values))
+		for i := range weights {
+			weights[i] = 1
+		}
+		return allocate(total, weights), nil
+
+	case models.SplitExact:
+		parts := make([]int64, len(values))
+		var sum int64
+		for i, v := range values {
+			parts[i] = toCents(v)
+			sum += parts[i]
+		}
+		if sum != total {
+			return nil, fmt.Errorf("exact amounts add up to %.2f, not %.2f", fromCents(sum), fromCents(total))
+		}
+		return parts, nil
+
+	case models.SplitPercentage:
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		if math.Abs(sum-100) > 0.01 {
+			return nil, fmt.Errorf("percentages add up to %g, not 100", sum)
+		}
+		return allocate(total, values), nil
+
+	case models.SplitShares:
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		if sum <= 0 {
+			return nil, errors.New("at least one participant needs a share")
+		}
+		return allocate(total, values), nil
+	}
+	return nil, errors.New("invalid split method")
+}
+
+type debtTransfer struct {
+	from, to string
+	amount   int64
+}
+
+// simplifyDebts turns net positions (positive: is owed, negative: owes) into
+// a short list of payments that settles all of them. It repeatedly pays the
+// largest creditor from the largest debtor, which needs at most n-1 payments
+// for n parties.
+func simplifyDebts(net map[string]int64) []debtTransfer {
+	type position struct {
+		key    string
+		amount int64
+	}
+	var debtors, creditors []position
+	for k, v := range net {
+		switch {
+		case v < 0:
+			debtors = append(debtors, position{k, -v})
+		case v > 0:
+			creditors = append(creditors, position{k, v})
+		}
+	}
+	byAmount := func(p []position) func(i, j int) bool {
+		return func(i, j int) bool {
+			if p[i].amount != p[j].amount {
+				return p[i].amount > p[j].amount
+			}
+			return p[i].key < p[j].key
+		}
+	}
+
+	var transfers []debtTransfer
+	for len(debtors) > 0 && len(creditors) > 0 {
+		sort.Slice(debtors, byAmount(debtors))
+		sort.Slice(creditors, byAmount(creditors))
+		d, c := &debtors[0], &creditors[0]
+
+		amount := min(d.amount, c.amount)
+		transfers = append(transfers, debtTransfer{from: d.key, to: c.key, amount: amount})
+		d.amount -= amount
+		c.amount -= amount
+		if d.amount == 0 {
+			debtors = debtors[1:]
+		}
+		if c.amount == 0 {
+			creditors = creditors[1:]
+		}
+	}
+	return transfers
+}
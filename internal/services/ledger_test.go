@@ -0,0 +1,159 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+)
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int64
+		weights []float64
+		want    []int64
+	}{
+		{"even", 900, []float64{1, 1, 1}, []int64{300, 300, 300}},
+		{"leftover cent goes first", 1000, []float64{1, 1, 1}, []int64{334, 333, 333}},
+		{"largest remainder wins", 100, []float64{1, 2}, []int64{33, 67}},
+		{"zero weight", 500, []float64{0, 1}, []int64{0, 500}},
+		{"no weight", 500, []float64{0, 0}, []int64{0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allocate(tt.total, tt.weights); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allocate(%d, %v) = %v, want %v", tt.total, tt.weights, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitAmounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  models.SplitMethod
+		values  []float64
+		want    []int64
+		wantErr bool
+	}{
+		{"equal", models.SplitEqual, []float64{0, 0, 0}, []int64{334, 333, 333}, false},
+		{"exact", models.SplitExact, []float64{2.5, 7.5}, []int64{250, 750}, false},
+		{"exact short", models.SplitExact, []float64{2.5, 7}, nil, true},
+		{"percentage", models.SplitPercentage, []float64{25, 75}, []int64{250, 750}, false},
+		{"percentage off", models.SplitPercentage, []float64{25, 70}, nil, true},
+		{"shares", models.SplitShares, []float64{1, 3}, []int64{250, 750}, false},
+		{"no shares", models.SplitShares, []float64{0, 0}, nil, true},
+		{"negative", models.SplitShares, []float64{-1, 2}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitAmounts(tt.method, 1000, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitAmounts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// settles applies the transfers to net and reports whether everyone ends up
+// even.
+func settles(net map[string]int64, transfers []debtTransfer) bool {
+	left := map[string]int64{}
+	for k, v := range net {
+		left[k] = v
+	}
+	for _, tr := range transfers {
+		if tr.amount <= 0 {
+			return false
+		}
+		left[tr.from] += tr.amount
+		left[tr.to] -= tr.amount
+	}
+	for _, v := range left {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSimplifyDebts(t *testing.T) {
+	tests := []struct {
+		name string
+		net  map[string]int64
+		want []debtTransfer
+	}{
+		{"nobody owes", map[string]int64{"a": 0, "b": 0}, nil},
+		{"one debt", map[string]int64{"a": -500, "b": 500}, []debtTransfer{{"a", "b", 500}}},
+		{
+			// a owes b 10 and b owes c 10: a pays c directly.
+			"chain collapses",
+			map[string]int64{"a": -1000, "b": 0, "c": 1000},
+			[]debtTransfer{{"a", "c", 1000}},
+		},
+		{
+			"largest debtor pays largest creditor",
+			map[string]int64{"a": -700, "b": -300, "c": 600, "d": 400},
+			[]debtTransfer{{"a", "c", 600}, {"b", "d", 300}, {"a", "d", 100}},
+		},
+		{
+			"ties break by key",
+			map[string]int64{"b": -100, "a": -100, "c": 200},
+			[]debtTransfer{{"a", "c", 100}, {"b", "c", 100}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := simplifyDebts(tt.net)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("simplifyDebts = %v, want %v", got, tt.want)
+			}
+			if !settles(tt.net, got) {
+				t.Errorf("transfers %v do not settle %v", got, tt.net)
+			}
+		})
+	}
+}
+
+func TestSimplifyDebtsAtMostNMinusOne(t *testing.T) {
+	net := map[string]int64{"a": -1234, "b": -1, "c": 600, "d": -400, "e": 1035, "f": 0}
+	got := simplifyDebts(net)
+	if len(got) > len(net)-1 {
+		t.Errorf("%d transfers for %d parties", len(got), len(net))
+	}
+	if !settles(net, got) {
+		t.Errorf("transfers %v do not settle %v", got, net)
+	}
+}
+
+func TestSimplifiedPositions(t *testing.T) {
+	alice, bob, carol := models.UserParty("alice"), models.UserParty("bob"), models.UserParty("carol")
+	entries := []models.LedgerEntry{
+		// Bob owes Alice 10 and Carol owes Bob 10, so Carol should pay Alice.
+		newLedgerEntry(bob, alice, 1000, "EUR"),
+		newLedgerEntry(carol, bob, 1000, "EUR"),
+		// Currencies are netted separately.
+		newLedgerEntry(alice, bob, 300, "USD"),
+		// Contacts and users outside the group are left out.
+		newLedgerEntry(models.Party{ContactID: "c1"}, alice, 500, "EUR"),
+		newLedgerEntry(models.UserParty("dave"), alice, 500, "EUR"),
+	}
+
+	got := simplifiedPositions(entries, []string{"alice", "bob", "carol"}, alice)
+	want := map[string]map[string]int64{
+		carol.Key(): {"EUR": 1000},
+		bob.Key():   {"USD": -300},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("simplifiedPositions = %v, want %v", got, want)
+	}
+
+	direct := netPositions(entries, alice)
+	if direct[bob.Key()]["EUR"] != 1000 || direct[carol.Key()] != nil {
+		t.Errorf("netPositions = %v, want bob owing 10 EUR and nothing from carol", direct)
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+)
+
+const (
+	// loginFailureWindow is how long failed logins are remembered after the
+	// most recent one.
+	loginFailureWindow = 24 * time.Hour
+	// An address is shared by everyone behind the same NAT or proxy, so it
+	// gets more room than a single account.
+	ipFailureMultiplier = 4
+)
+
+var ErrTooManyAttempts = errors.New("too many failed login attempts")
+
+// LoginLockedError is returned while an account or client is locked out.
+// It matches ErrTooManyAttempts.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string { return ErrTooManyAttempts.Error() }
+
+func (e *LoginLockedError) Is(target error) bool { return target == ErrTooManyAttempts }
+
+// LoginThrottleConfig controls lockout after repeated failed logins.
+type LoginThrottleConfig struct {
+	// MaxFailures is how many failures an email address may have before it
+	// is locked. Zero disables throttling.
+	MaxFailures int
+	// Lockout is the first lockout; each further failure doubles it.
+	Lockout    time.Duration
+	MaxLockout time.Duration
+}
+
+type loginKeys struct {
+	email string
+	ip    string
+}
+
+func newLoginKeys(email, ip string) loginKeys {
+	k := loginKeys{email: "email:" + strings.ToLower(strings.TrimSpace(email))}
+	if ip != "" {
+		k.ip = "ip:" + ip
+	}
+	return k
+}
+
+func (k loginKeys) all() []string {
+	if k.ip == "" {
+		return []string{k.email}
+	}
+	return []string{k.email, k.ip}
+}
+
+// checkLoginThrottle fails with a LoginLockedError if either key is locked.
+func (s *authService) checkLoginThrottle(ctx context.Context, keys loginKeys) error {
+	if s.throttle.MaxFailures <= 0 {
+		return nil
+	}
+	counters, err := s.loginThrottles.Find(ctx, keys.all())
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	var wait time.Duration
+	for _, c := range counters {
+		if c.LockedUntil != nil && c.LockedUntil.After(now) {
+			wait = max(wait, c.LockedUntil.Sub(now))
+		}
+	}
+	if wait > 0 {
+		return &LoginLockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// loginFailed records a failed login against both keys. It returns a
+// LoginLockedError if this failure locked either of them, and
+// ErrInvalidCredentials otherwise. u is nil for unknown addresses, which are
+// counted all the same so lockouts do not reveal which accounts exist.
+func (s *authService) loginFailed(ctx context.Context, keys loginKeys, u *models.User, ip string) error {
+	if s.throttle.MaxFailures <= 0 {
+		return ErrInvalidCredentials
+	}
+
+	var wait time.Duration
+	record := func(key string, limit int) *models.LoginThrottle {
+		c, err := s.loginThrottles.RecordFailure(ctx, key, loginFailureWindow)
+		if err != nil {
+			log.Printf("auth: record login failure for %s: %v", key, err)
+			return nil
+		}
+		if c.Failures < limit {
+			return c
+		}
+		d := s.lockoutFor(c.Failures - limit)
+		if err := s.loginThrottles.Lock(ctx, key, c.LastFailureAt.Add(d)); err != nil {
+			log.Printf("auth: lock %s: %v", key, err)
+			return c
+		}
+		wait = max(wait, d)
+		return c
+	}
+
+	c := record(keys.email, s.throttle.MaxFailures)
+	if keys.ip != "" {
+		record(keys.ip, s.throttle.MaxFailures*ipFailureMultiplier)
+	}
+
+	// Tell the owner once per run of failures, when the account first locks.
+	if u != nil && c != nil && c.Failures == s.throttle.MaxFailures {
+		s.notify(ctx, u.Email, "Sign-in to your account was temporarily locked", fmt.Sprintf(
+			"There were %d failed attempts to sign in to your account, most recently from %s.\n"+
+				"Further attempts are blocked for %s.\n\n"+
+				"If this was not you, consider changing your password and turning on two-factor authentication.\n",
+			c.Failures, orUnknown(ip), s.lockoutFor(0)))
+	}
+
+	if wait > 0 {
+		return &LoginLockedError{RetryAfter: wait}
+	}
+	return ErrInvalidCredentials
+}
+
+// loginSucceeded clears the account's failures. The address keeps its
+// count: one working password must not wipe out guesses at other accounts.
+func (s *authService) loginSucceeded(ctx context.Context, keys loginKeys) {
+	if s.throttle.MaxFailures <= 0 {
+		return
+	}
+	if err := s.loginThrottles.Reset(ctx, []string{keys.email}); err != nil {
+		log.Printf("auth: reset login throttle: %v", err)
+	}
+}
+
+// lockoutFor returns the lockout after n failures beyond the limit.
+func (s *authService) lockoutFor(n int) time.Duration {
+	d := s.throttle.Lockout
+	for range n {
+		if d >= s.throttle.MaxLockout {
+			break
+		}
+		d *= 2
+	}
+	return min(d, s.throttle.MaxLockout)
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "an unknown address"
+	}
+	return s
+}
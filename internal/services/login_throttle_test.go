@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type memLoginThrottles struct {
+	repositories.LoginThrottleRepository
+	byKey map[string]*models.LoginThrottle
+}
+
+func (m *memLoginThrottles) Find(_ context.Context, keys []string) ([]models.LoginThrottle, error) {
+	var res []models.LoginThrottle
+	now := time.Now().UTC()
+	for _, k := range keys {
+		if c, ok := m.byKey[k]; ok && c.ExpiresAt.After(now) {
+			res = append(res, *c)
+		}
+	}
+	return res, nil
+}
+
+func (m *memLoginThrottles) RecordFailure(_ context.Context, key string, window time.Duration) (*models.LoginThrottle, error) {
+	now := time.Now().UTC()
+	c, ok := m.byKey[key]
+	if !ok || !c.ExpiresAt.After(now) {
+		c = &models.LoginThrottle{Key: key}
+		m.byKey[key] = c
+	}
+	c.Failures++
+	c.LastFailureAt = now
+	c.ExpiresAt = now.Add(window)
+	cp := *c
+	return &cp, nil
+}
+
+func (m *memLoginThrottles) Lock(_ context.Context, key string, until time.Time) error {
+	if c, ok := m.byKey[key]; ok {
+		if c.LockedUntil == nil || until.After(*c.LockedUntil) {
+			c.LockedUntil = &until
+		}
+		if until.After(c.ExpiresAt) {
+			c.ExpiresAt = until
+		}
+	}
+	return nil
+}
+
+func (m *memLoginThrottles) Reset(_ context.Context, keys []string) error {
+	for _, k := range keys {
+		delete(m.byKey, k)
+	}
+	return nil
+}
+
+func TestLockoutFor(t *testing.T) {
+	tests := []struct {
+		name          string
+		lockout, maxL time.Duration
+		n             int
+		want          time.Duration
+	}{
+		{"first lockout", time.Minute, time.Hour, 0, time.Minute},
+		{"doubles", time.Minute, time.Hour, 1, 2 * time.Minute},
+		{"doubles again", time.Minute, time.Hour, 3, 8 * time.Minute},
+		{"capped", time.Minute, time.Hour, 6, time.Hour},
+		{"stays capped", time.Minute, time.Hour, 1000, time.Hour},
+		{"first lockout above the cap", 2 * time.Hour, time.Hour, 0, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &authService{throttle: LoginThrottleConfig{MaxFailures: 5, Lockout: tt.lockout, MaxLockout: tt.maxL}}
+			if got := s.lockoutFor(tt.n); got != tt.want {
+				t.Errorf("lockoutFor(%d) = %s, want %s", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func newThrottleTestService(t *testing.T, maxFailures int) (*authService, *memLoginThrottles) {
+	t.Helper()
+	svc, u := newTestAuthService(newMemSessions())
+	hash, err := bcrypt.GenerateFromPassword([]byte("right-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.PasswordHash = string(hash)
+	throttles := &memLoginThrottles{byKey: map[string]*models.LoginThrottle{}}
+	svc.loginThrottles = throttles
+	svc.throttle = LoginThrottleConfig{MaxFailures: maxFailures, Lockout: time.Minute, MaxLockout: time.Hour}
+	return svc, throttles
+}
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	svc, throttles := newThrottleTestService(t, 3)
+	meta := SessionMeta{IP: "203.0.113.7"}
+
+	for i := 1; i < 3; i++ {
+		if _, err := svc.Login(ctx, "a@example.com", "wrong", meta); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("failure %d: err = %v, want ErrInvalidCredentials", i, err)
+		}
+	}
+	_, err := svc.Login(ctx, "a@example.com", "wrong", meta)
+	var locked *LoginLockedError
+	if !errors.As(err, &locked) || locked.RetryAfter != time.Minute {
+		t.Fatalf("third failure: err = %v, want a one-minute lockout", err)
+	}
+	if sent := *svc.mailer.(*sentMail); len(sent) != 1 {
+		t.Errorf("sent %d lockout notices, want 1", len(sent))
+	}
+
+	// While locked, even the right password is refused.
+	if _, err := svc.Login(ctx, "a@example.com", "right-password", meta); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("right password while locked: err = %v, want ErrTooManyAttempts", err)
+	}
+
+	// Once the lockout has passed, each further failure doubles it.
+	past := time.Now().Add(-time.Second)
+	throttles.byKey["email:a@example.com"].LockedUntil = &past
+	_, err = svc.Login(ctx, "a@example.com", "wrong", meta)
+	if !errors.As(err, &locked) || locked.RetryAfter != 2*time.Minute {
+		t.Fatalf("fourth failure: err = %v, want a two-minute lockout", err)
+	}
+
+	throttles.byKey["email:a@example.com"].LockedUntil = &past
+	if _, err := svc.Login(ctx, "a@example.com", "right-password", meta); err != nil {
+		t.Fatalf("login after the lockout: %v", err)
+	}
+	if _, ok := throttles.byKey["email:a@example.com"]; ok {
+		t.Error("the account's failures were not cleared by a successful login")
+	}
+}
+
+func TestLoginLockoutUnknownAccount(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newThrottleTestService(t, 2)
+
+	svc.Login(ctx, "nobody@example.com", "x", SessionMeta{})
+	_, err := svc.Login(ctx, "nobody@example.com", "x", SessionMeta{})
+	if !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("err = %v, want a lockout like any other account", err)
+	}
+}
+
+// Guessing across many accounts from one address locks the address, and a
+// successful login in between does not clear it.
+func TestLoginLockoutByAddress(t *testing.T) {
+	ctx := context.Background()
+	svc, throttles := newThrottleTestService(t, 2)
+	meta := SessionMeta{IP: "203.0.113.7"}
+	limit := 2 * ipFailureMultiplier
+
+	for i := 0; i < limit-1; i++ {
+		email := "victim" + string(rune('a'+i)) + "@example.com"
+		if _, err := svc.Login(ctx, email, "guess", meta); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("guess %d: err = %v, want ErrInvalidCredentials", i, err)
+		}
+		if _, err := svc.Login(ctx, "a@example.com", "right-password", meta); err != nil {
+			t.Fatalf("own login %d: %v", i, err)
+		}
+	}
+	if got := throttles.byKey["ip:203.0.113.7"].Failures; got != limit-1 {
+		t.Fatalf("address has %d failures, want %d", got, limit-1)
+	}
+
+	if _, err := svc.Login(ctx, "victimz@example.com", "guess", meta); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("err = %v, want the address locked", err)
+	}
+	if _, err := svc.Login(ctx, "a@example.com", "right-password", meta); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("err = %v, want every login from the address refused", err)
+	}
+	if _, err := svc.Login(ctx, "a@example.com", "right-password", SessionMeta{IP: "198.51.100.1"}); err != nil {
+		t.Errorf("login from another address: %v", err)
+	}
+}
+
+func TestLoginThrottleDisabled(t *testing.T) {
+	ctx := context.Background()
+	svc, throttles := newThrottleTestService(t, 0)
+	for i := 0; i < 20; i++ {
+		if _, err := svc.Login(ctx, "a@example.com", "wrong", SessionMeta{IP: "203.0.113.7"}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("err = %v, want ErrInvalidCredentials", err)
+		}
+	}
+	if len(throttles.byKey) != 0 {
+		t.Error("failures were recorded with throttling disabled")
+	}
+}
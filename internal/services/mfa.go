@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	totpIssuer          = "Personal Assistant"
+	totpSkew            = 1 // accept one step either side for clock drift
+	mfaChallengeTTL     = 5 * time.Minute
+	mfaChallengeRetries = 5
+	recoveryCodeCount   = 10
+	recoveryCodeLength  = 10
+)
+
+type MFAChallenge struct {
+	Token     string `json:"challengeToken"`
+	ExpiresIn int64  `json:"expiresIn"` // seconds
+}
+
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabledAt,omitempty"`
+	RecoveryCodesRemaining int        `json:"recoveryCodesRemaining"`
+	EnrolmentPending       bool       `json:"enrolmentPending"`
+}
+
+// TOTPEnrolment is what an authenticator app needs. URI is the otpauth://
+// payload to render as a QR code; Secret is for manual entry.
+type TOTPEnrolment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+func (s *authService) issueMFAChallenge(ctx context.Context, u *models.User) (*MFAChallenge, error) {
+	token, hash, err := newOpaqueToken("mfa_")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tokens.Create(ctx, &models.UserToken{
+		UserID:    u.ID,
+		Purpose:   models.TokenMFAChallenge,
+		TokenHash: hash,
+		Email:     u.Email,
+		ExpiresAt: time.Now().UTC().Add(mfaChallengeTTL),
+	}); err != nil {
+		return nil, err
+	}
+	return &MFAChallenge{Token: token, ExpiresIn: int64(mfaChallengeTTL.Seconds())}, nil
+}
+
+func (s *authService) VerifyMFA(ctx context.Context, challengeToken, code string, meta SessionMeta) (*models.User, *TokenPair, error) {
+	hash := hashToken(challengeToken)
+	t, err := s.tokens.FindActive(ctx, models.TokenMFAChallenge, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t == nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	u, err := s.users.FindByID(ctx, t.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u == nil || !u.MFA.Enabled {
+		return nil, nil, ErrInvalidToken
+	}
+	keys := newLoginKeys(u.Email, meta.IP)
+	if err := s.checkLoginThrottle(ctx, keys); err != nil {
+		return nil, nil, err
+	}
+
+	ok, err := s.checkSecondFactor(ctx, u, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		if err := s.tokens.RecordFailure(ctx, t.ID, mfaChallengeRetries); err != nil {
+			return nil, nil, err
+		}
+		s.audit.Record(ctx, AuditEntry{UserID: u.ID, Action: models.AuditLoginFailed, EntityType: models.AuditEntityUser, EntityID: u.ID})
+		// Wrong codes count against the account like wrong passwords, so
+		// asking for new challenges does not reset the lockout.
+		if err := s.loginFailed(ctx, keys, u, meta.IP); errors.Is(err, ErrTooManyAttempts) {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidMFACode
+	}
+
+	// Consume only now so that a mistyped code does not cost the challenge.
+	consumed, err := s.tokens.Consume(ctx, models.TokenMFAChallenge, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if consumed == nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	s.loginSucceeded(ctx, keys)
+
+	tokens, err := s.startSession(ctx, u, meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.audit.Record(ctx, AuditEntry{UserID: u.ID, Action: models.AuditLogin, EntityType: models.AuditEntityUser, EntityID: u.ID})
+	return u, tokens, nil
+}
+
+func (s *authService) MFAStatus(ctx context.Context, userID string) (*MFAStatus, error) {
+	u, err := s.requireUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &MFAStatus{
+		Enabled:                u.MFA.Enabled,
+		EnabledAt:              u.MFA.EnabledAt,
+		RecoveryCodesRemaining: len(u.MFA.RecoveryCodeHashes),
+		EnrolmentPending:       !u.MFA.Enabled && u.MFA.PendingSecret != "",
+	}, nil
+}
+
+func (s *authService) BeginTOTPEnrolment(ctx context.Context, userID string) (*TOTPEnrolment, error) {
+	u, err := s.requireUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.MFA.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.SetPendingTOTP(ctx, u.ID, secret); err != nil {
+		return nil, err
+	}
+	return &TOTPEnrolment{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, u.Email, secret),
+	}, nil
+}
+
+func (s *authService) ConfirmTOTPEnrolment(ctx context.Context, userID, code string) ([]string, error) {
+	u, err := s.requireUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.MFA.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if u.MFA.PendingSecret == "" {
+		return nil, ErrNoPendingEnrolment
+	}
+
+	step, ok := totp.Validate(u.MFA.PendingSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := s.users.EnableTOTP(ctx, u.ID, u.MFA.PendingSecret, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		// A second enrolment replaced the secret in the meantime.
+		return nil, ErrNoPendingEnrolment
+	}
+	return codes, nil
+}
+
+func (s *authService) DisableMFA(ctx context.Context, userID, password, code string) error {
+	u, err := s.reauthenticate(ctx, userID, password, code)
+	if err != nil {
+		return err
+	}
+	return s.users.DisableMFA(ctx, u.ID)
+}
+
+func (s *authService) RegenerateRecoveryCodes(ctx context.Context, userID, password, code string) ([]string, error) {
+	u, err := s.reauthenticate(ctx, userID, password, code)
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.SetRecoveryCodes(ctx, u.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// reauthenticate checks the password and a second factor before sensitive
+// changes to the second factor itself.
+func (s *authService) reauthenticate(ctx context.Context, userID, password, code string) (*models.User, error) {
+	u, err := s.requireUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !u.MFA.Enabled {
+		return nil, ErrMFANotEnabled
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	ok, err := s.checkSecondFactor(ctx, u, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	return u, nil
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery
+// code. Both are single-use.
+func (s *authService) checkSecondFactor(ctx context.Context, u *models.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return false, nil
+	}
+	if step, ok := totp.Validate(u.MFA.TOTPSecret, code, time.Now(), totpSkew); ok {
+		return s.users.UseTOTPStep(ctx, u.ID, step)
+	}
+	if len(code) == totp.Digits {
+		return false, nil
+	}
+	return s.users.UseRecoveryCode(ctx, u.ID, hashToken(normalizeRecoveryCode(code)))
+}
+
+func (s *authService) requireUser(ctx context.Context, userID string) (*models.User, error) {
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errors.New("user not found")
+	}
+	return u, nil
+}
+
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// newRecoveryCodes returns codes formatted for display ("abcde-fghij") and
+// the hashes of their normalised form.
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	buf := make([]byte, recoveryCodeLength)
+	for range recoveryCodeCount {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		var b strings.Builder
+		for i, c := range buf {
+			if i == recoveryCodeLength/2 {
+				b.WriteByte('-')
+			}
+			// 256 is not a multiple of the alphabet size; the bias is
+			// negligible for one-time codes.
+			b.WriteByte(recoveryAlphabet[int(c)%len(recoveryAlphabet)])
+		}
+		code := b.String()
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(normalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+	"github.com/ronak4195/personal-assistant/internal/totp"
+)
+
+// memChallenges stores MFA challenge tokens.
+type memChallenges struct {
+	repositories.UserTokenRepository
+	byHash map[string]*models.UserToken
+}
+
+func (m *memChallenges) Create(_ context.Context, t *models.UserToken) error {
+	t.ID = "token-" + strconv.Itoa(len(m.byHash)+1)
+	m.byHash[t.TokenHash] = t
+	return nil
+}
+
+func (m *memChallenges) FindActive(_ context.Context, purpose models.UserTokenPurpose, hash string) (*models.UserToken, error) {
+	t, ok := m.byHash[hash]
+	if !ok || t.Purpose != purpose || t.UsedAt != nil || !t.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	return t, nil
+}
+
+func (m *memChallenges) RecordFailure(_ context.Context, id string, maxAttempts int) error {
+	for _, t := range m.byHash {
+		if t.ID == id {
+			t.Attempts++
+			if t.Attempts >= maxAttempts {
+				now := time.Now()
+				t.UsedAt = &now
+			}
+		}
+	}
+	return nil
+}
+
+func newMFATestService(t *testing.T) (*authService, *memUsers, []string) {
+	t.Helper()
+	svc, u := newTestAuthService(newMemSessions())
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.MFA.Enabled = true
+	u.MFA.TOTPSecret = secret
+	u.MFA.RecoveryCodeHashes = hashes
+	return svc, svc.users.(*memUsers), codes
+}
+
+func TestCheckSecondFactorTOTPIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	svc, users, _ := newMFATestService(t)
+	u := users.byID["user-1"]
+
+	code, err := totp.Code(u.MFA.TOTPSecret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := svc.checkSecondFactor(ctx, u, code); err != nil || !ok {
+		t.Fatalf("first use = %v, %v; want true", ok, err)
+	}
+	if ok, _ := svc.checkSecondFactor(ctx, u, code); ok {
+		t.Error("the same code was accepted twice")
+	}
+
+	// An older step is refused once a later one has been used.
+	prev, _ := totp.Code(u.MFA.TOTPSecret, totp.Step(time.Now())-1)
+	if prev != code {
+		if ok, _ := svc.checkSecondFactor(ctx, u, prev); ok {
+			t.Error("a code from an earlier step was accepted after a later one")
+		}
+	}
+}
+
+func TestCheckSecondFactorRecoveryCodes(t *testing.T) {
+	ctx := context.Background()
+	svc, users, codes := newMFATestService(t)
+	u := users.byID["user-1"]
+
+	// Codes are accepted however the user types them, but only once.
+	typed := strings.ToUpper(strings.ReplaceAll(codes[0], "-", " "))
+	if ok, err := svc.checkSecondFactor(ctx, u, typed); err != nil || !ok {
+		t.Fatalf("recovery code %q = %v, %v; want true", typed, ok, err)
+	}
+	if ok, _ := svc.checkSecondFactor(ctx, u, codes[0]); ok {
+		t.Error("a recovery code was accepted twice")
+	}
+	if got := len(u.MFA.RecoveryCodeHashes); got != recoveryCodeCount-1 {
+		t.Errorf("%d recovery codes left, want %d", got, recoveryCodeCount-1)
+	}
+
+	for _, code := range []string{"", "   ", "abcde-fghij", "123456"} {
+		if ok, _ := svc.checkSecondFactor(ctx, u, code); ok {
+			t.Errorf("code %q was accepted", code)
+		}
+	}
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), recoveryCodeCount)
+	}
+	format := regexp.MustCompile(`^[` + recoveryAlphabet + `]{5}-[` + recoveryAlphabet + `]{5}$`)
+	seen := map[string]bool{}
+	for i, c := range codes {
+		if !format.MatchString(c) {
+			t.Errorf("code %q is not formatted as xxxxx-xxxxx", c)
+		}
+		if hashes[i] != hashToken(normalizeRecoveryCode(c)) {
+			t.Errorf("hash %d does not match code %q", i, c)
+		}
+		if seen[c] {
+			t.Errorf("code %q repeated", c)
+		}
+		seen[c] = true
+	}
+}
+
+// A correct password must not reset the lockout before the second factor
+// passes, or each new challenge would allow more guesses at the code.
+func TestRepeatedChallengesLockTheAccount(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newThrottleTestService(t, 3)
+	u := svc.users.(*memUsers).byID["user-1"]
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.MFA.Enabled = true
+	u.MFA.TOTPSecret = secret
+	svc.tokens = &memChallenges{byHash: map[string]*models.UserToken{}}
+	meta := SessionMeta{IP: "203.0.113.7"}
+
+	// A six-digit code that is wrong at every step the check accepts.
+	valid := map[string]bool{}
+	for d := int64(-totpSkew); d <= totpSkew; d++ {
+		c, _ := totp.Code(secret, totp.Step(time.Now())+d)
+		valid[c] = true
+	}
+	wrong := "000000"
+	for i := 1; valid[wrong]; i++ {
+		wrong = strconv.Itoa(100000 + i)
+	}
+
+	// One wrong code per challenge, each challenge from the right password.
+	for i := 1; i <= 3; i++ {
+		res, err := svc.Login(ctx, u.Email, "right-password", meta)
+		if err != nil {
+			t.Fatalf("login %d: %v", i, err)
+		}
+		_, _, err = svc.VerifyMFA(ctx, res.Challenge.Token, wrong, meta)
+		if i < 3 && !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("guess %d: err = %v, want ErrInvalidMFACode", i, err)
+		}
+		if i == 3 && !errors.Is(err, ErrTooManyAttempts) {
+			t.Fatalf("guess %d: err = %v, want the account locked", i, err)
+		}
+	}
+
+	if _, err := svc.Login(ctx, u.Email, "right-password", meta); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("login while locked: err = %v, want ErrTooManyAttempts", err)
+	}
+}
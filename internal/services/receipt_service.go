@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/extract"
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+// ReceiptDraft is a transaction prefilled from a receipt. It is not saved;
+// the user confirms or corrects it and then updates the transaction.
+type ReceiptDraft struct {
+	Type        models.TransactionType `json:"type"`
+	Amount      *float64               `json:"amount,omitempty"`
+	Currency    string                 `json:"currency,omitempty"`
+	Date        *time.Time             `json:"date,omitempty"`
+	Description *string                `json:"description,omitempty"`
+}
+
+type ReceiptExtraction struct {
+	AttachmentID  string `json:"attachmentId"`
+	TransactionID string `json:"transactionId"`
+	*extract.Result
+	Draft ReceiptDraft `json:"draft"`
+}
+
+type ReceiptService interface {
+	// Extract reads an attachment as a receipt. Nothing is changed; the
+	// result lists the candidates for each field and a draft built from the
+	// most likely ones.
+	Extract(ctx context.Context, scope repositories.Scope, attachmentID string) (*ReceiptExtraction, error)
+}
+
+type receiptService struct {
+	attachments AttachmentService
+	extractor   extract.Extractor
+}
+
+func NewReceiptService(attachments AttachmentService, extractor extract.Extractor) ReceiptService {
+	return &receiptService{attachments: attachments, extractor: extractor}
+}
+
+func (s *receiptService) Extract(ctx context.Context, scope repositories.Scope, attachmentID string) (*ReceiptExtraction, error) {
+	a, rc, err := s.attachments.Open(ctx, scope, attachmentID, false)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read attachment: %w", err)
+	}
+
+	res, err := s.extractor.Extract(ctx, a.ContentType, data, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	draft := ReceiptDraft{Type: models.TransactionTypeExpense}
+	if len(res.Fields.Total) > 0 {
+		draft.Amount = &res.Fields.Total[0].Value
+	}
+	if len(res.Fields.Currency) > 0 {
+		draft.Currency = res.Fields.Currency[0].Value
+	}
+	if len(res.Fields.Date) > 0 {
+		draft.Date = &res.Fields.Date[0].Value
+	}
+	if len(res.Fields.Merchant) > 0 {
+		draft.Description = &res.Fields.Merchant[0].Value
+	}
+
+	return &ReceiptExtraction{
+		AttachmentID:  a.ID,
+		TransactionID: a.TransactionID,
+		Result:        res,
+		Draft:         draft,
+	}, nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+)
+
+// occurrenceAt returns the n-th occurrence (0 = the anchor itself) of a
+// series repeating at the given interval. Monthly and yearly series are computed from
+// the anchor so that e.g. the 31st clamps to the last day of shorter months
+// instead of drifting.
+func occurrenceAt(anchor time.Time, interval models.RepeatInterval, n int) time.Time {
+	switch interval {
+	case models.RepeatDaily:
+		return anchor.AddDate(0, 0, n)
+	case models.RepeatWeekly:
+		return anchor.AddDate(0, 0, 7*n)
+	case models.RepeatMonthly:
+		return addMonthsClamped(anchor, n)
+	case models.RepeatYearly:
+		return addMonthsClamped(anchor, 12*n)
+	default:
+		return anchor
+	}
+}
+
+func addMonthsClamped(t time.Time, months int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	if d > last {
+		d = last
+	}
+	return time.Date(first.Year(), first.Month(), d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// occurrencesBetween lists the occurrences of a series in [from, to).
+func occurrencesBetween(anchor time.Time, interval models.RepeatInterval, from, to time.Time) []time.Time {
+	var res []time.Time
+	if interval == "" || interval == models.RepeatNone {
+		if !anchor.Before(from) && anchor.Before(to) {
+			res = append(res, anchor)
+		}
+		return res
+	}
+	// occurrenceAt never advances for an interval it does not know.
+	if !interval.Valid() {
+		return nil
+	}
+
+	n := 0
+	// Jump close to the window instead of walking from a distant anchor.
+	if anchor.Before(from) {
+		switch interval {
+		case models.RepeatDaily:
+			n = int(from.Sub(anchor).Hours()/24) - 1
+		case models.RepeatWeekly:
+			n = int(from.Sub(anchor).Hours()/(24*7)) - 1
+		case models.RepeatMonthly:
+			n = (from.Year()-anchor.Year())*12 + int(from.Month()) - int(anchor.Month()) - 1
+		case models.RepeatYearly:
+			n = from.Year() - anchor.Year() - 1
+		}
+		if n < 0 {
+			n = 0
+		}
+	}
+	for {
+		t := occurrenceAt(anchor, interval, n)
+		if !t.Before(to) {
+			break
+		}
+		if !t.Before(from) {
+			res = append(res, t)
+		}
+		n++
+	}
+	return res
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 9, 30, 0, 0, time.UTC)
+}
+
+func TestAddMonthsClamped(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   time.Time
+		months int
+		want   time.Time
+	}{
+		{"same day", date(2026, 3, 15), 1, date(2026, 4, 15)},
+		{"31st into 30-day month", date(2026, 1, 31), 3, date(2026, 4, 30)},
+		{"31st into February", date(2026, 1, 31), 1, date(2026, 2, 28)},
+		{"31st into leap February", date(2028, 1, 31), 1, date(2028, 2, 29)},
+		{"across year end", date(2026, 11, 30), 3, date(2027, 2, 28)},
+		{"backwards", date(2026, 3, 31), -1, date(2026, 2, 28)},
+		{"leap day plus a year", date(2028, 2, 29), 12, date(2029, 2, 28)},
+		{"leap day plus four years", date(2028, 2, 29), 48, date(2032, 2, 29)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addMonthsClamped(tt.from, tt.months); !got.Equal(tt.want) {
+				t.Errorf("addMonthsClamped(%s, %d) = %s, want %s", tt.from, tt.months, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrencesBetween(t *testing.T) {
+	tests := []struct {
+		name     string
+		anchor   time.Time
+		interval models.RepeatInterval
+		from, to time.Time
+		want     []time.Time
+	}{
+		{
+			name:     "one-off inside window",
+			anchor:   date(2026, 5, 10),
+			interval: models.RepeatNone,
+			from:     date(2026, 5, 1),
+			to:       date(2026, 6, 1),
+			want:     []time.Time{date(2026, 5, 10)},
+		},
+		{
+			name:     "one-off before window",
+			anchor:   date(2026, 4, 10),
+			interval: "",
+			from:     date(2026, 5, 1),
+			to:       date(2026, 6, 1),
+		},
+		{
+			name:     "window end is exclusive",
+			anchor:   date(2026, 5, 1),
+			interval: models.RepeatWeekly,
+			from:     date(2026, 5, 1),
+			to:       date(2026, 5, 15),
+			want:     []time.Time{date(2026, 5, 1), date(2026, 5, 8)},
+		},
+		{
+			name:     "daily from a distant anchor",
+			anchor:   date(2020, 1, 1),
+			interval: models.RepeatDaily,
+			from:     date(2026, 5, 1),
+			to:       date(2026, 5, 4),
+			want:     []time.Time{date(2026, 5, 1), date(2026, 5, 2), date(2026, 5, 3)},
+		},
+		{
+			name:     "monthly on the 31st clamps without drifting",
+			anchor:   date(2026, 1, 31),
+			interval: models.RepeatMonthly,
+			from:     date(2026, 2, 1),
+			to:       date(2026, 6, 1),
+			want:     []time.Time{date(2026, 2, 28), date(2026, 3, 31), date(2026, 4, 30), date(2026, 5, 31)},
+		},
+		{
+			name:     "yearly on a leap day",
+			anchor:   date(2024, 2, 29),
+			interval: models.RepeatYearly,
+			from:     date(2026, 1, 1),
+			to:       date(2029, 1, 1),
+			want:     []time.Time{date(2026, 2, 28), date(2027, 2, 28), date(2028, 2, 29)},
+		},
+		{
+			name:     "yearly outside window",
+			anchor:   date(2025, 12, 25),
+			interval: models.RepeatYearly,
+			from:     date(2026, 1, 1),
+			to:       date(2026, 12, 1),
+		},
+		{
+			name:     "anchor after window start",
+			anchor:   date(2026, 5, 20),
+			interval: models.RepeatMonthly,
+			from:     date(2026, 5, 1),
+			to:       date(2026, 8, 1),
+			want:     []time.Time{date(2026, 5, 20), date(2026, 6, 20), date(2026, 7, 20)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := occurrencesBetween(tt.anchor, tt.interval, tt.from, tt.to)
+			if !sameTimes(got, tt.want) {
+				t.Errorf("occurrencesBetween = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// An unknown interval must not loop forever, nor fill the window with
+// copies of the anchor.
+func TestOccurrencesBetweenUnknownInterval(t *testing.T) {
+	for _, anchor := range []time.Time{date(2026, 4, 1), date(2026, 5, 10)} {
+		got := occurrencesBetween(anchor, "biweekly", date(2026, 5, 1), date(2026, 6, 1))
+		if got != nil {
+			t.Errorf("anchor %s: occurrencesBetween = %v, want nil", anchor, got)
+		}
+	}
+}
+
+func TestValidateReminderInterval(t *testing.T) {
+	for _, interval := range []models.RepeatInterval{models.RepeatNone, models.RepeatDaily, models.RepeatWeekly, models.RepeatMonthly, models.RepeatYearly} {
+		if err := validateReminder(&models.Reminder{Title: "Rent", RepeatInterval: interval}); err != nil {
+			t.Errorf("%s: %v", interval, err)
+		}
+	}
+	for _, interval := range []models.RepeatInterval{"biweekly", "", "Weekly"} {
+		if err := validateReminder(&models.Reminder{Title: "Rent", RepeatInterval: interval}); err == nil {
+			t.Errorf("%q was accepted", interval)
+		}
+	}
+}
+
+// Occurrences keep their wall-clock time when the zone changes offset.
+func TestOccurrencesBetweenAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	at := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 8, 0, 0, 0, loc) }
+
+	daily := occurrencesBetween(at(3, 1), models.RepeatDaily, at(3, 7), at(3, 10))
+	if want := []time.Time{at(3, 7), at(3, 8), at(3, 9)}; !sameTimes(daily, want) {
+		t.Errorf("daily = %v, want %v", daily, want)
+	}
+
+	weekly := occurrencesBetween(at(10, 26), models.RepeatWeekly, at(10, 26), at(11, 10))
+	if want := []time.Time{at(10, 26), at(11, 2), at(11, 9)}; !sameTimes(weekly, want) {
+		t.Errorf("weekly = %v, want %v", weekly, want)
+	}
+	for _, o := range weekly {
+		if o.Hour() != 8 {
+			t.Errorf("occurrence %s moved off 08:00", o)
+		}
+	}
+}
+
+func sameTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -10,25 +10,27 @@ import (
 )
 
 type ReminderService interface {
-	Create(ctx context.Context, r *models.Reminder) (*models.Reminder, error)
+	Create(ctx context.Context, scope repositories.Scope, r *models.Reminder) (*models.Reminder, error)
 	List(ctx context.Context, f repositories.ReminderFilter) ([]models.Reminder, error)
-	Get(ctx context.Context, userID, id string) (*models.Reminder, error)
-	Update(ctx context.Context, userID string, r *models.Reminder) (*models.Reminder, error)
-	Delete(ctx context.Context, userID, id string) error
+	Get(ctx context.Context, scope repositories.Scope, id string) (*models.Reminder, error)
+	Update(ctx context.Context, scope repositories.Scope, r *models.Reminder) (*models.Reminder, error)
+	Delete(ctx context.Context, scope repositories.Scope, id string) error
 }
 
 type reminderService struct {
-	repo repositories.ReminderRepository
+	repo  repositories.ReminderRepository
+	tags  repositories.TagRepository
+	audit AuditService
 }
 
-func NewReminderService(repo repositories.ReminderRepository) ReminderService {
-	return &reminderService{repo: repo}
+func NewReminderService(repo repositories.ReminderRepository, tags repositories.TagRepository, audit AuditService) ReminderService {
+	return &reminderService{repo: repo, tags: tags, audit: audit}
 }
 
-func (s *reminderService) Create(ctx context.Context, r *models.Reminder) (*models.Reminder, error) {
-	if r.Title == "" {
-		return nil, errors.New("title is required")
-	}
+func (s *reminderService) Create(ctx context.Context, scope repositories.Scope, r *models.Reminder) (*models.Reminder, error) {
+	r.UserID = scope.UserID
+	r.HouseholdID = scope.HouseholdID
+
 	if r.DueAt.IsZero() {
 		r.DueAt = time.Now().Add(1 * time.Hour)
 	}
@@ -36,10 +38,24 @@ func (s *reminderService) Create(ctx context.Context, r *models.Reminder) (*mode
 		r.RepeatInterval = models.RepeatNone
 	}
 	r.IsActive = true
+	if err := validateReminder(r); err != nil {
+		return nil, err
+	}
+	var err error
+	if r.TagIDs, err = checkTagIDs(ctx, s.tags, scope, r.TagIDs); err != nil {
+		return nil, err
+	}
 
 	if err := s.repo.Create(ctx, r); err != nil {
 		return nil, err
 	}
+	s.audit.Record(ctx, AuditEntry{
+		UserID:     scope.UserID,
+		Action:     models.AuditCreated,
+		EntityType: models.AuditEntityReminder,
+		EntityID:   r.ID,
+		After:      r,
+	})
 	return r, nil
 }
 
@@ -47,18 +63,19 @@ func (s *reminderService) List(ctx context.Context, f repositories.ReminderFilte
 	return s.repo.List(ctx, f)
 }
 
-func (s *reminderService) Get(ctx context.Context, userID, id string) (*models.Reminder, error) {
-	return s.repo.FindByID(ctx, id, userID)
+func (s *reminderService) Get(ctx context.Context, scope repositories.Scope, id string) (*models.Reminder, error) {
+	return s.repo.FindByID(ctx, id, scope)
 }
 
-func (s *reminderService) Update(ctx context.Context, userID string, r *models.Reminder) (*models.Reminder, error) {
-	existing, err := s.repo.FindByID(ctx, r.ID, userID)
+func (s *reminderService) Update(ctx context.Context, scope repositories.Scope, r *models.Reminder) (*models.Reminder, error) {
+	existing, err := s.repo.FindByID(ctx, r.ID, scope)
 	if err != nil {
 		return nil, err
 	}
 	if existing == nil {
 		return nil, errors.New("reminder not found")
 	}
+	before := *existing
 
 	if r.Title != "" {
 		existing.Title = r.Title
@@ -73,13 +90,73 @@ func (s *reminderService) Update(ctx context.Context, userID string, r *models.R
 		existing.RepeatInterval = r.RepeatInterval
 	}
 	existing.IsActive = r.IsActive
+	if r.Amount != nil {
+		existing.Amount = r.Amount
+	}
+	if r.Currency != nil {
+		existing.Currency = r.Currency
+	}
+	if r.Type != nil {
+		existing.Type = r.Type
+	}
+	if r.CategoryID != nil {
+		existing.CategoryID = r.CategoryID
+	}
+	if r.TagIDs != nil {
+		if existing.TagIDs, err = checkTagIDs(ctx, s.tags, scope, r.TagIDs); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateReminder(existing); err != nil {
+		return nil, err
+	}
 
-	if err := s.repo.Update(ctx, existing); err != nil {
+	if err := s.repo.Update(ctx, scope, existing); err != nil {
 		return nil, err
 	}
+	s.audit.Record(ctx, AuditEntry{
+		UserID:     scope.UserID,
+		Action:     models.AuditUpdated,
+		EntityType: models.AuditEntityReminder,
+		EntityID:   existing.ID,
+		Before:     &before,
+		After:      existing,
+	})
 	return existing, nil
 }
 
-func (s *reminderService) Delete(ctx context.Context, userID, id string) error {
-	return s.repo.Delete(ctx, id, userID)
+func (s *reminderService) Delete(ctx context.Context, scope repositories.Scope, id string) error {
+	existing, err := s.repo.FindByID(ctx, id, scope)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id, scope); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, AuditEntry{
+		UserID:     scope.UserID,
+		Action:     models.AuditDeleted,
+		EntityType: models.AuditEntityReminder,
+		EntityID:   id,
+		Before:     existing,
+	})
+	return nil
+}
+
+// validateReminder checks a reminder's title, repeat interval and money
+// fields.
+func validateReminder(r *models.Reminder) error {
+	if r.Title == "" {
+		return errors.New("title is required")
+	}
+	if !r.RepeatInterval.Valid() {
+		return errors.New("repeatInterval must be none, daily, weekly, monthly or yearly")
+	}
+	if r.Amount != nil && *r.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if r.Type != nil && *r.Type != models.TransactionTypeIncome && *r.Type != models.TransactionTypeExpense {
+		return errors.New("invalid type")
+	}
+	return nil
 }
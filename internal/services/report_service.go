@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"errors"
+	"sort"
 	"time"
 
 	"github.com/ronak4195/personal-assistant/internal/models"
@@ -25,6 +26,13 @@ const (
 	GroupNone        GroupBy = "none"
 	GroupCategory    GroupBy = "category"
 	GroupSubcategory GroupBy = "subcategory"
+	GroupTree        GroupBy = "tree"
+	GroupTag         GroupBy = "tag"
+)
+
+const (
+	uncategorisedName = "Uncategorised"
+	untaggedName      = "Untagged"
 )
 
 type SummaryTotals struct {
@@ -40,6 +48,17 @@ type CategorySummary struct {
 	Expenses     float64 `json:"expenses"`
 }
 
+// TagSummary totals the transactions carrying a tag. A transaction with
+// several tags counts towards each of them, so these do not add up to the
+// overall totals. The untagged bucket has an empty ID.
+type TagSummary struct {
+	TagID    string  `json:"tagId"`
+	TagName  string  `json:"tagName"`
+	Income   float64 `json:"income"`
+	Expenses float64 `json:"expenses"`
+	Count    int     `json:"count"`
+}
+
 type SubcategorySummary struct {
 	SubcategoryID   string  `json:"subcategoryId"`
 	SubcategoryName string  `json:"subcategoryName"`
@@ -47,6 +66,19 @@ type SubcategorySummary struct {
 	Expenses        float64 `json:"expenses"`
 }
 
+// CategoryTreeNode carries the totals booked directly against a category
+// (Income/Expenses) and the totals rolled up from its whole subtree
+// (TotalIncome/TotalExpenses). The uncategorised bucket has an empty ID.
+type CategoryTreeNode struct {
+	CategoryID    string             `json:"categoryId"`
+	CategoryName  string             `json:"categoryName"`
+	Income        float64            `json:"income"`
+	Expenses      float64            `json:"expenses"`
+	TotalIncome   float64            `json:"totalIncome"`
+	TotalExpenses float64            `json:"totalExpenses"`
+	Children      []CategoryTreeNode `json:"children,omitempty"`
+}
+
 type SummaryReport struct {
 	Period struct {
 		Start time.Time `json:"start"`
@@ -55,27 +87,36 @@ type SummaryReport struct {
 	Totals     SummaryTotals        `json:"totals"`
 	ByCategory []CategorySummary    `json:"byCategory,omitempty"`
 	BySubcat   []SubcategorySummary `json:"bySubcategory,omitempty"`
+	Tree       []CategoryTreeNode   `json:"tree,omitempty"`
+	ByTag      []TagSummary         `json:"byTag,omitempty"`
 }
 
 type ReportService interface {
-	GetSummary(ctx context.Context, userID string, period SummaryPeriod, start, end *time.Time, groupBy GroupBy) (*SummaryReport, error)
+	GetSummary(ctx context.Context, scope repositories.Scope, period SummaryPeriod, start, end *time.Time, groupBy GroupBy) (*SummaryReport, error)
+	GetForecast(ctx context.Context, scope repositories.Scope, days int) (*ForecastReport, error)
 }
 
 type reportService struct {
-	txRepo  repositories.TransactionRepository
-	catRepo repositories.CategoryRepository
+	txRepo       repositories.TransactionRepository
+	catRepo      repositories.CategoryRepository
+	reminderRepo repositories.ReminderRepository
+	tagRepo      repositories.TagRepository
+	now          func() time.Time
 }
 
-func NewReportService(txRepo repositories.TransactionRepository, catRepo repositories.CategoryRepository) ReportService {
+func NewReportService(txRepo repositories.TransactionRepository, catRepo repositories.CategoryRepository, reminderRepo repositories.ReminderRepository, tagRepo repositories.TagRepository) ReportService {
 	return &reportService{
-		txRepo:  txRepo,
-		catRepo: catRepo,
+		txRepo:       txRepo,
+		catRepo:      catRepo,
+		reminderRepo: reminderRepo,
+		tagRepo:      tagRepo,
+		now:          time.Now,
 	}
 }
 
-func (s *reportService) GetSummary(ctx context.Context, userID string, period SummaryPeriod, start, end *time.Time, groupBy GroupBy) (*SummaryReport, error) {
+func (s *reportService) GetSummary(ctx context.Context, scope repositories.Scope, period SummaryPeriod, start, end *time.Time, groupBy GroupBy) (*SummaryReport, error) {
 	var from, to time.Time
-	now := time.Now().UTC()
+	now := s.now().UTC()
 
 	switch period {
 	case PeriodDaily:
@@ -104,7 +145,7 @@ func (s *reportService) GetSummary(ctx context.Context, userID string, period Su
 		return nil, errors.New("invalid period")
 	}
 
-	txs, err := s.txRepo.ListByDateRange(ctx, userID, from, to)
+	txs, err := s.txRepo.ListByDateRange(ctx, scope, from, to)
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +181,7 @@ func (s *reportService) GetSummary(ctx context.Context, userID string, period Su
 			if _, ok := agg[id]; !ok {
 				// Lazy load category name
 				if _, ok := catName[id]; !ok {
-					c, _ := s.catRepo.FindByID(ctx, id, userID)
+					c, _ := s.catRepo.FindByID(ctx, id, scope)
 					if c != nil {
 						catName[id] = c.Name
 					}
@@ -168,7 +209,7 @@ func (s *reportService) GetSummary(ctx context.Context, userID string, period Su
 			id := *tx.SubcategoryID
 			if _, ok := agg[id]; !ok {
 				if _, ok := catName[id]; !ok {
-					c, _ := s.catRepo.FindByID(ctx, id, userID)
+					c, _ := s.catRepo.FindByID(ctx, id, scope)
 					if c != nil {
 						catName[id] = c.Name
 					}
@@ -187,6 +228,18 @@ func (s *reportService) GetSummary(ctx context.Context, userID string, period Su
 		for _, v := range agg {
 			report.BySubcat = append(report.BySubcat, *v)
 		}
+	case GroupTree:
+		cats, err := s.catRepo.List(ctx, scope, nil)
+		if err != nil {
+			return nil, err
+		}
+		report.Tree = buildCategoryTree(cats, txs)
+	case GroupTag:
+		tags, err := s.tagRepo.List(ctx, scope)
+		if err != nil {
+			return nil, err
+		}
+		report.ByTag = summarizeByTag(tags, txs)
 	case GroupNone:
 		// nothing extra
 	default:
@@ -195,3 +248,172 @@ func (s *reportService) GetSummary(ctx context.Context, userID string, period Su
 
 	return report, nil
 }
+
+// summarizeByTag totals txs per tag, in tag name order, followed by the
+// transactions without any (known) tag.
+func summarizeByTag(tags []models.Tag, txs []models.Transaction) []TagSummary {
+	index := make(map[string]int, len(tags))
+	out := make([]TagSummary, len(tags))
+	for i, t := range tags {
+		index[t.ID] = i
+		out[i] = TagSummary{TagID: t.ID, TagName: t.Name}
+	}
+	untagged := TagSummary{TagName: untaggedName}
+
+	add := func(ts *TagSummary, tx models.Transaction) {
+		ts.Count++
+		if tx.Type == models.TransactionTypeIncome {
+			ts.Income += tx.Amount
+		} else if tx.Type == models.TransactionTypeExpense {
+			ts.Expenses += tx.Amount
+		}
+	}
+	for _, tx := range txs {
+		tagged := false
+		for _, id := range tx.TagIDs {
+			if i, ok := index[id]; ok {
+				add(&out[i], tx)
+				tagged = true
+			}
+		}
+		if !tagged {
+			add(&untagged, tx)
+		}
+	}
+
+	summaries := make([]TagSummary, 0, len(out)+1)
+	for _, ts := range out {
+		if ts.Count > 0 {
+			summaries = append(summaries, ts)
+		}
+	}
+	if untagged.Count > 0 {
+		summaries = append(summaries, untagged)
+	}
+	return summaries
+}
+
+// buildCategoryTree attributes each transaction to its most specific category
+// (subcategory first, then category) and rolls the amounts up to the roots.
+// Transactions without a known category land in a trailing uncategorised node.
+// Subtrees without any amounts are pruned.
+func buildCategoryTree(cats []models.Category, txs []models.Transaction) []CategoryTreeNode {
+	byID := make(map[string]models.Category, len(cats))
+	for _, c := range cats {
+		byID[c.ID] = c
+	}
+
+	type totals struct{ income, expenses float64 }
+	direct := map[string]*totals{}
+	uncategorised := &totals{}
+
+	for _, tx := range txs {
+		t := uncategorised
+		for _, id := range []*string{tx.SubcategoryID, tx.CategoryID} {
+			if id == nil {
+				continue
+			}
+			if _, ok := byID[*id]; ok {
+				if direct[*id] == nil {
+					direct[*id] = &totals{}
+				}
+				t = direct[*id]
+				break
+			}
+		}
+		if tx.Type == models.TransactionTypeIncome {
+			t.income += tx.Amount
+		} else if tx.Type == models.TransactionTypeExpense {
+			t.expenses += tx.Amount
+		}
+	}
+
+	children := map[string][]models.Category{}
+	var roots []models.Category
+	for _, c := range cats {
+		if c.ParentID != nil && *c.ParentID != c.ID {
+			if _, ok := byID[*c.ParentID]; ok {
+				children[*c.ParentID] = append(children[*c.ParentID], c)
+				continue
+			}
+		}
+		roots = append(roots, c)
+	}
+
+	visited := map[string]bool{}
+	var build func(c models.Category) (CategoryTreeNode, bool)
+	build = func(c models.Category) (CategoryTreeNode, bool) {
+		visited[c.ID] = true
+		node := CategoryTreeNode{
+			CategoryID:   c.ID,
+			CategoryName: c.Name,
+		}
+		if t := direct[c.ID]; t != nil {
+			node.Income = t.income
+			node.Expenses = t.expenses
+		}
+		node.TotalIncome = node.Income
+		node.TotalExpenses = node.Expenses
+
+		kids := children[c.ID]
+		sortCategoriesByName(kids)
+		for _, k := range kids {
+			if visited[k.ID] {
+				continue
+			}
+			child, ok := build(k)
+			if !ok {
+				continue
+			}
+			node.TotalIncome += child.TotalIncome
+			node.TotalExpenses += child.TotalExpenses
+			node.Children = append(node.Children, child)
+		}
+		return node, node.TotalIncome != 0 || node.TotalExpenses != 0
+	}
+
+	var tree []CategoryTreeNode
+	sortCategoriesByName(roots)
+	for _, c := range roots {
+		if node, ok := build(c); ok {
+			tree = append(tree, node)
+		}
+	}
+	// Categories caught in a parent cycle are never reached from a root;
+	// surface them as roots rather than dropping their amounts.
+	rest := make([]models.Category, 0)
+	for _, c := range cats {
+		if !visited[c.ID] {
+			rest = append(rest, c)
+		}
+	}
+	sortCategoriesByName(rest)
+	for _, c := range rest {
+		if visited[c.ID] {
+			continue
+		}
+		if node, ok := build(c); ok {
+			tree = append(tree, node)
+		}
+	}
+
+	if uncategorised.income != 0 || uncategorised.expenses != 0 {
+		tree = append(tree, CategoryTreeNode{
+			CategoryName:  uncategorisedName,
+			Income:        uncategorised.income,
+			Expenses:      uncategorised.expenses,
+			TotalIncome:   uncategorised.income,
+			TotalExpenses: uncategorised.expenses,
+		})
+	}
+	return tree
+}
+
+func sortCategoriesByName(cats []models.Category) {
+	sort.Slice(cats, func(i, j int) bool {
+		if cats[i].Name != cats[j].Name {
+			return cats[i].Name < cats[j].Name
+		}
+		return cats[i].ID < cats[j].ID
+	})
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+	"github.com/ronak4195/personal-assistant/internal/search"
+)
+
+const snippetWidth = 160
+
+var ErrEmptySearch = errors.New("search query is empty")
+
+type SearchHighlight struct {
+	Field string `json:"field"`
+	// Snippet is HTML-escaped text with the matches in <mark> tags.
+	Snippet string `json:"snippet"`
+}
+
+type SearchHit[T any] struct {
+	Item T `json:"item"`
+	// Category is the "Parent / Child" path of the item's category, or of
+	// the category itself for category hits.
+	Category   string            `json:"category,omitempty"`
+	Score      float64           `json:"score,omitempty"`
+	Highlights []SearchHighlight `json:"highlights,omitempty"`
+}
+
+type SearchGroup[T any] struct {
+	Total int64          `json:"total"`
+	Items []SearchHit[T] `json:"items"`
+}
+
+// SearchResults holds one group per kind searched; kinds that were not
+// searched are nil.
+type SearchResults struct {
+	Query        string                           `json:"query"`
+	Transactions *SearchGroup[models.Transaction] `json:"transactions,omitempty"`
+	Reminders    *SearchGroup[models.Reminder]    `json:"reminders,omitempty"`
+	Categories   *SearchGroup[models.Category]    `json:"categories,omitempty"`
+}
+
+type SearchService interface {
+	// Search runs a query in the search language (see package search) over
+	// the kinds in allowed, returning up to limit hits per kind. Malformed
+	// queries return a *search.ParseError.
+	Search(ctx context.Context, scope repositories.Scope, query string, allowed []search.Kind, limit int64) (*SearchResults, error)
+}
+
+type searchService struct {
+	repo       repositories.SearchRepository
+	categories repositories.CategoryRepository
+	tags       repositories.TagRepository
+}
+
+func NewSearchService(repo repositories.SearchRepository, categories repositories.CategoryRepository, tags repositories.TagRepository) SearchService {
+	return &searchService{
+		repo:       repo,
+		categories: categories,
+		tags:       tags,
+	}
+}
+
+func (s *searchService) Search(ctx context.Context, scope repositories.Scope, query string, allowed []search.Kind, limit int64) (*SearchResults, error) {
+	q, err := search.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	if q.IsEmpty() {
+		return nil, ErrEmptySearch
+	}
+	wants := func(k search.Kind) bool {
+		for _, a := range allowed {
+			if a == k {
+				return q.Includes(k)
+			}
+		}
+		return false
+	}
+
+	f := repositories.SearchFilter{
+		Scope:    scope,
+		Types:    q.Types,
+		Currency: q.Currency,
+		From:     q.From,
+		Until:    q.Until,
+		Limit:    limit,
+	}
+	if q.HasText() {
+		f.Text = q.TextSearch()
+	}
+	if r := q.Amount; r != nil {
+		f.MinAmount, f.MaxAmount = r.Min, r.Max
+		f.MinExclusive, f.MaxExclusive = r.MinExclusive, r.MaxExclusive
+	}
+
+	cats, err := s.categories.List(ctx, scope, nil)
+	if err != nil {
+		return nil, err
+	}
+	paths := categoryPaths(cats)
+	if len(q.Categories) > 0 {
+		f.CategoryIDs = matchCategoryPaths(paths, q.Categories)
+	}
+
+	// An unknown tag matches nothing rather than being an error, like an
+	// unknown word.
+	records := true
+	for _, name := range q.Tags {
+		t, err := s.tags.FindByName(ctx, scope, name)
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			records = false
+			break
+		}
+		f.TagIDs = append(f.TagIDs, t.ID)
+	}
+
+	terms := q.Terms()
+	res := &SearchResults{Query: query}
+
+	if wants(search.KindTransactions) {
+		group := &SearchGroup[models.Transaction]{Items: []SearchHit[models.Transaction]{}}
+		if records {
+			hits, total, err := s.repo.Transactions(ctx, f)
+			if err != nil {
+				return nil, err
+			}
+			group.Total = total
+			for _, h := range hits {
+				group.Items = append(group.Items, SearchHit[models.Transaction]{
+					Item:       h.Doc,
+					Category:   categoryPath(paths, h.Doc.SubcategoryID, h.Doc.CategoryID),
+					Score:      h.Score,
+					Highlights: highlights(terms, "description", h.Doc.Description),
+				})
+			}
+		}
+		res.Transactions = group
+	}
+
+	if wants(search.KindReminders) {
+		group := &SearchGroup[models.Reminder]{Items: []SearchHit[models.Reminder]{}}
+		if records {
+			hits, total, err := s.repo.Reminders(ctx, f)
+			if err != nil {
+				return nil, err
+			}
+			group.Total = total
+			for _, h := range hits {
+				group.Items = append(group.Items, SearchHit[models.Reminder]{
+					Item:     h.Doc,
+					Category: categoryPath(paths, h.Doc.CategoryID),
+					Score:    h.Score,
+					Highlights: append(
+						highlights(terms, "title", &h.Doc.Title),
+						highlights(terms, "description", h.Doc.Description)...,
+					),
+				})
+			}
+		}
+		res.Reminders = group
+	}
+
+	// Categories have no amounts, dates or tags, so a query using those
+	// qualifiers is about records only.
+	if wants(search.KindCategories) && !q.HasRecordFilters() {
+		group := &SearchGroup[models.Category]{Items: []SearchHit[models.Category]{}}
+		hits, total, err := s.repo.Categories(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		group.Total = total
+		for _, h := range hits {
+			group.Items = append(group.Items, SearchHit[models.Category]{
+				Item:       h.Doc,
+				Category:   paths[h.Doc.ID],
+				Score:      h.Score,
+				Highlights: highlights(terms, "name", &h.Doc.Name),
+			})
+		}
+		res.Categories = group
+	}
+
+	return res, nil
+}
+
+// matchCategoryPaths returns the categories whose path contains any of the
+// names, ignoring case. Matching on the path brings in the subcategories of
+// a matched parent. The result is never nil, so no match finds nothing.
+func matchCategoryPaths(paths map[string]string, names []string) []string {
+	ids := []string{}
+	for id, path := range paths {
+		path = strings.ToLower(path)
+		for _, n := range names {
+			if strings.Contains(path, strings.ToLower(n)) {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// categoryPath returns the path of the first category ID that is set.
+func categoryPath(paths map[string]string, ids ...*string) string {
+	for _, id := range ids {
+		if id != nil {
+			return paths[*id]
+		}
+	}
+	return ""
+}
+
+func highlights(terms []string, field string, text *string) []SearchHighlight {
+	if text == nil {
+		return nil
+	}
+	snippet, ok := search.Highlight(*text, terms, snippetWidth)
+	if !ok {
+		return nil
+	}
+	return []SearchHighlight{{Field: field, Snippet: snippet}}
+}
@@ -0,0 +1,869 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+var (
+	ErrContactNotFound     = errors.New("contact not found")
+	ErrContactHasBalance   = errors.New("contact still has an outstanding balance; settle up first")
+	ErrSplitNotFound       = errors.New("transaction is not split")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrCounterpartNotFound is returned alike for accounts that do not
+	// exist and for those the caller may not name, so that splits cannot be
+	// used to find out who has an account.
+	ErrCounterpartNotFound = errors.New("no linked account or household member matches; send them a contact link or add them as a contact")
+	ErrContactLinkNotFound = errors.New("contact link not found")
+	ErrContactLinkExists   = errors.New("you already sent a link request to this address")
+	ErrSettlementNotFound  = errors.New("settlement not found or no longer pending")
+)
+
+// PartyRef names a participant in a request: a registered user by ID or
+// email address, or one of the caller's contacts. Registered users must be
+// linked contacts of the caller or share a household with them.
+type PartyRef struct {
+	UserID    string `json:"userId,omitempty"`
+	Email     string `json:"email,omitempty"`
+	ContactID string `json:"contactId,omitempty"`
+}
+
+type SplitParticipant struct {
+	PartyRef
+	// Value is an amount, a percentage or a number of shares depending on
+	// the split method. It is not needed for equal splits.
+	Value float64 `json:"value,omitempty"`
+}
+
+type SplitRequest struct {
+	Method       models.SplitMethod `json:"method"`
+	Participants []SplitParticipant `json:"participants"`
+}
+
+// SettlementRequest records a payment between the caller and one
+// counterpart: set From when the counterpart paid the caller, To when the
+// caller paid the counterpart.
+type SettlementRequest struct {
+	From     *PartyRef `json:"from,omitempty"`
+	To       *PartyRef `json:"to,omitempty"`
+	Amount   float64   `json:"amount"`
+	Currency string    `json:"currency"`
+	Note     *string   `json:"note,omitempty"`
+	Date     time.Time `json:"date"`
+}
+
+type CurrencyBalance struct {
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+}
+
+// CounterpartBalance is what one counterpart owes the user per currency.
+// Negative amounts are what the user owes them.
+type CounterpartBalance struct {
+	models.Party
+	Name     string            `json:"name"`
+	Balances []CurrencyBalance `json:"balances"`
+}
+
+type SplitService interface {
+	CreateContact(ctx context.Context, userID string, c *models.Contact) (*models.Contact, error)
+	ListContacts(ctx context.Context, userID string) ([]models.Contact, error)
+	UpdateContact(ctx context.Context, userID string, c *models.Contact) (*models.Contact, error)
+	// DeleteContact refuses while the contact owes or is owed anything.
+	DeleteContact(ctx context.Context, userID, id string) error
+
+	// Split divides an expense transaction between participants, replacing
+	// any earlier split of it. Only whoever recorded the transaction (and so
+	// paid for it) can split it.
+	Split(ctx context.Context, scope repositories.Scope, transactionID string, req SplitRequest) (*models.ExpenseSplit, error)
+	GetSplit(ctx context.Context, scope repositories.Scope, transactionID string) (*models.ExpenseSplit, error)
+	RemoveSplit(ctx context.Context, scope repositories.Scope, transactionID string) error
+
+	// RequestContactLink asks the account with email to link with the
+	// user. The request is saved whether or not such an account exists.
+	RequestContactLink(ctx context.Context, userID, email string) (*models.ContactLink, error)
+	ListContactLinks(ctx context.Context, userID string) ([]models.ContactLink, error)
+	AcceptContactLink(ctx context.Context, userID, id string) (*models.ContactLink, error)
+	// RemoveContactLink declines, withdraws or ends a link; either side
+	// can remove it.
+	RemoveContactLink(ctx context.Context, userID, id string) error
+
+	// Settle records a payment. With a contact it is confirmed at once and
+	// the caller's transaction is added; with another user it stays pending
+	// until they confirm it.
+	Settle(ctx context.Context, userID string, req SettlementRequest) (*models.Settlement, error)
+	// ConfirmSettlement confirms a pending settlement recorded by the other
+	// party, adding the ledger entry and the matching expense and income
+	// transactions for both.
+	ConfirmSettlement(ctx context.Context, userID, id string) (*models.Settlement, error)
+	// DeclineSettlement rejects a pending settlement; the party who recorded
+	// it can withdraw it the same way.
+	DeclineSettlement(ctx context.Context, userID, id string) error
+	ListSettlements(ctx context.Context, userID string, limit, offset int64) ([]models.Settlement, int64, error)
+
+	// Balances nets the ledger per counterpart. With simplify, the debts
+	// among the user and their registered counterparts are rerouted to need
+	// as few payments as possible; contacts are only known to their owner and
+	// always keep direct balances.
+	Balances(ctx context.Context, userID string, simplify bool) ([]CounterpartBalance, error)
+}
+
+type splitService struct {
+	repo         repositories.SplitRepository
+	contacts     repositories.ContactRepository
+	users        repositories.UserRepository
+	households   repositories.HouseholdRepository
+	transactions TransactionService
+}
+
+func NewSplitService(repo repositories.SplitRepository, contacts repositories.ContactRepository, users repositories.UserRepository, households repositories.HouseholdRepository, transactions TransactionService) SplitService {
+	return &splitService{
+		repo:         repo,
+		contacts:     contacts,
+		users:        users,
+		households:   households,
+		transactions: transactions,
+	}
+}
+
+func (s *splitService) CreateContact(ctx context.Context, userID string, c *models.Contact) (*models.Contact, error) {
+	if err := normalizeContact(c); err != nil {
+		return nil, err
+	}
+	c.ID = ""
+	c.UserID = userID
+	if err := s.contacts.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *splitService) ListContacts(ctx context.Context, userID string) ([]models.Contact, error) {
+	return s.contacts.List(ctx, userID)
+}
+
+func (s *splitService) UpdateContact(ctx context.Context, userID string, c *models.Contact) (*models.Contact, error) {
+	existing, err := s.contacts.FindByID(ctx, c.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrContactNotFound
+	}
+	if c.Name != "" {
+		existing.Name = c.Name
+	}
+	if c.Email != nil {
+		existing.Email = c.Email
+	}
+	if err := normalizeContact(existing); err != nil {
+		return nil, err
+	}
+	if err := s.contacts.Update(ctx, existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+func normalizeContact(c *models.Contact) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if c.Email != nil {
+		email := strings.ToLower(strings.TrimSpace(*c.Email))
+		if email == "" {
+			c.Email = nil
+		} else {
+			c.Email = &email
+		}
+	}
+	return nil
+}
+
+func (s *splitService) DeleteContact(ctx context.Context, userID, id string) error {
+	c, err := s.contacts.FindByID(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return ErrContactNotFound
+	}
+
+	entries, err := s.repo.ListEntries(ctx, []string{userID})
+	if err != nil {
+		return err
+	}
+	balances := netPositions(entries, models.UserParty(userID))
+	for _, cents := range balances[models.Party{ContactID: id}.Key()] {
+		if cents != 0 {
+			return ErrContactHasBalance
+		}
+	}
+	return s.contacts.Delete(ctx, id, userID)
+}
+
+func (s *splitService) RequestContactLink(ctx context.Context, userID, email string) (*models.ContactLink, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if _, err := mail.ParseAddress(email); err != nil || strings.ContainsAny(email, "<> ") {
+		return nil, errors.New("invalid email")
+	}
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errors.New("user not found")
+	}
+	if email == u.Email {
+		return nil, errors.New("you cannot link with yourself")
+	}
+
+	l := &models.ContactLink{RequesterID: userID, Email: email}
+	created, err := s.contacts.CreateLink(ctx, l)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrContactLinkExists
+	}
+	return l, nil
+}
+
+func (s *splitService) ListContactLinks(ctx context.Context, userID string) ([]models.ContactLink, error) {
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errors.New("user not found")
+	}
+	return s.contacts.ListLinks(ctx, userID, u.Email)
+}
+
+func (s *splitService) AcceptContactLink(ctx context.Context, userID, id string) (*models.ContactLink, error) {
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errors.New("user not found")
+	}
+	l, err := s.contacts.FindLink(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil || l.Email != u.Email || l.Status != models.ContactLinkPending {
+		return nil, ErrContactLinkNotFound
+	}
+	// Otherwise anyone could sign up with the requested address and link.
+	if !u.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+	ok, err := s.contacts.AcceptLink(ctx, l.ID, u.Email, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrContactLinkNotFound
+	}
+	return s.contacts.FindLink(ctx, l.ID)
+}
+
+func (s *splitService) RemoveContactLink(ctx context.Context, userID, id string) error {
+	l, err := s.contacts.FindLink(ctx, id)
+	if err != nil {
+		return err
+	}
+	if l == nil {
+		return ErrContactLinkNotFound
+	}
+	mine := l.RequesterID == userID || l.AddresseeID == userID
+	if !mine && l.Status == models.ContactLinkPending {
+		u, err := s.users.FindByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		mine = u != nil && u.Email == l.Email
+	}
+	if !mine {
+		return ErrContactLinkNotFound
+	}
+	return s.contacts.DeleteLink(ctx, l.ID)
+}
+
+func (s *splitService) splittableTransaction(ctx context.Context, scope repositories.Scope, id string) (*models.Transaction, error) {
+	tx, err := s.transactions.Get(ctx, scope, id)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, ErrTransactionNotFound
+	}
+	if tx.UserID != scope.UserID {
+		return nil, errors.New("only whoever recorded a transaction can split it")
+	}
+	return tx, nil
+}
+
+func (s *splitService) Split(ctx context.Context, scope repositories.Scope, transactionID string, req SplitRequest) (*models.ExpenseSplit, error) {
+	tx, err := s.splittableTransaction(ctx, scope, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if tx.Type != models.TransactionTypeExpense {
+		return nil, errors.New("only expenses can be split")
+	}
+	if !req.Method.Valid() {
+		return nil, errors.New("method must be equal, exact, percentage or shares")
+	}
+	if len(req.Participants) == 0 {
+		return nil, errors.New("participants are required")
+	}
+
+	payer := models.UserParty(tx.UserID)
+	parties := make([]models.Party, len(req.Participants))
+	values := make([]float64, len(req.Participants))
+	seen := map[string]bool{}
+	others := 0
+	for i, p := range req.Participants {
+		party, err := s.resolveParty(ctx, scope.UserID, p.PartyRef)
+		if err != nil {
+			return nil, err
+		}
+		if seen[party.Key()] {
+			return nil, errors.New("each participant can only appear once")
+		}
+		seen[party.Key()] = true
+		if party != payer {
+			others++
+		}
+		parties[i] = party
+		values[i] = p.Value
+	}
+	if others == 0 {
+		return nil, errors.New("add at least one participant besides yourself")
+	}
+
+	amounts, err := splitAmounts(req.Method, toCents(tx.Amount), values)
+	if err != nil {
+		return nil, err
+	}
+
+	split := &models.ExpenseSplit{
+		TransactionID: tx.ID,
+		CreatedBy:     scope.UserID,
+		PaidBy:        tx.UserID,
+		Method:        req.Method,
+		Amount:        tx.Amount,
+		Currency:      tx.Currency,
+		Description:   tx.Description,
+		Date:          tx.Date,
+	}
+	var entries []models.LedgerEntry
+	for i, party := range parties {
+		share := models.SplitShare{Party: party, Amount: fromCents(amounts[i])}
+		if req.Method != models.SplitEqual {
+			share.Value = values[i]
+		}
+		split.Shares = append(split.Shares, share)
+		if party == payer || amounts[i] == 0 {
+			continue
+		}
+		entries = append(entries, newLedgerEntry(party, payer, amounts[i], tx.Currency))
+	}
+
+	if existing, err := s.repo.FindSplitByTransaction(ctx, tx.ID); err != nil {
+		return nil, err
+	} else if existing != nil {
+		if err := s.repo.DeleteSplit(ctx, existing.ID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.CreateSplit(ctx, split, entries); err != nil {
+		return nil, err
+	}
+	return split, nil
+}
+
+func (s *splitService) GetSplit(ctx context.Context, scope repositories.Scope, transactionID string) (*models.ExpenseSplit, error) {
+	tx, err := s.transactions.Get(ctx, scope, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, ErrTransactionNotFound
+	}
+	split, err := s.repo.FindSplitByTransaction(ctx, tx.ID)
+	if err != nil {
+		return nil, err
+	}
+	if split == nil {
+		return nil, ErrSplitNotFound
+	}
+	return split, nil
+}
+
+func (s *splitService) RemoveSplit(ctx context.Context, scope repositories.Scope, transactionID string) error {
+	tx, err := s.splittableTransaction(ctx, scope, transactionID)
+	if err != nil {
+		return err
+	}
+	split, err := s.repo.FindSplitByTransaction(ctx, tx.ID)
+	if err != nil {
+		return err
+	}
+	if split == nil {
+		return ErrSplitNotFound
+	}
+	return s.repo.DeleteSplit(ctx, split.ID)
+}
+
+func (s *splitService) Settle(ctx context.Context, userID string, req SettlementRequest) (*models.Settlement, error) {
+	if (req.From == nil) == (req.To == nil) {
+		return nil, errors.New("set either from or to")
+	}
+	if req.Amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	if req.Currency == "" {
+		return nil, errors.New("currency is required")
+	}
+	if req.Date.IsZero() {
+		req.Date = time.Now().UTC()
+	}
+
+	me := models.UserParty(userID)
+	ref := req.To
+	if ref == nil {
+		ref = req.From
+	}
+	other, err := s.resolveParty(ctx, userID, *ref)
+	if err != nil {
+		return nil, err
+	}
+	if other == me {
+		return nil, errors.New("you cannot settle up with yourself")
+	}
+
+	st := &models.Settlement{
+		CreatedBy: userID,
+		From:      me,
+		To:        other,
+		Amount:    req.Amount,
+		Currency:  req.Currency,
+		Note:      req.Note,
+		Date:      req.Date,
+		Status:    models.SettlementConfirmed,
+	}
+	if req.From != nil {
+		st.From, st.To = other, me
+	}
+
+	// Another user has to agree before the payment counts, so nothing is
+	// booked for either side yet.
+	if other.UserID != "" {
+		st.Status = models.SettlementPending
+		if err := s.repo.CreateSettlement(ctx, st, nil); err != nil {
+			return nil, err
+		}
+		return st, nil
+	}
+
+	created, err := s.settlementTransactions(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+	entry := newLedgerEntry(st.To, st.From, toCents(st.Amount), st.Currency)
+	if err := s.repo.CreateSettlement(ctx, st, []models.LedgerEntry{entry}); err != nil {
+		s.removeSettlementTransactions(ctx, created)
+		return nil, err
+	}
+	return st, nil
+}
+
+// pendingSettlement returns a pending settlement between userID and another
+// registered user.
+func (s *splitService) pendingSettlement(ctx context.Context, userID, id string) (*models.Settlement, error) {
+	st, err := s.repo.FindSettlement(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil || st.Status != models.SettlementPending ||
+		(st.From.UserID != userID && st.To.UserID != userID) {
+		return nil, ErrSettlementNotFound
+	}
+	return st, nil
+}
+
+func (s *splitService) ConfirmSettlement(ctx context.Context, userID, id string) (*models.Settlement, error) {
+	st, err := s.pendingSettlement(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if st.CreatedBy == userID {
+		return nil, errors.New("the other party has to confirm this settlement")
+	}
+
+	created, err := s.settlementTransactions(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+	entry := newLedgerEntry(st.To, st.From, toCents(st.Amount), st.Currency)
+	ok, err := s.repo.ConfirmSettlement(ctx, st, entry)
+	if err == nil && !ok {
+		err = ErrSettlementNotFound
+	}
+	if err != nil {
+		s.removeSettlementTransactions(ctx, created)
+		return nil, err
+	}
+	return st, nil
+}
+
+func (s *splitService) DeclineSettlement(ctx context.Context, userID, id string) error {
+	st, err := s.pendingSettlement(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	ok, err := s.repo.DeclineSettlement(ctx, st.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSettlementNotFound
+	}
+	return nil
+}
+
+type settlementTransaction struct {
+	userID, id string
+}
+
+// settlementTransactions adds an expense for the payer and income for the
+// payee, for whichever of them is a registered user, and sets them on st.
+// They go through the transaction service so that they are audited like any
+// other.
+func (s *splitService) settlementTransactions(ctx context.Context, st *models.Settlement) ([]settlementTransaction, error) {
+	fromName := s.partyName(ctx, st.CreatedBy, st.From)
+	toName := s.partyName(ctx, st.CreatedBy, st.To)
+
+	var created []settlementTransaction
+	record := func(owner models.Party, typ models.TransactionType, desc string) error {
+		if owner.UserID == "" {
+			return nil
+		}
+		tx, err := s.transactions.Create(ctx, repositories.Scope{UserID: owner.UserID}, &models.Transaction{
+			Type:        typ,
+			Amount:      st.Amount,
+			Currency:    st.Currency,
+			Description: &desc,
+			Date:        st.Date,
+		})
+		if err != nil {
+			return err
+		}
+		created = append(created, settlementTransaction{userID: owner.UserID, id: tx.ID})
+		return nil
+	}
+	err := record(st.From, models.TransactionTypeExpense, settlementDescription("Settle-up to", toName))
+	if err == nil {
+		err = record(st.To, models.TransactionTypeIncome, settlementDescription("Settle-up from", fromName))
+	}
+	if err != nil {
+		s.removeSettlementTransactions(ctx, created)
+		return nil, err
+	}
+
+	st.TransactionIDs = nil
+	for _, t := range created {
+		st.TransactionIDs = append(st.TransactionIDs, t.id)
+	}
+	return created, nil
+}
+
+func (s *splitService) removeSettlementTransactions(ctx context.Context, created []settlementTransaction) {
+	for _, t := range created {
+		if err := s.transactions.Delete(ctx, repositories.Scope{UserID: t.userID}, t.id); err != nil {
+			log.Printf("splits: remove settlement transaction %s: %v", t.id, err)
+		}
+	}
+}
+
+// partyName is the display name of a party; contacts are looked up among
+// those of owner.
+func (s *splitService) partyName(ctx context.Context, owner string, p models.Party) string {
+	if p.ContactID != "" {
+		if c, err := s.contacts.FindByID(ctx, p.ContactID, owner); err == nil && c != nil {
+			return c.Name
+		}
+		return ""
+	}
+	if u, err := s.users.FindByID(ctx, p.UserID); err == nil && u != nil {
+		return u.Name
+	}
+	return ""
+}
+
+func settlementDescription(prefix, name string) string {
+	if name == "" {
+		return prefix + " contact"
+	}
+	return prefix + " " + name
+}
+
+func (s *splitService) ListSettlements(ctx context.Context, userID string, limit, offset int64) ([]models.Settlement, int64, error) {
+	return s.repo.ListSettlements(ctx, userID, limit, offset)
+}
+
+func (s *splitService) Balances(ctx context.Context, userID string, simplify bool) ([]CounterpartBalance, error) {
+	me := models.UserParty(userID)
+	entries, err := s.repo.ListEntries(ctx, []string{userID})
+	if err != nil {
+		return nil, err
+	}
+	balances := netPositions(entries, me)
+
+	if simplify {
+		group := []string{userID}
+		for key := range balances {
+			if id, ok := strings.CutPrefix(key, "user:"); ok {
+				group = append(group, id)
+			}
+		}
+		groupEntries, err := s.repo.ListEntries(ctx, group)
+		if err != nil {
+			return nil, err
+		}
+		for key := range balances {
+			if strings.HasPrefix(key, "user:") {
+				delete(balances, key)
+			}
+		}
+		for key, byCurrency := range simplifiedPositions(groupEntries, group, me) {
+			balances[key] = byCurrency
+		}
+	}
+
+	return s.describeBalances(ctx, userID, balances)
+}
+
+// netPositions sums the entries involving me per counterpart and currency,
+// in cents. Positive amounts are owed to me.
+func netPositions(entries []models.LedgerEntry, me models.Party) map[string]map[string]int64 {
+	out := map[string]map[string]int64{}
+	add := func(other models.Party, currency string, cents int64) {
+		byCurrency := out[other.Key()]
+		if byCurrency == nil {
+			byCurrency = map[string]int64{}
+			out[other.Key()] = byCurrency
+		}
+		byCurrency[currency] += cents
+	}
+	for _, e := range entries {
+		cents := toCents(e.Amount)
+		switch me {
+		case e.Creditor:
+			add(e.Debtor, e.Currency, cents)
+		case e.Debtor:
+			add(e.Creditor, e.Currency, -cents)
+		}
+	}
+	return out
+}
+
+// simplifiedPositions nets the debts among the given users and returns the
+// payments involving me from simplifyDebts, in the shape of netPositions.
+func simplifiedPositions(entries []models.LedgerEntry, group []string, me models.Party) map[string]map[string]int64 {
+	inGroup := map[string]bool{}
+	for _, id := range group {
+		inGroup[id] = true
+	}
+	net := map[string]map[string]int64{}
+	for _, e := range entries {
+		if e.Debtor.UserID == "" || e.Creditor.UserID == "" ||
+			!inGroup[e.Debtor.UserID] || !inGroup[e.Creditor.UserID] {
+			continue
+		}
+		if net[e.Currency] == nil {
+			net[e.Currency] = map[string]int64{}
+		}
+		cents := toCents(e.Amount)
+		net[e.Currency][e.Debtor.Key()] -= cents
+		net[e.Currency][e.Creditor.Key()] += cents
+	}
+
+	out := map[string]map[string]int64{}
+	add := func(key, currency string, cents int64) {
+		if out[key] == nil {
+			out[key] = map[string]int64{}
+		}
+		out[key][currency] += cents
+	}
+	for currency, positions := range net {
+		for _, t := range simplifyDebts(positions) {
+			switch me.Key() {
+			case t.to:
+				add(t.from, currency, t.amount)
+			case t.from:
+				add(t.to, currency, -t.amount)
+			}
+		}
+	}
+	return out
+}
+
+func (s *splitService) describeBalances(ctx context.Context, userID string, balances map[string]map[string]int64) ([]CounterpartBalance, error) {
+	var contactIDs []string
+	for key := range balances {
+		if id, ok := strings.CutPrefix(key, "contact:"); ok {
+			contactIDs = append(contactIDs, id)
+		}
+	}
+	contacts, err := s.contacts.FindByIDs(ctx, userID, contactIDs)
+	if err != nil {
+		return nil, err
+	}
+	contactNames := map[string]string{}
+	for _, c := range contacts {
+		contactNames[c.ID] = c.Name
+	}
+
+	out := []CounterpartBalance{}
+	for key, byCurrency := range balances {
+		cb := CounterpartBalance{}
+		for currency, cents := range byCurrency {
+			if cents != 0 {
+				cb.Balances = append(cb.Balances, CurrencyBalance{Currency: currency, Amount: fromCents(cents)})
+			}
+		}
+		if len(cb.Balances) == 0 {
+			continue
+		}
+		sort.Slice(cb.Balances, func(i, j int) bool { return cb.Balances[i].Currency < cb.Balances[j].Currency })
+
+		if id, ok := strings.CutPrefix(key, "contact:"); ok {
+			cb.ContactID = id
+			cb.Name = contactNames[id]
+		} else {
+			cb.UserID = strings.TrimPrefix(key, "user:")
+			u, err := s.users.FindByID(ctx, cb.UserID)
+			if err != nil {
+				return nil, err
+			}
+			if u != nil {
+				cb.Name = u.Name
+			}
+		}
+		out = append(out, cb)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Key() < out[j].Key()
+	})
+	return out, nil
+}
+
+// resolveParty looks up a participant.
+func (s *splitService) resolveParty(ctx context.Context, userID string, ref PartyRef) (models.Party, error) {
+	set := 0
+	for _, v := range []string{ref.UserID, ref.Email, ref.ContactID} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return models.Party{}, errors.New("identify each participant by exactly one of userId, email or contactId")
+	}
+
+	if ref.ContactID != "" {
+		c, err := s.contacts.FindByID(ctx, ref.ContactID, userID)
+		if err != nil {
+			return models.Party{}, err
+		}
+		if c == nil {
+			return models.Party{}, ErrContactNotFound
+		}
+		return models.Party{ContactID: c.ID}, nil
+	}
+
+	var (
+		u   *models.User
+		err error
+	)
+	if ref.Email != "" {
+		u, err = s.users.FindByEmail(ctx, strings.ToLower(strings.TrimSpace(ref.Email)))
+	} else {
+		u, err = s.users.FindByID(ctx, ref.UserID)
+	}
+	if err != nil {
+		return models.Party{}, err
+	}
+	if u == nil {
+		return models.Party{}, ErrCounterpartNotFound
+	}
+	if allowed, err := s.canName(ctx, userID, u.ID); err != nil {
+		return models.Party{}, err
+	} else if !allowed {
+		return models.Party{}, ErrCounterpartNotFound
+	}
+	return models.UserParty(u.ID), nil
+}
+
+// canName reports whether userID may name otherID in splits and
+// settlements: they need an accepted contact link or a shared household.
+func (s *splitService) canName(ctx context.Context, userID, otherID string) (bool, error) {
+	if userID == otherID {
+		return true, nil
+	}
+	if linked, err := s.contacts.Linked(ctx, userID, otherID); err != nil || linked {
+		return linked, err
+	}
+
+	mine, err := s.households.ListMemberships(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if len(mine) == 0 {
+		return false, nil
+	}
+	theirs, err := s.households.ListMemberships(ctx, otherID)
+	if err != nil {
+		return false, err
+	}
+	shared := map[string]bool{}
+	for _, m := range mine {
+		shared[m.HouseholdID] = true
+	}
+	for _, m := range theirs {
+		if shared[m.HouseholdID] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func newLedgerEntry(debtor, creditor models.Party, cents int64, currency string) models.LedgerEntry {
+	e := models.LedgerEntry{
+		Debtor:   debtor,
+		Creditor: creditor,
+		Amount:   fromCents(cents),
+		Currency: currency,
+	}
+	for _, p := range []models.Party{debtor, creditor} {
+		if p.UserID != "" {
+			e.Users = append(e.Users, p.UserID)
+		}
+	}
+	return e
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+type memContacts struct {
+	repositories.ContactRepository
+	links [][2]string
+}
+
+func (m *memContacts) Linked(_ context.Context, userID, otherID string) (bool, error) {
+	for _, l := range m.links {
+		if (l[0] == userID && l[1] == otherID) || (l[0] == otherID && l[1] == userID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type memHouseholds struct {
+	repositories.HouseholdRepository
+	members map[string][]string // user ID to household IDs
+}
+
+func (m *memHouseholds) ListMemberships(_ context.Context, userID string) ([]models.HouseholdMember, error) {
+	var out []models.HouseholdMember
+	for _, id := range m.members[userID] {
+		out = append(out, models.HouseholdMember{HouseholdID: id, UserID: userID})
+	}
+	return out, nil
+}
+
+type memSettlements struct {
+	repositories.SplitRepository
+	byID    map[string]*models.Settlement
+	entries []models.LedgerEntry
+}
+
+func (m *memSettlements) CreateSettlement(_ context.Context, s *models.Settlement, entries []models.LedgerEntry) error {
+	s.ID = strconv.Itoa(len(m.byID) + 1)
+	cp := *s
+	m.byID[s.ID] = &cp
+	m.entries = append(m.entries, entries...)
+	return nil
+}
+
+func (m *memSettlements) FindSettlement(_ context.Context, id string) (*models.Settlement, error) {
+	if s, ok := m.byID[id]; ok {
+		cp := *s
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (m *memSettlements) ConfirmSettlement(_ context.Context, s *models.Settlement, entry models.LedgerEntry) (bool, error) {
+	stored := m.byID[s.ID]
+	if stored == nil || stored.Status != models.SettlementPending {
+		return false, nil
+	}
+	stored.Status = models.SettlementConfirmed
+	stored.TransactionIDs = s.TransactionIDs
+	s.Status = models.SettlementConfirmed
+	m.entries = append(m.entries, entry)
+	return true, nil
+}
+
+func (m *memSettlements) DeclineSettlement(_ context.Context, id string) (bool, error) {
+	stored := m.byID[id]
+	if stored == nil || stored.Status != models.SettlementPending {
+		return false, nil
+	}
+	stored.Status = models.SettlementDeclined
+	return true, nil
+}
+
+// memTransactions stands in for the transaction service, which is where
+// settlement transactions must go to be audited.
+type memTransactions struct {
+	TransactionService
+	created map[string]*models.Transaction
+	deleted []string
+}
+
+func (m *memTransactions) Create(_ context.Context, scope repositories.Scope, tx *models.Transaction) (*models.Transaction, error) {
+	tx.ID = "tx-" + strconv.Itoa(len(m.created)+1)
+	tx.UserID = scope.UserID
+	m.created[tx.ID] = tx
+	return tx, nil
+}
+
+func (m *memTransactions) Delete(_ context.Context, _ repositories.Scope, id string) error {
+	m.deleted = append(m.deleted, id)
+	return nil
+}
+
+func newTestSplitService() (*splitService, *memSettlements, *memTransactions) {
+	users := &memUsers{byID: map[string]*models.User{}}
+	for _, id := range []string{"alice", "bob", "carol", "dave"} {
+		users.byID[id] = &models.User{ID: id, Email: id + "@example.com", Name: id}
+	}
+	settlements := &memSettlements{byID: map[string]*models.Settlement{}}
+	transactions := &memTransactions{created: map[string]*models.Transaction{}}
+	return &splitService{
+		repo:         settlements,
+		contacts:     &memContacts{links: [][2]string{{"alice", "bob"}}},
+		users:        users,
+		households:   &memHouseholds{members: map[string][]string{"alice": {"h1"}, "carol": {"h1"}, "dave": {"h2"}}},
+		transactions: transactions,
+	}, settlements, transactions
+}
+
+func TestResolvePartyNeedsLinkOrHousehold(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestSplitService()
+
+	tests := []struct {
+		name string
+		ref  PartyRef
+		want error
+	}{
+		{"self", PartyRef{UserID: "alice"}, nil},
+		{"linked contact", PartyRef{UserID: "bob"}, nil},
+		{"linked contact by email", PartyRef{Email: " Bob@Example.com"}, nil},
+		{"household member", PartyRef{Email: "carol@example.com"}, nil},
+		{"registered stranger", PartyRef{UserID: "dave"}, ErrCounterpartNotFound},
+		{"stranger by email", PartyRef{Email: "dave@example.com"}, ErrCounterpartNotFound},
+		{"no such account", PartyRef{Email: "nobody@example.com"}, ErrCounterpartNotFound},
+		{"no such user ID", PartyRef{UserID: "nobody"}, ErrCounterpartNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.resolveParty(ctx, "alice", tt.ref)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSettleWithUserNeedsConfirmation(t *testing.T) {
+	ctx := context.Background()
+	svc, settlements, transactions := newTestSplitService()
+
+	st, err := svc.Settle(ctx, "alice", SettlementRequest{To: &PartyRef{UserID: "bob"}, Amount: 25, Currency: "EUR"})
+	if err != nil {
+		t.Fatalf("Settle: %v", err)
+	}
+	if st.Status != models.SettlementPending {
+		t.Errorf("status = %s, want pending", st.Status)
+	}
+	if len(transactions.created) != 0 || len(settlements.entries) != 0 {
+		t.Fatalf("pending settlement booked %d transactions and %d ledger entries", len(transactions.created), len(settlements.entries))
+	}
+
+	// Only the other party can confirm.
+	if _, err := svc.ConfirmSettlement(ctx, "alice", st.ID); err == nil {
+		t.Error("creator confirmed their own settlement")
+	}
+	if _, err := svc.ConfirmSettlement(ctx, "carol", st.ID); !errors.Is(err, ErrSettlementNotFound) {
+		t.Errorf("outsider: err = %v, want ErrSettlementNotFound", err)
+	}
+
+	st, err = svc.ConfirmSettlement(ctx, "bob", st.ID)
+	if err != nil {
+		t.Fatalf("ConfirmSettlement: %v", err)
+	}
+	if st.Status != models.SettlementConfirmed || len(st.TransactionIDs) != 2 {
+		t.Fatalf("confirmed settlement = %+v", st)
+	}
+	owners := map[string]models.TransactionType{}
+	for _, id := range st.TransactionIDs {
+		tx := transactions.created[id]
+		owners[tx.UserID] = tx.Type
+	}
+	if owners["alice"] != models.TransactionTypeExpense || owners["bob"] != models.TransactionTypeIncome {
+		t.Errorf("transactions by owner = %v", owners)
+	}
+	if len(settlements.entries) != 1 || settlements.entries[0].Debtor != models.UserParty("bob") {
+		t.Errorf("ledger entries = %+v, want bob owing alice", settlements.entries)
+	}
+
+	if _, err := svc.ConfirmSettlement(ctx, "bob", st.ID); !errors.Is(err, ErrSettlementNotFound) {
+		t.Errorf("second confirmation: err = %v, want ErrSettlementNotFound", err)
+	}
+}
+
+func TestDeclineSettlement(t *testing.T) {
+	ctx := context.Background()
+	svc, settlements, transactions := newTestSplitService()
+
+	st, err := svc.Settle(ctx, "alice", SettlementRequest{From: &PartyRef{UserID: "bob"}, Amount: 10, Currency: "EUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.DeclineSettlement(ctx, "bob", st.ID); err != nil {
+		t.Fatalf("DeclineSettlement: %v", err)
+	}
+	if _, err := svc.ConfirmSettlement(ctx, "bob", st.ID); !errors.Is(err, ErrSettlementNotFound) {
+		t.Errorf("confirm after decline: err = %v, want ErrSettlementNotFound", err)
+	}
+	if len(transactions.created) != 0 || len(settlements.entries) != 0 {
+		t.Errorf("declined settlement booked %d transactions and %d ledger entries", len(transactions.created), len(settlements.entries))
+	}
+}
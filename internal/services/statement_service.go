@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/pdf"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+const statementTopExpenses = 5
+
+type StatementService interface {
+	// MonthlyPDF renders the statement for the calendar month (UTC)
+	// containing `month`.
+	MonthlyPDF(ctx context.Context, scope repositories.Scope, month time.Time) ([]byte, error)
+}
+
+type statementService struct {
+	reports ReportService
+	txRepo  repositories.TransactionRepository
+	catRepo repositories.CategoryRepository
+	now     func() time.Time
+}
+
+func NewStatementService(reports ReportService, txRepo repositories.TransactionRepository, catRepo repositories.CategoryRepository) StatementService {
+	return &statementService{
+		reports: reports,
+		txRepo:  txRepo,
+		catRepo: catRepo,
+		now:     time.Now,
+	}
+}
+
+func (s *statementService) MonthlyPDF(ctx context.Context, scope repositories.Scope, month time.Time) ([]byte, error) {
+	from := startOfMonthUTC(month)
+	to := from.AddDate(0, 1, 0).Add(-time.Nanosecond)
+
+	summary, err := s.reports.GetSummary(ctx, scope, PeriodCustom, &from, &to, GroupCategory)
+	if err != nil {
+		return nil, err
+	}
+	txs, err := s.txRepo.ListByDateRange(ctx, scope, from, to)
+	if err != nil {
+		return nil, err
+	}
+	cats, err := s.catRepo.List(ctx, scope, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(txs, func(i, j int) bool { return txs[i].Date.Before(txs[j].Date) })
+	byCategory := append([]CategorySummary(nil), summary.ByCategory...)
+	sort.Slice(byCategory, func(i, j int) bool {
+		if byCategory[i].Expenses != byCategory[j].Expenses {
+			return byCategory[i].Expenses > byCategory[j].Expenses
+		}
+		return byCategory[i].CategoryName < byCategory[j].CategoryName
+	})
+
+	var expenses []models.Transaction
+	for _, tx := range txs {
+		if tx.Type == models.TransactionTypeExpense {
+			expenses = append(expenses, tx)
+		}
+	}
+	sort.SliceStable(expenses, func(i, j int) bool { return expenses[i].Amount > expenses[j].Amount })
+	if len(expenses) > statementTopExpenses {
+		expenses = expenses[:statementTopExpenses]
+	}
+
+	w := newStatementWriter(from, s.now().UTC())
+	w.header()
+	w.totals(summary.Totals)
+	w.categoryTable(byCategory)
+	w.topExpenses(expenses, categoryPaths(cats))
+	w.transactions(txs, categoryPaths(cats))
+	w.footers()
+
+	return w.doc.Bytes()
+}
+
+// categoryPaths maps each category ID to its full "Parent / Child" name.
+func categoryPaths(cats []models.Category) map[string]string {
+	byID := make(map[string]models.Category, len(cats))
+	for _, c := range cats {
+		byID[c.ID] = c
+	}
+	paths := make(map[string]string, len(cats))
+	for _, c := range cats {
+		parts := []string{c.Name}
+		seen := map[string]bool{c.ID: true}
+		cur := c
+		for cur.ParentID != nil && !seen[*cur.ParentID] {
+			parent, ok := byID[*cur.ParentID]
+			if !ok {
+				break
+			}
+			seen[parent.ID] = true
+			parts = append([]string{parent.Name}, parts...)
+			cur = parent
+		}
+		paths[c.ID] = strings.Join(parts, " / ")
+	}
+	return paths
+}
+
+func transactionCategoryName(tx models.Transaction, paths map[string]string) string {
+	for _, id := range []*string{tx.SubcategoryID, tx.CategoryID} {
+		if id != nil {
+			if p, ok := paths[*id]; ok {
+				return p
+			}
+		}
+	}
+	return uncategorisedName
+}
+
+const (
+	stmtMargin     = 40.0
+	stmtLineHeight = 15.0
+	stmtBodySize   = 9.0
+	stmtFooterY    = pdf.PageHeight - 25
+	stmtBottom     = pdf.PageHeight - 50
+)
+
+type statementColumn struct {
+	title string
+	x     float64
+	width float64
+	right bool
+}
+
+// statementWriter lays the statement out top to bottom, starting a new page
+// (and repeating the active table header) whenever the next row would not fit.
+type statementWriter struct {
+	doc         *pdf.Document
+	page        *pdf.Page
+	y           float64
+	month       time.Time
+	generatedAt time.Time
+	columns     []statementColumn
+}
+
+func newStatementWriter(month, generatedAt time.Time) *statementWriter {
+	w := &statementWriter{
+		doc:         pdf.New(),
+		month:       month,
+		generatedAt: generatedAt,
+	}
+	w.newPage()
+	return w
+}
+
+func (w *statementWriter) newPage() {
+	w.page = w.doc.AddPage()
+	w.y = stmtMargin
+	if len(w.doc.Pages()) > 1 {
+		w.page.Text(stmtMargin, w.y, pdf.Helvetica, 8, "Statement for "+w.month.Format("January 2006")+" (continued)")
+		w.y += 20
+	}
+}
+
+func (w *statementWriter) ensure(height float64) {
+	if w.y+height <= stmtBottom {
+		return
+	}
+	w.newPage()
+	if w.columns != nil {
+		w.tableHeader()
+	}
+}
+
+func (w *statementWriter) header() {
+	w.page.Text(stmtMargin, w.y+18, pdf.HelveticaBold, 18, "Monthly statement")
+	w.page.TextRight(pdf.PageWidth-stmtMargin, w.y+18, pdf.HelveticaBold, 14, w.month.Format("January 2006"))
+	w.y += 32
+	w.page.Text(stmtMargin, w.y, pdf.Helvetica, 8, "Generated "+w.generatedAt.Format("2 Jan 2006 15:04 MST"))
+	w.y += 8
+	w.page.Line(stmtMargin, w.y, pdf.PageWidth-stmtMargin, w.y, 0.8)
+	w.y += 20
+}
+
+func (w *statementWriter) section(title string) {
+	w.columns = nil
+	w.ensure(50)
+	w.y += 8
+	w.page.Text(stmtMargin, w.y, pdf.HelveticaBold, 12, title)
+	w.y += 16
+}
+
+func (w *statementWriter) totals(t SummaryTotals) {
+	w.section("Summary")
+	rows := [][2]string{
+		{"Income", money(t.Income)},
+		{"Expenses", money(t.Expenses)},
+		{"Net savings", money(t.Savings)},
+	}
+	for i, r := range rows {
+		font := pdf.Helvetica
+		if i == len(rows)-1 {
+			font = pdf.HelveticaBold
+		}
+		w.page.Text(stmtMargin, w.y, font, 10, r[0])
+		w.page.TextRight(stmtMargin+220, w.y, font, 10, r[1])
+		w.y += stmtLineHeight
+	}
+}
+
+func (w *statementWriter) categoryTable(rows []CategorySummary) {
+	w.section("By category")
+	if len(rows) == 0 {
+		w.note("No categorised transactions this month.")
+		return
+	}
+	w.beginTable([]statementColumn{
+		{title: "Category", x: stmtMargin, width: 280},
+		{title: "Income", x: 420, width: 90, right: true},
+		{title: "Expenses", x: pdf.PageWidth - stmtMargin, width: 90, right: true},
+	})
+	for _, r := range rows {
+		name := r.CategoryName
+		if name == "" {
+			name = r.CategoryID
+		}
+		w.row(name, money(r.Income), money(r.Expenses))
+	}
+}
+
+func (w *statementWriter) topExpenses(txs []models.Transaction, paths map[string]string) {
+	w.section("Top expenses")
+	if len(txs) == 0 {
+		w.note("No expenses this month.")
+		return
+	}
+	w.beginTable([]statementColumn{
+		{title: "Date", x: stmtMargin, width: 60},
+		{title: "Description", x: 105, width: 200},
+		{title: "Category", x: 310, width: 150},
+		{title: "Amount", x: pdf.PageWidth - stmtMargin, width: 80, right: true},
+	})
+	for _, tx := range txs {
+		w.row(tx.Date.UTC().Format("02 Jan"), describe(tx), transactionCategoryName(tx, paths), money(tx.Amount)+" "+tx.Currency)
+	}
+}
+
+func (w *statementWriter) transactions(txs []models.Transaction, paths map[string]string) {
+	w.section("Transactions")
+	if len(txs) == 0 {
+		w.note("No transactions this month.")
+		return
+	}
+	w.beginTable([]statementColumn{
+		{title: "Date", x: stmtMargin, width: 60},
+		{title: "Description", x: 105, width: 190},
+		{title: "Category", x: 300, width: 120},
+		{title: "Type", x: 425, width: 40},
+		{title: "Amount", x: pdf.PageWidth - stmtMargin, width: 85, right: true},
+	})
+	for _, tx := range txs {
+		amount := money(tx.Amount)
+		if tx.Type == models.TransactionTypeExpense {
+			amount = "-" + amount
+		}
+		w.row(tx.Date.UTC().Format("02 Jan"), describe(tx), transactionCategoryName(tx, paths), string(tx.Type), amount+" "+tx.Currency)
+	}
+}
+
+func (w *statementWriter) note(s string) {
+	w.ensure(stmtLineHeight)
+	w.page.Text(stmtMargin, w.y, pdf.Helvetica, stmtBodySize, s)
+	w.y += stmtLineHeight
+}
+
+func (w *statementWriter) beginTable(cols []statementColumn) {
+	w.columns = nil
+	w.ensure(2 * stmtLineHeight)
+	w.columns = cols
+	w.tableHeader()
+}
+
+func (w *statementWriter) tableHeader() {
+	w.page.FillRect(stmtMargin-4, w.y-11, pdf.PageWidth-2*stmtMargin+8, stmtLineHeight, 0.9)
+	for _, c := range w.columns {
+		if c.right {
+			w.page.TextRight(c.x, w.y, pdf.HelveticaBold, stmtBodySize, c.title)
+		} else {
+			w.page.Text(c.x, w.y, pdf.HelveticaBold, stmtBodySize, c.title)
+		}
+	}
+	w.y += stmtLineHeight
+}
+
+func (w *statementWriter) row(values ...string) {
+	w.ensure(stmtLineHeight)
+	for i, c := range w.columns {
+		v := pdf.Truncate(values[i], pdf.Helvetica, stmtBodySize, c.width)
+		if c.right {
+			w.page.TextRight(c.x, w.y, pdf.Helvetica, stmtBodySize, v)
+		} else {
+			w.page.Text(c.x, w.y, pdf.Helvetica, stmtBodySize, v)
+		}
+	}
+	w.page.Line(stmtMargin, w.y+4, pdf.PageWidth-stmtMargin, w.y+4, 0.2)
+	w.y += stmtLineHeight
+}
+
+func (w *statementWriter) footers() {
+	pages := w.doc.Pages()
+	for i, p := range pages {
+		p.TextRight(pdf.PageWidth-stmtMargin, stmtFooterY, pdf.Helvetica, 8, fmt.Sprintf("Page %d of %d", i+1, len(pages)))
+	}
+}
+
+func describe(tx models.Transaction) string {
+	if tx.Description != nil && *tx.Description != "" {
+		return *tx.Description
+	}
+	return "-"
+}
+
+func money(v float64) string {
+	neg := v < 0
+	if neg {
+		v = -v
+	}
+	s := fmt.Sprintf("%.2f", v)
+	intPart, frac := s[:len(s)-3], s[len(s)-3:]
+	var b strings.Builder
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if neg {
+		return "-" + b.String() + frac
+	}
+	return b.String() + frac
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+type fixedSummary struct {
+	ReportService
+}
+
+func (fixedSummary) GetSummary(context.Context, repositories.Scope, SummaryPeriod, *time.Time, *time.Time, GroupBy) (*SummaryReport, error) {
+	return &SummaryReport{}, nil
+}
+
+type noCategories struct {
+	repositories.CategoryRepository
+}
+
+func (noCategories) List(context.Context, repositories.Scope, *string) ([]models.Category, error) {
+	return nil, nil
+}
+
+// pageStreams inflates the content stream of every page, in order.
+func pageStreams(t *testing.T, doc []byte) []string {
+	t.Helper()
+	var pages []string
+	for {
+		i := bytes.Index(doc, []byte("stream\n"))
+		if i < 0 {
+			return pages
+		}
+		doc = doc[i+len("stream\n"):]
+		end := bytes.Index(doc, []byte("\nendstream"))
+		if end < 0 {
+			t.Fatal("unterminated stream")
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(doc[:end]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, string(content))
+		doc = doc[end+len("\nendstream"):]
+	}
+}
+
+func TestStatementRepeatsTableHeaderOnEveryPage(t *testing.T) {
+	month := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	var txs []models.Transaction
+	for i := 0; i < 150; i++ {
+		txs = append(txs, models.Transaction{
+			Type: models.TransactionTypeExpense, Amount: float64(i + 1), Currency: "EUR",
+			Description: strPtr(fmt.Sprintf("Purchase %03d", i)), Date: month.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	svc := &statementService{
+		reports: fixedSummary{},
+		txRepo:  memCharges{txs: txs},
+		catRepo: noCategories{},
+		now:     func() time.Time { return month.AddDate(0, 1, 0) },
+	}
+
+	out, err := svc.MonthlyPDF(context.Background(), repositories.PersonalScope("user-1"), month)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := pageStreams(t, out)
+	if len(pages) < 3 {
+		t.Fatalf("rendered %d pages, want at least 3 for %d transactions", len(pages), len(txs))
+	}
+	if !bytes.Contains(out, []byte(fmt.Sprintf("/Count %d", len(pages)))) {
+		t.Errorf("page tree does not count %d pages", len(pages))
+	}
+
+	rows := 0
+	for i, p := range pages {
+		if !strings.Contains(p, fmt.Sprintf("(Page %d of %d) Tj", i+1, len(pages))) {
+			t.Errorf("page %d has no footer", i+1)
+		}
+		rows += strings.Count(p, "(Purchase ")
+		if i == 0 {
+			continue
+		}
+		if !strings.Contains(p, `\(continued\)) Tj`) {
+			t.Errorf("page %d is not marked as continued", i+1)
+		}
+		for _, col := range []string{"Date", "Description", "Category", "Type", "Amount"} {
+			if !strings.Contains(p, "("+col+") Tj") {
+				t.Errorf("page %d does not repeat the %s column header", i+1, col)
+			}
+		}
+	}
+	// Every transaction once, plus the top expenses table.
+	if want := len(txs) + statementTopExpenses; rows != want {
+		t.Errorf("rendered %d rows, want %d", rows, want)
+	}
+}
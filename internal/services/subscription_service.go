@@ -0,0 +1,375 @@
+package services
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+type SubscriptionCadence string
+
+const (
+	CadenceWeekly  SubscriptionCadence = "weekly"
+	CadenceMonthly SubscriptionCadence = "monthly"
+	CadenceAnnual  SubscriptionCadence = "annual"
+)
+
+const (
+	subscriptionHistoryYears = 2
+	// Charges further than this from the series median are treated as
+	// one-off purchases from the same merchant rather than part of the series.
+	subscriptionAmountTolerance = 0.4
+	// Share of intervals that must match the cadence for a series to count.
+	subscriptionIntervalAgreement = 0.75
+)
+
+type cadenceRule struct {
+	cadence        SubscriptionCadence
+	minDays        float64
+	maxDays        float64
+	minOccurrences int
+	repeat         models.RepeatInterval
+}
+
+var cadenceRules = []cadenceRule{
+	{CadenceWeekly, 5, 9, 4, models.RepeatWeekly},
+	{CadenceMonthly, 26, 35, 3, models.RepeatMonthly},
+	{CadenceAnnual, 350, 380, 2, models.RepeatYearly},
+}
+
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
+type PriceChange struct {
+	Date time.Time `json:"date"`
+	From float64   `json:"from"`
+	To   float64   `json:"to"`
+}
+
+type DetectedSubscription struct {
+	Key              string              `json:"key"`
+	Description      string              `json:"description"`
+	Currency         string              `json:"currency"`
+	CategoryID       *string             `json:"categoryId,omitempty"`
+	Cadence          SubscriptionCadence `json:"cadence"`
+	Occurrences      int                 `json:"occurrences"`
+	AverageAmount    float64             `json:"averageAmount"`
+	LastAmount       float64             `json:"lastAmount"`
+	FirstChargeDate  time.Time           `json:"firstChargeDate"`
+	LastChargeDate   time.Time           `json:"lastChargeDate"`
+	NextExpectedDate time.Time           `json:"nextExpectedDate"`
+	PriceChanges     []PriceChange       `json:"priceChanges"`
+	Status           string              `json:"status"` // "detected" or "confirmed"
+	ReminderID       *string             `json:"reminderId,omitempty"`
+	TransactionIDs   []string            `json:"transactionIds"`
+
+	repeat models.RepeatInterval
+}
+
+type SubscriptionService interface {
+	Detect(ctx context.Context, userID string) ([]DetectedSubscription, error)
+	Confirm(ctx context.Context, userID, key string) (*models.Reminder, error)
+	Dismiss(ctx context.Context, userID, key string) error
+}
+
+type subscriptionService struct {
+	txRepo    repositories.TransactionRepository
+	decisions repositories.SubscriptionDecisionRepository
+	reminders ReminderService
+	now       func() time.Time
+}
+
+func NewSubscriptionService(txRepo repositories.TransactionRepository, decisions repositories.SubscriptionDecisionRepository, reminders ReminderService) SubscriptionService {
+	return &subscriptionService{
+		txRepo:    txRepo,
+		decisions: decisions,
+		reminders: reminders,
+		now:       time.Now,
+	}
+}
+
+func (s *subscriptionService) Detect(ctx context.Context, userID string) ([]DetectedSubscription, error) {
+	detected, err := s.detectAll(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	decisions, err := s.decisions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]models.SubscriptionDecision, len(decisions))
+	for _, d := range decisions {
+		byKey[d.Key] = d
+	}
+
+	res := make([]DetectedSubscription, 0, len(detected))
+	for _, d := range detected {
+		if dec, ok := byKey[d.Key]; ok {
+			if dec.Status == models.SubscriptionDismissed {
+				continue
+			}
+			d.Status = string(dec.Status)
+			d.ReminderID = dec.ReminderID
+		}
+		res = append(res, d)
+	}
+	return res, nil
+}
+
+func (s *subscriptionService) Confirm(ctx context.Context, userID, key string) (*models.Reminder, error) {
+	sub, err := s.find(ctx, userID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	decisions, err := s.decisions.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range decisions {
+		if d.Key == key && d.Status == models.SubscriptionConfirmed {
+			return nil, errors.New("subscription already confirmed")
+		}
+	}
+
+	amount := sub.LastAmount
+	currency := sub.Currency
+	typ := models.TransactionTypeExpense
+	rem, err := s.reminders.Create(ctx, repositories.PersonalScope(userID), &models.Reminder{
+		Title:          sub.Description,
+		DueAt:          sub.NextExpectedDate,
+		RepeatInterval: sub.repeat,
+		Amount:         &amount,
+		Currency:       &currency,
+		Type:           &typ,
+		CategoryID:     sub.CategoryID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.decisions.Upsert(ctx, &models.SubscriptionDecision{
+		UserID:     userID,
+		Key:        key,
+		Status:     models.SubscriptionConfirmed,
+		ReminderID: &rem.ID,
+	}); err != nil {
+		// Unrecorded, the reminder would be orphaned and a retry would
+		// create a second one.
+		if delErr := s.reminders.Delete(context.WithoutCancel(ctx), repositories.PersonalScope(userID), rem.ID); delErr != nil {
+			log.Printf("subscriptions: remove reminder %s after failed confirm: %v", rem.ID, delErr)
+		}
+		return nil, err
+	}
+	return rem, nil
+}
+
+func (s *subscriptionService) Dismiss(ctx context.Context, userID, key string) error {
+	if _, err := s.find(ctx, userID, key); err != nil {
+		return err
+	}
+	return s.decisions.Upsert(ctx, &models.SubscriptionDecision{
+		UserID: userID,
+		Key:    key,
+		Status: models.SubscriptionDismissed,
+	})
+}
+
+func (s *subscriptionService) find(ctx context.Context, userID, key string) (*DetectedSubscription, error) {
+	detected, err := s.detectAll(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range detected {
+		if detected[i].Key == key {
+			return &detected[i], nil
+		}
+	}
+	return nil, ErrSubscriptionNotFound
+}
+
+func (s *subscriptionService) detectAll(ctx context.Context, userID string) ([]DetectedSubscription, error) {
+	now := s.now().UTC()
+	txs, err := s.txRepo.ListByDateRange(ctx, repositories.PersonalScope(userID), now.AddDate(-subscriptionHistoryYears, 0, 0), now)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string][]models.Transaction{}
+	for _, tx := range txs {
+		if tx.Type != models.TransactionTypeExpense || tx.Description == nil {
+			continue
+		}
+		norm := normalizeMerchant(*tx.Description)
+		if norm == "" {
+			continue
+		}
+		k := norm + "|" + strings.ToUpper(tx.Currency)
+		groups[k] = append(groups[k], tx)
+	}
+
+	var res []DetectedSubscription
+	for k, group := range groups {
+		if sub, ok := detectSeries(group, now); ok {
+			sum := sha1.Sum([]byte(k))
+			sub.Key = hex.EncodeToString(sum[:])[:16]
+			res = append(res, sub)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if !res[i].NextExpectedDate.Equal(res[j].NextExpectedDate) {
+			return res[i].NextExpectedDate.Before(res[j].NextExpectedDate)
+		}
+		return res[i].Key < res[j].Key
+	})
+	return res, nil
+}
+
+// detectSeries decides whether a group of same-merchant charges forms a
+// regular series and summarises it. Series whose next charge is overdue by
+// more than a full cycle are considered cancelled and skipped.
+func detectSeries(group []models.Transaction, now time.Time) (DetectedSubscription, bool) {
+	amounts := make([]float64, len(group))
+	for i, tx := range group {
+		amounts[i] = tx.Amount
+	}
+	med := median(amounts)
+
+	var series []models.Transaction
+	for _, tx := range group {
+		if math.Abs(tx.Amount-med) <= med*subscriptionAmountTolerance {
+			series = append(series, tx)
+		}
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Date.Before(series[j].Date) })
+
+	// Collapse same-day duplicates (e.g. a retried charge).
+	deduped := series[:0]
+	for _, tx := range series {
+		if n := len(deduped); n > 0 && sameDay(deduped[n-1].Date, tx.Date) {
+			continue
+		}
+		deduped = append(deduped, tx)
+	}
+	series = deduped
+	if len(series) < 2 {
+		return DetectedSubscription{}, false
+	}
+
+	intervals := make([]float64, 0, len(series)-1)
+	for i := 1; i < len(series); i++ {
+		intervals = append(intervals, series[i].Date.Sub(series[i-1].Date).Hours()/24)
+	}
+	medInterval := median(intervals)
+
+	var rule *cadenceRule
+	for i := range cadenceRules {
+		if medInterval >= cadenceRules[i].minDays && medInterval <= cadenceRules[i].maxDays {
+			rule = &cadenceRules[i]
+			break
+		}
+	}
+	if rule == nil || len(series) < rule.minOccurrences {
+		return DetectedSubscription{}, false
+	}
+	matching := 0
+	for _, d := range intervals {
+		if d >= rule.minDays && d <= rule.maxDays {
+			matching++
+		}
+	}
+	if float64(matching)/float64(len(intervals)) < subscriptionIntervalAgreement {
+		return DetectedSubscription{}, false
+	}
+
+	first, last := series[0], series[len(series)-1]
+	next := occurrenceAt(last.Date.UTC(), rule.repeat, 1)
+	if now.After(occurrenceAt(last.Date.UTC(), rule.repeat, 2)) {
+		return DetectedSubscription{}, false
+	}
+
+	sub := DetectedSubscription{
+		Description:      strings.TrimSpace(*last.Description),
+		Currency:         last.Currency,
+		Cadence:          rule.cadence,
+		Occurrences:      len(series),
+		LastAmount:       last.Amount,
+		FirstChargeDate:  first.Date,
+		LastChargeDate:   last.Date,
+		NextExpectedDate: next,
+		PriceChanges:     []PriceChange{},
+		Status:           "detected",
+		repeat:           rule.repeat,
+	}
+
+	catCount := map[string]int{}
+	var total float64
+	for i, tx := range series {
+		total += tx.Amount
+		sub.TransactionIDs = append(sub.TransactionIDs, tx.ID)
+		if tx.CategoryID != nil {
+			catCount[*tx.CategoryID]++
+		}
+		if i > 0 && math.Abs(tx.Amount-series[i-1].Amount) >= 0.01 {
+			sub.PriceChanges = append(sub.PriceChanges, PriceChange{
+				Date: tx.Date,
+				From: series[i-1].Amount,
+				To:   tx.Amount,
+			})
+		}
+	}
+	sub.AverageAmount = math.Round(total/float64(len(series))*100) / 100
+
+	best := 0
+	for id, n := range catCount {
+		if n > best || (n == best && sub.CategoryID != nil && id < *sub.CategoryID) {
+			best = n
+			catID := id
+			sub.CategoryID = &catID
+		}
+	}
+	return sub, true
+}
+
+// normalizeMerchant reduces a description to lower-case words so that
+// "NETFLIX.COM 8823" and "Netflix.com #9911" fall into the same series.
+func normalizeMerchant(desc string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(desc) {
+		if unicode.IsLetter(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func median(vals []float64) float64 {
+	if len(vals) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), vals...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.UTC().Date()
+	by, bm, bd := b.UTC().Date()
+	return ay == by && am == bm && ad == bd
+}
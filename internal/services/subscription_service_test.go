@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+type memCharges struct {
+	repositories.TransactionRepository
+	txs []models.Transaction
+}
+
+func (m memCharges) ListByDateRange(context.Context, repositories.Scope, time.Time, time.Time) ([]models.Transaction, error) {
+	return m.txs, nil
+}
+
+type failingDecisions struct {
+	repositories.SubscriptionDecisionRepository
+}
+
+func (failingDecisions) ListByUser(context.Context, string) ([]models.SubscriptionDecision, error) {
+	return nil, nil
+}
+
+func (failingDecisions) Upsert(context.Context, *models.SubscriptionDecision) error {
+	return errors.New("write failed")
+}
+
+type memReminders struct {
+	ReminderService
+	created []string
+	deleted []string
+}
+
+func (m *memReminders) Create(_ context.Context, _ repositories.Scope, r *models.Reminder) (*models.Reminder, error) {
+	r.ID = "reminder-1"
+	m.created = append(m.created, r.ID)
+	return r, nil
+}
+
+func (m *memReminders) Delete(_ context.Context, _ repositories.Scope, id string) error {
+	m.deleted = append(m.deleted, id)
+	return nil
+}
+
+func TestConfirmSubscriptionRemovesReminderWhenDecisionFails(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	desc := "NETFLIX.COM"
+	var charges []models.Transaction
+	for i := 4; i >= 1; i-- {
+		charges = append(charges, models.Transaction{
+			Type: models.TransactionTypeExpense, Amount: 15.99, Currency: "EUR",
+			Description: &desc, Date: now.AddDate(0, -i, 0),
+		})
+	}
+	reminders := &memReminders{}
+	svc := &subscriptionService{
+		txRepo:    memCharges{txs: charges},
+		decisions: failingDecisions{},
+		reminders: reminders,
+		now:       func() time.Time { return now },
+	}
+
+	detected, err := svc.detectAll(ctx, "user-1")
+	if err != nil || len(detected) != 1 {
+		t.Fatalf("detected %d subscriptions (err %v), want 1", len(detected), err)
+	}
+	if _, err := svc.Confirm(ctx, "user-1", detected[0].Key); err == nil {
+		t.Fatal("Confirm succeeded although the decision was not saved")
+	}
+	if len(reminders.created) != 1 || len(reminders.deleted) != 1 || reminders.deleted[0] != reminders.created[0] {
+		t.Errorf("created %v and deleted %v; the reminder should be removed again", reminders.created, reminders.deleted)
+	}
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxTagNameLength = 50
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("a tag with this name already exists")
+)
+
+// BulkTagRequest adds and removes tags on many transactions and reminders at
+// once. Tags are given by ID or name; Add is applied before Remove.
+type BulkTagRequest struct {
+	TransactionIDs []string `json:"transactionIds"`
+	ReminderIDs    []string `json:"reminderIds"`
+	Add            []string `json:"add"`
+	Remove         []string `json:"remove"`
+}
+
+// BulkTagResult counts the documents a bulk request matched.
+type BulkTagResult struct {
+	Transactions int64 `json:"transactions"`
+	Reminders    int64 `json:"reminders"`
+}
+
+type TagService interface {
+	Create(ctx context.Context, scope repositories.Scope, name string, color *string) (*models.Tag, error)
+	List(ctx context.Context, scope repositories.Scope) ([]models.Tag, error)
+	Update(ctx context.Context, scope repositories.Scope, id string, name, color *string) (*models.Tag, error)
+	// Delete removes the tag and takes it off everything that carries it.
+	Delete(ctx context.Context, scope repositories.Scope, id string) error
+	// Resolve turns tag IDs or names into IDs. Unknown tags are an error
+	// wrapping ErrTagNotFound.
+	Resolve(ctx context.Context, scope repositories.Scope, refs []string) ([]string, error)
+	Bulk(ctx context.Context, scope repositories.Scope, req BulkTagRequest) (*BulkTagResult, error)
+}
+
+type tagService struct {
+	repo         repositories.TagRepository
+	transactions repositories.TransactionRepository
+	reminders    repositories.ReminderRepository
+	audit        AuditService
+}
+
+func NewTagService(repo repositories.TagRepository, transactions repositories.TransactionRepository, reminders repositories.ReminderRepository, audit AuditService) TagService {
+	return &tagService{
+		repo:         repo,
+		transactions: transactions,
+		reminders:    reminders,
+		audit:        audit,
+	}
+}
+
+func (s *tagService) Create(ctx context.Context, scope repositories.Scope, name string, color *string) (*models.Tag, error) {
+	name, err := s.checkName(ctx, scope, name, "")
+	if err != nil {
+		return nil, err
+	}
+	t := &models.Tag{
+		UserID:      scope.UserID,
+		HouseholdID: scope.HouseholdID,
+		Name:        name,
+		Color:       color,
+	}
+	if err := s.repo.Create(ctx, t); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, AuditEntry{
+		UserID:     scope.UserID,
+		Action:     models.AuditCreated,
+		EntityType: models.AuditEntityTag,
+		EntityID:   t.ID,
+		After:      t,
+	})
+	return t, nil
+}
+
+func (s *tagService) List(ctx context.Context, scope repositories.Scope) ([]models.Tag, error) {
+	return s.repo.List(ctx, scope)
+}
+
+func (s *tagService) Update(ctx context.Context, scope repositories.Scope, id string, name, color *string) (*models.Tag, error) {
+	t, err := s.repo.FindByID(ctx, id, scope)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrTagNotFound
+	}
+	before := *t
+
+	if name != nil {
+		if t.Name, err = s.checkName(ctx, scope, *name, t.ID); err != nil {
+			return nil, err
+		}
+	}
+	if color != nil {
+		if *color == "" {
+			t.Color = nil
+		} else {
+			t.Color = color
+		}
+	}
+	if err := s.repo.Update(ctx, scope, t); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, AuditEntry{
+		UserID:     scope.UserID,
+		Action:     models.AuditUpdated,
+		EntityType: models.AuditEntityTag,
+		EntityID:   t.ID,
+		Before:     &before,
+		After:      t,
+	})
+	return t, nil
+}
+
+// checkName trims name and makes sure no other tag in scope (other than
+// selfID) already uses it.
+func (s *tagService) checkName(ctx context.Context, scope repositories.Scope, name, selfID string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+	if len(name) > maxTagNameLength {
+		return "", fmt.Errorf("name must be at most %d characters", maxTagNameLength)
+	}
+	if strings.Contains(name, ",") {
+		return "", errors.New("name must not contain commas")
+	}
+	existing, err := s.repo.FindByName(ctx, scope, name)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil && existing.ID != selfID {
+		return "", ErrTagExists
+	}
+	return name, nil
+}
+
+func (s *tagService) Delete(ctx context.Context, scope repositories.Scope, id string) error {
+	t, err := s.repo.FindByID(ctx, id, scope)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return ErrTagNotFound
+	}
+	if err := s.transactions.UntagAll(ctx, scope, id); err != nil {
+		return err
+	}
+	if err := s.reminders.UntagAll(ctx, scope, id); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id, scope); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, AuditEntry{
+		UserID:     scope.UserID,
+		Action:     models.AuditDeleted,
+		EntityType: models.AuditEntityTag,
+		EntityID:   id,
+		Before:     t,
+	})
+	return nil
+}
+
+func (s *tagService) Resolve(ctx context.Context, scope repositories.Scope, refs []string) ([]string, error) {
+	var ids []string
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			continue
+		}
+		var (
+			t   *models.Tag
+			err error
+		)
+		if primitive.IsValidObjectID(ref) {
+			t, err = s.repo.FindByID(ctx, ref, scope)
+		}
+		if err == nil && t == nil {
+			t, err = s.repo.FindByName(ctx, scope, ref)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			return nil, fmt.Errorf("%w: %s", ErrTagNotFound, ref)
+		}
+		if !seen[t.ID] {
+			seen[t.ID] = true
+			ids = append(ids, t.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (s *tagService) Bulk(ctx context.Context, scope repositories.Scope, req BulkTagRequest) (*BulkTagResult, error) {
+	if len(req.TransactionIDs) == 0 && len(req.ReminderIDs) == 0 {
+		return nil, errors.New("transactionIds or reminderIds are required")
+	}
+	add, err := s.Resolve(ctx, scope, req.Add)
+	if err != nil {
+		return nil, err
+	}
+	remove, err := s.Resolve(ctx, scope, req.Remove)
+	if err != nil {
+		return nil, err
+	}
+	if len(add) == 0 && len(remove) == 0 {
+		return nil, errors.New("add or remove is required")
+	}
+
+	res := &BulkTagResult{}
+	targets := []struct {
+		ids     []string
+		add     func(context.Context, repositories.Scope, []string, []string) (int64, error)
+		remove  func(context.Context, repositories.Scope, []string, []string) (int64, error)
+		matched *int64
+	}{
+		{req.TransactionIDs, s.transactions.AddTags, s.transactions.RemoveTags, &res.Transactions},
+		{req.ReminderIDs, s.reminders.AddTags, s.reminders.RemoveTags, &res.Reminders},
+	}
+	for _, t := range targets {
+		if len(t.ids) == 0 {
+			continue
+		}
+		if len(add) > 0 {
+			n, err := t.add(ctx, scope, t.ids, add)
+			if err != nil {
+				return nil, err
+			}
+			*t.matched = n
+		}
+		if len(remove) > 0 {
+			n, err := t.remove(ctx, scope, t.ids, remove)
+			if err != nil {
+				return nil, err
+			}
+			*t.matched = n
+		}
+	}
+	return res, nil
+}
+
+// checkTagIDs de-duplicates the tags set on a transaction or reminder and
+// makes sure they all exist in scope.
+func checkTagIDs(ctx context.Context, tags repositories.TagRepository, scope repositories.Scope, ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	var unique []string
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	found, err := tags.FindByIDs(ctx, scope, unique)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) != len(unique) {
+		return nil, ErrTagNotFound
+	}
+	return unique, nil
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"errors"
+	"log"
 	"time"
 
 	"github.com/ronak4195/personal-assistant/internal/models"
@@ -10,42 +11,52 @@ import (
 )
 
 type TransactionService interface {
-	Create(ctx context.Context, tx *models.Transaction) (*models.Transaction, error)
-	Get(ctx context.Context, userID, id string) (*models.Transaction, error)
+	Create(ctx context.Context, scope repositories.Scope, tx *models.Transaction) (*models.Transaction, error)
+	Get(ctx context.Context, scope repositories.Scope, id string) (*models.Transaction, error)
 	List(ctx context.Context, f repositories.TransactionFilter) ([]models.Transaction, int64, error)
-	Update(ctx context.Context, userID string, tx *models.Transaction) (*models.Transaction, error)
-	Delete(ctx context.Context, userID, id string) error
+	Update(ctx context.Context, scope repositories.Scope, tx *models.Transaction) (*models.Transaction, error)
+	Delete(ctx context.Context, scope repositories.Scope, id string) error
 }
 
 type transactionService struct {
 	repo         repositories.TransactionRepository
 	categoryRepo repositories.CategoryRepository
+	tags         repositories.TagRepository
+	splits       repositories.SplitRepository
+	attachments  AttachmentService
+	insights     InsightService
+	audit        AuditService
 }
 
-func NewTransactionService(repo repositories.TransactionRepository, catRepo repositories.CategoryRepository) TransactionService {
+func NewTransactionService(repo repositories.TransactionRepository, catRepo repositories.CategoryRepository, tags repositories.TagRepository, splits repositories.SplitRepository, attachments AttachmentService, insights InsightService, audit AuditService) TransactionService {
 	return &transactionService{
 		repo:         repo,
 		categoryRepo: catRepo,
+		tags:         tags,
+		splits:       splits,
+		attachments:  attachments,
+		insights:     insights,
+		audit:        audit,
 	}
 }
 
-func (s *transactionService) Create(ctx context.Context, tx *models.Transaction) (*models.Transaction, error) {
-	if tx.Amount <= 0 {
-		return nil, errors.New("amount must be positive")
-	}
-	if tx.Type != models.TransactionTypeIncome && tx.Type != models.TransactionTypeExpense {
-		return nil, errors.New("invalid type")
+func (s *transactionService) Create(ctx context.Context, scope repositories.Scope, tx *models.Transaction) (*models.Transaction, error) {
+	tx.UserID = scope.UserID
+	tx.HouseholdID = scope.HouseholdID
+
+	if err := validateTransaction(tx); err != nil {
+		return nil, err
 	}
 
-	if tx.CategoryID != nil {
-		if _, err := s.categoryRepo.FindByID(ctx, *tx.CategoryID, tx.UserID); err != nil {
-			return nil, err
-		}
+	if err := checkCategoryID(ctx, s.categoryRepo, scope, tx.CategoryID); err != nil {
+		return nil, err
 	}
-	if tx.SubcategoryID != nil {
-		if _, err := s.categoryRepo.FindByID(ctx, *tx.SubcategoryID, tx.UserID); err != nil {
-			return nil, err
-		}
+	if err := checkCategoryID(ctx, s.categoryRepo, scope, tx.SubcategoryID); err != nil {
+		return nil, err
+	}
+	var err error
+	if tx.TagIDs, err = checkTagIDs(ctx, s.tags, scope, tx.TagIDs); err != nil {
+		return nil, err
 	}
 	if tx.Date.IsZero() {
 		tx.Date = time.Now().UTC()
@@ -54,25 +65,41 @@ func (s *transactionService) Create(ctx context.Context, tx *models.Transaction)
 	if err := s.repo.Create(ctx, tx); err != nil {
 		return nil, err
 	}
+	s.audit.Record(ctx, AuditEntry{
+		UserID:     scope.UserID,
+		Action:     models.AuditCreated,
+		EntityType: models.AuditEntityTransaction,
+		EntityID:   tx.ID,
+		After:      tx,
+	})
+
+	// Insights are personal and best effort; a failure here must not fail
+	// the write.
+	if s.insights != nil && !scope.IsHousehold() {
+		if err := s.insights.Analyze(ctx, tx); err != nil {
+			log.Printf("insights: analyze transaction %s: %v", tx.ID, err)
+		}
+	}
 	return tx, nil
 }
 
-func (s *transactionService) Get(ctx context.Context, userID, id string) (*models.Transaction, error) {
-	return s.repo.FindByID(ctx, id, userID)
+func (s *transactionService) Get(ctx context.Context, scope repositories.Scope, id string) (*models.Transaction, error) {
+	return s.repo.FindByID(ctx, id, scope)
 }
 
 func (s *transactionService) List(ctx context.Context, f repositories.TransactionFilter) ([]models.Transaction, int64, error) {
 	return s.repo.List(ctx, f)
 }
 
-func (s *transactionService) Update(ctx context.Context, userID string, tx *models.Transaction) (*models.Transaction, error) {
-	existing, err := s.repo.FindByID(ctx, tx.ID, userID)
+func (s *transactionService) Update(ctx context.Context, scope repositories.Scope, tx *models.Transaction) (*models.Transaction, error) {
+	existing, err := s.repo.FindByID(ctx, tx.ID, scope)
 	if err != nil {
 		return nil, err
 	}
 	if existing == nil {
 		return nil, errors.New("transaction not found")
 	}
+	before := *existing
 
 	// Update allowed fields
 	if tx.Type != "" {
@@ -87,25 +114,86 @@ func (s *transactionService) Update(ctx context.Context, userID string, tx *mode
 	if tx.Currency != "" {
 		existing.Currency = tx.Currency
 	}
+	if existing.Type != before.Type || existing.Amount != before.Amount || existing.Currency != before.Currency {
+		split, err := s.splits.FindSplitByTransaction(ctx, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		if split != nil {
+			return nil, errors.New("transaction is split; remove the split before changing its type, amount or currency")
+		}
+	}
 	if tx.CategoryID != nil {
+		if err := checkCategoryID(ctx, s.categoryRepo, scope, tx.CategoryID); err != nil {
+			return nil, err
+		}
 		existing.CategoryID = tx.CategoryID
 	}
 	if tx.SubcategoryID != nil {
+		if err := checkCategoryID(ctx, s.categoryRepo, scope, tx.SubcategoryID); err != nil {
+			return nil, err
+		}
 		existing.SubcategoryID = tx.SubcategoryID
 	}
 	if tx.Description != nil {
 		existing.Description = tx.Description
 	}
+	if tx.TagIDs != nil {
+		if existing.TagIDs, err = checkTagIDs(ctx, s.tags, scope, tx.TagIDs); err != nil {
+			return nil, err
+		}
+	}
 	if !tx.Date.IsZero() {
 		existing.Date = tx.Date
 	}
 
-	if err := s.repo.Update(ctx, existing); err != nil {
+	if err := s.repo.Update(ctx, scope, existing); err != nil {
 		return nil, err
 	}
+	s.audit.Record(ctx, AuditEntry{
+		UserID:     scope.UserID,
+		Action:     models.AuditUpdated,
+		EntityType: models.AuditEntityTransaction,
+		EntityID:   existing.ID,
+		Before:     &before,
+		After:      existing,
+	})
 	return existing, nil
 }
 
-func (s *transactionService) Delete(ctx context.Context, userID, id string) error {
-	return s.repo.Delete(ctx, id, userID)
+func (s *transactionService) Delete(ctx context.Context, scope repositories.Scope, id string) error {
+	existing, err := s.repo.FindByID(ctx, id, scope)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id, scope); err != nil {
+		return err
+	}
+	if existing != nil {
+		if err := s.splits.DeleteByTransaction(ctx, id); err != nil {
+			log.Printf("splits: remove split of deleted transaction %s: %v", id, err)
+		}
+		if err := s.attachments.DeleteByTransaction(ctx, scope, id); err != nil {
+			log.Printf("attachments: remove attachments of deleted transaction %s: %v", id, err)
+		}
+	}
+	s.audit.Record(ctx, AuditEntry{
+		UserID:     scope.UserID,
+		Action:     models.AuditDeleted,
+		EntityType: models.AuditEntityTransaction,
+		EntityID:   id,
+		Before:     existing,
+	})
+	return nil
+}
+
+// validateTransaction checks the fields every transaction needs.
+func validateTransaction(tx *models.Transaction) error {
+	if tx.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if tx.Type != models.TransactionTypeIncome && tx.Type != models.TransactionTypeExpense {
+		return errors.New("invalid type")
+	}
+	return nil
 }
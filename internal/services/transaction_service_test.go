@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ronak4195/personal-assistant/internal/models"
+	"github.com/ronak4195/personal-assistant/internal/repositories"
+)
+
+// memCategoryRepo finds categories only within the scope, like the Mongo
+// filter does.
+type memCategoryRepo struct {
+	repositories.CategoryRepository
+	byID map[string]models.Category
+}
+
+func (m *memCategoryRepo) FindByID(_ context.Context, id string, scope repositories.Scope) (*models.Category, error) {
+	c, ok := m.byID[id]
+	if !ok || c.HouseholdID != scope.HouseholdID || (!scope.IsHousehold() && c.UserID != scope.UserID) {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+type memTransactionStore struct {
+	repositories.TransactionRepository
+	byID map[string]models.Transaction
+}
+
+func (m *memTransactionStore) Create(_ context.Context, tx *models.Transaction) error {
+	tx.ID = "tx-1"
+	m.byID[tx.ID] = *tx
+	return nil
+}
+
+func (m *memTransactionStore) FindByID(_ context.Context, id string, _ repositories.Scope) (*models.Transaction, error) {
+	if tx, ok := m.byID[id]; ok {
+		return &tx, nil
+	}
+	return nil, nil
+}
+
+func (m *memTransactionStore) Update(_ context.Context, _ repositories.Scope, tx *models.Transaction) error {
+	m.byID[tx.ID] = *tx
+	return nil
+}
+
+func newTestTransactionService() *transactionService {
+	return &transactionService{
+		repo: &memTransactionStore{byID: map[string]models.Transaction{}},
+		categoryRepo: &memCategoryRepo{byID: map[string]models.Category{
+			"shared":   {ID: "shared", UserID: "alice", HouseholdID: "h1"},
+			"personal": {ID: "personal", UserID: "bob"},
+		}},
+		audit: nopAudit{},
+	}
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestCreateTransactionChecksCategory(t *testing.T) {
+	ctx := context.Background()
+	scope := repositories.HouseholdScope("bob", "h1")
+
+	tests := []struct {
+		name     string
+		category *string
+		sub      *string
+		want     error
+	}{
+		{"household category", strPtr("shared"), nil, nil},
+		{"no category", nil, nil, nil},
+		{"missing category", strPtr("gone"), nil, ErrCategoryNotFound},
+		{"member's personal category", strPtr("personal"), nil, ErrCategoryNotFound},
+		{"missing subcategory", strPtr("shared"), strPtr("gone"), ErrCategoryNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestTransactionService()
+			tx := &models.Transaction{Amount: 10, Currency: "EUR", Type: models.TransactionTypeExpense, CategoryID: tt.category, SubcategoryID: tt.sub}
+			if _, err := svc.Create(ctx, scope, tx); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateTransactionChecksCategory(t *testing.T) {
+	ctx := context.Background()
+	scope := repositories.HouseholdScope("bob", "h1")
+	svc := newTestTransactionService()
+	created, err := svc.Create(ctx, scope, &models.Transaction{Amount: 10, Currency: "EUR", Type: models.TransactionTypeExpense})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"gone", "personal"} {
+		_, err := svc.Update(ctx, scope, &models.Transaction{ID: created.ID, CategoryID: strPtr(id)})
+		if !errors.Is(err, ErrCategoryNotFound) {
+			t.Errorf("category %s: err = %v, want ErrCategoryNotFound", id, err)
+		}
+		_, err = svc.Update(ctx, scope, &models.Transaction{ID: created.ID, SubcategoryID: strPtr(id)})
+		if !errors.Is(err, ErrCategoryNotFound) {
+			t.Errorf("subcategory %s: err = %v, want ErrCategoryNotFound", id, err)
+		}
+	}
+	stored, _ := svc.repo.FindByID(ctx, created.ID, scope)
+	if stored.CategoryID != nil || stored.SubcategoryID != nil {
+		t.Errorf("rejected category was stored: %+v", stored)
+	}
+
+	updated, err := svc.Update(ctx, scope, &models.Transaction{ID: created.ID, CategoryID: strPtr("shared")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.CategoryID == nil || *updated.CategoryID != "shared" {
+		t.Errorf("categoryId = %v, want shared", updated.CategoryID)
+	}
+}
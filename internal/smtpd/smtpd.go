@@ -0,0 +1,395 @@
+// Package smtpd is a small SMTP server for receiving mail addressed to this
+// application. It implements what mail servers need to deliver to it
+// (RFC 5321 without TLS or authentication) and hands each message over
+// whole.
+package smtpd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxLineLength  = 2048
+	maxRecipients  = 100
+	commandTimeout = 5 * time.Minute
+	dataTimeout    = 10 * time.Minute
+)
+
+var ErrServerClosed = errors.New("smtpd: server closed")
+
+// Rejection is an error Deliver returns to refuse a message for good; the
+// sender is told not to retry. Any other error is reported as temporary.
+type Rejection string
+
+func (r Rejection) Error() string { return string(r) }
+
+type Server struct {
+	Addr string
+	// Hostname is announced in the greeting.
+	Hostname string
+	// MaxSize is the largest message accepted, in bytes.
+	MaxSize int64
+	// MaxConns bounds the sessions served at once; 0 means 50.
+	MaxConns int
+	// Recipient reports whether mail for the address is accepted.
+	Recipient func(ctx context.Context, addr string) (bool, error)
+	// Deliver receives each message, as sent, with its envelope.
+	Deliver func(ctx context.Context, from string, to []string, data []byte) error
+
+	mu       sync.Mutex
+	ln       net.Listener
+	conns    map[net.Conn]struct{}
+	closing  bool
+	sessions sync.WaitGroup
+}
+
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln until Shutdown is called, when it
+// returns ErrServerClosed.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		ln.Close()
+		return ErrServerClosed
+	}
+	s.ln = ln
+	s.conns = map[net.Conn]struct{}{}
+	s.mu.Unlock()
+
+	maxConns := s.MaxConns
+	if maxConns <= 0 {
+		maxConns = 50
+	}
+	slots := make(chan struct{}, maxConns)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closing := s.closing
+			s.mu.Unlock()
+			if closing {
+				return ErrServerClosed
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			fmt.Fprintf(conn, "421 4.3.2 Too many connections, try again later\r\n")
+			conn.Close()
+			continue
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.sessions.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				conn.Close()
+				<-slots
+				s.sessions.Done()
+			}()
+			s.serve(conn)
+		}()
+	}
+}
+
+// Shutdown stops accepting connections and waits for open sessions to end,
+// closing them when ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	if s.ln != nil {
+		s.ln.Close()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.sessions.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for c := range s.conns {
+			c.Close()
+		}
+		s.mu.Unlock()
+		<-done
+		return ctx.Err()
+	}
+}
+
+type session struct {
+	srv  *Server
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+
+	helo string
+	from *string
+	to   []string
+}
+
+func (s *Server) serve(conn net.Conn) {
+	ss := &session{
+		srv:  s,
+		conn: conn,
+		r:    bufio.NewReaderSize(conn, maxLineLength),
+		w:    bufio.NewWriter(conn),
+	}
+	hostname := s.Hostname
+	if hostname == "" {
+		hostname = "localhost"
+	}
+	ss.reply(220, hostname+" ESMTP ready")
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(commandTimeout))
+		line, err := ss.readLine()
+		if err != nil {
+			if errors.Is(err, bufio.ErrBufferFull) {
+				ss.reply(500, "5.5.2 Line too long")
+			}
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			ss.reset()
+			ss.helo = arg
+			ss.reply(250, hostname)
+		case "EHLO":
+			ss.reset()
+			ss.helo = arg
+			ss.reply(250, hostname, "SIZE "+strconv.FormatInt(s.MaxSize, 10), "8BITMIME", "PIPELINING")
+		case "MAIL":
+			ss.mail(arg)
+		case "RCPT":
+			ss.rcpt(arg)
+		case "DATA":
+			if !ss.data() {
+				return
+			}
+		case "RSET":
+			ss.reset()
+			ss.reply(250, "2.0.0 OK")
+		case "NOOP":
+			ss.reply(250, "2.0.0 OK")
+		case "VRFY":
+			ss.reply(252, "2.5.0 Cannot verify, but will accept and attempt delivery")
+		case "QUIT":
+			ss.reply(221, "2.0.0 Bye")
+			return
+		case "STARTTLS", "AUTH":
+			ss.reply(502, "5.5.1 Not implemented")
+		default:
+			ss.reply(500, "5.5.2 Unknown command")
+		}
+	}
+}
+
+// readData reads the message up to the line holding a single ".",
+// undoing dot-stuffing. Line endings are kept as sent. A message over
+// MaxSize is read to the end, so the session stays in step with the sender,
+// but not kept.
+func (ss *session) readData() (data []byte, tooBig bool, err error) {
+	var buf bytes.Buffer
+	lineStart := true
+	for {
+		chunk, err := ss.r.ReadSlice('\n')
+		if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+			return nil, false, err
+		}
+		if lineStart {
+			if bytes.Equal(chunk, []byte(".\r\n")) || bytes.Equal(chunk, []byte(".\n")) {
+				return buf.Bytes(), tooBig, nil
+			}
+			chunk = bytes.TrimPrefix(chunk, []byte("."))
+		}
+		lineStart = err == nil
+
+		if tooBig {
+			continue
+		}
+		if int64(buf.Len()+len(chunk)) > ss.srv.MaxSize {
+			tooBig = true
+			buf = bytes.Buffer{}
+			continue
+		}
+		buf.Write(chunk)
+	}
+}
+
+func (ss *session) readLine() (string, error) {
+	line, err := ss.r.ReadSlice('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// reply writes a response; more than one line makes a multi-line reply.
+func (ss *session) reply(code int, lines ...string) {
+	ss.conn.SetWriteDeadline(time.Now().Add(time.Minute))
+	for i, l := range lines {
+		sep := "-"
+		if i == len(lines)-1 {
+			sep = " "
+		}
+		fmt.Fprintf(ss.w, "%d%s%s\r\n", code, sep, l)
+	}
+	ss.w.Flush()
+}
+
+func (ss *session) reset() {
+	ss.from = nil
+	ss.to = nil
+}
+
+// path reads the address from "FROM:<addr> PARAMS" or "TO:<addr>".
+func path(arg, prefix string) (addr, params string, ok bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	rest := strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(rest, "<") {
+		return "", "", false
+	}
+	end := strings.IndexByte(rest, '>')
+	if end < 0 {
+		return "", "", false
+	}
+	return rest[1:end], strings.TrimSpace(rest[end+1:]), true
+}
+
+func (ss *session) mail(arg string) {
+	if ss.helo == "" {
+		ss.reply(503, "5.5.1 Send HELO or EHLO first")
+		return
+	}
+	if ss.from != nil {
+		ss.reply(503, "5.5.1 Sender already given")
+		return
+	}
+	from, params, ok := path(arg, "FROM:")
+	if !ok {
+		ss.reply(501, "5.5.4 Syntax: MAIL FROM:<address>")
+		return
+	}
+	for _, p := range strings.Fields(params) {
+		k, v, _ := strings.Cut(p, "=")
+		if strings.EqualFold(k, "SIZE") {
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > ss.srv.MaxSize {
+				ss.reply(552, "5.3.4 Message too big")
+				return
+			}
+		}
+	}
+	ss.from = &from
+	ss.reply(250, "2.1.0 OK")
+}
+
+func (ss *session) rcpt(arg string) {
+	if ss.from == nil {
+		ss.reply(503, "5.5.1 Send MAIL first")
+		return
+	}
+	to, _, ok := path(arg, "TO:")
+	if !ok || to == "" {
+		ss.reply(501, "5.5.4 Syntax: RCPT TO:<address>")
+		return
+	}
+	if len(ss.to) >= maxRecipients {
+		ss.reply(452, "4.5.3 Too many recipients")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	accepted, err := ss.srv.Recipient(ctx, to)
+	switch {
+	case err != nil:
+		log.Printf("smtpd: check recipient %s: %v", to, err)
+		ss.reply(451, "4.3.0 Temporary failure, try again later")
+	case !accepted:
+		ss.reply(550, "5.1.1 No such mailbox")
+	default:
+		ss.to = append(ss.to, to)
+		ss.reply(250, "2.1.5 OK")
+	}
+}
+
+// data reads and delivers the message. It returns false when the
+// connection can no longer be used.
+func (ss *session) data() bool {
+	if len(ss.to) == 0 {
+		ss.reply(503, "5.5.1 Send RCPT first")
+		return true
+	}
+	ss.reply(354, "End data with <CR><LF>.<CR><LF>")
+
+	ss.conn.SetReadDeadline(time.Now().Add(dataTimeout))
+	data, tooBig, err := ss.readData()
+	if err != nil {
+		return false
+	}
+	if tooBig {
+		ss.reset()
+		ss.reply(552, "5.3.4 Message too big")
+		return true
+	}
+
+	from, to := *ss.from, ss.to
+	ss.reset()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	err = ss.srv.Deliver(ctx, from, to, data)
+	var rejection Rejection
+	switch {
+	case errors.As(err, &rejection):
+		ss.reply(554, "5.6.0 "+rejection.Error())
+	case err != nil:
+		log.Printf("smtpd: deliver message from %s: %v", from, err)
+		ss.reply(451, "4.3.0 Temporary failure, try again later")
+	default:
+		ss.reply(250, "2.0.0 OK: queued")
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+// Package storage keeps uploaded files outside the database, on the local
+// filesystem or in an S3-compatible bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var ErrNotFound = errors.New("storage: object not found")
+
+// Store holds opaque objects under slash-separated keys such as
+// "attachments/abc123".
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Open returns ErrNotFound for a missing key.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete succeeds when the key is already gone.
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey rejects keys that could escape the store's root.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}